@@ -0,0 +1,151 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	shared_aws "github.com/naemono/go-cloud-actions/cmd/shared/aws"
+	shared_azure "github.com/naemono/go-cloud-actions/cmd/shared/azure"
+	shared_google "github.com/naemono/go-cloud-actions/cmd/shared/google"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	// RootCmd is the apply command
+	RootCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Apply a manifest of resources in any public cloud",
+		Long: `A cli to create the resources of a multi-document yaml manifest which do not exist yet, and update
+the ones which differ from the manifest, in dependency order.  Applying the same manifest again
+leaves existing resources unchanged.
+
+Every document is a resource of one of the kinds ResourceGroup, NetworkProfile, VPC, Subnet, Peering,
+ContainerGroup or GKECluster, and can reference the outputs of other resources, such as the id of a
+VPC, as ${VPC/name.id}.  Resources are applied after the resources they reference, and after the ones
+listed in metadata.dependsOn.
+
+Every provider's auth flags are accepted, and only the providers used by the manifest need credentials.
+
+With --plan, the changes applying the manifest would make are printed without making them.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared_azure.PersistentPreRun(cmd, args)
+			shared_aws.PersistentPreRun(cmd, args)
+			shared_google.PersistentPreRun(cmd, args)
+			viper.BindPFlag("filename", cmd.Flags().Lookup("filename"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"filename"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planManifest)
+			}
+			return applyManifest()
+		},
+	}
+)
+
+func init() {
+	RootCmd.Flags().StringP("filename", "f", "", "manifest to apply, or - to read it from stdin")
+	shared.AddPlanFlag(RootCmd)
+	shared_azure.AddAuthFlagsToCommand(RootCmd)
+	shared_aws.AddAuthFlagsToCommand(RootCmd)
+	shared_google.AddAuthFlagsToCommand(RootCmd)
+}
+
+// applyConfig will return the config of the applier, which tags every created resource with the tags of the command
+func applyConfig() (apply.Config, error) {
+	t, err := shared.Tags()
+	if err != nil {
+		return apply.Config{}, err
+	}
+	return apply.Config{
+		Azure:  shared_azure.AuthConfig(),
+		AWS:    shared_aws.AuthConfig(),
+		Google: shared_google.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+		Tags:   t,
+	}, nil
+}
+
+func planManifest() (plan.Plan, error) {
+	resources, err := apply.ReadFile(viper.GetString("filename"))
+	if err != nil {
+		return plan.Plan{}, err
+	}
+	conf, err := applyConfig()
+	if err != nil {
+		return plan.Plan{}, err
+	}
+	return shared.PlanResources(conf, resources...)
+}
+
+func applyManifest() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	resources, err := apply.ReadFile(viper.GetString("filename"))
+	if err != nil {
+		return err
+	}
+	conf, err := applyConfig()
+	if err != nil {
+		return err
+	}
+	applier, err := apply.New(conf)
+	if err != nil {
+		return err
+	}
+	logger.Infof("applying %d resources", len(resources))
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	results, err := applier.Apply(ctx, resources)
+	if len(results) > 0 {
+		if perr := printResults(results); perr != nil && err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+func printResults(results []apply.Result) error {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "kind"},
+			{Header: "name"},
+			{Header: "action"},
+			{Header: "id"},
+			{Header: "changes", Wide: true},
+			{Header: "outputs", Wide: true},
+		},
+	}
+	for _, r := range results {
+		changes := make([]string, 0, len(r.Changes))
+		for _, c := range r.Changes {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", c.Field, c.Actual, c.Desired))
+		}
+		outputs := make([]string, 0, len(r.Outputs))
+		for k, v := range r.Outputs {
+			outputs = append(outputs, k+"="+v)
+		}
+		sort.Strings(outputs)
+		table.AddRow(
+			string(r.Kind),
+			r.Name,
+			string(r.Action),
+			r.Outputs["id"],
+			strings.Join(changes, ", "),
+			strings.Join(outputs, ","))
+	}
+	return shared.Print(results, table)
+}
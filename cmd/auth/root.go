@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	shared_google "github.com/naemono/go-cloud-actions/cmd/shared/google"
+	auth_google "github.com/naemono/go-cloud-actions/pkg/auth/google"
+	"github.com/naemono/go-cloud-actions/pkg/kubeconfig"
+)
+
+var (
+	// RootCmd is the base auth command for all clouds
+	RootCmd = &cobra.Command{
+		Use:   "auth",
+		Short: "Mint credentials of public clouds",
+		Long:  `A cli to mint credentials of public clouds for other tools, such as kubectl.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+	gkeTokenCmd = &cobra.Command{
+		Use:   "gke-token",
+		Short: "print an exec credential of gke clusters for kubectl",
+		Long: `A kubectl exec credential plugin printing an access token of gke clusters, minted from the credentials
+of the google auth flags.  It is configured in kubeconfigs by compute google get-credentials.`,
+		PersistentPreRun: shared_google.PersistentPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return gkeToken()
+		},
+	}
+)
+
+func init() {
+	shared_google.AddAuthFlagsToCommand(gkeTokenCmd)
+	RootCmd.AddCommand(gkeTokenCmd)
+}
+
+func gkeToken() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ts, err := auth_google.TokenSource(ctx, shared_google.AuthConfig(), auth_google.CloudPlatformScope)
+	if err != nil {
+		return err
+	}
+	token, err := ts.Token()
+	if err != nil {
+		return errors.Wrap(err, "failed to mint google access token")
+	}
+	credential := kubeconfig.ExecCredential{
+		APIVersion: kubeconfig.ExecAPIVersion,
+		Kind:       "ExecCredential",
+		Status:     kubeconfig.ExecCredentialStatus{Token: token.AccessToken},
+	}
+	if !token.Expiry.IsZero() {
+		credential.Status.ExpirationTimestamp = token.Expiry.UTC().Format(time.RFC3339)
+	}
+	return json.NewEncoder(os.Stdout).Encode(credential)
+}
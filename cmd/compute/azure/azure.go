@@ -2,22 +2,25 @@ package azure
 
 import (
 	"context"
-	"encoding/json"
 	"io/ioutil"
 	"time"
 
-	"gopkg.in/yaml.v3"
-
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
 
+	"github.com/naemono/go-cloud-actions/cmd/shared"
 	shared_azure "github.com/naemono/go-cloud-actions/cmd/shared/azure"
-	auth_azure "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	"github.com/naemono/go-cloud-actions/pkg/convert"
 	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
 	azure_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/azure"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
 	"github.com/naemono/go-cloud-actions/pkg/validate"
 )
 
@@ -38,11 +41,15 @@ var (
 				cmd.Parent().PersistentPreRun(cmd.Parent(), args)
 			}
 			viper.BindPFlag("file", cmd.Flags().Lookup("file"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(viper.GetViper(), []string{"file"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateContainersGroup)
+			}
 			return createContainersGroup()
 		},
 	}
@@ -52,6 +59,7 @@ func init() {
 	shared_azure.AddAuthFlagsToCommand(AzureCmd)
 
 	computeCreateContainerInstanceCmd.Flags().StringP("file", "f", "", "container yaml file to deploy")
+	shared.AddPlanFlag(computeCreateContainerInstanceCmd)
 
 	AzureCmd.AddCommand(computeCreateContainerInstanceCmd)
 }
@@ -59,13 +67,12 @@ func init() {
 func createContainersGroup() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("creating containers group")
+	t, err := shared.Tags()
+	if err != nil {
+		return err
+	}
 	client, err := azure_serverless.New(azure_serverless.Config{
-		AuthConfig: auth_azure.AuthConfig{
-			SubscriptionID: viper.GetString("subscription-id"),
-			ClientID:       viper.GetString("client-id"),
-			ClientSecret:   viper.GetString("client-secret"),
-			TenantID:       viper.GetString("tenant-id"),
-		},
+		AuthConfig: shared_azure.AuthConfig(),
 	})
 	if err != nil {
 		return err
@@ -77,34 +84,59 @@ func createContainersGroup() error {
 	if err != nil {
 		return err
 	}
+	req.Tags = tags.Merge(t, req.Tags)
 	logger.Infof("creating container group with request: %+v", req)
 	var cg containerinstance.ContainerGroup
 	cg, err = client.CreateContainerGroup(ctx, req)
 	if err != nil {
 		return err
 	}
-	logger.Infof("container group '%s' created", *cg.Name)
-	return nil
-}
-
-func readContainersFile(filename string) (request azure_serverless.CreateContainerRequest, err error) {
-	var fileBytes []byte
-	fileBytes, err = ioutil.ReadFile(filename)
-	if err != nil {
-		return request, errors.Wrap(err, "failed to read file")
+	logger.Infof("container group '%s' created", to.String(cg.Name))
+	state, ip := "", ""
+	if cg.ContainerGroupProperties != nil {
+		state = to.String(cg.ProvisioningState)
+		if cg.IPAddress != nil {
+			ip = to.String(cg.IPAddress.IP)
+		}
 	}
-	temp := map[string]interface{}{}
-	err = yaml.Unmarshal(fileBytes, &temp)
-	if err != nil {
-		return request, errors.Wrap(err, "failed to encode containers yaml file into valid map")
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "location"},
+			{Header: "state"},
+			{Header: "ip"},
+			{Header: "id", Wide: true},
+		},
 	}
-	fileBytes, err = json.Marshal(&temp)
+	table.AddRow(to.String(cg.Name), to.String(cg.Location), state, ip, to.String(cg.ID))
+	return shared.Print(cg, table)
+}
+
+// planCreateContainersGroup will plan the container group as a manifest ContainerGroup, whose containers are
+// updated in place when their images differ
+func planCreateContainersGroup() (plan.Plan, error) {
+	req, err := readContainersFile(viper.GetString("file"))
 	if err != nil {
-		return request, errors.Wrap(err, "failed to marshal map back to json")
+		return plan.Plan{}, err
 	}
-	err = json.Unmarshal(fileBytes, &request)
+	return shared.PlanResources(apply.Config{
+		Azure:  shared_azure.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+	}, apply.Resource{
+		Kind:     apply.KindContainerGroup,
+		Metadata: apply.Metadata{Name: req.ContainerGroupName},
+		Spec: map[string]interface{}{
+			"resourceGroup": req.ResourceGroupName,
+			"location":      req.Location,
+			"properties":    req.ContainerGroupProperties,
+		},
+	})
+}
+
+func readContainersFile(filename string) (azure_serverless.CreateContainerRequest, error) {
+	fileBytes, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return request, errors.Wrap(err, "failed to encode containers yaml file into valid json")
+		return azure_serverless.CreateContainerRequest{}, errors.Wrap(err, "failed to read file")
 	}
-	return
+	return convert.ParseContainerGroup(fileBytes)
 }
@@ -0,0 +1,473 @@
+package azure
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	shared_azure "github.com/naemono/go-cloud-actions/cmd/shared/azure"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	azure_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/azure"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	containerInstanceCmd = &cobra.Command{
+		Use:     "container-instance",
+		Aliases: []string{"aci"},
+		Short:   "control container instances in azure's public clouds",
+		Long:    `A cli to control the container groups of container instances in Azure's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("resource-group", cmd.Flags().Lookup("resource-group"))
+		},
+	}
+	containerInstanceListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list container groups in azure's public clouds",
+		Long:  `A cli to list the container groups of a resource group in Azure's public cloud.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"resource-group"}); err != nil {
+				return err
+			}
+			return listContainerGroups()
+		},
+	}
+	containerInstanceGetCmd = &cobra.Command{
+		Use:   "get",
+		Short: "get container group in azure's public clouds",
+		Long: `A cli to get a container group in Azure's public cloud, along with the state, restart count and
+events of each of its containers.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"resource-group", "name"}); err != nil {
+				return err
+			}
+			return getContainerGroup()
+		},
+	}
+	containerInstanceLogsCmd = &cobra.Command{
+		Use:   "logs",
+		Short: "print the logs of a container in azure's public clouds",
+		Long: `A cli to print the logs of a container of a container group in Azure's public cloud, and with
+--follow, to keep printing the lines written since every --interval until interrupted.  --container can
+be left out of container groups with a single container.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("container", cmd.Flags().Lookup("container"))
+			viper.BindPFlag("tail", cmd.Flags().Lookup("tail"))
+			viper.BindPFlag("timestamps", cmd.Flags().Lookup("timestamps"))
+			viper.BindPFlag("follow", cmd.Flags().Lookup("follow"))
+			viper.BindPFlag("interval", cmd.Flags().Lookup("interval"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"resource-group", "name"}); err != nil {
+				return err
+			}
+			return containerLogs()
+		},
+	}
+	containerInstanceExecCmd = &cobra.Command{
+		Use:   "exec",
+		Short: "execute a command in a container in azure's public clouds",
+		Long: `A cli to execute a command, such as a shell, in a container of a container group in Azure's public
+cloud, connecting the terminal to it until it exits.  Azure does not split the command into arguments.
+--container can be left out of container groups with a single container.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("container", cmd.Flags().Lookup("container"))
+			viper.BindPFlag("command", cmd.Flags().Lookup("command"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"resource-group", "name", "command"}); err != nil {
+				return err
+			}
+			return execContainer()
+		},
+	}
+	containerInstanceRestartCmd = &cobra.Command{
+		Use:   "restart",
+		Short: "restart container group in azure's public clouds",
+		Long:  `A cli to restart every container of a container group in place in Azure's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"resource-group", "name"}); err != nil {
+				return err
+			}
+			return changeContainerGroup("restarting", (*azure_serverless.Client).RestartContainerGroup)
+		},
+	}
+	containerInstanceStopCmd = &cobra.Command{
+		Use:   "stop",
+		Short: "stop container group in azure's public clouds",
+		Long: `A cli to stop every container of a container group in Azure's public cloud, which keeps its
+configuration, but is no longer billed, until it is started again.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"resource-group", "name"}); err != nil {
+				return err
+			}
+			return changeContainerGroup("stopping", (*azure_serverless.Client).StopContainerGroup)
+		},
+	}
+	containerInstanceStartCmd = &cobra.Command{
+		Use:   "start",
+		Short: "start container group in azure's public clouds",
+		Long:  `A cli to start every container of a stopped container group in Azure's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"resource-group", "name"}); err != nil {
+				return err
+			}
+			return changeContainerGroup("starting", (*azure_serverless.Client).StartContainerGroup)
+		},
+	}
+	containerInstanceDeleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete container group in azure's public clouds",
+		Long:  `A cli to delete a container group, waiting for it to be deleted, in Azure's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"resource-group", "name"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeleteContainerGroup)
+			}
+			return deleteContainerGroup()
+		},
+	}
+)
+
+func init() {
+	containerInstanceCmd.PersistentFlags().StringP("resource-group", "r", "", "name of resource group")
+
+	for _, cmd := range []*cobra.Command{
+		containerInstanceGetCmd,
+		containerInstanceLogsCmd,
+		containerInstanceExecCmd,
+		containerInstanceRestartCmd,
+		containerInstanceStopCmd,
+		containerInstanceStartCmd,
+		containerInstanceDeleteCmd,
+	} {
+		cmd.Flags().StringP("name", "n", "", "name of container group")
+		containerInstanceCmd.AddCommand(cmd)
+	}
+	containerInstanceCmd.AddCommand(containerInstanceListCmd)
+
+	containerInstanceLogsCmd.Flags().String("container", "", "name of container (default the only container of the group)")
+	containerInstanceLogsCmd.Flags().Int("tail", 0, "number of lines from the end of the logs to print (default all)")
+	containerInstanceLogsCmd.Flags().Bool("timestamps", false, "prefix every line with the time it was written")
+	containerInstanceLogsCmd.Flags().BoolP("follow", "f", false, "keep printing new lines until interrupted")
+	containerInstanceLogsCmd.Flags().Duration("interval", 5*time.Second, "interval between polls for new lines with --follow")
+
+	containerInstanceExecCmd.Flags().String("container", "", "name of container (default the only container of the group)")
+	containerInstanceExecCmd.Flags().String("command", "/bin/sh", "command to execute")
+
+	shared.AddPlanFlag(containerInstanceDeleteCmd)
+
+	AzureCmd.AddCommand(containerInstanceCmd)
+}
+
+func newServerlessClient() (*azure_serverless.Client, error) {
+	return azure_serverless.New(azure_serverless.Config{
+		AuthConfig: shared_azure.AuthConfig(),
+		Logger:     logging.GetLogger(viper.GetString("loglevel")),
+	})
+}
+
+func listContainerGroups() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	groups, err := client.ListContainerGroups(ctx, viper.GetString("resource-group"))
+	if err != nil {
+		return err
+	}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "location"},
+			{Header: "state"},
+			{Header: "containers"},
+			{Header: "ip"},
+			{Header: "id", Wide: true},
+		},
+	}
+	for _, cg := range groups {
+		state, containers, ip := containerGroupSummary(cg)
+		table.AddRow(to.String(cg.Name), to.String(cg.Location), state, strconv.Itoa(containers), ip, to.String(cg.ID))
+	}
+	return shared.Print(groups, table)
+}
+
+func getContainerGroup() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cg, err := client.GetContainerGroup(ctx, viper.GetString("resource-group"), viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(cg, containerGroupTable(cg))
+}
+
+// containerGroupTable will return a table of the containers of a container group, with their state and restart
+// count, each followed by its events, and the events of the group itself
+func containerGroupTable(cg containerinstance.ContainerGroup) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "container"},
+			{Header: "image"},
+			{Header: "state"},
+			{Header: "restarts"},
+			{Header: "time"},
+			{Header: "event"},
+		},
+	}
+	if cg.ContainerGroupProperties == nil {
+		return table
+	}
+	if cg.InstanceView != nil {
+		table.AddRow("", "", to.String(cg.InstanceView.State), "", "", "")
+		addEventRows(&table, cg.InstanceView.Events)
+	}
+	if cg.Containers == nil {
+		return table
+	}
+	for _, container := range *cg.Containers {
+		state, restarts := "", ""
+		var events *[]containerinstance.Event
+		if container.ContainerProperties != nil && container.ContainerProperties.InstanceView != nil {
+			view := container.ContainerProperties.InstanceView
+			if view.CurrentState != nil {
+				state = to.String(view.CurrentState.DetailStatus)
+				if state == "" {
+					state = to.String(view.CurrentState.State)
+				}
+			}
+			if view.RestartCount != nil {
+				restarts = strconv.Itoa(int(*view.RestartCount))
+			}
+			events = view.Events
+		}
+		image := ""
+		if container.ContainerProperties != nil {
+			image = to.String(container.Image)
+		}
+		table.AddRow(to.String(container.Name), image, state, restarts, "", "")
+		addEventRows(&table, events)
+	}
+	return table
+}
+
+func addEventRows(table *printer.Table, events *[]containerinstance.Event) {
+	if events == nil {
+		return
+	}
+	for _, event := range *events {
+		at := ""
+		if event.LastTimestamp != nil {
+			at = event.LastTimestamp.Format(time.RFC3339)
+		}
+		message := to.String(event.Name)
+		if to.String(event.Message) != "" {
+			message += ": " + to.String(event.Message)
+		}
+		if event.Count != nil && *event.Count > 1 {
+			message += " (x" + strconv.Itoa(int(*event.Count)) + ")"
+		}
+		table.AddRow("", "", "", "", at, message)
+	}
+}
+
+// containerGroupSummary will return the state, number of containers, and ip of a container group
+func containerGroupSummary(cg containerinstance.ContainerGroup) (state string, containers int, ip string) {
+	if cg.ContainerGroupProperties == nil {
+		return "", 0, ""
+	}
+	state = to.String(cg.ProvisioningState)
+	if cg.InstanceView != nil && to.String(cg.InstanceView.State) != "" {
+		state = to.String(cg.InstanceView.State)
+	}
+	if cg.Containers != nil {
+		containers = len(*cg.Containers)
+	}
+	if cg.IPAddress != nil {
+		ip = to.String(cg.IPAddress.IP)
+	}
+	return state, containers, ip
+}
+
+// interruptContext will return a context which is cancelled once the command is interrupted
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+func containerLogs() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	req := azure_serverless.LogsRequest{
+		ResourceGroupName:  viper.GetString("resource-group"),
+		ContainerGroupName: viper.GetString("name"),
+		ContainerName:      viper.GetString("container"),
+		Tail:               viper.GetInt("tail"),
+		Timestamps:         viper.GetBool("timestamps"),
+	}
+	if viper.GetBool("follow") {
+		ctx, cancel := interruptContext()
+		defer cancel()
+		return client.FollowContainerLogs(ctx, req, viper.GetDuration("interval"), os.Stdout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	logs, err := client.ContainerLogs(ctx, req)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.WriteString(logs)
+	return err
+}
+
+// execContainer will execute the command in the container, with the terminal in raw mode when stdin is a terminal,
+// so that every key is sent to the command as it is typed
+func execContainer() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	req := azure_serverless.ExecRequest{
+		ResourceGroupName:  viper.GetString("resource-group"),
+		ContainerGroupName: viper.GetString("name"),
+		ContainerName:      viper.GetString("container"),
+		Command:            viper.GetString("command"),
+		Rows:               24,
+		Cols:               80,
+	}
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		if cols, rows, err := term.GetSize(fd); err == nil {
+			req.Rows, req.Cols = rows, cols
+		}
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return errors.Wrap(err, "failed to put terminal in raw mode")
+		}
+		defer term.Restore(fd, state)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return client.Exec(ctx, req, os.Stdin, os.Stdout)
+}
+
+// changeContainerGroup will make a change, such as restarting, to the container group of the name flag, and print
+// the resulting container group
+func changeContainerGroup(action string, change func(*azure_serverless.Client, context.Context, string, string) error) error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	logger.Infof("%s container group %s", action, viper.GetString("name"))
+	if err = change(client, ctx, viper.GetString("resource-group"), viper.GetString("name")); err != nil {
+		return err
+	}
+	cg, err := client.GetContainerGroup(ctx, viper.GetString("resource-group"), viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(cg, containerGroupTable(cg))
+}
+
+func deleteContainerGroup() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err = client.DeleteContainerGroup(ctx, viper.GetString("resource-group"), viper.GetString("name")); err != nil {
+		return err
+	}
+	logger.Infof("container group '%s' deleted", viper.GetString("name"))
+	return nil
+}
+
+// planDeleteContainerGroup will plan the deletion of the container group, when it exists
+func planDeleteContainerGroup() (plan.Plan, error) {
+	var p plan.Plan
+	client, err := newServerlessClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cg, err := client.GetContainerGroup(ctx, viper.GetString("resource-group"), viper.GetString("name"))
+	if err == azure_serverless.ErrNotFound {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	state, _, ip := containerGroupSummary(cg)
+	p.Add(plan.Delete(string(apply.KindContainerGroup), viper.GetString("name"), map[string]string{
+		"id":    to.String(cg.ID),
+		"state": state,
+		"ip":    ip,
+	}))
+	return p, nil
+}
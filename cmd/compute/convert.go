@@ -0,0 +1,113 @@
+package compute
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	"github.com/naemono/go-cloud-actions/pkg/convert"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+const (
+	// toAzure converts a kubernetes Deployment into an azure container group
+	toAzure = "azure"
+	// toKubernetes converts an azure container group into a kubernetes Deployment
+	toKubernetes = "kubernetes"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "convert between kubernetes deployments and azure container groups",
+	Long: `A cli to convert a kubernetes Deployment, along with the ConfigMaps of its volumes and environment, into an
+azure container group file, as given to compute azure create-container-instance, or such a file into a Deployment
+and ConfigMaps.  The result is printed, and the fields which cannot be converted are warned about.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		shared.RunParentsPersistentPreRun(cmd, args)
+		viper.BindPFlag("file", cmd.Flags().Lookup("file"))
+		viper.BindPFlag("to", cmd.Flags().Lookup("to"))
+		viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+		viper.BindPFlag("resource-group", cmd.Flags().Lookup("resource-group"))
+		viper.BindPFlag("location", cmd.Flags().Lookup("location"))
+		viper.BindPFlag("ip-address-type", cmd.Flags().Lookup("ip-address-type"))
+		viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace"))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validate.NotEmpty(viper.GetViper(), []string{"file", "to"}); err != nil {
+			return err
+		}
+		return convertFile()
+	},
+}
+
+func init() {
+	convertCmd.Flags().StringP("file", "f", "", "kubernetes yaml, or azure container group yaml, file to convert")
+	convertCmd.Flags().String("to", "", "format to convert to, azure or kubernetes")
+	convertCmd.Flags().StringP("name", "n", "", "name of the container group or deployment (default that of the converted one)")
+	convertCmd.Flags().StringP("resource-group", "r", "", "resource group of the container group, converting to azure")
+	convertCmd.Flags().StringP("location", "L", "", "location of the container group, converting to azure")
+	convertCmd.Flags().String("ip-address-type", "", "expose the ports of the container group on a Public or Private ip address, converting to azure (default none)")
+	convertCmd.Flags().String("namespace", "", "namespace of the deployment and configmaps, converting to kubernetes")
+
+	RootCmd.AddCommand(convertCmd)
+}
+
+func convertFile() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	b, err := ioutil.ReadFile(viper.GetString("file"))
+	if err != nil {
+		return errors.Wrap(err, "failed to read file")
+	}
+	var (
+		out      []byte
+		warnings []string
+	)
+	switch viper.GetString("to") {
+	case toAzure:
+		deployment, configMaps, parseWarnings, err := convert.ParseKubernetes(b)
+		if err != nil {
+			return err
+		}
+		req, convertWarnings, err := convert.ToContainerGroup(deployment, configMaps, convert.AzureOptions{
+			Name:          viper.GetString("name"),
+			ResourceGroup: viper.GetString("resource-group"),
+			Location:      viper.GetString("location"),
+			IPAddressType: viper.GetString("ip-address-type"),
+		})
+		if err != nil {
+			return err
+		}
+		warnings = append(parseWarnings, convertWarnings...)
+		if out, err = convert.MarshalContainerGroup(req); err != nil {
+			return err
+		}
+	case toKubernetes:
+		req, err := convert.ParseContainerGroup(b)
+		if err != nil {
+			return err
+		}
+		deployment, configMaps, convertWarnings, err := convert.ToKubernetes(req, convert.KubernetesOptions{
+			Name:      viper.GetString("name"),
+			Namespace: viper.GetString("namespace"),
+		})
+		if err != nil {
+			return err
+		}
+		warnings = convertWarnings
+		if out, err = convert.MarshalKubernetes(deployment, configMaps); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("invalid format %q to convert to, must be one of %s, %s", viper.GetString("to"), toAzure, toKubernetes)
+	}
+	for _, warning := range warnings {
+		logger.Warnf("%s", warning)
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,332 @@
+package google
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/api/container/v1"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	serverless_google "github.com/naemono/go-cloud-actions/pkg/serverless/google"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	getClusterCmd = &cobra.Command{
+		Use:   "get-cluster",
+		Short: "get gke cluster in google's public clouds",
+		Long:  `A cli to get a gke cluster, along with its node pools, in Google's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			bindClusterFlags(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "name"}); err != nil {
+				return err
+			}
+			return getCluster()
+		},
+	}
+	listClustersCmd = &cobra.Command{
+		Use:   "list-clusters",
+		Short: "list gke clusters in google's public clouds",
+		Long:  `A cli to list the gke clusters of a location, or of every location by default, in Google's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("project-id", cmd.Flags().Lookup("project-id"))
+			viper.BindPFlag("location", cmd.Flags().Lookup("location"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location"}); err != nil {
+				return err
+			}
+			return listClusters()
+		},
+	}
+	deleteClusterCmd = &cobra.Command{
+		Use:   "delete-cluster",
+		Short: "delete gke cluster in google's public clouds",
+		Long: `A cli to delete a gke cluster in Google's public cloud.  Deletion continues in the background, unless
+--wait is given.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			bindClusterFlags(cmd)
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "name"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeleteCluster)
+			}
+			return deleteCluster()
+		},
+	}
+	upgradeClusterCmd = &cobra.Command{
+		Use:   "upgrade-cluster",
+		Short: "upgrade gke cluster in google's public clouds",
+		Long: `A cli to upgrade the masters of a gke cluster to a kubernetes version in Google's public cloud.  The
+node pools of autopilot clusters follow their masters, while those of standard clusters are upgraded with
+node-pool upgrade.  The upgrade continues in the background, unless --wait is given.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			bindClusterFlags(cmd)
+			viper.BindPFlag("cluster-version", cmd.Flags().Lookup("cluster-version"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "name"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planUpgradeCluster)
+			}
+			return upgradeCluster()
+		},
+	}
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{getClusterCmd, deleteClusterCmd, upgradeClusterCmd} {
+		addClusterFlags(cmd)
+		GoogleCmd.AddCommand(cmd)
+	}
+	listClustersCmd.Flags().StringP("project-id", "p", "", "google project id/name")
+	listClustersCmd.Flags().StringP("location", "L", "-", "location of the clusters (default every location)")
+	GoogleCmd.AddCommand(listClustersCmd)
+
+	deleteClusterCmd.Flags().Bool("wait", false, "wait for the cluster to be deleted")
+	shared.AddPlanFlag(deleteClusterCmd)
+
+	upgradeClusterCmd.Flags().String("cluster-version", "", "kubernetes version to upgrade to (default latest)")
+	upgradeClusterCmd.Flags().Bool("wait", false, "wait for the cluster to be upgraded")
+	shared.AddPlanFlag(upgradeClusterCmd)
+}
+
+// addClusterFlags will add the flags naming a single cluster to a command
+func addClusterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("project-id", "p", "", "google project id/name")
+	cmd.Flags().StringP("location", "L", "", "location of the cluster")
+	cmd.Flags().StringP("name", "N", "", "name of the cluster")
+}
+
+func bindClusterFlags(cmd *cobra.Command) {
+	viper.BindPFlag("project-id", cmd.Flags().Lookup("project-id"))
+	viper.BindPFlag("location", cmd.Flags().Lookup("location"))
+	viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+}
+
+func getCluster() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cluster, err := client.GetCluster(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(cluster, clusterTable(cluster))
+}
+
+func listClusters() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	clusters, err := client.ListClusters(ctx, viper.GetString("project-id"), viper.GetString("location"))
+	if err != nil {
+		return err
+	}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "location"},
+			{Header: "mode"},
+			{Header: "version"},
+			{Header: "nodes"},
+			{Header: "status"},
+			{Header: "endpoint", Wide: true},
+			{Header: "network", Wide: true},
+		},
+	}
+	for _, cluster := range clusters {
+		table.AddRow(cluster.Name, cluster.Location, clusterMode(cluster), cluster.CurrentMasterVersion,
+			strconv.FormatInt(cluster.CurrentNodeCount, 10), cluster.Status, cluster.Endpoint, cluster.Network)
+	}
+	return shared.Print(clusters, table)
+}
+
+// clusterTable will return a table of a cluster's masters, followed by its node pools, whose current number of nodes
+// is listed with node-pool list, as the initial node count of a pool is not changed by resizes
+func clusterTable(cluster *container.Cluster) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "mode"},
+			{Header: "version"},
+			{Header: "nodes"},
+			{Header: "machine type"},
+			{Header: "autoscaling"},
+			{Header: "status"},
+			{Header: "endpoint", Wide: true},
+		},
+	}
+	table.AddRow(cluster.Name, clusterMode(cluster), cluster.CurrentMasterVersion,
+		strconv.FormatInt(cluster.CurrentNodeCount, 10), "", "", cluster.Status, cluster.Endpoint)
+	for _, pool := range cluster.NodePools {
+		machineType := ""
+		if pool.Config != nil {
+			machineType = pool.Config.MachineType
+		}
+		table.AddRow("  "+pool.Name, "node pool", pool.Version, "", machineType, autoscaling(pool), pool.Status, "")
+	}
+	return table
+}
+
+func clusterMode(cluster *container.Cluster) string {
+	if cluster.Autopilot != nil && cluster.Autopilot.Enabled {
+		return string(serverless_google.ModeAutopilot)
+	}
+	return string(serverless_google.ModeStandard)
+}
+
+func autoscaling(pool *container.NodePool) string {
+	if pool.Autoscaling == nil || !pool.Autoscaling.Enabled {
+		return ""
+	}
+	return strconv.FormatInt(pool.Autoscaling.MinNodeCount, 10) + "-" + strconv.FormatInt(pool.Autoscaling.MaxNodeCount, 10)
+}
+
+// operationTable will return a table of a pending operation
+func operationTable(op *container.Operation) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "operation"},
+			{Header: "type"},
+			{Header: "status"},
+			{Header: "target"},
+			{Header: "started"},
+		},
+	}
+	table.AddRow(op.Name, op.OperationType, op.Status, op.TargetLink, op.StartTime)
+	return table
+}
+
+// printClusterOperation will print an operation changing the cluster of the name flag, or with --wait, wait for it
+// to be done and print the changed cluster
+func printClusterOperation(ctx context.Context, client *serverless_google.Client, op *container.Operation) error {
+	if !viper.GetBool("wait") {
+		return shared.Print(op, operationTable(op))
+	}
+	if _, err := client.WaitForOperation(ctx, viper.GetString("project-id"), viper.GetString("location"), op); err != nil {
+		return err
+	}
+	cluster, err := client.GetCluster(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(cluster, clusterTable(cluster))
+}
+
+func deleteCluster() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := operationContext()
+	defer cancel()
+	op, err := client.DeleteCluster(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	if op == nil {
+		logger.Infof("cluster '%s' does not exist", viper.GetString("name"))
+		return nil
+	}
+	if !viper.GetBool("wait") {
+		return shared.Print(op, operationTable(op))
+	}
+	if _, err = client.WaitForOperation(ctx, viper.GetString("project-id"), viper.GetString("location"), op); err != nil {
+		return err
+	}
+	logger.Infof("cluster '%s' deleted", viper.GetString("name"))
+	return nil
+}
+
+// planDeleteCluster will plan the deletion of the cluster, when it exists
+func planDeleteCluster() (plan.Plan, error) {
+	var p plan.Plan
+	client, err := newServerlessClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cluster, err := client.GetCluster(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("name"))
+	if err == serverless_google.ErrNotFound {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Delete(string(apply.KindGKECluster), viper.GetString("name"), map[string]string{
+		"id":       cluster.SelfLink,
+		"status":   cluster.Status,
+		"location": cluster.Location,
+	}))
+	return p, nil
+}
+
+func upgradeCluster() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := operationContext()
+	defer cancel()
+	op, err := client.UpgradeCluster(ctx, viper.GetString("project-id"), viper.GetString("location"),
+		viper.GetString("name"), viper.GetString("cluster-version"))
+	if err != nil {
+		return err
+	}
+	return printClusterOperation(ctx, client, op)
+}
+
+// planUpgradeCluster will plan the upgrade as an update of the version of the cluster's masters, to the concrete
+// version its version flag, the latest by default, resolves to
+func planUpgradeCluster() (plan.Plan, error) {
+	var p plan.Plan
+	client, err := newServerlessClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cluster, err := client.GetCluster(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("name"))
+	if err != nil {
+		return p, err
+	}
+	version, err := client.ResolveMasterVersion(ctx, viper.GetString("project-id"), viper.GetString("location"),
+		viper.GetString("cluster-version"))
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Update(string(apply.KindGKECluster), viper.GetString("name"),
+		map[string]string{"version": version}, map[string]string{"version": cluster.CurrentMasterVersion}))
+	return p, nil
+}
@@ -0,0 +1,117 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	shared_google "github.com/naemono/go-cloud-actions/cmd/shared/google"
+	"github.com/naemono/go-cloud-actions/pkg/kubeconfig"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var getCredentialsCmd = &cobra.Command{
+	Use:   "get-credentials",
+	Short: "write kubeconfig of gke cluster in google's public clouds",
+	Long: `A cli to write or merge a kubeconfig context of a gke cluster in Google's public cloud, which becomes the
+current context.  kubectl authenticates with tokens minted by this binary's auth gke-token exec credential plugin,
+from the credentials of the google auth flags given here.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		shared.RunParentsPersistentPreRun(cmd, args)
+		bindClusterFlags(cmd)
+		viper.BindPFlag("kubeconfig", cmd.Flags().Lookup("kubeconfig"))
+		viper.BindPFlag("kube-context", cmd.Flags().Lookup("kube-context"))
+		viper.BindPFlag("internal-ip", cmd.Flags().Lookup("internal-ip"))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "name"}); err != nil {
+			return err
+		}
+		return getCredentials()
+	},
+}
+
+func init() {
+	addClusterFlags(getCredentialsCmd)
+	getCredentialsCmd.Flags().String("kubeconfig", "", "kubeconfig file to write (default the first of $KUBECONFIG, or ~/.kube/config)")
+	getCredentialsCmd.Flags().String("kube-context", "", "name of the kubeconfig context (default gke_<project>_<location>_<name>)")
+	getCredentialsCmd.Flags().Bool("internal-ip", false, "use the internal ip of the cluster's endpoint, such as of private endpoint clusters")
+
+	GoogleCmd.AddCommand(getCredentialsCmd)
+}
+
+func getCredentials() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cluster, err := client.GetCluster(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	endpoint := cluster.Endpoint
+	if viper.GetBool("internal-ip") {
+		if cluster.PrivateClusterConfig == nil || cluster.PrivateClusterConfig.PrivateEndpoint == "" {
+			return errors.Errorf("cluster %s has no internal ip endpoint", cluster.Name)
+		}
+		endpoint = cluster.PrivateClusterConfig.PrivateEndpoint
+	}
+	if endpoint == "" || cluster.MasterAuth == nil {
+		return errors.Errorf("cluster %s has no endpoint yet, it is %s", cluster.Name, cluster.Status)
+	}
+	command, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "failed to find the path of this binary")
+	}
+	if command, err = filepath.EvalSymlinks(command); err != nil {
+		return errors.Wrap(err, "failed to find the path of this binary")
+	}
+
+	authArgs, err := shared_google.AuthArgs()
+	if err != nil {
+		return err
+	}
+
+	path, err := kubeconfig.Path(viper.GetString("kubeconfig"))
+	if err != nil {
+		return err
+	}
+	config, err := kubeconfig.Load(path)
+	if err != nil {
+		return err
+	}
+	name := viper.GetString("kube-context")
+	if name == "" {
+		name = fmt.Sprintf("gke_%s_%s_%s", viper.GetString("project-id"), viper.GetString("location"), cluster.Name)
+	}
+	config.SetCluster(name, kubeconfig.Cluster{
+		Server:                   "https://" + endpoint,
+		CertificateAuthorityData: cluster.MasterAuth.ClusterCaCertificate,
+	})
+	config.SetUser(name, kubeconfig.User{
+		Exec: &kubeconfig.ExecConfig{
+			APIVersion:  kubeconfig.ExecAPIVersion,
+			Command:     command,
+			Args:        append([]string{"auth", "gke-token"}, authArgs...),
+			InstallHint: "the cloud binary which wrote this kubeconfig, whose path is the command of the exec plugin",
+		},
+	})
+	config.SetContext(name, kubeconfig.Context{Cluster: name, User: name})
+	config.CurrentContext = name
+	if err = config.Write(path); err != nil {
+		return err
+	}
+	logger.Infof("kubeconfig context '%s' of cluster '%s' written to %s", name, cluster.Name, path)
+	return nil
+}
@@ -7,8 +7,11 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	google_auth "github.com/naemono/go-cloud-actions/pkg/auth/google"
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	shared_google "github.com/naemono/go-cloud-actions/cmd/shared/google"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
 	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
 	serverless_google "github.com/naemono/go-cloud-actions/pkg/serverless/google"
 	"github.com/naemono/go-cloud-actions/pkg/validate"
 )
@@ -16,77 +19,197 @@ import (
 var (
 	// GoogleCmd is the base google compute's command
 	GoogleCmd = &cobra.Command{
-		Use:   "google",
-		Short: "Control compute/gke  in google's public clouds",
-		Long:  `A cli to interact with compute/gke in Google's public cloud.`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			if cmd.Parent() != nil && cmd.Parent().PersistentPreRun != nil {
-				cmd.Parent().PersistentPreRun(cmd.Parent(), args)
-			}
-			viper.BindPFlag("google-credentials-file-path", cmd.Flags().Lookup("google-credentials-file-path"))
-		},
+		Use:              "google",
+		Short:            "Control compute/gke  in google's public clouds",
+		Long:             `A cli to interact with compute/gke in Google's public cloud.`,
+		PersistentPreRun: shared_google.PersistentPreRun,
 	}
 	createCmd = &cobra.Command{
 		Use:   "create-cluster",
 		Short: "create gke cluster in google's public clouds",
-		Long:  `A cli to create gke clusters in Google's public cloud.`,
+		Long: `A cli to create gke clusters in Google's public cloud.  Autopilot clusters have their nodes managed by
+google, while standard clusters are created with a default node pool, configured by the node flags.  Creation
+continues in the background, unless --wait is given.`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			if cmd.Parent() != nil && cmd.Parent().PersistentPreRun != nil {
 				cmd.Parent().PersistentPreRun(cmd.Parent(), args)
 			}
 			viper.BindPFlag("project-id", cmd.Flags().Lookup("project-id"))
 			viper.BindPFlag("network-name", cmd.Flags().Lookup("network-name"))
+			viper.BindPFlag("subnetwork", cmd.Flags().Lookup("subnetwork"))
 			viper.BindPFlag("cluster-ipv4-cidr", cmd.Flags().Lookup("cluster-ipv4-cidr"))
+			viper.BindPFlag("pods-range", cmd.Flags().Lookup("pods-range"))
+			viper.BindPFlag("services-range", cmd.Flags().Lookup("services-range"))
 			viper.BindPFlag("description", cmd.Flags().Lookup("description"))
 			viper.BindPFlag("location", cmd.Flags().Lookup("location"))
 			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("mode", cmd.Flags().Lookup("mode"))
+			viper.BindPFlag("cluster-version", cmd.Flags().Lookup("cluster-version"))
+			bindNodePoolFlags(cmd)
+			viper.BindPFlag("private-nodes", cmd.Flags().Lookup("private-nodes"))
+			viper.BindPFlag("private-endpoint", cmd.Flags().Lookup("private-endpoint"))
+			viper.BindPFlag("master-ipv4-cidr", cmd.Flags().Lookup("master-ipv4-cidr"))
+			viper.BindPFlag("master-authorized-networks", cmd.Flags().Lookup("master-authorized-networks"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(
 				viper.GetViper(),
-				[]string{"google-credentials-file-path", "project-id", "network-name", "cluster-ipv4-cidr", "description", "location", "name"}); err != nil {
+				[]string{"project-id", "network-name", "description", "location", "name", "mode"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateCluster)
+			}
 			return createCluster()
 		},
 	}
 )
 
 func init() {
-	GoogleCmd.PersistentFlags().StringP("google-credentials-file-path", "G", "", "google service account credentials json file")
+	shared_google.AddAuthFlagsToCommand(GoogleCmd)
 
 	createCmd.Flags().StringP("project-id", "p", "", "google project id/name")
 	createCmd.Flags().StringP("network-name", "n", "", "google project network name")
-	createCmd.Flags().StringP("cluster-ipv4-cidr", "c", "", "ipv4 cidr of cluster")
+	createCmd.Flags().StringP("subnetwork", "s", "", "name of the subnetwork of the network holding the nodes (default the network's automatic subnetwork)")
+	createCmd.Flags().StringP("cluster-ipv4-cidr", "c", "", "ipv4 cidr of the pods of the cluster, when no --pods-range is given")
+	createCmd.Flags().String("pods-range", "", "secondary range name of the subnetwork, cidr or netmask (/14) of the pods of the cluster (default chosen by gke)")
+	createCmd.Flags().String("services-range", "", "secondary range name of the subnetwork, cidr or netmask (/20) of the services of the cluster (default chosen by gke)")
 	createCmd.Flags().StringP("description", "d", "", "description of cluster")
 	createCmd.Flags().StringP("location", "L", "", "location in which to create a cluster")
 	createCmd.Flags().StringP("name", "N", "", "name of the cluster to create")
+	createCmd.Flags().String("mode", string(serverless_google.ModeAutopilot), "mode of the cluster, autopilot or standard")
+	createCmd.Flags().String("cluster-version", "", "initial kubernetes version of the cluster (default latest)")
+	addNodePoolFlags(createCmd)
+	createCmd.Flags().Bool("private-nodes", false, "give the nodes of the cluster internal ips only")
+	createCmd.Flags().Bool("private-endpoint", false, "only expose the internal ip of the cluster's endpoint, requires --private-nodes")
+	createCmd.Flags().String("master-ipv4-cidr", "", "/28 ipv4 cidr of the masters of a cluster with private nodes")
+	createCmd.Flags().StringSlice("master-authorized-networks", nil, "ipv4 cidrs allowed to reach the cluster's endpoint (default any)")
+	createCmd.Flags().Bool("wait", false, "wait for the cluster to be created")
+	shared.AddPlanFlag(createCmd)
 
 	GoogleCmd.AddCommand(createCmd)
 }
 
+// waitTimeout is how long commands run with --wait wait for their operation to be done
+const waitTimeout = 30 * time.Minute
+
+// addNodePoolFlags will add the flags configuring the nodes of a node pool to a command
+func addNodePoolFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("node-count", 0, "initial number of nodes in each of the cluster's zones (default 3 in standard clusters)")
+	cmd.Flags().String("machine-type", "", "machine type of the nodes (default e2-medium in standard clusters)")
+	cmd.Flags().Int("disk-size", 0, "boot disk size of the nodes in GB (default 100)")
+	cmd.Flags().Int("min-nodes", 0, "minimum number of nodes in each zone when autoscaling")
+	cmd.Flags().Int("max-nodes", 0, "maximum number of nodes in each zone, which enables autoscaling")
+}
+
+func bindNodePoolFlags(cmd *cobra.Command) {
+	viper.BindPFlag("node-count", cmd.Flags().Lookup("node-count"))
+	viper.BindPFlag("machine-type", cmd.Flags().Lookup("machine-type"))
+	viper.BindPFlag("disk-size", cmd.Flags().Lookup("disk-size"))
+	viper.BindPFlag("min-nodes", cmd.Flags().Lookup("min-nodes"))
+	viper.BindPFlag("max-nodes", cmd.Flags().Lookup("max-nodes"))
+}
+
+// nodePoolConfig will return the node pool config of the node flags, with the defaults of standard clusters when
+// standard is set
+func nodePoolConfig(standard bool) serverless_google.NodePoolConfig {
+	config := serverless_google.NodePoolConfig{
+		NodeCount:   viper.GetInt("node-count"),
+		MachineType: viper.GetString("machine-type"),
+		DiskSizeGB:  viper.GetInt("disk-size"),
+		MinNodes:    viper.GetInt("min-nodes"),
+		MaxNodes:    viper.GetInt("max-nodes"),
+	}
+	if !standard {
+		return config
+	}
+	if config.NodeCount == 0 {
+		config.NodeCount = 3
+	}
+	if config.MachineType == "" {
+		config.MachineType = "e2-medium"
+	}
+	return config
+}
+
+func newServerlessClient() (*serverless_google.Client, error) {
+	return serverless_google.New(serverless_google.Config{
+		AuthConfig: shared_google.AuthConfig(),
+		Logger:     logging.GetLogger(viper.GetString("loglevel")),
+	})
+}
+
+// operationContext will return the context of a command requesting an operation, which lasts until the operation is
+// done with --wait
+func operationContext() (context.Context, context.CancelFunc) {
+	if viper.GetBool("wait") {
+		return context.WithTimeout(context.Background(), waitTimeout)
+	}
+	return context.WithTimeout(context.Background(), 30*time.Second)
+}
+
 func createCluster() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("creating cluster")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t, err := shared.Tags()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := operationContext()
 	defer cancel()
-	client, err := serverless_google.New(serverless_google.Config{
-		AuthConfig: google_auth.AuthConfig{
-			CredentialsFilePath: viper.GetString("google-credentials-file-path"),
-		},
-		Logger: logger,
-	})
+	client, err := newServerlessClient()
 	if err != nil {
 		return err
 	}
-	return client.CreateCluster(ctx, serverless_google.CreateClusterRequest{
+	mode := serverless_google.ClusterMode(viper.GetString("mode"))
+	op, err := client.CreateCluster(ctx, serverless_google.CreateClusterRequest{
 		ClusterCommon: serverless_google.ClusterCommon{
 			ProjectID:   viper.GetString("project-id"),
 			NetworkName: viper.GetString("network-name"),
 		},
+		Subnetwork:      viper.GetString("subnetwork"),
 		ClusterIpv4Cidr: viper.GetString("cluster-ipv4-cidr"),
+		PodsRange:       viper.GetString("pods-range"),
+		ServicesRange:   viper.GetString("services-range"),
 		Description:     viper.GetString("description"),
 		Location:        viper.GetString("location"),
 		Name:            viper.GetString("name"),
+		Tags:            t,
+		Mode:            mode,
+		Version:         viper.GetString("cluster-version"),
+		NodePool:        nodePoolConfig(mode == serverless_google.ModeStandard),
+		Private: serverless_google.PrivateConfig{
+			Nodes:               viper.GetBool("private-nodes"),
+			Endpoint:            viper.GetBool("private-endpoint"),
+			MasterIpv4CidrBlock: viper.GetString("master-ipv4-cidr"),
+		},
+		MasterAuthorizedNetworks: viper.GetStringSlice("master-authorized-networks"),
+	})
+	if err != nil {
+		return err
+	}
+	return printClusterOperation(ctx, client, op)
+}
+
+// planCreateCluster will plan the cluster as a manifest GKECluster
+func planCreateCluster() (plan.Plan, error) {
+	return shared.PlanResources(apply.Config{
+		Google: shared_google.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+	}, apply.Resource{
+		Kind:     apply.KindGKECluster,
+		Metadata: apply.Metadata{Name: viper.GetString("name")},
+		Spec: map[string]interface{}{
+			"projectId":       viper.GetString("project-id"),
+			"location":        viper.GetString("location"),
+			"network":         viper.GetString("network-name"),
+			"subnetwork":      viper.GetString("subnetwork"),
+			"clusterIpv4Cidr": viper.GetString("cluster-ipv4-cidr"),
+			"podsRange":       viper.GetString("pods-range"),
+			"servicesRange":   viper.GetString("services-range"),
+			"description":     viper.GetString("description"),
+		},
 	})
 }
@@ -0,0 +1,442 @@
+package google
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/api/container/v1"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	serverless_google "github.com/naemono/go-cloud-actions/pkg/serverless/google"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+// kindNodePool is the kind of node pools in plans
+const kindNodePool = "GKENodePool"
+
+var (
+	nodePoolCmd = &cobra.Command{
+		Use:   "node-pool",
+		Short: "control node pools of gke clusters in google's public clouds",
+		Long:  `A cli to create, list, resize, upgrade and delete the node pools of standard gke clusters in Google's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("project-id", cmd.Flags().Lookup("project-id"))
+			viper.BindPFlag("location", cmd.Flags().Lookup("location"))
+			viper.BindPFlag("cluster", cmd.Flags().Lookup("cluster"))
+		},
+	}
+	nodePoolCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "create node pool in google's public clouds",
+		Long: `A cli to create a node pool of a standard gke cluster in Google's public cloud.  Creation continues in
+the background, unless --wait is given.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("node-version", cmd.Flags().Lookup("node-version"))
+			bindNodePoolFlags(cmd)
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "cluster", "name"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateNodePool)
+			}
+			return createNodePool()
+		},
+	}
+	nodePoolListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list node pools in google's public clouds",
+		Long:  `A cli to list the node pools of a gke cluster in Google's public cloud.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "cluster"}); err != nil {
+				return err
+			}
+			return listNodePools()
+		},
+	}
+	nodePoolResizeCmd = &cobra.Command{
+		Use:   "resize",
+		Short: "resize node pool in google's public clouds",
+		Long: `A cli to resize a node pool of a gke cluster to a number of nodes in each of the cluster's zones in
+Google's public cloud.  The resize continues in the background, unless --wait is given.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("node-count", cmd.Flags().Lookup("node-count"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "cluster", "name"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planResizeNodePool)
+			}
+			return changeNodePool(func(client *serverless_google.Client, ctx context.Context, projectID, location, cluster, name string) (*container.Operation, error) {
+				return client.ResizeNodePool(ctx, projectID, location, cluster, name, viper.GetInt("node-count"))
+			})
+		},
+	}
+	nodePoolUpgradeCmd = &cobra.Command{
+		Use:   "upgrade",
+		Short: "upgrade node pool in google's public clouds",
+		Long: `A cli to upgrade the nodes of a node pool of a gke cluster to a kubernetes version, by default that of
+the cluster's masters, in Google's public cloud.  The upgrade continues in the background, unless --wait is given.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("node-version", cmd.Flags().Lookup("node-version"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "cluster", "name"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planUpgradeNodePool)
+			}
+			return changeNodePool(func(client *serverless_google.Client, ctx context.Context, projectID, location, cluster, name string) (*container.Operation, error) {
+				return client.UpgradeNodePool(ctx, projectID, location, cluster, name, viper.GetString("node-version"))
+			})
+		},
+	}
+	nodePoolDeleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete node pool in google's public clouds",
+		Long: `A cli to delete a node pool of a gke cluster in Google's public cloud.  Deletion continues in the
+background, unless --wait is given.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"project-id", "location", "cluster", "name"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeleteNodePool)
+			}
+			return deleteNodePool()
+		},
+	}
+)
+
+func init() {
+	nodePoolCmd.PersistentFlags().StringP("project-id", "p", "", "google project id/name")
+	nodePoolCmd.PersistentFlags().StringP("location", "L", "", "location of the cluster")
+	nodePoolCmd.PersistentFlags().StringP("cluster", "c", "", "name of the cluster")
+
+	for _, cmd := range []*cobra.Command{nodePoolCreateCmd, nodePoolResizeCmd, nodePoolUpgradeCmd, nodePoolDeleteCmd} {
+		cmd.Flags().StringP("name", "N", "", "name of the node pool")
+		cmd.Flags().Bool("wait", false, "wait for the change to the node pool to be done")
+		shared.AddPlanFlag(cmd)
+		nodePoolCmd.AddCommand(cmd)
+	}
+	nodePoolCmd.AddCommand(nodePoolListCmd)
+
+	addNodePoolFlags(nodePoolCreateCmd)
+	nodePoolCreateCmd.Flags().String("node-version", "", "kubernetes version of the nodes (default that of the cluster's masters)")
+
+	nodePoolResizeCmd.Flags().Int("node-count", 0, "number of nodes in each of the cluster's zones")
+
+	nodePoolUpgradeCmd.Flags().String("node-version", "", "kubernetes version to upgrade the nodes to (default that of the cluster's masters)")
+
+	GoogleCmd.AddCommand(nodePoolCmd)
+}
+
+func createNodePool() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := operationContext()
+	defer cancel()
+	op, err := client.CreateNodePool(ctx, serverless_google.NodePoolRequest{
+		ProjectID:      viper.GetString("project-id"),
+		Location:       viper.GetString("location"),
+		ClusterName:    viper.GetString("cluster"),
+		Name:           viper.GetString("name"),
+		NodePoolConfig: nodePoolConfig(true),
+		Version:        viper.GetString("node-version"),
+	})
+	if err != nil {
+		return err
+	}
+	return printNodePoolOperation(ctx, client, op)
+}
+
+// planCreateNodePool will plan the node pool as a creation, or as an update of the fields of an existing node pool
+func planCreateNodePool() (plan.Plan, error) {
+	var p plan.Plan
+	desired := nodePoolFields(nodePoolConfig(true), viper.GetString("node-version"))
+	_, actual, err := observeNodePool()
+	if err == serverless_google.ErrNotFound {
+		p.Add(plan.Create(kindNodePool, viper.GetString("name"), desired))
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Update(kindNodePool, viper.GetString("name"), desired, actual))
+	return p, nil
+}
+
+// planResizeNodePool will plan the resize as an update of the node count of the node pool
+func planResizeNodePool() (plan.Plan, error) {
+	var p plan.Plan
+	_, actual, err := observeNodePool()
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Update(kindNodePool, viper.GetString("name"),
+		map[string]string{"nodeCount": strconv.Itoa(viper.GetInt("node-count"))}, actual))
+	return p, nil
+}
+
+// planUpgradeNodePool will plan the upgrade as an update of the version of the node pool, to that of the cluster's
+// masters by default
+func planUpgradeNodePool() (plan.Plan, error) {
+	var p plan.Plan
+	client, err := newServerlessClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	version := viper.GetString("node-version")
+	if version == "" {
+		cluster, err := client.GetCluster(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("cluster"))
+		if err != nil {
+			return p, err
+		}
+		version = cluster.CurrentMasterVersion
+	}
+	pool, err := client.GetNodePool(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("cluster"), viper.GetString("name"))
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Update(kindNodePool, viper.GetString("name"), map[string]string{"version": version},
+		map[string]string{"version": pool.Version}))
+	return p, nil
+}
+
+// planDeleteNodePool will plan the deletion of the node pool, when it exists
+func planDeleteNodePool() (plan.Plan, error) {
+	var p plan.Plan
+	client, err := newServerlessClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pool, err := client.GetNodePool(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("cluster"), viper.GetString("name"))
+	if err == serverless_google.ErrNotFound {
+		p.Add(plan.NoOp(kindNodePool, viper.GetString("name")))
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Delete(kindNodePool, viper.GetString("name"), map[string]string{
+		"id":      pool.SelfLink,
+		"status":  pool.Status,
+		"version": pool.Version,
+	}))
+	return p, nil
+}
+
+// observeNodePool will get the node pool of the name flag, along with its plan fields
+func observeNodePool() (*container.NodePool, map[string]string, error) {
+	client, err := newServerlessClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pool, err := client.GetNodePool(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("cluster"), viper.GetString("name"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sizes, err := client.NodePoolSizes(ctx, pool)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pool, actualNodePoolFields(pool, sizes), nil
+}
+
+// nodePoolFields will return the plan fields of a node pool of the given config and version
+func nodePoolFields(config serverless_google.NodePoolConfig, version string) map[string]string {
+	fields := map[string]string{
+		"nodeCount":   strconv.Itoa(config.NodeCount),
+		"machineType": config.MachineType,
+		"version":     version,
+	}
+	if config.DiskSizeGB > 0 {
+		fields["diskSizeGb"] = strconv.Itoa(config.DiskSizeGB)
+	}
+	if config.MaxNodes > 0 {
+		fields["autoscaling"] = strconv.Itoa(config.MinNodes) + "-" + strconv.Itoa(config.MaxNodes)
+	}
+	return fields
+}
+
+// actualNodePoolFields will return the plan fields of an existing node pool of the given sizes in each of its zones.
+// Its node count is that of every zone, or the count of each zone when they differ.
+func actualNodePoolFields(pool *container.NodePool, sizes []int64) map[string]string {
+	fields := map[string]string{
+		"nodeCount":   zoneNodeCounts(sizes),
+		"version":     pool.Version,
+		"autoscaling": autoscaling(pool),
+	}
+	if pool.Config != nil {
+		fields["machineType"] = pool.Config.MachineType
+		fields["diskSizeGb"] = strconv.FormatInt(pool.Config.DiskSizeGb, 10)
+	}
+	return fields
+}
+
+func listNodePools() error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pools, err := client.ListNodePools(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("cluster"))
+	if err != nil {
+		return err
+	}
+	return printNodePools(ctx, client, pools)
+}
+
+// printNodePools will print node pools, along with their current number of nodes
+func printNodePools(ctx context.Context, client *serverless_google.Client, pools []*container.NodePool) error {
+	sizes := make(map[string][]int64, len(pools))
+	for _, pool := range pools {
+		poolSizes, err := client.NodePoolSizes(ctx, pool)
+		if err != nil {
+			return err
+		}
+		sizes[pool.Name] = poolSizes
+	}
+	return shared.Print(pools, nodePoolsTable(sizes, pools...))
+}
+
+// nodePoolsTable will return a table of node pools, whose nodes are the total of the sizes of each pool in each of
+// its zones
+func nodePoolsTable(sizes map[string][]int64, pools ...*container.NodePool) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "version"},
+			{Header: "nodes"},
+			{Header: "machine type"},
+			{Header: "disk size"},
+			{Header: "autoscaling"},
+			{Header: "status"},
+		},
+	}
+	for _, pool := range pools {
+		machineType, diskSize := "", ""
+		if pool.Config != nil {
+			machineType, diskSize = pool.Config.MachineType, strconv.FormatInt(pool.Config.DiskSizeGb, 10)
+		}
+		var nodes int64
+		for _, size := range sizes[pool.Name] {
+			nodes += size
+		}
+		table.AddRow(pool.Name, pool.Version, strconv.FormatInt(nodes, 10), machineType, diskSize,
+			autoscaling(pool), pool.Status)
+	}
+	return table
+}
+
+// zoneNodeCounts will return the number of nodes in each zone of a node pool of the given sizes, once when every
+// zone has as many
+func zoneNodeCounts(sizes []int64) string {
+	counts := make([]string, 0, len(sizes))
+	uniform := true
+	for _, size := range sizes {
+		counts = append(counts, strconv.FormatInt(size, 10))
+		uniform = uniform && size == sizes[0]
+	}
+	if uniform && len(counts) > 1 {
+		counts = counts[:1]
+	}
+	return strings.Join(counts, ",")
+}
+
+// changeNodePool will make a change, such as resizing, to the node pool of the name flag, and print the operation
+// making it
+func changeNodePool(change func(*serverless_google.Client, context.Context, string, string, string, string) (*container.Operation, error)) error {
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := operationContext()
+	defer cancel()
+	op, err := change(client, ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("cluster"), viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	return printNodePoolOperation(ctx, client, op)
+}
+
+// printNodePoolOperation will print an operation changing the node pool of the name flag, or with --wait, wait for
+// it to be done and print the node pools of the cluster
+func printNodePoolOperation(ctx context.Context, client *serverless_google.Client, op *container.Operation) error {
+	if !viper.GetBool("wait") {
+		return shared.Print(op, operationTable(op))
+	}
+	if _, err := client.WaitForOperation(ctx, viper.GetString("project-id"), viper.GetString("location"), op); err != nil {
+		return err
+	}
+	pools, err := client.ListNodePools(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("cluster"))
+	if err != nil {
+		return err
+	}
+	return printNodePools(ctx, client, pools)
+}
+
+func deleteNodePool() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	client, err := newServerlessClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := operationContext()
+	defer cancel()
+	op, err := client.DeleteNodePool(ctx, viper.GetString("project-id"), viper.GetString("location"), viper.GetString("cluster"), viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	if op == nil {
+		logger.Infof("node pool '%s' does not exist", viper.GetString("name"))
+		return nil
+	}
+	if !viper.GetBool("wait") {
+		return shared.Print(op, operationTable(op))
+	}
+	if _, err = client.WaitForOperation(ctx, viper.GetString("project-id"), viper.GetString("location"), op); err != nil {
+		return err
+	}
+	logger.Infof("node pool '%s' of cluster '%s' deleted", viper.GetString("name"), viper.GetString("cluster"))
+	return nil
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	auth_azure "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	auth_google "github.com/naemono/go-cloud-actions/pkg/auth/google"
+	"github.com/naemono/go-cloud-actions/pkg/config"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+const redacted = "REDACTED"
+
+var (
+	// RootCmd is the root config command
+	RootCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Control the named contexts of the cloud config file",
+		Long: `A cli to control the named contexts of the cloud config file (~/.config/cloud/config.yaml).
+
+A context holds the provider, account, credentials and region defaults of one public cloud account.
+The values of the current context (or the one given with --context) are used for every flag not given
+on the command line, and every value can also be overridden with a CLOUD_ prefixed environment
+variable, such as CLOUD_CLIENT_SECRET for --client-secret.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+	getContextsCmd = &cobra.Command{
+		Use:   "get-contexts",
+		Short: "list the contexts of the cloud config file",
+		Long:  `A cli to list the contexts of the cloud config file, marking the current context.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return getContexts()
+		},
+	}
+	useContextCmd = &cobra.Command{
+		Use:   "use-context NAME",
+		Short: "set the current context of the cloud config file",
+		Long:  `A cli to set the current context of the cloud config file, used when --context is not given.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return useContext(args[0])
+		},
+	}
+	setContextCmd = &cobra.Command{
+		Use:   "set-context NAME",
+		Short: "create or update a context of the cloud config file",
+		Long:  `A cli to create a context of the cloud config file, or update the given fields of an existing context.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setContext(cmd, args[0])
+		},
+	}
+)
+
+func init() {
+	setContextCmd.Flags().String("provider", "", "public cloud provider of the context (aws, azure, google)")
+	setContextCmd.Flags().String("subscription-id", "", "azure subscription id")
+	setContextCmd.Flags().String("tenant-id", "", "azure tenant id")
+	setContextCmd.Flags().String("client-id", "", "azure client id")
+	setContextCmd.Flags().String("client-secret", "", "azure client secret (stored in plain text, prefer CLOUD_CLIENT_SECRET)")
+	setContextCmd.Flags().String("auth-method", "", "azure auth method (client-secret, client-certificate, managed-identity, device-code, cli, workload-identity)")
+	setContextCmd.Flags().String("certificate-path", "", "azure client certificate (pkcs12) file")
+	setContextCmd.Flags().String("federated-token-file", "", "azure workload identity federated token file")
+	setContextCmd.Flags().String("azure-environment", "", "azure cloud (public, usgovernment, china, german) or environment json file")
+	setContextCmd.Flags().String("profile", "", "aws profile")
+	setContextCmd.Flags().String("region", "", "aws region")
+	setContextCmd.Flags().String("role-arn", "", "aws role arn to assume (space separated to assume a chain of roles)")
+	setContextCmd.Flags().String("mfa-serial", "", "aws mfa device required to assume the role")
+	setContextCmd.Flags().String("sso-start-url", "", "aws iam identity center (sso) start url")
+	setContextCmd.Flags().String("sso-region", "", "aws iam identity center (sso) region")
+	setContextCmd.Flags().String("sso-account-id", "", "aws account id to sign in to with sso")
+	setContextCmd.Flags().String("sso-role-name", "", "aws permission set (role) name to sign in with sso")
+	setContextCmd.Flags().String("google-credentials-file-path", "", "google service account credentials json file")
+	setContextCmd.Flags().String("google-auth-method", "", "google auth method (credentials-file, adc, external-account, access-token)")
+	setContextCmd.Flags().String("google-external-account-file", "", "google external account credential configuration json file")
+	setContextCmd.Flags().String("google-impersonate-service-account", "", "google service account to impersonate")
+	setContextCmd.Flags().String("tags", "", "tags (key=value,...) to apply to every created resource")
+	setContextCmd.Flags().String("tags-file", "", "yaml or json file of tags to apply to every created resource")
+	setContextCmd.Flags().String("owner", "", "owner of created resources, given in their default owner tag")
+	setContextCmd.Flags().Bool("use", false, "also make this the current context")
+
+	RootCmd.AddCommand(getContextsCmd)
+	RootCmd.AddCommand(useContextCmd)
+	RootCmd.AddCommand(setContextCmd)
+}
+
+func loadConfig() (string, *config.Config, error) {
+	path, err := config.Path(viper.GetString("config"))
+	if err != nil {
+		return "", nil, err
+	}
+	conf, err := config.Load(path)
+	return path, conf, err
+}
+
+func getContexts() error {
+	_, conf, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	type namedContext struct {
+		Name    string `json:"name"`
+		Current bool   `json:"current"`
+		config.Context
+	}
+	contexts := []namedContext{}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "current"},
+			{Header: "name"},
+			{Header: "provider"},
+			{Header: "account"},
+			{Header: "region"},
+			{Header: "tenant id", Wide: true},
+			{Header: "client id", Wide: true},
+			{Header: "credentials file", Wide: true},
+		},
+	}
+	for _, name := range conf.ContextNames() {
+		ctx := conf.Contexts[name]
+		if ctx.ClientSecret != "" {
+			ctx.ClientSecret = redacted
+		}
+		contexts = append(contexts, namedContext{Name: name, Current: name == conf.CurrentContext, Context: ctx})
+		current := ""
+		if name == conf.CurrentContext {
+			current = "*"
+		}
+		account := ctx.SubscriptionID
+		if account == "" {
+			account = ctx.Profile
+		}
+		table.AddRow(current, name, ctx.Provider, account, ctx.Region, ctx.TenantID, ctx.ClientID, ctx.GoogleCredentialsFilePath)
+	}
+	return shared.Print(contexts, table)
+}
+
+func useContext(name string) error {
+	path, conf, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err = conf.UseContext(name); err != nil {
+		return err
+	}
+	if err = conf.Save(path); err != nil {
+		return err
+	}
+	logging.GetLogger(viper.GetString("loglevel")).Infof("switched to context %s", name)
+	return nil
+}
+
+func setContext(cmd *cobra.Command, name string) error {
+	path, conf, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	ctx := conf.Contexts[name]
+	for flag, value := range map[string]*string{
+		"provider":                           &ctx.Provider,
+		"subscription-id":                    &ctx.SubscriptionID,
+		"tenant-id":                          &ctx.TenantID,
+		"client-id":                          &ctx.ClientID,
+		"client-secret":                      &ctx.ClientSecret,
+		"auth-method":                        &ctx.AuthMethod,
+		"certificate-path":                   &ctx.CertificatePath,
+		"federated-token-file":               &ctx.FederatedTokenFile,
+		"azure-environment":                  &ctx.AzureEnvironment,
+		"profile":                            &ctx.Profile,
+		"region":                             &ctx.Region,
+		"role-arn":                           &ctx.RoleARN,
+		"mfa-serial":                         &ctx.MFASerial,
+		"sso-start-url":                      &ctx.SSOStartURL,
+		"sso-region":                         &ctx.SSORegion,
+		"sso-account-id":                     &ctx.SSOAccountID,
+		"sso-role-name":                      &ctx.SSORoleName,
+		"google-credentials-file-path":       &ctx.GoogleCredentialsFilePath,
+		"google-auth-method":                 &ctx.GoogleAuthMethod,
+		"google-external-account-file":       &ctx.GoogleExternalAccountFile,
+		"google-impersonate-service-account": &ctx.GoogleImpersonateAccount,
+		"tags":                               &ctx.Tags,
+		"tags-file":                          &ctx.TagsFile,
+		"owner":                              &ctx.Owner,
+	} {
+		if cmd.Flags().Changed(flag) {
+			*value, _ = cmd.Flags().GetString(flag)
+		}
+	}
+	if ctx.Provider != "" {
+		if _, err = peering.ParseProvider(ctx.Provider); err != nil {
+			return err
+		}
+	}
+	if _, err = auth_azure.ParseMethod(ctx.AuthMethod); err != nil {
+		return err
+	}
+	if _, err = auth_azure.ParseEnvironment(ctx.AzureEnvironment); err != nil {
+		return err
+	}
+	if _, err = auth_google.ParseMethod(ctx.GoogleAuthMethod); err != nil {
+		return err
+	}
+	if _, err = tags.Parse(strings.Split(ctx.Tags, ",")); err != nil {
+		return err
+	}
+	conf.Contexts[name] = ctx
+	if use, _ := cmd.Flags().GetBool("use"); use || conf.CurrentContext == "" {
+		conf.CurrentContext = name
+	}
+	if err = conf.Save(path); err != nil {
+		return err
+	}
+	logging.GetLogger(viper.GetString("loglevel")).Infof("context %s saved to %s", name, path)
+	return nil
+}
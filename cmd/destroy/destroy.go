@@ -0,0 +1,165 @@
+package destroy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	shared_aws "github.com/naemono/go-cloud-actions/cmd/shared/aws"
+	shared_azure "github.com/naemono/go-cloud-actions/cmd/shared/azure"
+	shared_google "github.com/naemono/go-cloud-actions/cmd/shared/google"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+)
+
+var (
+	// RootCmd is the destroy command
+	RootCmd = &cobra.Command{
+		Use:   "destroy",
+		Short: "Destroy the resources of a manifest, or selected by tags, along with their dependents",
+		Long: `A cli to delete the existing resources of a manifest, or the aws vpcs or azure resource groups
+having every tag of --selector, along with their dependents, in reverse dependency order.
+
+The dependents which would prevent a resource's deletion are discovered and deleted first: the peerings,
+nat gateways (releasing their elastic ips), detached network interfaces, subnets, route tables, internet
+gateways and security groups of aws vpcs, the container groups using azure network profiles, and the
+container groups and network profiles of azure resource groups.  Network interfaces in use by other aws
+resources, such as instances, are errors.
+
+The deletions are printed, and only made once confirmed, unless --force is given.  With --plan, they are
+printed without being made.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared_azure.PersistentPreRun(cmd, args)
+			shared_aws.PersistentPreRun(cmd, args)
+			shared_google.PersistentPreRun(cmd, args)
+			viper.BindPFlag("filename", cmd.Flags().Lookup("filename"))
+			viper.BindPFlag("provider", cmd.Flags().Lookup("provider"))
+			viper.BindPFlag("force", cmd.Flags().Lookup("force"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selector, err := cmd.Flags().GetStringToString("selector")
+			if err != nil {
+				return err
+			}
+			filename := viper.GetString("filename")
+			if (filename == "") == (len(selector) == 0) {
+				return errors.New("exactly one of --filename or --selector must be given")
+			}
+			if filename == "-" && !viper.GetBool("force") && !shared.Planning() {
+				return errors.New("--force must be given to destroy a manifest read from stdin, which cannot be confirmed")
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			defer cancel()
+			teardown, err := newTeardown(ctx, filename, selector)
+			if err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, func() (plan.Plan, error) { return teardown.Plan(), nil })
+			}
+			return destroy(ctx, teardown)
+		},
+	}
+)
+
+func init() {
+	RootCmd.Flags().StringP("filename", "f", "", "manifest whose resources to destroy, or - to read it from stdin")
+	RootCmd.Flags().StringToString("selector", nil, "tags (key=value,...) of the aws vpcs or azure resource groups to destroy")
+	RootCmd.Flags().String("provider", "", "provider of the resources selected by --selector (aws, azure)")
+	RootCmd.Flags().Bool("force", false, "delete without confirmation")
+	shared.AddPlanFlag(RootCmd)
+	shared_azure.AddAuthFlagsToCommand(RootCmd)
+	shared_aws.AddAuthFlagsToCommand(RootCmd)
+	shared_google.AddAuthFlagsToCommand(RootCmd)
+}
+
+// newTeardown will discover the resources of the manifest at filename, or selected by selector, and their dependents
+func newTeardown(ctx context.Context, filename string, selector map[string]string) (*apply.Teardown, error) {
+	applier, err := apply.New(apply.Config{
+		Azure:  shared_azure.AuthConfig(),
+		AWS:    shared_aws.AuthConfig(),
+		Google: shared_google.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if filename != "" {
+		resources, err := apply.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return applier.Teardown(ctx, resources)
+	}
+	provider, err := peering.ParseProvider(viper.GetString("provider"))
+	if err != nil {
+		return nil, err
+	}
+	return applier.TeardownSelector(ctx, apply.Selector{Provider: provider, Tags: selector})
+}
+
+func destroy(ctx context.Context, teardown *apply.Teardown) error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	p := teardown.Plan()
+	if !p.HasChanges() {
+		logger.Info("no resources to destroy")
+		return nil
+	}
+	if !viper.GetBool("force") {
+		if err := shared.PrintPlan(p); err != nil {
+			return err
+		}
+		confirmed, err := confirm(len(p.Resources))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("destroy cancelled")
+		}
+	}
+	logger.Infof("destroying %d resources", len(p.Resources))
+	results, err := teardown.Run(ctx)
+	if len(results) > 0 {
+		if perr := printResults(results); perr != nil && err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+// confirm will prompt on the terminal for the deletion of count resources, which only yes confirms
+func confirm(count int) (bool, error) {
+	fmt.Fprintf(os.Stderr, "Delete these %d resources? Only 'yes' will be accepted: ", count)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, errors.Wrap(err, "failed to read confirmation")
+	}
+	return strings.TrimSpace(line) == "yes", nil
+}
+
+func printResults(results []apply.Result) error {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "kind"},
+			{Header: "name"},
+			{Header: "action"},
+			{Header: "id"},
+		},
+	}
+	for _, r := range results {
+		table.AddRow(string(r.Kind), r.Name, string(r.Action), r.Outputs["id"])
+	}
+	return shared.Print(results, table)
+}
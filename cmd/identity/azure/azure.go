@@ -2,15 +2,21 @@ package azure
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/naemono/go-cloud-actions/cmd/shared"
 	shared_azure "github.com/naemono/go-cloud-actions/cmd/shared/azure"
-	auth_azure "github.com/naemono/go-cloud-actions/pkg/auth/azure"
 	azure_identity "github.com/naemono/go-cloud-actions/pkg/identity/azure"
 	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
 	"github.com/naemono/go-cloud-actions/pkg/validate"
 )
 
@@ -42,11 +48,15 @@ var (
 			}
 			viper.BindPFlag("app-id", cmd.Flags().Lookup("app-id"))
 			viper.BindPFlag("display-name", cmd.Flags().Lookup("display-name"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(viper.GetViper(), []string{"app-id", "display-name"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateUser)
+			}
 			return createUser()
 		},
 	}
@@ -72,11 +82,15 @@ var (
 			viper.BindPFlag("display-name", cmd.Flags().Lookup("display-name"))
 			viper.BindPFlag("homepage", cmd.Flags().Lookup("homepage"))
 			viper.BindPFlag("identifier-uris", cmd.Flags().Lookup("identifier-uris"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(viper.GetViper(), []string{"display-name"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateApplication)
+			}
 			return createApplication()
 		},
 	}
@@ -90,11 +104,15 @@ var (
 			}
 			viper.BindPFlag("app-id", cmd.Flags().Lookup("app-id"))
 			viper.BindPFlag("display-name", cmd.Flags().Lookup("display-name"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(viper.GetViper(), []string{"app-id", "display-name"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planUpdateApplicationCredentials)
+			}
 			return updateApplicationCredentials()
 		},
 	}
@@ -138,12 +156,15 @@ func init() {
 	applicationAddCmd.Flags().StringP("display-name", "d", "", "display name of application")
 	applicationAddCmd.Flags().StringP("homepage", "H", "https://microsoft.com", "home page of application")
 	applicationAddCmd.Flags().StringSliceP("identifier-uris", "i", []string{}, "list of identifier uris for the application")
+	shared.AddPlanFlag(applicationAddCmd)
 
 	applicationAddCredentialsCmd.Flags().StringP("app-id", "a", "", "application id to add credentials")
 	applicationAddCredentialsCmd.Flags().StringP("display-name", "d", "", "display name of application")
+	shared.AddPlanFlag(applicationAddCredentialsCmd)
 
 	userAddCmd.Flags().StringP("app-id", "a", "", "application id to which to add this user")
 	userAddCmd.Flags().StringP("display-name", "d", "", "display name of application")
+	shared.AddPlanFlag(userAddCmd)
 
 	rolesListCmd.Flags().StringP("resource-group", "r", "", "resource group to use as scope")
 	rolesListCmd.Flags().StringP("vnet-name", "v", "", "vnet name to use as scope")
@@ -160,14 +181,10 @@ func init() {
 func createUser() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("creating user")
-	client := azure_identity.New(azure_identity.Config{
-		AuthConfig: auth_azure.AuthConfig{
-			SubscriptionID: viper.GetString("subscription-id"),
-			ClientID:       viper.GetString("client-id"),
-			ClientSecret:   viper.GetString("client-secret"),
-			TenantID:       viper.GetString("tenant-id"),
-		},
-	})
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	appID := viper.GetString("app-id")
@@ -178,21 +195,47 @@ func createUser() error {
 	if err != nil {
 		return err
 	}
-	logger.Infof("service principal created for app '%s'", *sp.DisplayName)
-	return nil
+	logger.Infof("service principal created for app '%s'", to.String(sp.DisplayName))
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "display name"},
+			{Header: "object id"},
+			{Header: "app id"},
+		},
+	}
+	table.AddRow(to.String(sp.DisplayName), to.String(sp.ObjectID), to.String(sp.AppID))
+	return shared.Print(sp, table)
+}
+
+// planCreateUser will plan the service principal, which is left unchanged when one of the display name exists
+func planCreateUser() (plan.Plan, error) {
+	var p plan.Plan
+	client, err := newClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	name := viper.GetString("display-name")
+	_, err = client.GetServicePrincipal(ctx, name)
+	if err == azure_identity.ErrNotFound {
+		p.Add(plan.Create("ServicePrincipal", name, map[string]string{"appId": viper.GetString("app-id")}))
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.NoOp("ServicePrincipal", name))
+	return p, nil
 }
 
 func createApplication() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("creating application")
-	client := azure_identity.New(azure_identity.Config{
-		AuthConfig: auth_azure.AuthConfig{
-			SubscriptionID: viper.GetString("subscription-id"),
-			ClientID:       viper.GetString("client-id"),
-			ClientSecret:   viper.GetString("client-secret"),
-			TenantID:       viper.GetString("tenant-id"),
-		},
-	})
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	app, err := client.CreateADApplication(ctx, azure_identity.ApplicationConfig{
@@ -204,21 +247,65 @@ func createApplication() error {
 	if err != nil {
 		return err
 	}
-	logger.Infof("application id %s created", *app.AppID)
-	return nil
+	logger.Infof("application id %s created", to.String(app.AppID))
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "display name"},
+			{Header: "object id"},
+			{Header: "app id"},
+			{Header: "homepage", Wide: true},
+		},
+	}
+	table.AddRow(to.String(app.DisplayName), to.String(app.ObjectID), to.String(app.AppID), to.String(app.Homepage))
+	return shared.Print(app, table)
+}
+
+// planCreateApplication will plan the application.  Existing applications cannot be updated by add, so an existing
+// application which differs is an error.
+func planCreateApplication() (plan.Plan, error) {
+	var p plan.Plan
+	client, err := newClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	name := viper.GetString("display-name")
+	desired := map[string]string{
+		"multiTenant":    strconv.FormatBool(viper.GetBool("multi-tenant")),
+		"homepage":       viper.GetString("homepage"),
+		"identifierUris": strings.Join(viper.GetStringSlice("identifier-uris"), ","),
+	}
+	app, err := client.GetADApplication(ctx, name)
+	if err == azure_identity.ErrNotFound {
+		p.Add(plan.Create("Application", name, desired))
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	actual := map[string]string{
+		"multiTenant": strconv.FormatBool(to.Bool(app.AvailableToOtherTenants)),
+		"homepage":    to.String(app.Homepage),
+	}
+	if app.IdentifierUris != nil {
+		actual["identifierUris"] = strings.Join(*app.IdentifierUris, ",")
+	}
+	change := plan.Update("Application", name, desired, actual)
+	if change.Action != plan.ActionNoOp {
+		return p, errors.Wrapf(azure_identity.ErrApplicationAlreadyExists, "application %s differs", name)
+	}
+	p.Add(change)
+	return p, nil
 }
 
 func updateApplicationCredentials() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("updating application credentials")
-	client := azure_identity.New(azure_identity.Config{
-		AuthConfig: auth_azure.AuthConfig{
-			SubscriptionID: viper.GetString("subscription-id"),
-			ClientID:       viper.GetString("client-id"),
-			ClientSecret:   viper.GetString("client-secret"),
-			TenantID:       viper.GetString("tenant-id"),
-		},
-	})
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	appID := viper.GetString("app-id")
@@ -229,29 +316,65 @@ func updateApplicationCredentials() error {
 	if err != nil {
 		return err
 	}
-	logger.Infof("password of %s assigned to application id %s", password, appID)
-	return nil
+	logger.Infof("password assigned to application id %s", appID)
+	credentials := struct {
+		AppID    string `json:"appId"`
+		Password string `json:"password"`
+	}{
+		AppID:    appID,
+		Password: password,
+	}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "app id"},
+			{Header: "password"},
+		},
+	}
+	table.AddRow(credentials.AppID, credentials.Password)
+	return shared.Print(credentials, table)
+}
+
+// planUpdateApplicationCredentials will plan the new password of the application, which is always added
+func planUpdateApplicationCredentials() (plan.Plan, error) {
+	var p plan.Plan
+	p.Add(plan.Update("Application", viper.GetString("app-id"), map[string]string{"passwordCredentials": "(new password)"}, nil))
+	return p, nil
 }
 
 func rolesList() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("listing roles")
-	client := azure_identity.New(azure_identity.Config{
-		AuthConfig: auth_azure.AuthConfig{
-			SubscriptionID: viper.GetString("subscription-id"),
-			ClientID:       viper.GetString("client-id"),
-			ClientSecret:   viper.GetString("client-secret"),
-			TenantID:       viper.GetString("tenant-id"),
-		},
-	})
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	roles, err := client.ListRoleDefinitions(ctx, viper.GetString("resource-group"), viper.GetString("vnet-name"))
 	if err != nil {
 		return err
 	}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "role name"},
+			{Header: "description"},
+			{Header: "id", Wide: true},
+		},
+	}
 	for _, role := range roles {
-		logger.Infof("role name: %s, description: %s", *role.Name, *role.Description)
+		roleName, description := "", ""
+		if role.RoleDefinitionProperties != nil {
+			roleName, description = to.String(role.RoleName), to.String(role.Description)
+		}
+		table.AddRow(to.String(role.Name), roleName, description, to.String(role.ID))
 	}
-	return nil
+	return shared.Print(roles, table)
+}
+
+func newClient() (*azure_identity.Client, error) {
+	return azure_identity.New(azure_identity.Config{
+		AuthConfig: shared_azure.AuthConfig(),
+		Logger:     logging.GetLogger(viper.GetString("loglevel")),
+	})
 }
@@ -3,10 +3,12 @@ package aws
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,9 +17,12 @@ import (
 
 	"github.com/naemono/go-cloud-actions/cmd/shared"
 	shared_aws "github.com/naemono/go-cloud-actions/cmd/shared/aws"
-	auth_aws "github.com/naemono/go-cloud-actions/pkg/auth/aws"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
 	"github.com/naemono/go-cloud-actions/pkg/logging"
 	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
 	"github.com/naemono/go-cloud-actions/pkg/validate"
 )
 
@@ -55,13 +60,18 @@ var (
 			viper.BindPFlag("cidr", cmd.Flags().Lookup("cidr"))
 			viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run"))
 			viper.BindPFlag("additional-tags", cmd.Flags().Lookup("additional-tags"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(
 				viper.GetViper(),
-				[]string{"profile", "name", "region", "cidr"}); err != nil {
+				[]string{"name", "region", "cidr"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateVPC)
+			}
 			return createVPC()
 		},
 	}
@@ -72,11 +82,15 @@ var (
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			shared.RunParentsPersistentPreRun(cmd, args)
 			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "profile", "id"}); err != nil {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeleteVPC)
+			}
 			return deleteVPC()
 		},
 	}
@@ -88,7 +102,7 @@ var (
 			shared.RunParentsPersistentPreRun(cmd, args)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "profile"}); err != nil {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region"}); err != nil {
 				return err
 			}
 			return listVPCs()
@@ -97,21 +111,37 @@ var (
 	vpcCreateSubnetCmd = &cobra.Command{
 		Use:   "create-subnet",
 		Short: "create subnet in VPC in AWS's public clouds",
-		Long:  `A cli to create subnets in vpcs in AWS's public cloud.`,
+		Long: `A cli to create subnets in vpcs in AWS's public cloud.
+
+With --prefix-length instead of --cidr, the subnet is given the first cidr block of that size, within the
+cidr blocks of the vpc, which overlaps none of its existing subnets.`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			shared.RunParentsPersistentPreRun(cmd, args)
 			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
 			viper.BindPFlag("cidr", cmd.Flags().Lookup("cidr"))
+			viper.BindPFlag("prefix-length", cmd.Flags().Lookup("prefix-length"))
 			viper.BindPFlag("az", cmd.Flags().Lookup("az"))
 			viper.BindPFlag("additional-tags", cmd.Flags().Lookup("additional-tags"))
 			viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(
 				viper.GetViper(),
-				[]string{"region", "profile", "id", "cidr", "az"}); err != nil {
+				[]string{"region", "id", "az"}); err != nil {
 				return err
 			}
+			if viper.GetInt("prefix-length") == 0 {
+				if err := validate.NotEmpty(viper.GetViper(), []string{"cidr"}); err != nil {
+					return err
+				}
+			} else if cmd.Flags().Changed("cidr") {
+				return errors.New("only one of --cidr or --prefix-length can be given")
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateSubnetInVPC)
+			}
 			return createSubnetInVPC()
 		},
 	}
@@ -124,7 +154,7 @@ var (
 			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "profile", "id"}); err != nil {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id"}); err != nil {
 				return err
 			}
 			return listSubnetsInVPC()
@@ -138,7 +168,7 @@ var (
 			shared.RunParentsPersistentPreRun(cmd, args)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "profile"}); err != nil {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region"}); err != nil {
 				return err
 			}
 			return listAZs()
@@ -152,15 +182,21 @@ func init() {
 	vpcCreateCmd.Flags().StringP("name", "n", "", "name of vpc")
 	vpcCreateCmd.Flags().StringP("cidr", "c", "10.4.240.0/21", "virtual network cidr to use")
 	vpcCreateCmd.Flags().BoolP("dry-run", "d", false, "dry-run the vpc creation")
-	vpcCreateCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment", "development"}, "tags to apply to vpc")
+	vpcCreateCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment=development"}, "tags (key=value,...) to apply to vpc, along with --tags")
+	vpcCreateCmd.Flags().BoolP("wait", "w", false, "wait for the vpc to be available")
+	shared.AddPlanFlag(vpcCreateCmd)
 
 	vpcDeleteCmd.Flags().StringP("id", "i", "", "vpc id to delete")
+	shared.AddPlanFlag(vpcDeleteCmd)
 
 	vpcCreateSubnetCmd.Flags().StringP("id", "i", "", "vpc id to create subnet within")
 	vpcCreateSubnetCmd.Flags().StringP("cidr", "c", "10.4.240.0/21", "virtual network cidr to use")
+	vpcCreateSubnetCmd.Flags().Int("prefix-length", 0, "prefix length of the next free cidr block of the vpc to use, instead of --cidr")
 	vpcCreateSubnetCmd.Flags().StringP("az", "a", "us-east-1a", "availability zone to create cidr within")
-	vpcCreateSubnetCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment", "development"}, "tags to apply to vpc subnet")
+	vpcCreateSubnetCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment=development"}, "tags (key=value,...) to apply to vpc subnet, along with --tags")
 	vpcCreateSubnetCmd.Flags().BoolP("dry-run", "d", false, "dry-run the vpc subnet creation")
+	vpcCreateSubnetCmd.Flags().BoolP("wait", "w", false, "wait for the vpc subnet to be available")
+	shared.AddPlanFlag(vpcCreateSubnetCmd)
 
 	vpcListSubnetsCmd.Flags().StringP("id", "i", "", "vpc id to list subnets within")
 
@@ -174,13 +210,21 @@ func init() {
 	regionCmd.AddCommand(azListCmd)
 }
 
+// waitTimeout is how long create commands run with --wait wait for the created resource to be available
+const waitTimeout = 5 * time.Minute
+
+// wait will return how long to wait for a created resource to be available, which is zero without --wait
+func wait() time.Duration {
+	if viper.GetBool("wait") {
+		return waitTimeout
+	}
+	return 0
+}
+
 func getLoggerAndNetworkClient() (*logrus.Entry, *aws_network.Client, error) {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	client, err := aws_network.New(aws_network.Config{
-		AuthConfig: auth_aws.AuthConfig{
-			Profile: viper.GetString("profile"),
-			Region:  viper.GetString("region"),
-		},
+		AuthConfig: shared_aws.AuthConfig(),
 	})
 	return logger, client, err
 }
@@ -191,28 +235,44 @@ func createVPC() error {
 		return err
 	}
 	logger.Infof("creating vpc")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	tagSpecifications, err := nameTags(types.ResourceTypeVpc)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout+time.Minute)
 	defer cancel()
 	request := aws_network.CreateVpcRequest{
-		CidrBlock:      viper.GetString("cidr"),
-		InstanceTenacy: types.Tenancy(types.VpcTenancyDefault),
-		TagSpecifications: []types.TagSpecification{
-			{
-				ResourceType: types.ResourceTypeVpc,
-				Tags: append(tagsFromSlice(viper.GetStringSlice("additional-tags")), types.Tag{
-					Key:   to.StringPtr("Name"),
-					Value: to.StringPtr(viper.GetString("name")),
-				}),
-			},
-		},
-		DryRun: viper.GetBool("dry-run"),
+		CidrBlock:         viper.GetString("cidr"),
+		InstanceTenacy:    types.Tenancy(types.VpcTenancyDefault),
+		TagSpecifications: tagSpecifications,
+		DryRun:            viper.GetBool("dry-run"),
+		Wait:              wait(),
+	}
+	vpc, err := client.CreateVPC(ctx, request)
+	if errors.Is(err, aws_network.ErrDryRun) {
+		logger.Infof("dry-run of aws vpc '%s' creation succeeded", viper.GetString("name"))
+		return nil
 	}
-	err = client.CreateVPC(ctx, request)
 	if err != nil {
 		return err
 	}
 	logger.Infof("aws vpc '%s' created", viper.GetString("name"))
-	return nil
+	return shared.Print(vpc, vpcsTable(vpc))
+}
+
+// planCreateVPC will plan the vpc as a manifest VPC, which is identified by its Name tag
+func planCreateVPC() (plan.Plan, error) {
+	return shared.PlanResources(apply.Config{
+		AWS:    shared_aws.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+	}, apply.Resource{
+		Kind:     apply.KindVPC,
+		Metadata: apply.Metadata{Name: viper.GetString("name")},
+		Spec: map[string]interface{}{
+			"cidrBlock":       viper.GetString("cidr"),
+			"instanceTenancy": string(types.TenancyDefault),
+		},
+	})
 }
 
 func listVPCs() error {
@@ -228,22 +288,30 @@ func listVPCs() error {
 	if err != nil {
 		return err
 	}
+	return shared.Print(vpcs, vpcsTable(vpcs...))
+}
+
+func vpcsTable(vpcs ...types.Vpc) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "id"},
+			{Header: "name"},
+			{Header: "cidr"},
+			{Header: "state"},
+			{Header: "default"},
+			{Header: "tags", Wide: true},
+		},
+	}
 	for _, vpc := range vpcs {
-		if vpc.IsDefault {
-			logger.WithField("cidr", *vpc.CidrBlock).Infof("default vpc id %s", *vpc.VpcId)
-			continue
-		}
-		if len(vpc.Tags) == 0 {
-			logger.WithField("cidr", *vpc.CidrBlock).Infof("unnamed vpc id: %s", *vpc.VpcId)
-			continue
-		}
-		tags := []string{}
-		for _, tag := range vpc.Tags {
-			tags = append(tags, fmt.Sprintf("%s: %s", *tag.Key, *tag.Value))
-		}
-		logger.WithField("cidr", *vpc.CidrBlock).Infof("vpc id %s with tags: %s", *vpc.VpcId, strings.Join(tags, ", "))
+		table.AddRow(
+			to.String(vpc.VpcId),
+			tagValue(vpc.Tags, "Name"),
+			to.String(vpc.CidrBlock),
+			string(vpc.State),
+			strconv.FormatBool(vpc.IsDefault),
+			tagsString(vpc.Tags))
 	}
-	return nil
+	return table
 }
 
 func deleteVPC() error {
@@ -257,28 +325,91 @@ func deleteVPC() error {
 	return client.DeleteVPC(ctx, viper.GetString("id"))
 }
 
+func planDeleteVPC() (plan.Plan, error) {
+	var p plan.Plan
+	_, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	vpc, err := client.GetVPC(ctx, viper.GetString("id"))
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Delete(string(apply.KindVPC), viper.GetString("id"), map[string]string{
+		"name":      tagValue(vpc.Tags, "Name"),
+		"cidrBlock": to.String(vpc.CidrBlock),
+		"state":     string(vpc.State),
+	}))
+	return p, nil
+}
+
 func createSubnetInVPC() error {
 	logger, client, err := getLoggerAndNetworkClient()
 	if err != nil {
 		return err
 	}
 	logger.Infof("creating subnet in vpc")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t, err := additionalTags()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout+time.Minute)
 	defer cancel()
-	return client.CreateSubnetInVPC(ctx, aws_network.CreateVpcSubnetRequest{
+	request := aws_network.CreateVpcSubnetRequest{
 		CidrBlock:        viper.GetString("cidr"),
 		VPCId:            viper.GetString("id"),
 		AvailabilityZone: viper.GetString("az"),
 		TagSpecifications: []types.TagSpecification{
 			{
 				ResourceType: types.ResourceTypeSubnet,
-				Tags: append(tagsFromSlice(viper.GetStringSlice("additional-tags")), types.Tag{
-					Key:   to.StringPtr("Availability-Zone"),
-					Value: to.StringPtr(viper.GetString("az")),
-				}),
+				Tags:         tags.Merge(t, tags.Tags{"Availability-Zone": viper.GetString("az")}).AWS(),
 			},
 		},
 		DryRun: viper.GetBool("dry-run"),
+		Wait:   wait(),
+	}
+	if prefixLength := viper.GetInt("prefix-length"); prefixLength != 0 {
+		request.CidrBlock = ""
+		request.PrefixLength = prefixLength
+	}
+	subnet, err := client.CreateSubnetInVPC(ctx, request)
+	if errors.Is(err, aws_network.ErrDryRun) {
+		logger.Infof("dry-run of aws vpc subnet creation in vpc '%s' succeeded", request.VPCId)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return shared.Print(subnet, subnetsTable(subnet))
+}
+
+// planCreateSubnetInVPC will plan the subnet as a manifest Subnet, which is identified by its cidr within its vpc
+func planCreateSubnetInVPC() (plan.Plan, error) {
+	cidr := viper.GetString("cidr")
+	if prefixLength := viper.GetInt("prefix-length"); prefixLength != 0 {
+		_, client, err := getLoggerAndNetworkClient()
+		if err != nil {
+			return plan.Plan{}, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if cidr, err = client.NextSubnetCIDR(ctx, viper.GetString("id"), prefixLength); err != nil {
+			return plan.Plan{}, err
+		}
+	}
+	return shared.PlanResources(apply.Config{
+		AWS:    shared_aws.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+	}, apply.Resource{
+		Kind:     apply.KindSubnet,
+		Metadata: apply.Metadata{Name: cidr},
+		Spec: map[string]interface{}{
+			"vpcId":            viper.GetString("id"),
+			"cidrBlock":        cidr,
+			"availabilityZone": viper.GetString("az"),
+		},
 	})
 }
 
@@ -295,20 +426,55 @@ func listSubnetsInVPC() error {
 	if err != nil {
 		return err
 	}
+	return shared.Print(subnets, subnetsTable(subnets...))
+}
+
+func subnetsTable(subnets ...types.Subnet) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "id"},
+			{Header: "az"},
+			{Header: "cidr"},
+			{Header: "available ips"},
+			{Header: "state", Wide: true},
+			{Header: "tags", Wide: true},
+		},
+	}
 	for _, subnet := range subnets {
-		logger.Infof("subnet az: %s, cidr: %s", *subnet.AvailabilityZone, *subnet.CidrBlock)
+		table.AddRow(
+			to.String(subnet.SubnetId),
+			to.String(subnet.AvailabilityZone),
+			to.String(subnet.CidrBlock),
+			strconv.Itoa(int(subnet.AvailableIpAddressCount)),
+			string(subnet.State),
+			tagsString(subnet.Tags))
 	}
-	return nil
+	return table
 }
 
-func tagsFromSlice(s []string) (tags []types.Tag) {
-	for i := 0; i < len(s); i += 2 {
-		if i+1 > len(s) {
-			return tags
+// additionalTags will return the tags of the command, overridden by those of --additional-tags, which are given as
+// key=value pairs, or as alternating keys and values
+func additionalTags() (tags.Tags, error) {
+	t, err := shared.Tags()
+	if err != nil {
+		return nil, err
+	}
+	pairs := viper.GetStringSlice("additional-tags")
+	if len(pairs) > 0 && !strings.Contains(strings.Join(pairs, ""), "=") {
+		if len(pairs)%2 != 0 {
+			return nil, errors.Errorf("additional tags %v must be key=value pairs, or alternating keys and values", pairs)
+		}
+		legacy := make([]string, 0, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			legacy = append(legacy, pairs[i]+"="+pairs[i+1])
 		}
-		tags = append(tags, types.Tag{Key: to.StringPtr(s[i]), Value: to.StringPtr(s[i+1])})
+		pairs = legacy
+	}
+	additional, err := tags.Parse(pairs)
+	if err != nil {
+		return nil, err
 	}
-	return tags
+	return tags.Merge(t, additional), nil
 }
 
 func listAZs() error {
@@ -324,8 +490,35 @@ func listAZs() error {
 	if err != nil {
 		return err
 	}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "id", Wide: true},
+			{Header: "region", Wide: true},
+			{Header: "state"},
+		},
+	}
 	for _, az := range azs {
-		logger.Infof("az: %s", *az.ZoneName)
+		table.AddRow(to.String(az.ZoneName), to.String(az.ZoneId), to.String(az.RegionName), string(az.State))
+	}
+	return shared.Print(azs, table)
+}
+
+// tagValue will return the value of the tag with the given key, or empty if there is none
+func tagValue(tags []types.Tag, key string) string {
+	for _, tag := range tags {
+		if to.String(tag.Key) == key {
+			return to.String(tag.Value)
+		}
+	}
+	return ""
+}
+
+// tagsString will return tags as a comma separated list of key=value
+func tagsString(tags []types.Tag) string {
+	values := []string{}
+	for _, tag := range tags {
+		values = append(values, fmt.Sprintf("%s=%s", to.String(tag.Key), to.String(tag.Value)))
 	}
-	return nil
+	return strings.Join(values, ",")
 }
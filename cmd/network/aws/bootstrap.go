@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	vpcBootstrapCmd = &cobra.Command{
+		Use:   "bootstrap",
+		Short: "create VPC with public and private subnets across availability zones in AWS's public clouds",
+		Long: `A cli to create a VPC in AWS's public cloud with an internet gateway, and a public and a private
+subnet in each of the first --availability-zones available availability zones of the region, splitting the
+cidr of the VPC evenly between them.
+
+Public subnets are routed to the internet through the internet gateway, and assign public ips on launch.
+Private subnets each have their own route table, routed to the internet through a single nat gateway in the
+first availability zone, a nat gateway in every availability zone with --nat-gateways per-az, or not at all
+with --nat-gateways none.
+
+Every resource is tagged with a Name prefixed by --name, along with --tags and --additional-tags, and is
+waited for to be available.  When a resource fails to be created, the resources created so far are printed, and can be
+deleted with destroy --selector.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("cidr", cmd.Flags().Lookup("cidr"))
+			viper.BindPFlag("availability-zones", cmd.Flags().Lookup("availability-zones"))
+			viper.BindPFlag("nat-gateways", cmd.Flags().Lookup("nat-gateways"))
+			viper.BindPFlag("additional-tags", cmd.Flags().Lookup("additional-tags"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"name", "region", "cidr"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planBootstrapVPC)
+			}
+			return bootstrapVPC()
+		},
+	}
+)
+
+func init() {
+	vpcBootstrapCmd.Flags().StringP("name", "n", "", "name of vpc, and prefix of the names of its resources")
+	vpcBootstrapCmd.Flags().StringP("cidr", "c", "10.4.240.0/21", "virtual network cidr to use")
+	vpcBootstrapCmd.Flags().IntP("availability-zones", "z", 2, "number of availability zones to create subnets in")
+	vpcBootstrapCmd.Flags().String("nat-gateways", string(aws_network.NatGatewaysSingle), "nat gateways of the private subnets (none, single, per-az)")
+	vpcBootstrapCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment=development"}, "tags (key=value,...) to apply to every resource, along with --tags")
+	shared.AddPlanFlag(vpcBootstrapCmd)
+
+	vpcCmd.AddCommand(vpcBootstrapCmd)
+}
+
+func bootstrapRequest() (aws_network.BootstrapRequest, error) {
+	t, err := additionalTags()
+	if err != nil {
+		return aws_network.BootstrapRequest{}, err
+	}
+	return aws_network.BootstrapRequest{
+		Name:              viper.GetString("name"),
+		CidrBlock:         viper.GetString("cidr"),
+		AvailabilityZones: viper.GetInt("availability-zones"),
+		NatGateways:       aws_network.NatGatewayMode(viper.GetString("nat-gateways")),
+		Tags:              t.AWS(),
+		Wait:              waitTimeout,
+	}, nil
+}
+
+func bootstrapVPC() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("bootstrapping vpc")
+	request, err := bootstrapRequest()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	result, err := client.Bootstrap(ctx, request)
+	if result.VPC.VpcId != nil {
+		if perr := shared.Print(result, bootstrapTable(result)); perr != nil && err == nil {
+			err = perr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	logger.Infof("aws vpc '%s' bootstrapped", viper.GetString("name"))
+	return nil
+}
+
+// planBootstrapVPC will plan the creation of every resource of the bootstrapped vpc, named by their Name tags
+func planBootstrapVPC() (plan.Plan, error) {
+	var p plan.Plan
+	_, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	request, err := bootstrapRequest()
+	if err != nil {
+		return p, err
+	}
+	zones, err := client.BootstrapLayout(ctx, request)
+	if err != nil {
+		return p, err
+	}
+	name := request.Name
+	p.Add(plan.Create(string(apply.KindVPC), name, map[string]string{"cidrBlock": request.CidrBlock}))
+	p.Add(plan.Create(string(apply.KindInternetGateway), name+"-igw", map[string]string{"vpc": name}))
+	p.Add(plan.Create(string(apply.KindRouteTable), name+"-public", map[string]string{
+		"vpc":   name,
+		"route": fmt.Sprintf("0.0.0.0/0 via %s-igw", name),
+	}))
+	for _, z := range zones {
+		p.Add(plan.Create(string(apply.KindSubnet), fmt.Sprintf("%s-public-%s", name, z.AvailabilityZone), map[string]string{
+			"cidrBlock":        to.String(z.PublicSubnet.CidrBlock),
+			"availabilityZone": z.AvailabilityZone,
+			"routeTable":       name + "-public",
+		}))
+	}
+	for i, z := range zones {
+		private := fmt.Sprintf("%s-private-%s", name, z.AvailabilityZone)
+		p.Add(plan.Create(string(apply.KindSubnet), private, map[string]string{
+			"cidrBlock":        to.String(z.PrivateSubnet.CidrBlock),
+			"availabilityZone": z.AvailabilityZone,
+			"routeTable":       private,
+		}))
+		fields := map[string]string{"vpc": name}
+		switch request.NatGateways {
+		case aws_network.NatGatewaysSingle:
+			fields["route"] = fmt.Sprintf("0.0.0.0/0 via %s-nat-%s", name, zones[0].AvailabilityZone)
+		case aws_network.NatGatewaysPerAZ:
+			fields["route"] = fmt.Sprintf("0.0.0.0/0 via %s-nat-%s", name, z.AvailabilityZone)
+		}
+		p.Add(plan.Create(string(apply.KindRouteTable), private, fields))
+		if request.NatGateways == aws_network.NatGatewaysPerAZ || (request.NatGateways == aws_network.NatGatewaysSingle && i == 0) {
+			p.Add(plan.Create(string(apply.KindNatGateway), fmt.Sprintf("%s-nat-%s", name, z.AvailabilityZone), map[string]string{
+				"subnet": fmt.Sprintf("%s-public-%s", name, z.AvailabilityZone),
+			}))
+		}
+	}
+	return p, nil
+}
+
+func bootstrapTable(result aws_network.BootstrapResult) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "kind"},
+			{Header: "availability zone"},
+			{Header: "id"},
+			{Header: "cidr"},
+		},
+	}
+	table.AddRow(string(apply.KindVPC), "", to.String(result.VPC.VpcId), to.String(result.VPC.CidrBlock))
+	if result.InternetGateway.InternetGatewayId != nil {
+		table.AddRow(string(apply.KindInternetGateway), "", to.String(result.InternetGateway.InternetGatewayId), "")
+	}
+	if result.PublicRouteTable.RouteTableId != nil {
+		table.AddRow(string(apply.KindRouteTable), "", to.String(result.PublicRouteTable.RouteTableId), "")
+	}
+	for _, z := range result.Zones {
+		table.AddRow(string(apply.KindSubnet), z.AvailabilityZone, to.String(z.PublicSubnet.SubnetId), to.String(z.PublicSubnet.CidrBlock))
+		if z.PrivateSubnet.SubnetId != nil {
+			table.AddRow(string(apply.KindSubnet), z.AvailabilityZone, to.String(z.PrivateSubnet.SubnetId), to.String(z.PrivateSubnet.CidrBlock))
+		}
+		if z.PrivateRouteTable.RouteTableId != nil {
+			table.AddRow(string(apply.KindRouteTable), z.AvailabilityZone, to.String(z.PrivateRouteTable.RouteTableId), "")
+		}
+		if z.NatGateway != nil {
+			table.AddRow(string(apply.KindNatGateway), z.AvailabilityZone, to.String(z.NatGateway.NatGatewayId), "")
+		}
+	}
+	return table
+}
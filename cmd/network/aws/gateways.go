@@ -0,0 +1,419 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	internetGatewayCmd = &cobra.Command{
+		Use:     "internet-gateway",
+		Aliases: []string{"igw"},
+		Short:   "control internet gateways in AWS's public clouds",
+		Long:    `A cli to control the internet gateways of VPCs in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+		},
+	}
+	internetGatewayCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "create internet gateway in AWS's public clouds",
+		Long:  `A cli to create an internet gateway, attached to a VPC, in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+			viper.BindPFlag("additional-tags", cmd.Flags().Lookup("additional-tags"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "name"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreate(apply.KindInternetGateway, map[string]string{"vpcId": viper.GetString("vpc-id")}))
+			}
+			return createInternetGateway()
+		},
+	}
+	internetGatewayListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list internet gateways of a VPC in AWS's public clouds",
+		Long:  `A cli to list the internet gateways attached to a VPC in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "vpc-id"}); err != nil {
+				return err
+			}
+			return listInternetGateways()
+		},
+	}
+	internetGatewayDeleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete internet gateway in AWS's public clouds",
+		Long:  `A cli to detach an internet gateway from its VPCs, and delete it, in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeleteInternetGateway)
+			}
+			return deleteInternetGateway()
+		},
+	}
+	natGatewayCmd = &cobra.Command{
+		Use:   "nat-gateway",
+		Short: "control nat gateways in AWS's public clouds",
+		Long:  `A cli to control the nat gateways of VPCs in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+		},
+	}
+	natGatewayCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "create nat gateway in AWS's public clouds",
+		Long: `A cli to create a nat gateway in a public subnet in AWS's public cloud.  An elastic ip is
+allocated for it unless --allocation-id is given.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("subnet-id", cmd.Flags().Lookup("subnet-id"))
+			viper.BindPFlag("allocation-id", cmd.Flags().Lookup("allocation-id"))
+			viper.BindPFlag("additional-tags", cmd.Flags().Lookup("additional-tags"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "name", "subnet-id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreate(apply.KindNatGateway, map[string]string{
+					"subnetId":     viper.GetString("subnet-id"),
+					"allocationId": viper.GetString("allocation-id"),
+				}))
+			}
+			return createNatGateway()
+		},
+	}
+	natGatewayListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list nat gateways of a VPC in AWS's public clouds",
+		Long:  `A cli to list the nat gateways of a VPC in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "vpc-id"}); err != nil {
+				return err
+			}
+			return listNatGateways()
+		},
+	}
+	natGatewayDeleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete nat gateway in AWS's public clouds",
+		Long:  `A cli to delete a nat gateway, waiting for it to be deleted, in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			viper.BindPFlag("release-eip", cmd.Flags().Lookup("release-eip"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeleteNatGateway)
+			}
+			return deleteNatGateway()
+		},
+	}
+)
+
+func init() {
+	internetGatewayCreateCmd.Flags().StringP("name", "n", "", "name of internet gateway")
+	internetGatewayCreateCmd.Flags().StringP("vpc-id", "i", "", "vpc id to attach the internet gateway to")
+	internetGatewayCreateCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment=development"}, "tags (key=value,...) to apply to internet gateway, along with --tags")
+	shared.AddPlanFlag(internetGatewayCreateCmd)
+
+	internetGatewayListCmd.Flags().StringP("vpc-id", "i", "", "vpc id to list internet gateways of")
+
+	internetGatewayDeleteCmd.Flags().String("id", "", "internet gateway id to delete")
+	shared.AddPlanFlag(internetGatewayDeleteCmd)
+
+	natGatewayCreateCmd.Flags().StringP("name", "n", "", "name of nat gateway")
+	natGatewayCreateCmd.Flags().String("subnet-id", "", "public subnet id to create the nat gateway in")
+	natGatewayCreateCmd.Flags().String("allocation-id", "", "allocation id of the elastic ip of the nat gateway (default a newly allocated elastic ip)")
+	natGatewayCreateCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment=development"}, "tags (key=value,...) to apply to nat gateway, along with --tags")
+	natGatewayCreateCmd.Flags().BoolP("wait", "w", false, "wait for the nat gateway to be available")
+	shared.AddPlanFlag(natGatewayCreateCmd)
+
+	natGatewayListCmd.Flags().StringP("vpc-id", "i", "", "vpc id to list nat gateways of")
+
+	natGatewayDeleteCmd.Flags().String("id", "", "nat gateway id to delete")
+	natGatewayDeleteCmd.Flags().Bool("release-eip", false, "release the elastic ip of the nat gateway once deleted")
+	shared.AddPlanFlag(natGatewayDeleteCmd)
+
+	AWSCmd.AddCommand(internetGatewayCmd)
+	AWSCmd.AddCommand(natGatewayCmd)
+	internetGatewayCmd.AddCommand(internetGatewayCreateCmd)
+	internetGatewayCmd.AddCommand(internetGatewayListCmd)
+	internetGatewayCmd.AddCommand(internetGatewayDeleteCmd)
+	natGatewayCmd.AddCommand(natGatewayCreateCmd)
+	natGatewayCmd.AddCommand(natGatewayListCmd)
+	natGatewayCmd.AddCommand(natGatewayDeleteCmd)
+}
+
+// nameTags will return the tag specification of a resource created by a command, with the Name tag of the name flag
+// along with the additional tags
+func nameTags(resourceType types.ResourceType) ([]types.TagSpecification, error) {
+	t, err := additionalTags()
+	if err != nil {
+		return nil, err
+	}
+	t = tags.Merge(t, tags.Tags{"Name": viper.GetString("name")})
+	return []types.TagSpecification{{ResourceType: resourceType, Tags: t.AWS()}}, nil
+}
+
+// planCreate will plan the creation of the resource of the name flag, for the kinds which are not identified by
+// their name, and are created anew by every run of their create command
+func planCreate(kind apply.Kind, fields map[string]string) func() (plan.Plan, error) {
+	return func() (plan.Plan, error) {
+		var p plan.Plan
+		p.Add(plan.Create(string(kind), viper.GetString("name"), fields))
+		return p, nil
+	}
+}
+
+func createInternetGateway() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("creating internet gateway")
+	tagSpecifications, err := nameTags(types.ResourceTypeInternetGateway)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gateway, err := client.CreateInternetGateway(ctx, viper.GetString("vpc-id"), tagSpecifications)
+	if err != nil {
+		return err
+	}
+	return shared.Print(gateway, internetGatewaysTable(gateway))
+}
+
+func listInternetGateways() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("listing internet gateways")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gateways, err := client.ListInternetGatewaysInVPC(ctx, viper.GetString("vpc-id"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(gateways, internetGatewaysTable(gateways...))
+}
+
+func internetGatewaysTable(gateways ...types.InternetGateway) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "id"},
+			{Header: "name"},
+			{Header: "vpcs"},
+			{Header: "tags", Wide: true},
+		},
+	}
+	for _, gateway := range gateways {
+		table.AddRow(
+			to.String(gateway.InternetGatewayId),
+			tagValue(gateway.Tags, "Name"),
+			strings.Join(attachedVPCs(gateway), ","),
+			tagsString(gateway.Tags))
+	}
+	return table
+}
+
+// attachedVPCs will return the ids of the vpcs an internet gateway is attached to
+func attachedVPCs(gateway types.InternetGateway) []string {
+	vpcs := []string{}
+	for _, attachment := range gateway.Attachments {
+		vpcs = append(vpcs, to.String(attachment.VpcId))
+	}
+	return vpcs
+}
+
+func deleteInternetGateway() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("deleting internet gateway")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gateway, err := client.GetInternetGateway(ctx, viper.GetString("id"))
+	if err != nil {
+		return err
+	}
+	for _, vpcID := range attachedVPCs(gateway) {
+		if err = client.DetachInternetGateway(ctx, viper.GetString("id"), vpcID); err != nil {
+			return err
+		}
+	}
+	return client.DeleteInternetGateway(ctx, viper.GetString("id"))
+}
+
+func planDeleteInternetGateway() (plan.Plan, error) {
+	var p plan.Plan
+	_, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gateway, err := client.GetInternetGateway(ctx, viper.GetString("id"))
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Delete(string(apply.KindInternetGateway), viper.GetString("id"), map[string]string{
+		"name": tagValue(gateway.Tags, "Name"),
+		"vpcs": strings.Join(attachedVPCs(gateway), ","),
+	}))
+	return p, nil
+}
+
+func createNatGateway() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("creating nat gateway")
+	tagSpecifications, err := nameTags(types.ResourceTypeNatgateway)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout+time.Minute)
+	defer cancel()
+	gateway, err := client.CreateNatGateway(ctx, aws_network.CreateNatGatewayRequest{
+		SubnetID:          viper.GetString("subnet-id"),
+		AllocationID:      viper.GetString("allocation-id"),
+		TagSpecifications: tagSpecifications,
+		Wait:              wait(),
+	})
+	if err != nil {
+		return err
+	}
+	return shared.Print(gateway, natGatewaysTable(gateway))
+}
+
+func listNatGateways() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("listing nat gateways")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gateways, err := client.ListNatGatewaysInVPC(ctx, viper.GetString("vpc-id"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(gateways, natGatewaysTable(gateways...))
+}
+
+func natGatewaysTable(gateways ...types.NatGateway) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "id"},
+			{Header: "name"},
+			{Header: "subnet"},
+			{Header: "public ips"},
+			{Header: "state"},
+			{Header: "tags", Wide: true},
+		},
+	}
+	for _, gateway := range gateways {
+		table.AddRow(
+			to.String(gateway.NatGatewayId),
+			tagValue(gateway.Tags, "Name"),
+			to.String(gateway.SubnetId),
+			strings.Join(natGatewayIPs(gateway), ","),
+			string(gateway.State),
+			tagsString(gateway.Tags))
+	}
+	return table
+}
+
+// natGatewayIPs will return the public ips of a nat gateway
+func natGatewayIPs(gateway types.NatGateway) []string {
+	ips := []string{}
+	for _, address := range gateway.NatGatewayAddresses {
+		ips = append(ips, to.String(address.PublicIp))
+	}
+	return ips
+}
+
+func deleteNatGateway() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("deleting nat gateway")
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+	return client.DeleteNatGateway(ctx, viper.GetString("id"), viper.GetBool("release-eip"))
+}
+
+func planDeleteNatGateway() (plan.Plan, error) {
+	var p plan.Plan
+	_, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return p, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	gateway, err := client.GetNatGateway(ctx, viper.GetString("id"))
+	if err != nil {
+		return p, err
+	}
+	fields := map[string]string{
+		"name":     tagValue(gateway.Tags, "Name"),
+		"subnetId": to.String(gateway.SubnetId),
+		"state":    string(gateway.State),
+	}
+	if viper.GetBool("release-eip") {
+		fields["elasticIps"] = strings.Join(natGatewayIPs(gateway), ",")
+	}
+	p.Add(plan.Delete(string(apply.KindNatGateway), viper.GetString("id"), fields))
+	return p, nil
+}
@@ -0,0 +1,444 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	routeTableCmd = &cobra.Command{
+		Use:   "route-table",
+		Short: "control route tables in AWS's public clouds",
+		Long:  `A cli to control the route tables of VPCs in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+		},
+	}
+	routeTableCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "create route table in AWS's public clouds",
+		Long:  `A cli to create a route table in a VPC in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+			viper.BindPFlag("additional-tags", cmd.Flags().Lookup("additional-tags"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "name", "vpc-id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreate(apply.KindRouteTable, map[string]string{"vpcId": viper.GetString("vpc-id")}))
+			}
+			return createRouteTable()
+		},
+	}
+	routeTableListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list route tables of a VPC in AWS's public clouds",
+		Long:  `A cli to list the route tables of a VPC in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "vpc-id"}); err != nil {
+				return err
+			}
+			return listRouteTables()
+		},
+	}
+	routeTableDeleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete route table in AWS's public clouds",
+		Long:  `A cli to delete a route table, which is not associated with any subnet, in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeleteRouteTable)
+			}
+			return deleteRouteTable()
+		},
+	}
+	routeTableAssociateCmd = &cobra.Command{
+		Use:   "associate",
+		Short: "associate route table with a subnet in AWS's public clouds",
+		Long:  `A cli to associate a route table with a subnet, replacing the main route table of its VPC, in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			viper.BindPFlag("subnet-id", cmd.Flags().Lookup("subnet-id"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id", "subnet-id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planAssociateRouteTable)
+			}
+			return associateRouteTable()
+		},
+	}
+	routeTableDisassociateCmd = &cobra.Command{
+		Use:   "disassociate",
+		Short: "disassociate route table from a subnet in AWS's public clouds",
+		Long:  `A cli to remove the association of a route table with a subnet in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			viper.BindPFlag("subnet-id", cmd.Flags().Lookup("subnet-id"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id", "subnet-id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDisassociateRouteTable)
+			}
+			return disassociateRouteTable()
+		},
+	}
+	routeTableCreateRouteCmd = &cobra.Command{
+		Use:   "create-route",
+		Short: "create route in route table in AWS's public clouds",
+		Long: `A cli to route a destination cidr through an internet gateway or a nat gateway in AWS's public cloud.  An
+existing route to the destination through another target is only replaced with --replace.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			viper.BindPFlag("destination", cmd.Flags().Lookup("destination"))
+			viper.BindPFlag("gateway-id", cmd.Flags().Lookup("gateway-id"))
+			viper.BindPFlag("nat-gateway-id", cmd.Flags().Lookup("nat-gateway-id"))
+			viper.BindPFlag("replace", cmd.Flags().Lookup("replace"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id", "destination"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateRoute)
+			}
+			return createRoute()
+		},
+	}
+)
+
+func init() {
+	routeTableCreateCmd.Flags().StringP("name", "n", "", "name of route table")
+	routeTableCreateCmd.Flags().StringP("vpc-id", "i", "", "vpc id to create the route table in")
+	routeTableCreateCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment=development"}, "tags (key=value,...) to apply to route table, along with --tags")
+	shared.AddPlanFlag(routeTableCreateCmd)
+
+	routeTableListCmd.Flags().StringP("vpc-id", "i", "", "vpc id to list route tables of")
+
+	routeTableDeleteCmd.Flags().String("id", "", "route table id to delete")
+	shared.AddPlanFlag(routeTableDeleteCmd)
+
+	routeTableAssociateCmd.Flags().String("id", "", "route table id to associate")
+	routeTableAssociateCmd.Flags().String("subnet-id", "", "subnet id to associate the route table with")
+	shared.AddPlanFlag(routeTableAssociateCmd)
+
+	routeTableDisassociateCmd.Flags().String("id", "", "route table id to disassociate")
+	routeTableDisassociateCmd.Flags().String("subnet-id", "", "subnet id to disassociate the route table from")
+	shared.AddPlanFlag(routeTableDisassociateCmd)
+
+	routeTableCreateRouteCmd.Flags().String("id", "", "route table id to create the route in")
+	routeTableCreateRouteCmd.Flags().StringP("destination", "d", "0.0.0.0/0", "destination cidr of the route")
+	routeTableCreateRouteCmd.Flags().String("gateway-id", "", "internet gateway id to route through")
+	routeTableCreateRouteCmd.Flags().String("nat-gateway-id", "", "nat gateway id to route through")
+	routeTableCreateRouteCmd.Flags().Bool("replace", false, "replace an existing route to the destination through another target")
+	shared.AddPlanFlag(routeTableCreateRouteCmd)
+
+	AWSCmd.AddCommand(routeTableCmd)
+	routeTableCmd.AddCommand(routeTableCreateCmd)
+	routeTableCmd.AddCommand(routeTableListCmd)
+	routeTableCmd.AddCommand(routeTableDeleteCmd)
+	routeTableCmd.AddCommand(routeTableAssociateCmd)
+	routeTableCmd.AddCommand(routeTableDisassociateCmd)
+	routeTableCmd.AddCommand(routeTableCreateRouteCmd)
+}
+
+func createRouteTable() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("creating route table")
+	tagSpecifications, err := nameTags(types.ResourceTypeRouteTable)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	table, err := client.CreateRouteTable(ctx, viper.GetString("vpc-id"), tagSpecifications)
+	if err != nil {
+		return err
+	}
+	return shared.Print(table, routeTablesTable(table))
+}
+
+func listRouteTables() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("listing route tables")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tables, err := client.ListRouteTablesInVPC(ctx, viper.GetString("vpc-id"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(tables, routeTablesTable(tables...))
+}
+
+func routeTablesTable(tables ...types.RouteTable) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "id"},
+			{Header: "name"},
+			{Header: "main"},
+			{Header: "subnets"},
+			{Header: "routes", Wide: true},
+			{Header: "tags", Wide: true},
+		},
+	}
+	for _, t := range tables {
+		main := false
+		for _, association := range t.Associations {
+			main = main || association.Main
+		}
+		table.AddRow(
+			to.String(t.RouteTableId),
+			tagValue(t.Tags, "Name"),
+			strconv.FormatBool(main),
+			strings.Join(associatedSubnets(t), ","),
+			routesString(t.Routes),
+			tagsString(t.Tags))
+	}
+	return table
+}
+
+// associatedSubnets will return the ids of the subnets a route table is explicitly associated with
+func associatedSubnets(table types.RouteTable) []string {
+	subnets := []string{}
+	for _, association := range table.Associations {
+		if association.SubnetId != nil {
+			subnets = append(subnets, to.String(association.SubnetId))
+		}
+	}
+	return subnets
+}
+
+// routesString will return routes as a comma separated list of destination=target
+func routesString(routes []types.Route) string {
+	values := []string{}
+	for _, route := range routes {
+		target := to.String(route.GatewayId)
+		for _, t := range []*string{route.NatGatewayId, route.VpcPeeringConnectionId, route.TransitGatewayId, route.NetworkInterfaceId} {
+			if t != nil {
+				target = to.String(t)
+			}
+		}
+		values = append(values, fmt.Sprintf("%s=%s", to.String(route.DestinationCidrBlock), target))
+	}
+	return strings.Join(values, ",")
+}
+
+func deleteRouteTable() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("deleting route table")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return client.DeleteRouteTable(ctx, viper.GetString("id"))
+}
+
+func planDeleteRouteTable() (plan.Plan, error) {
+	var p plan.Plan
+	table, err := getRouteTable()
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Delete(string(apply.KindRouteTable), viper.GetString("id"), map[string]string{
+		"name":    tagValue(table.Tags, "Name"),
+		"subnets": strings.Join(associatedSubnets(table), ","),
+		"routes":  routesString(table.Routes),
+	}))
+	return p, nil
+}
+
+func getRouteTable() (types.RouteTable, error) {
+	_, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return types.RouteTable{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return client.GetRouteTable(ctx, viper.GetString("id"))
+}
+
+func associateRouteTable() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("associating route table")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = client.AssociateRouteTable(ctx, viper.GetString("id"), viper.GetString("subnet-id"))
+	return err
+}
+
+// planAssociateRouteTable will plan the association as an update of the subnets of the route table
+func planAssociateRouteTable() (plan.Plan, error) {
+	var p plan.Plan
+	table, err := getRouteTable()
+	if err != nil {
+		return p, err
+	}
+	subnets := associatedSubnets(table)
+	desired := subnets
+	if !contains(subnets, viper.GetString("subnet-id")) {
+		desired = append(append([]string{}, subnets...), viper.GetString("subnet-id"))
+	}
+	p.Add(plan.Update(string(apply.KindRouteTable), viper.GetString("id"),
+		map[string]string{"subnets": strings.Join(desired, ",")},
+		map[string]string{"subnets": strings.Join(subnets, ",")}))
+	return p, nil
+}
+
+func disassociateRouteTable() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("disassociating route table")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	table, err := client.GetRouteTable(ctx, viper.GetString("id"))
+	if err != nil {
+		return err
+	}
+	for _, association := range table.Associations {
+		if to.String(association.SubnetId) == viper.GetString("subnet-id") {
+			return client.DisassociateRouteTable(ctx, to.String(association.RouteTableAssociationId))
+		}
+	}
+	return fmt.Errorf("route table %s is not associated with subnet %s", viper.GetString("id"), viper.GetString("subnet-id"))
+}
+
+// planDisassociateRouteTable will plan the disassociation as an update of the subnets of the route table
+func planDisassociateRouteTable() (plan.Plan, error) {
+	var p plan.Plan
+	table, err := getRouteTable()
+	if err != nil {
+		return p, err
+	}
+	subnets := associatedSubnets(table)
+	desired := []string{}
+	for _, subnet := range subnets {
+		if subnet != viper.GetString("subnet-id") {
+			desired = append(desired, subnet)
+		}
+	}
+	if len(desired) == len(subnets) {
+		p.Add(plan.NoOp(string(apply.KindRouteTable), viper.GetString("id")))
+		return p, nil
+	}
+	p.Add(plan.ResourceChange{
+		Kind:    string(apply.KindRouteTable),
+		Name:    viper.GetString("id"),
+		Action:  plan.ActionUpdate,
+		Changes: []plan.Change{{Field: "subnets", Actual: strings.Join(subnets, ","), Desired: strings.Join(desired, ",")}},
+	})
+	return p, nil
+}
+
+func createRoute() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("creating route")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err = client.CreateRoute(ctx, aws_network.CreateRouteRequest{
+		RouteTableID:    viper.GetString("id"),
+		DestinationCIDR: viper.GetString("destination"),
+		GatewayID:       viper.GetString("gateway-id"),
+		NatGatewayID:    viper.GetString("nat-gateway-id"),
+		Replace:         viper.GetBool("replace"),
+	})
+	if err != nil {
+		return err
+	}
+	table, err := client.GetRouteTable(ctx, viper.GetString("id"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(table, routeTablesTable(table))
+}
+
+// planCreateRoute will plan the route as an update of the route of its destination in the route table
+func planCreateRoute() (plan.Plan, error) {
+	var p plan.Plan
+	table, err := getRouteTable()
+	if err != nil {
+		return p, err
+	}
+	field := "route " + viper.GetString("destination")
+	desired := viper.GetString("gateway-id") + viper.GetString("nat-gateway-id")
+	actual := ""
+	for _, route := range table.Routes {
+		if to.String(route.DestinationCidrBlock) == viper.GetString("destination") {
+			actual = strings.TrimPrefix(routesString([]types.Route{route}), viper.GetString("destination")+"=")
+		}
+	}
+	if actual != "" && actual != desired && !viper.GetBool("replace") {
+		return p, fmt.Errorf("route to %s in route table %s already exists via %s, replace it with --replace",
+			viper.GetString("destination"), viper.GetString("id"), actual)
+	}
+	p.Add(plan.Update(string(apply.KindRouteTable), viper.GetString("id"),
+		map[string]string{field: desired},
+		map[string]string{field: actual}))
+	return p, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,322 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	securityGroupCmd = &cobra.Command{
+		Use:     "security-group",
+		Aliases: []string{"sg"},
+		Short:   "control security groups in AWS's public clouds",
+		Long:    `A cli to control the security groups of VPCs in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+		},
+	}
+	securityGroupCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "create security group in AWS's public clouds",
+		Long: `A cli to create a security group in a VPC in AWS's public cloud, which allows all egress, and
+the ingress of every --ingress rule, given as {protocol}:{port}[-{port}]:{cidr} or all:{cidr}.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+			viper.BindPFlag("description", cmd.Flags().Lookup("description"))
+			viper.BindPFlag("ingress", cmd.Flags().Lookup("ingress"))
+			viper.BindPFlag("additional-tags", cmd.Flags().Lookup("additional-tags"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "name", "vpc-id"}); err != nil {
+				return err
+			}
+			if _, err := ingressRules(); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreate(apply.KindSecurityGroup, map[string]string{
+					"vpcId":   viper.GetString("vpc-id"),
+					"ingress": strings.Join(viper.GetStringSlice("ingress"), ","),
+				}))
+			}
+			return createSecurityGroup()
+		},
+	}
+	securityGroupListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list security groups of a VPC in AWS's public clouds",
+		Long:  `A cli to list the security groups of a VPC in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "vpc-id"}); err != nil {
+				return err
+			}
+			return listSecurityGroups()
+		},
+	}
+	securityGroupDeleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete security group in AWS's public clouds",
+		Long:  `A cli to delete a security group in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeleteSecurityGroup)
+			}
+			return deleteSecurityGroup()
+		},
+	}
+	securityGroupAuthorizeIngressCmd = &cobra.Command{
+		Use:   "authorize-ingress",
+		Short: "allow ingress in security group in AWS's public clouds",
+		Long: `A cli to allow the ingress of every --ingress rule, given as {protocol}:{port}[-{port}]:{cidr} or
+all:{cidr}, in a security group in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			viper.BindPFlag("ingress", cmd.Flags().Lookup("ingress"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id", "ingress"}); err != nil {
+				return err
+			}
+			if _, err := ingressRules(); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planAuthorizeIngress)
+			}
+			return authorizeIngress()
+		},
+	}
+)
+
+func init() {
+	securityGroupCreateCmd.Flags().StringP("name", "n", "", "name of security group")
+	securityGroupCreateCmd.Flags().StringP("vpc-id", "i", "", "vpc id to create the security group in")
+	securityGroupCreateCmd.Flags().String("description", "", "description of security group (default the name)")
+	securityGroupCreateCmd.Flags().StringSlice("ingress", nil, "ingress rules to allow, such as tcp:443:0.0.0.0/0")
+	securityGroupCreateCmd.Flags().StringSliceP("additional-tags", "t", []string{"environment=development"}, "tags (key=value,...) to apply to security group, along with --tags")
+	shared.AddPlanFlag(securityGroupCreateCmd)
+
+	securityGroupListCmd.Flags().StringP("vpc-id", "i", "", "vpc id to list security groups of")
+
+	securityGroupDeleteCmd.Flags().String("id", "", "security group id to delete")
+	shared.AddPlanFlag(securityGroupDeleteCmd)
+
+	securityGroupAuthorizeIngressCmd.Flags().String("id", "", "security group id to allow ingress in")
+	securityGroupAuthorizeIngressCmd.Flags().StringSlice("ingress", nil, "ingress rules to allow, such as tcp:443:0.0.0.0/0")
+	shared.AddPlanFlag(securityGroupAuthorizeIngressCmd)
+
+	AWSCmd.AddCommand(securityGroupCmd)
+	securityGroupCmd.AddCommand(securityGroupCreateCmd)
+	securityGroupCmd.AddCommand(securityGroupListCmd)
+	securityGroupCmd.AddCommand(securityGroupDeleteCmd)
+	securityGroupCmd.AddCommand(securityGroupAuthorizeIngressCmd)
+}
+
+// ingressRules will parse the rules of the ingress flag
+func ingressRules() ([]aws_network.SecurityGroupRule, error) {
+	rules := []aws_network.SecurityGroupRule{}
+	for _, s := range viper.GetStringSlice("ingress") {
+		rule, err := aws_network.ParseSecurityGroupRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func createSecurityGroup() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	rules, err := ingressRules()
+	if err != nil {
+		return err
+	}
+	logger.Infof("creating security group")
+	tagSpecifications, err := nameTags(types.ResourceTypeSecurityGroup)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	group, err := client.CreateSecurityGroup(ctx, aws_network.CreateSecurityGroupRequest{
+		Name:              viper.GetString("name"),
+		Description:       viper.GetString("description"),
+		VPCId:             viper.GetString("vpc-id"),
+		Ingress:           rules,
+		TagSpecifications: tagSpecifications,
+	})
+	if err != nil {
+		return err
+	}
+	return shared.Print(group, securityGroupsTable(group))
+}
+
+func listSecurityGroups() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("listing security groups")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	groups, err := client.ListSecurityGroupsInVPC(ctx, viper.GetString("vpc-id"))
+	if err != nil {
+		return err
+	}
+	return shared.Print(groups, securityGroupsTable(groups...))
+}
+
+func securityGroupsTable(groups ...types.SecurityGroup) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "id"},
+			{Header: "name"},
+			{Header: "ingress"},
+			{Header: "description", Wide: true},
+			{Header: "tags", Wide: true},
+		},
+	}
+	for _, group := range groups {
+		table.AddRow(
+			to.String(group.GroupId),
+			to.String(group.GroupName),
+			strings.Join(permissionStrings(group.IpPermissions), ","),
+			to.String(group.Description),
+			tagsString(group.Tags))
+	}
+	return table
+}
+
+// permissionStrings will return the cidr ranges of ip permissions as rules in the format of the ingress flag
+func permissionStrings(permissions []types.IpPermission) []string {
+	rules := []string{}
+	for _, permission := range permissions {
+		for _, r := range permission.IpRanges {
+			if to.String(permission.IpProtocol) == "-1" {
+				rules = append(rules, "all:"+to.String(r.CidrIp))
+				continue
+			}
+			ports := fmt.Sprintf("%d", permission.FromPort)
+			if permission.ToPort != permission.FromPort {
+				ports = fmt.Sprintf("%d-%d", permission.FromPort, permission.ToPort)
+			}
+			rules = append(rules, fmt.Sprintf("%s:%s:%s", to.String(permission.IpProtocol), ports, to.String(r.CidrIp)))
+		}
+	}
+	return rules
+}
+
+func getSecurityGroup() (types.SecurityGroup, error) {
+	_, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return types.SecurityGroup{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return client.GetSecurityGroup(ctx, viper.GetString("id"))
+}
+
+func deleteSecurityGroup() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	logger.Infof("deleting security group")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return client.DeleteSecurityGroup(ctx, viper.GetString("id"))
+}
+
+func planDeleteSecurityGroup() (plan.Plan, error) {
+	var p plan.Plan
+	group, err := getSecurityGroup()
+	if err != nil {
+		return p, err
+	}
+	p.Add(plan.Delete(string(apply.KindSecurityGroup), viper.GetString("id"), map[string]string{
+		"name":    to.String(group.GroupName),
+		"vpcId":   to.String(group.VpcId),
+		"ingress": strings.Join(permissionStrings(group.IpPermissions), ","),
+	}))
+	return p, nil
+}
+
+func authorizeIngress() error {
+	logger, client, err := getLoggerAndNetworkClient()
+	if err != nil {
+		return err
+	}
+	rules, err := ingressRules()
+	if err != nil {
+		return err
+	}
+	logger.Infof("authorizing ingress in security group")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return client.AuthorizeSecurityGroupIngress(ctx, viper.GetString("id"), rules...)
+}
+
+// planAuthorizeIngress will plan the rules as an update of the ingress of the security group, to the rules it
+// already allows along with the new ones
+func planAuthorizeIngress() (plan.Plan, error) {
+	var p plan.Plan
+	group, err := getSecurityGroup()
+	if err != nil {
+		return p, err
+	}
+	rules, err := ingressRules()
+	if err != nil {
+		return p, err
+	}
+	actual := permissionStrings(group.IpPermissions)
+	desired := append([]string{}, actual...)
+	for _, rule := range rules {
+		s := fmt.Sprintf("%s:%d-%d:%s", rule.Protocol, rule.FromPort, rule.ToPort, rule.CIDR)
+		if rule.Protocol == "-1" {
+			s = "all:" + rule.CIDR
+		} else if rule.FromPort == rule.ToPort {
+			s = fmt.Sprintf("%s:%d:%s", rule.Protocol, rule.FromPort, rule.CIDR)
+		}
+		if !contains(desired, s) {
+			desired = append(desired, s)
+		}
+	}
+	p.Add(plan.Update(string(apply.KindSecurityGroup), viper.GetString("id"),
+		map[string]string{"ingress": strings.Join(desired, ",")},
+		map[string]string{"ingress": strings.Join(actual, ",")}))
+	return p, nil
+}
@@ -5,13 +5,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/naemono/go-cloud-actions/cmd/shared"
 	shared_azure "github.com/naemono/go-cloud-actions/cmd/shared/azure"
-	auth_azure "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
 	"github.com/naemono/go-cloud-actions/pkg/logging"
 	azure_network "github.com/naemono/go-cloud-actions/pkg/network/azure"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
 	"github.com/naemono/go-cloud-actions/pkg/validate"
 )
 
@@ -36,7 +40,11 @@ var (
 	networkProfileAddCmd = &cobra.Command{
 		Use:   "add",
 		Short: "add network profile in azure's public clouds",
-		Long:  `A cli to add network profile in Azure's public cloud.`,
+		Long: `A cli to add network profile in Azure's public cloud.
+
+The virtual network and subnet are created when missing.  A created subnet is given --subnet-cidr, which must be
+within the address space of the virtual network and overlap none of its subnets, or else the first free block
+of the address space with a prefix of --subnet-prefix-length bits.`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			if cmd.Parent() != nil && cmd.Parent().PersistentPreRun != nil {
 				cmd.Parent().PersistentPreRun(cmd.Parent(), args)
@@ -48,6 +56,8 @@ var (
 			viper.BindPFlag("subnet-name", cmd.Flags().Lookup("subnet-name"))
 			viper.BindPFlag("vnet-cidr", cmd.Flags().Lookup("vnet-cidr"))
 			viper.BindPFlag("subnet-cidr", cmd.Flags().Lookup("subnet-cidr"))
+			viper.BindPFlag("subnet-prefix-length", cmd.Flags().Lookup("subnet-prefix-length"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(
@@ -55,6 +65,9 @@ var (
 				[]string{"name", "resource-group", "location", "vnet-name", "subnet-name"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateNetworkProfile)
+			}
 			return createNetworkProfile()
 		},
 	}
@@ -86,7 +99,9 @@ func init() {
 	networkProfileAddCmd.Flags().StringP("vnet-name", "v", "", "name of the virtual network to use/create")
 	networkProfileAddCmd.Flags().StringP("subnet-name", "N", "", "name of the subnet to use/create")
 	networkProfileAddCmd.Flags().StringP("vnet-cidr", "V", "10.0.0.0/16", "virtual network cidr to use")
-	networkProfileAddCmd.Flags().StringP("subnet-cidr", "C", "10.0.0.0/24", "subnet cidr to use")
+	networkProfileAddCmd.Flags().StringP("subnet-cidr", "C", "", "subnet cidr to use, instead of --subnet-prefix-length")
+	networkProfileAddCmd.Flags().Int("subnet-prefix-length", azure_network.DefaultSubnetPrefixLength, "prefix length of the next free cidr block of the virtual network to use for the subnet")
+	shared.AddPlanFlag(networkProfileAddCmd)
 
 	networkProfileListCmd.Flags().StringP("resource-group", "r", "", "name of resource group")
 
@@ -98,13 +113,12 @@ func init() {
 func createNetworkProfile() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("creating network profile")
+	t, err := shared.Tags()
+	if err != nil {
+		return err
+	}
 	client, err := azure_network.New(azure_network.Config{
-		AuthConfig: auth_azure.AuthConfig{
-			SubscriptionID: viper.GetString("subscription-id"),
-			ClientID:       viper.GetString("client-id"),
-			ClientSecret:   viper.GetString("client-secret"),
-			TenantID:       viper.GetString("tenant-id"),
-		},
+		AuthConfig: shared_azure.AuthConfig(),
 	})
 	if err != nil {
 		return err
@@ -112,39 +126,102 @@ func createNetworkProfile() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	request := azure_network.NetworkProfileRequest{
-		Name:              viper.GetString("name"),
-		ResourceGroupName: viper.GetString("resource-group"),
-		Location:          strings.ToLower(viper.GetString("location")),
-		VnetName:          viper.GetString("vnet-name"),
-		SubnetName:        viper.GetString("subnet-name"),
+		Name:               viper.GetString("name"),
+		ResourceGroupName:  viper.GetString("resource-group"),
+		Location:           strings.ToLower(viper.GetString("location")),
+		VnetName:           viper.GetString("vnet-name"),
+		VnetAddressCIDR:    viper.GetString("vnet-cidr"),
+		SubnetName:         viper.GetString("subnet-name"),
+		SubnetAddressCIDR:  viper.GetString("subnet-cidr"),
+		SubnetPrefixLength: subnetPrefixLength(),
+		Tags:               t,
 	}
-	err = client.CreateNetworkProfile(ctx, request)
+	profile, err := client.CreateNetworkProfile(ctx, request)
 	if err != nil {
 		return err
 	}
 	logger.Infof("network profile '%s' created", viper.GetString("name"))
-	return nil
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "location"},
+			{Header: "state"},
+			{Header: "subnet"},
+			{Header: "subnet cidr"},
+			{Header: "id", Wide: true},
+		},
+	}
+	state := ""
+	if profile.Profile.ProfilePropertiesFormat != nil {
+		state = string(profile.Profile.ProvisioningState)
+	}
+	table.AddRow(
+		to.String(profile.Profile.Name),
+		to.String(profile.Profile.Location),
+		state,
+		viper.GetString("subnet-name"),
+		profile.SubnetCIDR,
+		to.String(profile.Profile.ID))
+	return shared.Print(profile, table)
+}
+
+// planCreateNetworkProfile will plan the network profile as a manifest NetworkProfile
+func planCreateNetworkProfile() (plan.Plan, error) {
+	return shared.PlanResources(apply.Config{
+		Azure:  shared_azure.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+	}, apply.Resource{
+		Kind:     apply.KindNetworkProfile,
+		Metadata: apply.Metadata{Name: viper.GetString("name")},
+		Spec: map[string]interface{}{
+			"resourceGroup":      viper.GetString("resource-group"),
+			"location":           viper.GetString("location"),
+			"vnetName":           viper.GetString("vnet-name"),
+			"vnetCidr":           viper.GetString("vnet-cidr"),
+			"subnetName":         viper.GetString("subnet-name"),
+			"subnetCidr":         viper.GetString("subnet-cidr"),
+			"subnetPrefixLength": subnetPrefixLength(),
+		},
+	})
+}
+
+// subnetPrefixLength will return the subnet prefix length flag, which is ignored when the subnet cidr flag is given
+func subnetPrefixLength() int {
+	if viper.GetString("subnet-cidr") != "" {
+		return 0
+	}
+	return viper.GetInt("subnet-prefix-length")
 }
 
 func listNetworkProfiles() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("listing network profiles")
 	client, err := azure_network.New(azure_network.Config{
-		AuthConfig: auth_azure.AuthConfig{
-			SubscriptionID: viper.GetString("subscription-id"),
-			ClientID:       viper.GetString("client-id"),
-			ClientSecret:   viper.GetString("client-secret"),
-			TenantID:       viper.GetString("tenant-id"),
-		},
+		AuthConfig: shared_azure.AuthConfig(),
 	})
 	if err != nil {
 		return err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	err = client.ListNetworkProfiles(ctx, viper.GetString("resource-group"))
+	profiles, err := client.ListNetworkProfiles(ctx, viper.GetString("resource-group"))
 	if err != nil {
 		return err
 	}
-	return nil
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "location"},
+			{Header: "state"},
+			{Header: "id", Wide: true},
+		},
+	}
+	for _, profile := range profiles {
+		state := ""
+		if profile.ProfilePropertiesFormat != nil {
+			state = string(profile.ProvisioningState)
+		}
+		table.AddRow(to.String(profile.Name), to.String(profile.Location), state, to.String(profile.ID))
+	}
+	return shared.Print(profiles, table)
 }
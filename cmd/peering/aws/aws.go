@@ -0,0 +1,339 @@
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	shared_aws "github.com/naemono/go-cloud-actions/cmd/shared/aws"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	peering_aws "github.com/naemono/go-cloud-actions/pkg/peering/aws"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	// AWSCmd is the base aws peering command
+	AWSCmd = &cobra.Command{
+		Use:   "aws",
+		Short: "Control peering of VPCs in AWS's public clouds",
+		Long:  `A cli to interact with peering of VPCs in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared_aws.PersistentPreRun(cmd, args)
+			viper.BindPFlag("peer-profile", cmd.Flags().Lookup("peer-profile"))
+			viper.BindPFlag("peer-region", cmd.Flags().Lookup("peer-region"))
+			viper.BindPFlag("peer-role-arn", cmd.Flags().Lookup("peer-role-arn"))
+			viper.BindPFlag("peer-external-id", cmd.Flags().Lookup("peer-external-id"))
+		},
+	}
+	createCmd = &cobra.Command{
+		Use:   "create",
+		Short: "create peering connections of VPCs in AWS's public clouds",
+		Long: `A cli to create peering connections of VPCs in AWS's public cloud.
+
+When the peer VPC lives in another account or region, --peer-profile and/or --peer-region
+select the credentials used to look up its owner, accept the request, and add its routes.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+			viper.BindPFlag("peer-vpc-id", cmd.Flags().Lookup("peer-vpc-id"))
+			viper.BindPFlag("peer-owner-id", cmd.Flags().Lookup("peer-owner-id"))
+			viper.BindPFlag("accept", cmd.Flags().Lookup("accept"))
+			viper.BindPFlag("routes", cmd.Flags().Lookup("routes"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			viper.BindPFlag("wait-timeout", cmd.Flags().Lookup("wait-timeout"))
+			viper.BindPFlag("allow-overlap", cmd.Flags().Lookup("allow-overlap"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(
+				viper.GetViper(),
+				[]string{"region", "name", "vpc-id", "peer-vpc-id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreatePeering)
+			}
+			return createPeering()
+		},
+	}
+	acceptCmd = &cobra.Command{
+		Use:   "accept",
+		Short: "accept peering connections of VPCs in AWS's public clouds",
+		Long:  `A cli to accept pending peering connections of VPCs in AWS's public cloud, using the peer credentials.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			viper.BindPFlag("routes", cmd.Flags().Lookup("routes"))
+			viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+			viper.BindPFlag("wait-timeout", cmd.Flags().Lookup("wait-timeout"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planAcceptPeering)
+			}
+			return acceptPeering()
+		},
+	}
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list peering connections of VPCs in AWS's public clouds",
+		Long:  `A cli to list peering connections of VPCs in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("vpc-id", cmd.Flags().Lookup("vpc-id"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region"}); err != nil {
+				return err
+			}
+			return listPeerings()
+		},
+	}
+	deleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete peering connections of VPCs in AWS's public clouds",
+		Long:  `A cli to delete peering connections of VPCs, and the routes through them, in AWS's public cloud.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			shared.RunParentsPersistentPreRun(cmd, args)
+			viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+			viper.BindPFlag("delete-routes", cmd.Flags().Lookup("delete-routes"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate.NotEmpty(viper.GetViper(), []string{"region", "id"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeletePeering)
+			}
+			return deletePeering()
+		},
+	}
+)
+
+func init() {
+	shared_aws.AddAuthFlagsToCommand(AWSCmd)
+	AWSCmd.PersistentFlags().StringP("peer-profile", "P", "", "aws profile of the account owning the peer vpc (defaults to --profile)")
+	AWSCmd.PersistentFlags().StringP("peer-region", "R", "", "aws region of the peer vpc (defaults to --region)")
+	AWSCmd.PersistentFlags().StringSlice("peer-role-arn", nil, "aws role arn to assume in the account owning the peer vpc, instead of --role-arn")
+	AWSCmd.PersistentFlags().StringSlice("peer-external-id", nil, "external id of each --peer-role-arn, in the same order")
+
+	createCmd.Flags().StringP("name", "n", "", "name of the peering connection")
+	createCmd.Flags().StringP("vpc-id", "i", "", "requester vpc id")
+	createCmd.Flags().StringP("peer-vpc-id", "I", "", "accepter (peer) vpc id")
+	createCmd.Flags().String("peer-owner-id", "", "aws account id owning the peer vpc (looked up with --peer-profile if empty)")
+	createCmd.Flags().BoolP("accept", "a", false, "accept the peering connection using the peer credentials")
+	createCmd.Flags().Bool("routes", false, "add routes to the peer cidrs in every route table of both vpcs (waits until active)")
+	createCmd.Flags().BoolP("wait", "w", false, "wait until the peering connection is active")
+	createCmd.Flags().Duration("wait-timeout", 5*time.Minute, "how long to wait for the peering connection")
+	createCmd.Flags().Bool("allow-overlap", false, "create the peering even when the address spaces of both networks overlap")
+	shared.AddPlanFlag(createCmd)
+
+	acceptCmd.Flags().StringP("id", "i", "", "vpc peering connection id to accept")
+	acceptCmd.Flags().Bool("routes", false, "add routes to the peer cidrs in every route table of both vpcs (waits until active)")
+	acceptCmd.Flags().BoolP("wait", "w", false, "wait until the peering connection is active")
+	acceptCmd.Flags().Duration("wait-timeout", 5*time.Minute, "how long to wait for the peering connection")
+	shared.AddPlanFlag(acceptCmd)
+
+	listCmd.Flags().StringP("vpc-id", "i", "", "vpc id in which to list peering connections (all if empty)")
+
+	deleteCmd.Flags().StringP("id", "i", "", "vpc peering connection id to delete")
+	deleteCmd.Flags().Bool("delete-routes", true, "delete the routes through the peering connection in both vpcs")
+	shared.AddPlanFlag(deleteCmd)
+
+	AWSCmd.AddCommand(createCmd)
+	AWSCmd.AddCommand(acceptCmd)
+	AWSCmd.AddCommand(listCmd)
+	AWSCmd.AddCommand(deleteCmd)
+}
+
+func getLoggerAndPeerer() (*logrus.Entry, *peering_aws.Peerer, error) {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	conf := peering_aws.Config{
+		AuthConfig: shared_aws.AuthConfig(),
+		Logger:     logger,
+	}
+	if viper.GetString("peer-profile") != "" || viper.GetString("peer-region") != "" || len(viper.GetStringSlice("peer-role-arn")) > 0 {
+		peer := conf.AuthConfig
+		if profile := viper.GetString("peer-profile"); profile != "" {
+			peer.Profile = profile
+		}
+		if arns := viper.GetStringSlice("peer-role-arn"); len(arns) > 0 {
+			peer.AssumeRoles = shared_aws.AssumeRoles(arns, viper.GetStringSlice("peer-external-id"))
+		}
+		if region := viper.GetString("peer-region"); region != "" {
+			peer.Region = region
+		}
+		conf.PeerAuthConfig = &peer
+	}
+	p, err := peering_aws.NewPeerer(conf)
+	return logger, p, err
+}
+
+// finishPeering will wait for the peering connection to be active, and add routes, as requested by flags
+func finishPeering(ctx context.Context, logger *logrus.Entry, p *peering_aws.Peerer, result peering.Peering) (peering.Peering, error) {
+	var err error
+	if viper.GetBool("wait") || viper.GetBool("routes") {
+		logger.Infof("waiting for vpc peering connection %s to become active", result.ID)
+		if result, err = p.WaitUntilActive(ctx, result.ID); err != nil {
+			return result, err
+		}
+	}
+	if viper.GetBool("routes") {
+		logger.Infof("adding routes through vpc peering connection %s", result.ID)
+		if err = p.CreateRoutes(ctx, result.ID); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func createPeering() error {
+	logger, p, err := getLoggerAndPeerer()
+	if err != nil {
+		return err
+	}
+	logger.Infof("creating vpc peering connection")
+	t, err := shared.Tags()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("wait-timeout"))
+	defer cancel()
+	result, err := p.Create(ctx, peering.CreateRequest{
+		Name:    viper.GetString("name"),
+		Network: peering.Network{Name: viper.GetString("vpc-id")},
+		RemoteNetwork: peering.Network{
+			Account: viper.GetString("peer-owner-id"),
+			Name:    viper.GetString("peer-vpc-id"),
+		},
+		AllowOverlap: viper.GetBool("allow-overlap"),
+		Tags:         t,
+	})
+	if err != nil {
+		return err
+	}
+	if viper.GetBool("accept") {
+		if _, err = p.WaitUntilPendingAcceptance(ctx, result.ID); err != nil {
+			return err
+		}
+		logger.Infof("accepting vpc peering connection %s", result.ID)
+		if result, err = p.Accept(ctx, result.ID); err != nil {
+			return err
+		}
+	}
+	if result, err = finishPeering(ctx, logger, p, result); err != nil {
+		return err
+	}
+	return shared.PrintPeering(result)
+}
+
+// planCreatePeering will plan the peering connection as a manifest Peering, which is identified by its Name tag
+func planCreatePeering() (plan.Plan, error) {
+	remote := viper.GetString("peer-vpc-id")
+	if owner := viper.GetString("peer-owner-id"); owner != "" {
+		remote = owner + "/" + remote
+	}
+	return shared.PlanResources(apply.Config{
+		AWS:    shared_aws.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+	}, apply.Resource{
+		Kind:     apply.KindPeering,
+		Metadata: apply.Metadata{Name: viper.GetString("name")},
+		Spec: map[string]interface{}{
+			"provider":      string(peering.ProviderAWS),
+			"network":       viper.GetString("vpc-id"),
+			"remoteNetwork": remote,
+		},
+	})
+}
+
+func acceptPeering() error {
+	logger, p, err := getLoggerAndPeerer()
+	if err != nil {
+		return err
+	}
+	logger.Infof("accepting vpc peering connection")
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("wait-timeout"))
+	defer cancel()
+	result, err := p.Accept(ctx, viper.GetString("id"))
+	if err != nil {
+		return err
+	}
+	if result, err = finishPeering(ctx, logger, p, result); err != nil {
+		return err
+	}
+	return shared.PrintPeering(result)
+}
+
+// planAcceptPeering will plan the peering connection becoming active, which is a no-op once it is
+func planAcceptPeering() (plan.Plan, error) {
+	var result plan.Plan
+	_, p, err := getLoggerAndPeerer()
+	if err != nil {
+		return result, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	id := viper.GetString("id")
+	actual, err := p.Get(ctx, peering.Network{}, id)
+	if err != nil {
+		return result, err
+	}
+	result.Add(plan.Update(string(apply.KindPeering), id,
+		map[string]string{"state": string(peering.StateActive)},
+		map[string]string{"state": string(actual.State)}))
+	return result, nil
+}
+
+func listPeerings() error {
+	logger, p, err := getLoggerAndPeerer()
+	if err != nil {
+		return err
+	}
+	logger.Infof("listing vpc peering connections")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	peerings, err := p.List(ctx, peering.Network{Name: viper.GetString("vpc-id")})
+	if err != nil {
+		return err
+	}
+	return shared.PrintPeerings(peerings)
+}
+
+func deletePeering() error {
+	logger, p, err := getLoggerAndPeerer()
+	if err != nil {
+		return err
+	}
+	logger.Infof("deleting vpc peering connection")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	id := viper.GetString("id")
+	if viper.GetBool("delete-routes") {
+		if err = p.DeleteRoutes(ctx, id); err != nil {
+			return err
+		}
+	}
+	return p.Delete(ctx, peering.Network{}, id)
+}
+
+func planDeletePeering() (plan.Plan, error) {
+	_, p, err := getLoggerAndPeerer()
+	if err != nil {
+		return plan.Plan{}, err
+	}
+	return shared.PlanPeeringDelete(p, peering.Network{}, viper.GetString("id"))
+}
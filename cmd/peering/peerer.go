@@ -0,0 +1,749 @@
+package peering
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/cmd/shared"
+	shared_aws "github.com/naemono/go-cloud-actions/cmd/shared/aws"
+	shared_azure "github.com/naemono/go-cloud-actions/cmd/shared/azure"
+	shared_google "github.com/naemono/go-cloud-actions/cmd/shared/google"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	auth_azure "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	auth_google "github.com/naemono/go-cloud-actions/pkg/auth/google"
+	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	peering_aws "github.com/naemono/go-cloud-actions/pkg/peering/aws"
+	peering_azure "github.com/naemono/go-cloud-actions/pkg/peering/azure"
+	peering_google "github.com/naemono/go-cloud-actions/pkg/peering/google"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+	"github.com/naemono/go-cloud-actions/pkg/validate"
+)
+
+var (
+	createCmd = &cobra.Command{
+		Use:   "create",
+		Short: "create a peering in any public cloud",
+		Long: `A cli to create a peering of VPCs/VNets in the public cloud selected by --provider.
+
+With --bidirectional, azure and google peerings are created along with the remote network's half, authenticated
+as --remote-tenant-id or --remote-google-credentials-file-path, and both halves are waited on until active.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			bindProviderFlags(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			viper.BindPFlag("remote-network", cmd.Flags().Lookup("remote-network"))
+			viper.BindPFlag("remote-region", cmd.Flags().Lookup("remote-region"))
+			viper.BindPFlag("remote-tenant-id", cmd.Flags().Lookup("remote-tenant-id"))
+			viper.BindPFlag("allow-forwarded-traffic", cmd.Flags().Lookup("allow-forwarded-traffic"))
+			viper.BindPFlag("allow-gateway-transit", cmd.Flags().Lookup("allow-gateway-transit"))
+			viper.BindPFlag("use-remote-gateways", cmd.Flags().Lookup("use-remote-gateways"))
+			viper.BindPFlag("import-custom-routes", cmd.Flags().Lookup("import-custom-routes"))
+			viper.BindPFlag("export-custom-routes", cmd.Flags().Lookup("export-custom-routes"))
+			viper.BindPFlag("allow-overlap", cmd.Flags().Lookup("allow-overlap"))
+			viper.BindPFlag("bidirectional", cmd.Flags().Lookup("bidirectional"))
+			viper.BindPFlag("remote-name", cmd.Flags().Lookup("remote-name"))
+			viper.BindPFlag("remote-google-credentials-file-path", cmd.Flags().Lookup("remote-google-credentials-file-path"))
+			viper.BindPFlag("wait-timeout", cmd.Flags().Lookup("wait-timeout"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateProviderFlags([]string{"name", "network", "remote-network"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreatePeering)
+			}
+			if viper.GetBool("bidirectional") {
+				return createBidirectionalPeering()
+			}
+			return createPeering()
+		},
+	}
+	checkCmd = &cobra.Command{
+		Use:   "check",
+		Short: "check the address spaces of networks to peer in any public cloud for overlaps",
+		Long: `A cli to fetch the address spaces of a VPC/VNet and a remote VPC/VNet in the public cloud selected by
+--provider, and print their overlapping prefixes, which would prevent the networks from being peered.  Exits
+with an error when any prefixes overlap.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			bindProviderFlags(cmd, args)
+			viper.BindPFlag("remote-network", cmd.Flags().Lookup("remote-network"))
+			viper.BindPFlag("remote-region", cmd.Flags().Lookup("remote-region"))
+			viper.BindPFlag("remote-tenant-id", cmd.Flags().Lookup("remote-tenant-id"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateProviderFlags([]string{"network", "remote-network"}); err != nil {
+				return err
+			}
+			return checkOverlaps()
+		},
+	}
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list peerings in any public cloud",
+		Long:  `A cli to list peerings of a VPC/VNet in the public cloud selected by --provider.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			bindProviderFlags(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateProviderFlags([]string{"network"}); err != nil {
+				return err
+			}
+			return listPeerings()
+		},
+	}
+	getCmd = &cobra.Command{
+		Use:   "get",
+		Short: "get a peering in any public cloud",
+		Long: `A cli to get a single peering of a VPC/VNet in the public cloud selected by --provider.  For google, the
+remote address space imported over the peering is listed in the region given by --region.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			bindProviderFlags(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateProviderFlags([]string{"name", "network"}); err != nil {
+				return err
+			}
+			return getPeering()
+		},
+	}
+	updateCmd = &cobra.Command{
+		Use:   "update",
+		Short: "update a peering in azure or google",
+		Long: `A cli to update the route exchange flags of a single peering of a VPC/VNet in the public cloud selected by
+--provider, which is azure or google.  Only flags given are changed, and flags of the other provider are ignored.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			bindProviderFlags(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateProviderFlags([]string{"name", "network"}); err != nil {
+				return err
+			}
+			update := peering.Update{
+				AllowForwardedTraffic: shared.ChangedBool(cmd, "allow-forwarded-traffic"),
+				AllowGatewayTransit:   shared.ChangedBool(cmd, "allow-gateway-transit"),
+				UseRemoteGateways:     shared.ChangedBool(cmd, "use-remote-gateways"),
+				ImportCustomRoutes:    shared.ChangedBool(cmd, "import-custom-routes"),
+				ExportCustomRoutes:    shared.ChangedBool(cmd, "export-custom-routes"),
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, func() (plan.Plan, error) {
+					return planUpdatePeering(update)
+				})
+			}
+			return updatePeering(update)
+		},
+	}
+	routesCmd = &cobra.Command{
+		Use:   "routes",
+		Short: "list the routes exchanged over a peering in google",
+		Long: `A cli to list the routes exchanged in both directions over a peering of a VPC in the region given by
+--region, which only google peerings expose.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			bindProviderFlags(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateProviderFlags([]string{"name", "network", "region"}); err != nil {
+				return err
+			}
+			return listPeeringRoutes()
+		},
+	}
+	deleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete a peering in any public cloud",
+		Long:  `A cli to delete a single peering of a VPC/VNet in the public cloud selected by --provider.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			bindProviderFlags(cmd, args)
+			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
+			shared.BindPlanFlag(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateProviderFlags([]string{"name", "network"}); err != nil {
+				return err
+			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planDeletePeering)
+			}
+			return deletePeering()
+		},
+	}
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{createCmd, checkCmd, listCmd, getCmd, updateCmd, routesCmd, deleteCmd} {
+		addProviderFlags(cmd)
+	}
+
+	createCmd.Flags().StringP("name", "n", "", "name of the peering to create")
+	createCmd.Flags().StringP("remote-network", "R", "", "remote network to peer with (same format as --network)")
+	createCmd.Flags().String("remote-region", "", "aws region of the remote vpc, when peering across regions")
+	createCmd.Flags().String("remote-tenant-id", "", "azure tenant id of the remote vnet, when peering across tenants")
+	createCmd.Flags().Bool("allow-forwarded-traffic", false, "azure: allow forwarded traffic from the remote vnet")
+	createCmd.Flags().Bool("allow-gateway-transit", false, "azure: allow the remote vnet to use this vnet's gateways")
+	createCmd.Flags().Bool("use-remote-gateways", false, "azure: use the remote vnet's gateways")
+	createCmd.Flags().Bool("import-custom-routes", false, "google: import custom routes from the remote network")
+	createCmd.Flags().Bool("export-custom-routes", false, "google: export custom routes to the remote network")
+	createCmd.Flags().Bool("allow-overlap", false, "create the peering even when the address spaces of both networks overlap")
+	createCmd.Flags().BoolP("bidirectional", "b", false, "azure and google: also create the remote network's half of the peering, and wait until both halves are active")
+	createCmd.Flags().String("remote-name", "", "name of the remote half of the peering when bidirectional (defaults to --name)")
+	createCmd.Flags().String("remote-google-credentials-file-path", "", "google: service account credentials json file of the remote project when bidirectional (defaults to --google-credentials-file-path)")
+	createCmd.Flags().Duration("wait-timeout", 5*time.Minute, "how long to wait for both halves to be active when bidirectional")
+	shared.AddPlanFlag(createCmd)
+
+	checkCmd.Flags().StringP("remote-network", "R", "", "remote network to check (same format as --network)")
+	checkCmd.Flags().String("remote-region", "", "aws region of the remote vpc, when in another region")
+	checkCmd.Flags().String("remote-tenant-id", "", "azure tenant id of the remote vnet, when in another tenant")
+
+	getCmd.Flags().StringP("name", "n", "", "name (or aws id) of the peering to get")
+
+	updateCmd.Flags().StringP("name", "n", "", "name of the peering to update")
+	updateCmd.Flags().Bool("allow-forwarded-traffic", false, "azure: allow forwarded traffic from the remote vnet")
+	updateCmd.Flags().Bool("allow-gateway-transit", false, "azure: allow the remote vnet to use this vnet's gateways")
+	updateCmd.Flags().Bool("use-remote-gateways", false, "azure: use the remote vnet's gateways")
+	updateCmd.Flags().Bool("import-custom-routes", false, "google: import custom routes from the remote network")
+	updateCmd.Flags().Bool("export-custom-routes", false, "google: export custom routes to the remote network")
+	shared.AddPlanFlag(updateCmd)
+
+	routesCmd.Flags().StringP("name", "n", "", "name of the peering whose routes to list")
+
+	deleteCmd.Flags().StringP("name", "n", "", "name (or aws id) of the peering to delete")
+	shared.AddPlanFlag(deleteCmd)
+
+	RootCmd.AddCommand(createCmd)
+	RootCmd.AddCommand(checkCmd)
+	RootCmd.AddCommand(listCmd)
+	RootCmd.AddCommand(getCmd)
+	RootCmd.AddCommand(updateCmd)
+	RootCmd.AddCommand(routesCmd)
+	RootCmd.AddCommand(deleteCmd)
+}
+
+// addProviderFlags will add the provider selection, network, and every provider's auth flags to a command
+func addProviderFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("provider", "", "public cloud provider to use (aws, azure, google)")
+	cmd.PersistentFlags().StringP("network", "N", "", "network to operate on: azure '{resource-group}/{vnet}', google '{project}/{network}', aws '{vpc-id}'")
+	shared_azure.AddAuthFlagsToCommand(cmd)
+	shared_aws.AddAuthFlagsToCommand(cmd)
+	shared_google.AddAuthFlagsToCommand(cmd)
+}
+
+func bindProviderFlags(cmd *cobra.Command, args []string) {
+	shared_azure.PersistentPreRun(cmd, args)
+	shared_aws.PersistentPreRun(cmd, args)
+	shared_google.PersistentPreRun(cmd, args)
+	viper.BindPFlag("provider", cmd.Flags().Lookup("provider"))
+	viper.BindPFlag("network", cmd.Flags().Lookup("network"))
+}
+
+// validateProviderFlags will validate the given keys, along with the auth keys required by the selected provider
+func validateProviderFlags(keys []string) error {
+	provider, err := peering.ParseProvider(viper.GetString("provider"))
+	if err != nil {
+		return err
+	}
+	if provider == peering.ProviderAWS {
+		keys = append(keys, "region")
+	}
+	return validate.NotEmpty(viper.GetViper(), keys)
+}
+
+// newPeerer will return the peering.Peerer for the provider selected by --provider
+func newPeerer(logger *logrus.Entry) (peering.Peerer, peering.Provider, error) {
+	provider, err := peering.ParseProvider(viper.GetString("provider"))
+	if err != nil {
+		return nil, provider, err
+	}
+	var p peering.Peerer
+	switch provider {
+	case peering.ProviderAWS:
+		p, err = peering_aws.NewPeerer(peering_aws.Config{
+			AuthConfig: shared_aws.AuthConfig(),
+			Logger:     logger,
+		})
+	case peering.ProviderAzure:
+		conf := shared_azure.AuthConfig()
+		if tenant := viper.GetString("remote-tenant-id"); tenant != "" {
+			conf.AuxTenantIDs = []string{tenant}
+		}
+		p, err = peering_azure.NewPeerer(peering_azure.Config{
+			AuthConfig: conf,
+			Logger:     logger,
+		})
+	case peering.ProviderGoogle:
+		p, err = peering_google.NewPeerer(peering_google.Config{
+			AuthConfig: shared_google.AuthConfig(),
+			Logger:     logger,
+		})
+	}
+	return p, provider, err
+}
+
+// localNetwork will parse --network for the given provider, defaulting the azure subscription and
+// aws region from the auth flags
+func localNetwork(provider peering.Provider) (peering.Network, error) {
+	n, err := peering.ParseNetwork(provider, viper.GetString("network"))
+	if err != nil {
+		return n, err
+	}
+	if provider == peering.ProviderAzure && n.Account == "" {
+		n.Account = viper.GetString("subscription-id")
+	}
+	if provider == peering.ProviderAWS && n.Region == "" {
+		n.Region = viper.GetString("region")
+	}
+	return n, nil
+}
+
+// localAndRemoteNetworks will parse --network and --remote-network for the given provider, defaulting the azure
+// subscription of the remote vnet to that of the local vnet
+func localAndRemoteNetworks(provider peering.Provider) (peering.Network, peering.Network, error) {
+	local, err := localNetwork(provider)
+	if err != nil {
+		return local, peering.Network{}, err
+	}
+	remote, err := peering.ParseNetwork(provider, viper.GetString("remote-network"))
+	if err != nil {
+		return local, remote, err
+	}
+	if provider == peering.ProviderAzure && remote.Account == "" {
+		remote.Account = local.Account
+	}
+	remote.Region = viper.GetString("remote-region")
+	return local, remote, nil
+}
+
+// remotePeerer will return the peering.Peerer of the remote network of a bidirectional peering: for azure one
+// authenticated against the remote subscription and --remote-tenant-id, with a token for the local tenant, and for
+// google one authenticated with --remote-google-credentials-file-path
+func remotePeerer(provider peering.Provider, remote peering.Network, logger *logrus.Entry) (peering.Peerer, error) {
+	if err := supportsBidirectional(provider); err != nil {
+		return nil, err
+	}
+	switch provider {
+	case peering.ProviderAzure:
+		return peering_azure.NewPeerer(peering_azure.Config{
+			AuthConfig: remoteAzureAuthConfig(remote),
+			Logger:     logger,
+		})
+	case peering.ProviderGoogle:
+		return peering_google.NewPeerer(peering_google.Config{
+			AuthConfig: remoteGoogleAuthConfig(),
+			Logger:     logger,
+		})
+	}
+	return nil, nil
+}
+
+// supportsBidirectional will return an error when the provider's peerings cannot be created bidirectionally
+func supportsBidirectional(provider peering.Provider) error {
+	if provider != peering.ProviderAzure && provider != peering.ProviderGoogle {
+		return errors.Errorf("bidirectional peerings are not supported by %s, create aws peerings with 'peering aws create --accept'", provider)
+	}
+	return nil
+}
+
+// remoteAzureAuthConfig will return the azure auth config of the remote vnet of a bidirectional peering
+func remoteAzureAuthConfig(remote peering.Network) auth_azure.AuthConfig {
+	conf := shared_azure.AuthConfig()
+	conf.SubscriptionID = remote.Account
+	if tenant := viper.GetString("remote-tenant-id"); tenant != "" && tenant != conf.TenantID {
+		conf.AuxTenantIDs = []string{conf.TenantID}
+		conf.TenantID = tenant
+	}
+	return conf
+}
+
+// remoteGoogleAuthConfig will return the google auth config of the remote project of a bidirectional peering
+func remoteGoogleAuthConfig() auth_google.AuthConfig {
+	conf := shared_google.AuthConfig()
+	if remoteCredentials := viper.GetString("remote-google-credentials-file-path"); remoteCredentials != "" {
+		conf.CredentialsFilePath = remoteCredentials
+		conf.Method = auth_google.MethodCredentialsFile
+	}
+	return conf
+}
+
+func checkOverlaps() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	p, provider, err := newPeerer(logger)
+	if err != nil {
+		return err
+	}
+	local, remote, err := localAndRemoteNetworks(provider)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	overlaps, err := peering.Overlaps(ctx, p, p, peering.CreateRequest{Network: local, RemoteNetwork: remote})
+	if err != nil {
+		return err
+	}
+	if len(overlaps) == 0 {
+		logger.Infof("address spaces of %s and %s do not overlap", local.Name, remote.Name)
+		return nil
+	}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "prefix"},
+			{Header: "remote prefix"},
+		},
+	}
+	for _, o := range overlaps {
+		table.AddRow(o.Prefix, o.RemotePrefix)
+	}
+	if err = shared.Print(overlaps, table); err != nil {
+		return err
+	}
+	return errors.Wrapf(peering.ErrOverlap, "%d prefixes of %s and %s overlap", len(overlaps), local.Name, remote.Name)
+}
+
+func createPeering() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	logger.Infof("creating peering")
+	p, provider, err := newPeerer(logger)
+	if err != nil {
+		return err
+	}
+	local, remote, err := localAndRemoteNetworks(provider)
+	if err != nil {
+		return err
+	}
+	request, err := createRequest(local, remote)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := p.Create(ctx, request)
+	if err != nil {
+		return err
+	}
+	return shared.PrintPeering(result)
+}
+
+// createBidirectionalPeering will create both halves of the peering, each with the peerer of its own network
+func createBidirectionalPeering() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	logger.Infof("creating bidirectional peering")
+	p, provider, err := newPeerer(logger)
+	if err != nil {
+		return err
+	}
+	local, remote, err := localAndRemoteNetworks(provider)
+	if err != nil {
+		return err
+	}
+	r, err := remotePeerer(provider, remote, logger)
+	if err != nil {
+		return err
+	}
+	request, err := createRequest(local, remote)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("wait-timeout"))
+	defer cancel()
+	localResult, remoteResult, err := peering.CreateBidirectional(ctx, p, r, peering.BidirectionalRequest{
+		CreateRequest: request,
+		RemoteName:    viper.GetString("remote-name"),
+	})
+	if err != nil {
+		return err
+	}
+	return shared.PrintPeerings([]peering.Peering{localResult, remoteResult})
+}
+
+// createRequest will return the request to create the peering of local with remote given by the flags
+func createRequest(local, remote peering.Network) (peering.CreateRequest, error) {
+	t, err := shared.Tags()
+	if err != nil {
+		return peering.CreateRequest{}, err
+	}
+	return peering.CreateRequest{
+		Name:          viper.GetString("name"),
+		Network:       local,
+		RemoteNetwork: remote,
+		RouteExchange: routeExchange(),
+		AllowOverlap:  viper.GetBool("allow-overlap"),
+		Tags:          t,
+	}, nil
+}
+
+func routeExchange() peering.RouteExchange {
+	return peering.RouteExchange{
+		AllowForwardedTraffic: viper.GetBool("allow-forwarded-traffic"),
+		AllowGatewayTransit:   viper.GetBool("allow-gateway-transit"),
+		UseRemoteGateways:     viper.GetBool("use-remote-gateways"),
+		ImportCustomRoutes:    viper.GetBool("import-custom-routes"),
+		ExportCustomRoutes:    viper.GetBool("export-custom-routes"),
+	}
+}
+
+// planCreatePeering will plan the peering as a manifest Peering, along with the remote half when bidirectional,
+// which is read with the credentials of the remote network
+func planCreatePeering() (plan.Plan, error) {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	provider, err := peering.ParseProvider(viper.GetString("provider"))
+	if err != nil {
+		return plan.Plan{}, err
+	}
+	if viper.GetBool("bidirectional") {
+		if err = supportsBidirectional(provider); err != nil {
+			return plan.Plan{}, err
+		}
+	}
+	p, err := shared.PlanResources(apply.Config{
+		Azure:  shared_azure.AuthConfig(),
+		AWS:    shared_aws.AuthConfig(),
+		Google: shared_google.AuthConfig(),
+		Logger: logger,
+	}, peeringResource(viper.GetString("name"), viper.GetString("network"), viper.GetString("remote-network"),
+		viper.GetString("remote-tenant-id"), routeExchange()))
+	if err != nil || !viper.GetBool("bidirectional") {
+		return p, err
+	}
+	local, remote, err := localAndRemoteNetworks(provider)
+	if err != nil {
+		return p, err
+	}
+	name := viper.GetString("remote-name")
+	if name == "" {
+		name = viper.GetString("name")
+	}
+	// the networks of the remote half are qualified, as it is read with the subscription or project of the remote
+	remoteResult, err := shared.PlanResources(apply.Config{
+		Azure:  remoteAzureAuthConfig(remote),
+		Google: remoteGoogleAuthConfig(),
+		Logger: logger,
+	}, peeringResource(name, networkReference(provider, remote), networkReference(provider, local),
+		viper.GetString("tenant-id"), peering.ReverseRouteExchange(routeExchange())))
+	p.Add(remoteResult.Resources...)
+	return p, err
+}
+
+// peeringResource will return the manifest Peering of the named peering of network with remoteNetwork
+func peeringResource(name, network, remoteNetwork, remoteTenant string, re peering.RouteExchange) apply.Resource {
+	return apply.Resource{
+		Kind:     apply.KindPeering,
+		Metadata: apply.Metadata{Name: name},
+		Spec: map[string]interface{}{
+			"provider":              viper.GetString("provider"),
+			"network":               network,
+			"remoteNetwork":         remoteNetwork,
+			"remoteRegion":          viper.GetString("remote-region"),
+			"remoteTenantId":        remoteTenant,
+			"allowForwardedTraffic": re.AllowForwardedTraffic,
+			"allowGatewayTransit":   re.AllowGatewayTransit,
+			"useRemoteGateways":     re.UseRemoteGateways,
+			"importCustomRoutes":    re.ImportCustomRoutes,
+			"exportCustomRoutes":    re.ExportCustomRoutes,
+		},
+	}
+}
+
+// networkReference will return a network as a reference of the provider, including its subscription or project
+func networkReference(provider peering.Provider, n peering.Network) string {
+	if provider == peering.ProviderAzure {
+		return strings.Join([]string{n.Account, n.Group, n.Name}, "/")
+	}
+	return n.Account + "/" + n.Name
+}
+
+func listPeerings() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	logger.Infof("listing peerings")
+	p, provider, err := newPeerer(logger)
+	if err != nil {
+		return err
+	}
+	var local peering.Network
+	if local, err = localNetwork(provider); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	peerings, err := p.List(ctx, local)
+	if err != nil {
+		return err
+	}
+	return shared.PrintPeerings(peerings)
+}
+
+func getPeering() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	p, provider, err := newPeerer(logger)
+	if err != nil {
+		return err
+	}
+	var local peering.Network
+	if local, err = localNetwork(provider); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := p.Get(ctx, local, viper.GetString("name"))
+	if err != nil {
+		return err
+	}
+	if provider == peering.ProviderGoogle && viper.GetString("region") != "" {
+		client, err := newGoogleClient(logger)
+		if err != nil {
+			return err
+		}
+		result.RemoteAddressSpace, err = client.ListPeeringRemoteAddressSpace(ctx, googlePeeringRequest(local))
+		if err != nil {
+			return err
+		}
+	}
+	return shared.PrintPeering(result)
+}
+
+// updater will return the peering.Updater of the provider selected by --provider, and the local network
+func updater(logger *logrus.Entry) (peering.Updater, peering.Peerer, peering.Network, error) {
+	p, provider, err := newPeerer(logger)
+	if err != nil {
+		return nil, nil, peering.Network{}, err
+	}
+	u, ok := p.(peering.Updater)
+	if !ok {
+		return nil, nil, peering.Network{}, errors.Errorf("updating peerings is not supported by %s", provider)
+	}
+	local, err := localNetwork(provider)
+	return u, p, local, err
+}
+
+func updatePeering(update peering.Update) error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	u, _, local, err := updater(logger)
+	if err != nil {
+		return err
+	}
+	logger.Infof("updating peering")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	result, err := u.Update(ctx, local, viper.GetString("name"), update)
+	if err != nil {
+		return err
+	}
+	return shared.PrintPeering(result)
+}
+
+// planUpdatePeering will plan the route exchange flags of the update which were given
+func planUpdatePeering(update peering.Update) (plan.Plan, error) {
+	_, p, local, err := updater(logging.GetLogger(viper.GetString("loglevel")))
+	if err != nil {
+		return plan.Plan{}, err
+	}
+	return shared.PlanPeeringUpdate(p, local, viper.GetString("name"), shared.BoolFields(map[string]*bool{
+		"allowForwardedTraffic": update.AllowForwardedTraffic,
+		"allowGatewayTransit":   update.AllowGatewayTransit,
+		"useRemoteGateways":     update.UseRemoteGateways,
+		"importCustomRoutes":    update.ImportCustomRoutes,
+		"exportCustomRoutes":    update.ExportCustomRoutes,
+	}))
+}
+
+// listPeeringRoutes will list the routes exchanged over a google peering in the region given by --region
+func listPeeringRoutes() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	provider, err := peering.ParseProvider(viper.GetString("provider"))
+	if err != nil {
+		return err
+	}
+	if provider != peering.ProviderGoogle {
+		return errors.Errorf("listing the routes exchanged over peerings is not supported by %s", provider)
+	}
+	local, err := localNetwork(provider)
+	if err != nil {
+		return err
+	}
+	client, err := newGoogleClient(logger)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	routes, err := client.ListPeerings(ctx, googlePeeringRequest(local))
+	if err != nil {
+		return err
+	}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "dest range"},
+			{Header: "type"},
+			{Header: "next hop region"},
+			{Header: "priority", Wide: true},
+			{Header: "imported", Wide: true},
+		},
+	}
+	for _, r := range routes {
+		table.AddRow(r.DestRange, r.Type, r.NextHopRegion, strconv.FormatInt(r.Priority, 10), strconv.FormatBool(r.Imported))
+	}
+	return shared.Print(routes, table)
+}
+
+func newGoogleClient(logger *logrus.Entry) (*peering_google.Client, error) {
+	return peering_google.New(peering_google.Config{
+		AuthConfig: shared_google.AuthConfig(),
+		Logger:     logger,
+	})
+}
+
+// googlePeeringRequest will return the request of the routes of the peering given by --name of a google network in
+// the region given by --region
+func googlePeeringRequest(local peering.Network) peering_google.ListPeeringRequest {
+	return peering_google.ListPeeringRequest{
+		PeeringCommon: peering_google.PeeringCommon{
+			ProjectID:   local.Account,
+			NetworkName: local.Name,
+			PeeringName: viper.GetString("name"),
+		},
+		Region: viper.GetString("region"),
+	}
+}
+
+func deletePeering() error {
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	logger.Infof("deleting peering")
+	p, provider, err := newPeerer(logger)
+	if err != nil {
+		return err
+	}
+	var local peering.Network
+	if local, err = localNetwork(provider); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err = p.Delete(ctx, local, viper.GetString("name")); err != nil {
+		return err
+	}
+	logger.Infof("peering '%s' deleted", viper.GetString("name"))
+	return nil
+}
+
+func planDeletePeering() (plan.Plan, error) {
+	p, provider, err := newPeerer(logging.GetLogger(viper.GetString("loglevel")))
+	if err != nil {
+		return plan.Plan{}, err
+	}
+	local, err := localNetwork(provider)
+	if err != nil {
+		return plan.Plan{}, err
+	}
+	return shared.PlanPeeringDelete(p, local, viper.GetString("name"))
+}
@@ -3,8 +3,7 @@ package peering
 import (
 	"github.com/spf13/cobra"
 
-	"github.com/naemono/go-cloud-actions/cmd/peering/azure"
-	"github.com/naemono/go-cloud-actions/cmd/peering/google"
+	"github.com/naemono/go-cloud-actions/cmd/peering/aws"
 )
 
 var (
@@ -20,6 +19,7 @@ var (
 )
 
 func init() {
-	RootCmd.AddCommand(azure.AzureCmd)
-	RootCmd.AddCommand(google.GoogleCmd)
+	// the aws subtree accepts peering connections, and adds their routes, with the credentials of the peer
+	// account, which the provider independent commands cannot
+	RootCmd.AddCommand(aws.AWSCmd)
 }
@@ -5,12 +5,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/naemono/go-cloud-actions/cmd/shared"
 	shared_azure "github.com/naemono/go-cloud-actions/cmd/shared/azure"
-	auth_azure "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	"github.com/naemono/go-cloud-actions/pkg/apply"
 	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
 	azure_resources "github.com/naemono/go-cloud-actions/pkg/resources/azure"
 	"github.com/naemono/go-cloud-actions/pkg/validate"
 )
@@ -43,11 +47,15 @@ var (
 			}
 			viper.BindPFlag("name", cmd.Flags().Lookup("name"))
 			viper.BindPFlag("location", cmd.Flags().Lookup("location"))
+			shared.BindPlanFlag(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := validate.NotEmpty(viper.GetViper(), []string{"name", "location"}); err != nil {
 				return err
 			}
+			if shared.Planning() {
+				return shared.Plan(cmd, planCreateResourceGroup)
+			}
 			return createResourceGroup()
 		},
 	}
@@ -58,6 +66,7 @@ func init() {
 
 	resourceGroupAddCmd.Flags().StringP("name", "n", "", "name of resource group")
 	resourceGroupAddCmd.Flags().StringP("location", "L", "", "location/region of resource group")
+	shared.AddPlanFlag(resourceGroupAddCmd)
 
 	AzureCmd.AddCommand(resourceGroupsCmd)
 	resourceGroupsCmd.AddCommand(resourceGroupAddCmd)
@@ -66,23 +75,47 @@ func init() {
 func createResourceGroup() error {
 	logger := logging.GetLogger(viper.GetString("loglevel"))
 	logger.Infof("creating resource group")
+	t, err := shared.Tags()
+	if err != nil {
+		return err
+	}
 	client, err := azure_resources.New(azure_resources.Config{
-		AuthConfig: auth_azure.AuthConfig{
-			SubscriptionID: viper.GetString("subscription-id"),
-			ClientID:       viper.GetString("client-id"),
-			ClientSecret:   viper.GetString("client-secret"),
-			TenantID:       viper.GetString("tenant-id"),
-		},
+		AuthConfig: shared_azure.AuthConfig(),
 	})
 	if err != nil {
 		return err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	err = client.CreateResourceGroup(ctx, viper.GetString("name"), strings.ToLower(viper.GetString("location")))
+	group, err := client.CreateResourceGroup(ctx, viper.GetString("name"), strings.ToLower(viper.GetString("location")), t)
 	if err != nil {
 		return err
 	}
 	logger.Infof("resource group '%s' created", viper.GetString("name"))
-	return nil
+	state := ""
+	if group.Properties != nil {
+		state = to.String(group.Properties.ProvisioningState)
+	}
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "location"},
+			{Header: "state"},
+			{Header: "id", Wide: true},
+		},
+	}
+	table.AddRow(to.String(group.Name), to.String(group.Location), state, to.String(group.ID))
+	return shared.Print(group, table)
+}
+
+// planCreateResourceGroup will plan the resource group as a manifest ResourceGroup
+func planCreateResourceGroup() (plan.Plan, error) {
+	return shared.PlanResources(apply.Config{
+		Azure:  shared_azure.AuthConfig(),
+		Logger: logging.GetLogger(viper.GetString("loglevel")),
+	}, apply.Resource{
+		Kind:     apply.KindResourceGroup,
+		Metadata: apply.Metadata{Name: viper.GetString("name")},
+		Spec:     map[string]interface{}{"location": viper.GetString("location")},
+	})
 }
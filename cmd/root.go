@@ -1,17 +1,28 @@
 package cmd
 
 import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/naemono/go-cloud-actions/cmd/apply"
+	"github.com/naemono/go-cloud-actions/cmd/auth"
 	"github.com/naemono/go-cloud-actions/cmd/compute"
+	cloud_config "github.com/naemono/go-cloud-actions/cmd/config"
+	"github.com/naemono/go-cloud-actions/cmd/destroy"
 	"github.com/naemono/go-cloud-actions/cmd/identity"
 	"github.com/naemono/go-cloud-actions/cmd/network"
 	"github.com/naemono/go-cloud-actions/cmd/peering"
 	"github.com/naemono/go-cloud-actions/cmd/resources"
+	"github.com/naemono/go-cloud-actions/pkg/config"
 	"github.com/naemono/go-cloud-actions/pkg/logging"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
 )
 
 var (
@@ -28,22 +39,78 @@ var (
 		},
 	}
 	version string
+	output  printer.Output
 )
 
 func init() {
+	cobra.OnInitialize(initConfig)
 	CloudCmd.PersistentFlags().StringP("loglevel", "l", "info", "logging level")
 	viper.BindPFlag("loglevel", CloudCmd.PersistentFlags().Lookup("loglevel"))
+	CloudCmd.PersistentFlags().String("config", "", "config file holding named contexts (default ~/.config/cloud/config.yaml)")
+	viper.BindPFlag("config", CloudCmd.PersistentFlags().Lookup("config"))
+	CloudCmd.PersistentFlags().String("context", "", "name of the config context to use (default the current context)")
+	viper.BindPFlag("context", CloudCmd.PersistentFlags().Lookup("context"))
+	CloudCmd.PersistentFlags().VarP(&output, "output", "o",
+		"output format of results: table, wide, json, yaml, jsonpath=<template> or go-template=<template> (logs are written to stderr)")
+	viper.BindPFlag("output", CloudCmd.PersistentFlags().Lookup("output"))
+	CloudCmd.PersistentFlags().StringSlice("tags", nil, "tags (key=value,...) to apply to every created resource, as labels in google")
+	viper.BindPFlag("tags", CloudCmd.PersistentFlags().Lookup("tags"))
+	CloudCmd.PersistentFlags().String("tags-file", "", "yaml or json file of tags to apply to every created resource, overridden by --tags")
+	viper.BindPFlag("tags-file", CloudCmd.PersistentFlags().Lookup("tags-file"))
+	CloudCmd.PersistentFlags().String("owner", os.Getenv("USER"), "owner of created resources, given in their default owner tag")
+	viper.BindPFlag("owner", CloudCmd.PersistentFlags().Lookup("owner"))
+	CloudCmd.PersistentFlags().Bool("default-tags", true, "also apply the default owner, created-by and created-at tags to every created resource")
+	viper.BindPFlag("default-tags", CloudCmd.PersistentFlags().Lookup("default-tags"))
 	CloudCmd.AddCommand(compute.RootCmd)
 	CloudCmd.AddCommand(peering.RootCmd)
 	CloudCmd.AddCommand(identity.RootCmd)
 	CloudCmd.AddCommand(resources.RootCmd)
 	CloudCmd.AddCommand(network.RootCmd)
+	CloudCmd.AddCommand(cloud_config.RootCmd)
+	CloudCmd.AddCommand(apply.RootCmd)
+	CloudCmd.AddCommand(destroy.RootCmd)
+	CloudCmd.AddCommand(auth.RootCmd)
+}
+
+// initConfig will load the selected context of the config file as defaults for every flag, which are in turn
+// overridden by CLOUD_ prefixed environment variables, and flags given on the command line
+func initConfig() {
+	viper.SetEnvPrefix(config.EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+	logger := logging.GetLogger(viper.GetString("loglevel"))
+	path, err := config.Path(viper.GetString("config"))
+	if err != nil {
+		logger.WithError(err).Fatal("failed to find config file")
+	}
+	conf, err := config.Load(path)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to load config file")
+	}
+	ctx, ok, err := conf.Context(viper.GetString("context"))
+	if err != nil && viper.GetString("context") == "" {
+		// a missing current context must not prevent switching to another context
+		logger.WithError(err).Warn("ignoring current context of config file")
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Fatal("failed to load config context")
+	}
+	if !ok {
+		return
+	}
+	if err = viper.MergeConfigMap(ctx.Values()); err != nil {
+		logger.WithError(err).Fatal("failed to load config context")
+	}
 }
 
 // Run will run the main command
 func Run() {
 	logging.GetLogger(viper.GetString("loglevel")).Debugf("running cloud version: %s", version)
 	if err := CloudCmd.Execute(); err != nil {
+		if errors.Is(err, plan.ErrChangesPending) {
+			os.Exit(plan.ExitCodeChangesPending)
+		}
 		logrus.WithError(err).Fatal("failure running cloud command")
 	}
 }
@@ -3,19 +3,77 @@ package aws
 import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	auth_aws "github.com/naemono/go-cloud-actions/pkg/auth/aws"
 )
 
+var authFlags = []string{
+	"profile", "region", "access-key-id", "secret-access-key", "session-token",
+	"role-arn", "external-id", "role-session-name", "mfa-serial", "mfa-token", "web-identity-token-file",
+	"sso-start-url", "sso-region", "sso-account-id", "sso-role-name",
+}
+
 // PersistentPreRun is a shared persistent pre-run for aws commands
 func PersistentPreRun(cmd *cobra.Command, args []string) {
 	if cmd.Parent() != nil && cmd.Parent().PersistentPreRun != nil {
 		cmd.Parent().PersistentPreRun(cmd.Parent(), args)
 	}
-	viper.BindPFlag("profile", cmd.Flags().Lookup("profile"))
-	viper.BindPFlag("region", cmd.Flags().Lookup("region"))
+	for _, name := range authFlags {
+		viper.BindPFlag(name, cmd.Flags().Lookup(name))
+	}
 }
 
 // AddAuthFlagsToCommand is a shared command to add the aws auth components to any aws cobra command
 func AddAuthFlagsToCommand(cmd *cobra.Command) {
 	cmd.PersistentFlags().StringP("profile", "p", "", "aws profile to use")
 	cmd.PersistentFlags().StringP("region", "r", "", "aws region")
+	cmd.PersistentFlags().String("access-key-id", "", "aws access key id, instead of the profile credentials")
+	cmd.PersistentFlags().String("secret-access-key", "", "aws secret access key, instead of the profile credentials")
+	cmd.PersistentFlags().String("session-token", "", "aws session token of temporary access keys")
+	cmd.PersistentFlags().StringSlice("role-arn", nil, "aws role arn to assume, repeated to assume a chain of roles in order")
+	cmd.PersistentFlags().StringSlice("external-id", nil, "external id of each assumed role, in the same order as --role-arn")
+	cmd.PersistentFlags().String("role-session-name", auth_aws.DefaultRoleSessionName, "session name of assumed roles")
+	cmd.PersistentFlags().String("mfa-serial", "", "serial number or arn of the mfa device required to assume the first role")
+	cmd.PersistentFlags().String("mfa-token", "", "current mfa code (prompted for when --mfa-serial is given without it)")
+	cmd.PersistentFlags().String("web-identity-token-file", "", "web identity (oidc) token file, to assume the first --role-arn with")
+	cmd.PersistentFlags().String("sso-start-url", "", "aws iam identity center (sso) start url, signed in with aws sso login")
+	cmd.PersistentFlags().String("sso-region", "", "aws iam identity center (sso) region (defaults to --region)")
+	cmd.PersistentFlags().String("sso-account-id", "", "aws account id to sign in to with sso")
+	cmd.PersistentFlags().String("sso-role-name", "", "aws permission set (role) name to sign in with sso")
+}
+
+// AuthConfig will return the aws auth configuration given by the shared aws auth flags
+func AuthConfig() auth_aws.AuthConfig {
+	conf := auth_aws.AuthConfig{
+		Profile:              viper.GetString("profile"),
+		Region:               viper.GetString("region"),
+		AccessKeyID:          viper.GetString("access-key-id"),
+		SecretAccessKey:      viper.GetString("secret-access-key"),
+		SessionToken:         viper.GetString("session-token"),
+		SSOStartURL:          viper.GetString("sso-start-url"),
+		SSORegion:            viper.GetString("sso-region"),
+		SSOAccountID:         viper.GetString("sso-account-id"),
+		SSORoleName:          viper.GetString("sso-role-name"),
+		WebIdentityTokenFile: viper.GetString("web-identity-token-file"),
+		MFASerial:            viper.GetString("mfa-serial"),
+		MFATokenCode:         viper.GetString("mfa-token"),
+	}
+	conf.AssumeRoles = AssumeRoles(viper.GetStringSlice("role-arn"), viper.GetStringSlice("external-id"))
+	return conf
+}
+
+// AssumeRoles will return the chain of roles to assume, pairing each role arn with the external id at the same position
+func AssumeRoles(arns, externalIDs []string) []auth_aws.AssumeRole {
+	var roles []auth_aws.AssumeRole
+	for i, arn := range arns {
+		role := auth_aws.AssumeRole{
+			RoleARN:     arn,
+			SessionName: viper.GetString("role-session-name"),
+		}
+		if i < len(externalIDs) {
+			role.ExternalID = externalIDs[i]
+		}
+		roles = append(roles, role)
+	}
+	return roles
 }
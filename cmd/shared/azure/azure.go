@@ -3,6 +3,8 @@ package azure
 import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	auth_azure "github.com/naemono/go-cloud-actions/pkg/auth/azure"
 )
 
 // PersistentPreRun is a shared persistent pre-run for azure commands
@@ -14,6 +16,11 @@ func PersistentPreRun(cmd *cobra.Command, args []string) {
 	viper.BindPFlag("client-secret", cmd.Flags().Lookup("client-secret"))
 	viper.BindPFlag("subscription-id", cmd.Flags().Lookup("subscription-id"))
 	viper.BindPFlag("tenant-id", cmd.Flags().Lookup("tenant-id"))
+	viper.BindPFlag("auth-method", cmd.Flags().Lookup("auth-method"))
+	viper.BindPFlag("certificate-path", cmd.Flags().Lookup("certificate-path"))
+	viper.BindPFlag("certificate-password", cmd.Flags().Lookup("certificate-password"))
+	viper.BindPFlag("federated-token-file", cmd.Flags().Lookup("federated-token-file"))
+	viper.BindPFlag("azure-environment", cmd.Flags().Lookup("azure-environment"))
 }
 
 // AddAuthFlagsToCommand is a shared command to add the azure auth components to any azure cobra command
@@ -22,4 +29,24 @@ func AddAuthFlagsToCommand(cmd *cobra.Command) {
 	cmd.PersistentFlags().StringP("client-secret", "S", "", "azure client secret")
 	cmd.PersistentFlags().StringP("subscription-id", "s", "", "azure subscription id")
 	cmd.PersistentFlags().StringP("tenant-id", "t", "", "azure tenant id")
+	cmd.PersistentFlags().String("auth-method", string(auth_azure.MethodClientSecret), "azure auth method (client-secret, client-certificate, managed-identity, device-code, cli, workload-identity)")
+	cmd.PersistentFlags().String("certificate-path", "", "azure client certificate (pkcs12) file, with client-certificate auth")
+	cmd.PersistentFlags().String("certificate-password", "", "azure client certificate password, with client-certificate auth")
+	cmd.PersistentFlags().String("federated-token-file", "", "federated token file, with workload-identity auth (default $AZURE_FEDERATED_TOKEN_FILE)")
+	cmd.PersistentFlags().String("azure-environment", "public", "azure cloud (public, usgovernment, china, german), or the path to an environment json file such as for Azure Stack")
+}
+
+// AuthConfig will return the azure auth configuration given by the shared azure auth flags
+func AuthConfig() auth_azure.AuthConfig {
+	return auth_azure.AuthConfig{
+		SubscriptionID:      viper.GetString("subscription-id"),
+		ClientID:            viper.GetString("client-id"),
+		ClientSecret:        viper.GetString("client-secret"),
+		TenantID:            viper.GetString("tenant-id"),
+		Method:              auth_azure.Method(viper.GetString("auth-method")),
+		CertificatePath:     viper.GetString("certificate-path"),
+		CertificatePassword: viper.GetString("certificate-password"),
+		FederatedTokenFile:  viper.GetString("federated-token-file"),
+		Environment:         viper.GetString("azure-environment"),
+	}
 }
@@ -0,0 +1,76 @@
+package google
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	auth_google "github.com/naemono/go-cloud-actions/pkg/auth/google"
+)
+
+var authFlags = []string{
+	"google-credentials-file-path", "google-auth-method", "google-external-account-file", "google-access-token-env",
+	"google-impersonate-service-account", "google-impersonate-delegates",
+}
+
+// PersistentPreRun is a shared persistent pre-run for google commands
+func PersistentPreRun(cmd *cobra.Command, args []string) {
+	if cmd.Parent() != nil && cmd.Parent().PersistentPreRun != nil {
+		cmd.Parent().PersistentPreRun(cmd.Parent(), args)
+	}
+	for _, name := range authFlags {
+		viper.BindPFlag(name, cmd.Flags().Lookup(name))
+	}
+}
+
+// AddAuthFlagsToCommand is a shared command to add the google auth components to any google cobra command
+func AddAuthFlagsToCommand(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP("google-credentials-file-path", "G", "", "google service account credentials json file")
+	cmd.PersistentFlags().String("google-auth-method", "", "google auth method (credentials-file, adc, external-account, access-token), defaults to credentials-file when --google-credentials-file-path is given, and adc otherwise")
+	cmd.PersistentFlags().String("google-external-account-file", "", "google external account (workload identity federation) credential configuration json file, with external-account auth")
+	cmd.PersistentFlags().String("google-access-token-env", auth_google.DefaultAccessTokenEnv, "environment variable holding a google oauth2 access token, with access-token auth")
+	cmd.PersistentFlags().String("google-impersonate-service-account", "", "google service account to impersonate with the credentials of the auth method")
+	cmd.PersistentFlags().StringSlice("google-impersonate-delegates", nil, "chain of google service accounts delegating the impersonation, in order")
+}
+
+// AuthConfig will return the google auth configuration given by the shared google auth flags
+func AuthConfig() auth_google.AuthConfig {
+	return auth_google.AuthConfig{
+		CredentialsFilePath:       viper.GetString("google-credentials-file-path"),
+		Method:                    auth_google.Method(viper.GetString("google-auth-method")),
+		ExternalAccountFilePath:   viper.GetString("google-external-account-file"),
+		AccessTokenEnv:            viper.GetString("google-access-token-env"),
+		ImpersonateServiceAccount: viper.GetString("google-impersonate-service-account"),
+		ImpersonateDelegates:      viper.GetStringSlice("google-impersonate-delegates"),
+	}
+}
+
+// AuthArgs will return the shared google auth flags of the current configuration as command line arguments, such as
+// of an exec credential plugin run later, without the config context the configuration may have come from.  The
+// paths of credential files are absolute, as the plugin is run from the working directory of its caller.
+func AuthArgs() ([]string, error) {
+	var args []string
+	for _, name := range authFlags {
+		if name == "google-impersonate-delegates" {
+			for _, delegate := range viper.GetStringSlice(name) {
+				args = append(args, "--"+name+"="+delegate)
+			}
+			continue
+		}
+		value := viper.GetString(name)
+		if value == "" {
+			continue
+		}
+		if name == "google-credentials-file-path" || name == "google-external-account-file" {
+			path, err := filepath.Abs(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to find the absolute path of --%s", name)
+			}
+			value = path
+		}
+		args = append(args, "--"+name+"="+value)
+	}
+	return args, nil
+}
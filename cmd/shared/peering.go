@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+)
+
+// PrintPeering will print a single provider independent peering
+func PrintPeering(p peering.Peering) error {
+	return Print(p, peeringTable(p))
+}
+
+// PrintPeerings will print a list of provider independent peerings
+func PrintPeerings(peerings []peering.Peering) error {
+	return Print(peerings, peeringTable(peerings...))
+}
+
+func peeringTable(peerings ...peering.Peering) printer.Table {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "name"},
+			{Header: "provider", Wide: true},
+			{Header: "id", Wide: true},
+			{Header: "state"},
+			{Header: "network", Wide: true},
+			{Header: "remote network"},
+			{Header: "remote address space", Wide: true},
+			{Header: "sync level", Wide: true},
+		},
+	}
+	for _, p := range peerings {
+		table.AddRow(
+			p.Name,
+			string(p.Provider),
+			p.ID,
+			string(p.State),
+			p.Network,
+			p.RemoteNetwork,
+			strings.Join(p.RemoteAddressSpace, ","),
+			p.SyncLevel)
+	}
+	return table
+}
+
+// PlanPeeringDelete will plan the deletion of the named peering of network, which is a no-op when it does not exist
+func PlanPeeringDelete(p peering.Peerer, network peering.Network, name string) (plan.Plan, error) {
+	var result plan.Plan
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	actual, err := p.Get(ctx, network, name)
+	if err == peering.ErrNotFound {
+		result.Add(plan.NoOp(string(apply.KindPeering), name))
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.Add(plan.Delete(string(apply.KindPeering), name, map[string]string{
+		"id":            actual.ID,
+		"state":         string(actual.State),
+		"remoteNetwork": actual.RemoteNetwork,
+	}))
+	return result, nil
+}
+
+// PlanPeeringUpdate will plan the update of the named peering of network to the desired route exchange fields,
+// as named by RouteExchangeFields
+func PlanPeeringUpdate(p peering.Peerer, network peering.Network, name string, desired map[string]string) (plan.Plan, error) {
+	var result plan.Plan
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	actual, err := p.Get(ctx, network, name)
+	if err != nil {
+		return result, err
+	}
+	result.Add(plan.Update(string(apply.KindPeering), name, desired, RouteExchangeFields(actual.RouteExchange)))
+	return result, nil
+}
+
+// RouteExchangeFields will return the route exchange flags as plan fields, named as their json fields
+func RouteExchangeFields(r peering.RouteExchange) map[string]string {
+	return map[string]string{
+		"allowForwardedTraffic": strconv.FormatBool(r.AllowForwardedTraffic),
+		"allowGatewayTransit":   strconv.FormatBool(r.AllowGatewayTransit),
+		"useRemoteGateways":     strconv.FormatBool(r.UseRemoteGateways),
+		"importCustomRoutes":    strconv.FormatBool(r.ImportCustomRoutes),
+		"exportCustomRoutes":    strconv.FormatBool(r.ExportCustomRoutes),
+	}
+}
@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/pkg/apply"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+)
+
+// AddPlanFlag will add the --plan flag to a command which creates, updates or deletes resources
+func AddPlanFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("plan", false,
+		fmt.Sprintf("print the changes the command would make without making them, exiting with %d when changes are pending",
+			plan.ExitCodeChangesPending))
+}
+
+// BindPlanFlag will bind the --plan flag of a command, and is called from its persistent pre-run
+func BindPlanFlag(cmd *cobra.Command) {
+	viper.BindPFlag("plan", cmd.Flags().Lookup("plan"))
+}
+
+// Planning will return whether the command was run with --plan
+func Planning() bool {
+	return viper.GetBool("plan")
+}
+
+// Plan will print the plan returned by planner, for commands run with --plan.  When any resource would be changed
+// it returns plan.ErrChangesPending, without the usage of the command, so that Run exits with
+// plan.ExitCodeChangesPending.
+func Plan(cmd *cobra.Command, planner func() (plan.Plan, error)) error {
+	p, err := planner()
+	if err != nil {
+		return err
+	}
+	if err = PrintPlan(p); err != nil {
+		return err
+	}
+	if !p.HasChanges() {
+		return nil
+	}
+	cmd.SilenceErrors, cmd.SilenceUsage = true, true
+	return plan.ErrChangesPending
+}
+
+// PrintPlan will print the changes of a plan, one row per resource
+func PrintPlan(p plan.Plan) error {
+	table := printer.Table{
+		Columns: []printer.Column{
+			{Header: "kind"},
+			{Header: "name"},
+			{Header: "action"},
+			{Header: "changes"},
+		},
+	}
+	for _, r := range p.Resources {
+		changes := make([]string, 0, len(r.Changes))
+		for _, c := range r.Changes {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", c.Field, c.Actual, c.Desired))
+		}
+		table.AddRow(r.Kind, r.Name, string(r.Action), strings.Join(changes, ", "))
+	}
+	return Print(p, table)
+}
+
+// PlanResources will plan applying the given manifest resources, for the create commands of kinds which can also be
+// applied, so that both plan them alike
+func PlanResources(conf apply.Config, resources ...apply.Resource) (plan.Plan, error) {
+	applier, err := apply.New(conf)
+	if err != nil {
+		return plan.Plan{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	return applier.Plan(ctx, resources)
+}
+
+// BoolFields will return the non-nil values, as returned by ChangedBool, as plan fields, so that flags which were
+// not set are never planned
+func BoolFields(values map[string]*bool) map[string]string {
+	fields := map[string]string{}
+	for field, value := range values {
+		if value != nil {
+			fields[field] = strconv.FormatBool(*value)
+		}
+	}
+	return fields
+}
@@ -1,6 +1,13 @@
 package shared
 
-import "github.com/spf13/cobra"
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/pkg/printer"
+)
 
 // RunParentsPersistentPreRun will be used to ensure that the parent's
 // persistent pre-run is run for every cobra command
@@ -9,3 +16,26 @@ func RunParentsPersistentPreRun(cmd *cobra.Command, args []string) {
 		cmd.Parent().PersistentPreRun(cmd.Parent(), args)
 	}
 }
+
+// ChangedBool will return a pointer to the value of a bool flag, or nil if the flag was not set on the
+// command line, so that unset flags can leave existing values unchanged
+func ChangedBool(cmd *cobra.Command, name string) *bool {
+	if !cmd.Flags().Changed(name) {
+		return nil
+	}
+	value, err := cmd.Flags().GetBool(name)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// Print will print the results of a command to stdout, in the format given by the global output flag.
+// Table formats print the given table, all other formats print data.
+func Print(data interface{}, table printer.Table) error {
+	p, err := printer.New(viper.GetString("output"), os.Stdout)
+	if err != nil {
+		return err
+	}
+	return p.Print(data, table)
+}
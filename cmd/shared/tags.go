@@ -0,0 +1,40 @@
+package shared
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+// Tags will return the tags to apply to every resource created by a command: the default tags, unless disabled
+// with --default-tags=false, overridden by those of --tags-file, in turn overridden by those of --tags
+func Tags() (tags.Tags, error) {
+	var defaults, file tags.Tags
+	if viper.GetBool("default-tags") {
+		defaults = tags.Defaults(viper.GetString("owner"), time.Now())
+	}
+	if path := viper.GetString("tags-file"); path != "" {
+		var err error
+		if file, err = tags.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	// tags are a slice when given as a flag, and a comma separated string when given by a context or environment
+	var pairs []string
+	switch value := viper.Get("tags").(type) {
+	case string:
+		pairs = strings.Split(value, ",")
+	case []string:
+		pairs = value
+	default:
+		pairs = viper.GetStringSlice("tags")
+	}
+	flags, err := tags.Parse(pairs)
+	if err != nil {
+		return nil, err
+	}
+	return tags.Merge(defaults, file, flags), nil
+}
@@ -0,0 +1,247 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	aws_auth "github.com/naemono/go-cloud-actions/pkg/auth/aws"
+	azure_auth "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	google_auth "github.com/naemono/go-cloud-actions/pkg/auth/google"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+// Result is the result of applying a single resource
+type Result struct {
+	Kind    Kind              `json:"kind"`
+	Name    string            `json:"name"`
+	Action  plan.Action       `json:"action"`
+	Changes []plan.Change     `json:"changes,omitempty"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+// Config is the configuration of an applier.  Every provider's auth config is only used once a resource of
+// that provider is applied, and can be overridden per resource by the subscriptionId, region and projectId of
+// its spec.
+type Config struct {
+	Azure  azure_auth.AuthConfig
+	AWS    aws_auth.AuthConfig
+	Google google_auth.AuthConfig
+	Logger *logrus.Entry
+	// Tags are applied to every created resource, overridden by the tags of its spec
+	Tags tags.Tags
+}
+
+// Applier applies manifests, creating the resources which do not exist, and updating the ones which differ
+type Applier struct {
+	Config
+	clients *clients
+}
+
+// New will return a new applier
+func New(conf Config) (*Applier, error) {
+	a := &Applier{
+		Config: conf,
+	}
+	if a.Logger == nil {
+		a.Logger = logrus.NewEntry(logrus.New())
+		a.Logger.Logger.SetLevel(logrus.InfoLevel)
+		a.Logger.Logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+	a.clients = newClients(a.Config)
+	return a, nil
+}
+
+// kind is implemented by the spec of every manifest kind
+type kind interface {
+	// fields will return the comparable fields of the spec.  Empty fields are left to the provider, and are
+	// never compared.
+	fields() map[string]string
+	// observe will return the actual state of the named resource, or nil if it does not exist
+	observe(ctx context.Context, c *clients, name string) (*state, error)
+	// create will create the named resource, returning its resulting state
+	create(ctx context.Context, c *clients, name string) (*state, error)
+	// update will update the named existing resource with the given changes, returning its resulting state
+	update(ctx context.Context, c *clients, name string, changes []plan.Change) (*state, error)
+	// destroy will return the deletion of the named existing resource, after the deletion of every dependent which
+	// would prevent it
+	destroy(ctx context.Context, c *clients, name string, actual *state) ([]deletion, error)
+}
+
+// state is the actual state of a resource
+type state struct {
+	// fields are the comparable fields of the resource, keyed as the fields of its kind's spec
+	fields map[string]string
+	// outputs are the values other resources can reference, such as the id
+	outputs map[string]string
+}
+
+func newKind(k Kind) kind {
+	switch k {
+	case KindResourceGroup:
+		return &resourceGroupSpec{}
+	case KindNetworkProfile:
+		return &networkProfileSpec{}
+	case KindVPC:
+		return &vpcSpec{}
+	case KindSubnet:
+		return &subnetSpec{}
+	case KindPeering:
+		return &peeringSpec{}
+	case KindContainerGroup:
+		return &containerGroupSpec{}
+	case KindGKECluster:
+		return &gkeClusterSpec{}
+	}
+	return nil
+}
+
+// Apply will apply the resources in dependency order, returning the result of every applied resource.  On
+// failure, the results of the resources applied so far are returned along with the error.
+func (a *Applier) Apply(ctx context.Context, resources []Resource) ([]Result, error) {
+	ordered, err := Order(resources)
+	if err != nil {
+		return nil, err
+	}
+	outputs := map[string]map[string]string{}
+	results := make([]Result, 0, len(ordered))
+	for _, r := range ordered {
+		result, err := a.apply(ctx, r, outputs)
+		if err != nil {
+			return results, err
+		}
+		outputs[r.ID()] = result.Outputs
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Plan will return the changes applying the resources would make, without making them.  Resources referencing a
+// resource which would be created are planned as created as well, as the outputs they reference are only known once
+// applied.
+func (a *Applier) Plan(ctx context.Context, resources []Resource) (plan.Plan, error) {
+	var p plan.Plan
+	ordered, err := Order(resources)
+	if err != nil {
+		return p, err
+	}
+	// the outputs of resources which would be created are nil, and resolve as knownAfterApply
+	outputs := map[string]map[string]string{}
+	for _, r := range ordered {
+		k, err := decode(r, outputs)
+		if err != nil {
+			return p, err
+		}
+		if dependsOnCreated(r, outputs) {
+			p.Add(plan.Create(string(r.Kind), r.Metadata.Name, k.fields()))
+			outputs[r.ID()] = nil
+			continue
+		}
+		actual, err := k.observe(ctx, a.clients, r.Metadata.Name)
+		if err != nil {
+			return p, errors.Wrapf(err, "failed to get %s", r.ID())
+		}
+		if actual == nil {
+			p.Add(plan.Create(string(r.Kind), r.Metadata.Name, k.fields()))
+			outputs[r.ID()] = nil
+			continue
+		}
+		p.Add(plan.Update(string(r.Kind), r.Metadata.Name, k.fields(), actual.fields))
+		outputs[r.ID()] = actual.outputs
+	}
+	return p, nil
+}
+
+// dependsOnCreated will return whether r depends on a resource which would be created
+func dependsOnCreated(r Resource, outputs map[string]map[string]string) bool {
+	for _, d := range r.dependencies() {
+		if values, ok := outputs[d]; ok && values == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Applier) apply(ctx context.Context, r Resource, outputs map[string]map[string]string) (Result, error) {
+	result := Result{Kind: r.Kind, Name: r.Metadata.Name}
+	k, err := decode(r, outputs)
+	if err != nil {
+		return result, err
+	}
+	logger := a.Logger.WithField("resource", r.ID())
+	actual, err := k.observe(ctx, a.clients, r.Metadata.Name)
+	if err != nil {
+		return result, errors.Wrapf(err, "failed to get %s", r.ID())
+	}
+	if actual == nil {
+		logger.Infof("creating %s", r.ID())
+		created, err := k.create(ctx, a.clients, r.Metadata.Name)
+		if err != nil {
+			return result, errors.Wrapf(err, "failed to create %s", r.ID())
+		}
+		result.Action, result.Outputs = plan.ActionCreate, created.outputs
+		return result, nil
+	}
+	result.Changes = plan.Diff(k.fields(), actual.fields)
+	if len(result.Changes) == 0 {
+		logger.Debugf("%s is unchanged", r.ID())
+		result.Action, result.Outputs = plan.ActionNoOp, actual.outputs
+		return result, nil
+	}
+	logger.Infof("updating %s", r.ID())
+	updated, err := k.update(ctx, a.clients, r.Metadata.Name, result.Changes)
+	if err != nil {
+		return result, errors.Wrapf(err, "failed to update %s", r.ID())
+	}
+	result.Action, result.Outputs = plan.ActionUpdate, updated.outputs
+	return result, nil
+}
+
+// observeCreated will observe a resource that was just created, for the providers whose create does not return it
+func observeCreated(ctx context.Context, k kind, c *clients, name string) (*state, error) {
+	st, err := k.observe(ctx, c, name)
+	if err == nil && st == nil {
+		err = errors.New("not found after creation")
+	}
+	return st, err
+}
+
+// decode will resolve the references of the spec of r, and decode it into the spec of its kind.  Unknown
+// fields are errors, as a misspelled field would otherwise silently be left to the provider.
+func decode(r Resource, outputs map[string]map[string]string) (kind, error) {
+	spec, err := r.resolve(outputs)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode spec of %s", r.ID())
+	}
+	k := newKind(r.Kind)
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(k); err != nil {
+		return nil, errors.Wrapf(err, "invalid spec of %s", r.ID())
+	}
+	return k, nil
+}
+
+// immutable will return the error of changes that cannot be applied in place
+func immutable(changes []plan.Change) error {
+	fields := make([]string, 0, len(changes))
+	for _, c := range changes {
+		fields = append(fields, fmt.Sprintf("%s (%q, desired %q)", c.Field, c.Actual, c.Desired))
+	}
+	return errors.Errorf("%s cannot be changed in place, the resource must be deleted and applied again", strings.Join(fields, ", "))
+}
+
+// boolField will return the comparable field value of a bool
+func boolField(b bool) string {
+	return fmt.Sprintf("%t", b)
+}
@@ -0,0 +1,28 @@
+package apply
+
+import "testing"
+
+func TestDependsOnCreated(t *testing.T) {
+	outputs := map[string]map[string]string{
+		"VPC/existing": {"id": "vpc-1"},
+		"VPC/created":  nil,
+	}
+	tests := []struct {
+		name     string
+		resource Resource
+		want     bool
+	}{
+		{name: "no dependencies", resource: resource(KindSubnet, "s", nil)},
+		{name: "existing reference", resource: resource(KindSubnet, "s", map[string]interface{}{"vpcId": "${VPC/existing.id}"})},
+		{name: "created reference", resource: resource(KindSubnet, "s", map[string]interface{}{"vpcId": "${VPC/created.id}"}), want: true},
+		{name: "created dependsOn", resource: resource(KindSubnet, "s", nil, "VPC/existing", "VPC/created"), want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dependsOnCreated(tt.resource, outputs); got != tt.want {
+				t.Errorf("dependsOnCreated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package apply
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+
+	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+// waitAvailable is how long to wait for created vpcs and subnets to be available, before the resources using them
+// are applied
+const waitAvailable = 5 * time.Minute
+
+// vpcSpec is the spec of a VPC, which is identified by its Name tag, the name of the resource
+type vpcSpec struct {
+	Region          string            `json:"region,omitempty"`
+	CidrBlock       string            `json:"cidrBlock"`
+	InstanceTenancy string            `json:"instanceTenancy,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+}
+
+func (s *vpcSpec) fields() map[string]string {
+	return map[string]string{"cidrBlock": s.CidrBlock, "instanceTenancy": s.InstanceTenancy}
+}
+
+func (s *vpcSpec) observe(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.awsNetwork(s.Region)
+	if err != nil {
+		return nil, err
+	}
+	vpc, err := client.FindVPCByName(ctx, name)
+	if err == aws_network.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return vpcState(vpc), nil
+}
+
+func (s *vpcSpec) create(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.awsNetwork(s.Region)
+	if err != nil {
+		return nil, err
+	}
+	vpc, err := client.CreateVPC(ctx, aws_network.CreateVpcRequest{
+		CidrBlock:         s.CidrBlock,
+		InstanceTenacy:    types.Tenancy(s.InstanceTenancy),
+		TagSpecifications: nameTags(types.ResourceTypeVpc, name, c.createTags(s.Tags)),
+		Wait:              waitAvailable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vpcState(vpc), nil
+}
+
+func vpcState(vpc types.Vpc) *state {
+	return &state{
+		fields: map[string]string{"cidrBlock": to.String(vpc.CidrBlock), "instanceTenancy": string(vpc.InstanceTenancy)},
+		outputs: map[string]string{
+			"id":        to.String(vpc.VpcId),
+			"cidrBlock": to.String(vpc.CidrBlock),
+			"ownerId":   to.String(vpc.OwnerId),
+		},
+	}
+}
+
+func (s *vpcSpec) update(ctx context.Context, c *clients, name string, changes []plan.Change) (*state, error) {
+	return nil, immutable(changes)
+}
+
+func (s *vpcSpec) destroy(ctx context.Context, c *clients, name string, actual *state) ([]deletion, error) {
+	client, err := c.awsNetwork(s.Region)
+	if err != nil {
+		return nil, err
+	}
+	vpc, err := client.GetVPC(ctx, actual.outputs["id"])
+	if err != nil {
+		return nil, err
+	}
+	return vpcDeletions(ctx, c, s.Region, vpc)
+}
+
+// subnetSpec is the spec of a Subnet, which is identified by its cidr block within its vpc, and tagged with
+// the name of the resource
+type subnetSpec struct {
+	Region           string            `json:"region,omitempty"`
+	VpcID            string            `json:"vpcId"`
+	CidrBlock        string            `json:"cidrBlock"`
+	AvailabilityZone string            `json:"availabilityZone,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+}
+
+func (s *subnetSpec) fields() map[string]string {
+	return map[string]string{"availabilityZone": s.AvailabilityZone}
+}
+
+func (s *subnetSpec) observe(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.awsNetwork(s.Region)
+	if err != nil {
+		return nil, err
+	}
+	subnets, err := client.ListSubnetsInVPC(ctx, s.VpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, subnet := range subnets {
+		if to.String(subnet.CidrBlock) == s.CidrBlock {
+			return subnetState(subnet), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *subnetSpec) create(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.awsNetwork(s.Region)
+	if err != nil {
+		return nil, err
+	}
+	subnet, err := client.CreateSubnetInVPC(ctx, aws_network.CreateVpcSubnetRequest{
+		CidrBlock:         s.CidrBlock,
+		VPCId:             s.VpcID,
+		AvailabilityZone:  s.AvailabilityZone,
+		TagSpecifications: nameTags(types.ResourceTypeSubnet, name, c.createTags(s.Tags)),
+		Wait:              waitAvailable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subnetState(subnet), nil
+}
+
+func subnetState(subnet types.Subnet) *state {
+	return &state{
+		fields: map[string]string{"availabilityZone": to.String(subnet.AvailabilityZone)},
+		outputs: map[string]string{
+			"id":               to.String(subnet.SubnetId),
+			"cidrBlock":        to.String(subnet.CidrBlock),
+			"availabilityZone": to.String(subnet.AvailabilityZone),
+		},
+	}
+}
+
+func (s *subnetSpec) update(ctx context.Context, c *clients, name string, changes []plan.Change) (*state, error) {
+	return nil, immutable(changes)
+}
+
+func (s *subnetSpec) destroy(ctx context.Context, c *clients, name string, actual *state) ([]deletion, error) {
+	client, err := c.awsNetwork(s.Region)
+	if err != nil {
+		return nil, err
+	}
+	id := actual.outputs["id"]
+	return []deletion{{
+		kind:   KindSubnet,
+		name:   name,
+		id:     id,
+		fields: map[string]string{"id": id, "cidrBlock": actual.outputs["cidrBlock"], "availabilityZone": actual.outputs["availabilityZone"]},
+		delete: func(ctx context.Context) error { return client.DeleteSubnet(ctx, id) },
+	}}, nil
+}
+
+// vpcDeletions will return the deletion of a vpc, after its peerings, nat gateways (releasing their elastic ips),
+// detached network interfaces, subnets, route tables, internet gateways and security groups, none of which aws
+// deletes along with the vpc.  Network interfaces
+// in use are owned by resources, such as instances, which must be deleted first, and are errors.
+func vpcDeletions(ctx context.Context, c *clients, region string, vpc types.Vpc) ([]deletion, error) {
+	client, err := c.awsNetwork(region)
+	if err != nil {
+		return nil, err
+	}
+	vpcID := to.String(vpc.VpcId)
+	deletions := []deletion{}
+
+	p, err := c.peerer(peering.ProviderAWS, region, "")
+	if err != nil {
+		return nil, err
+	}
+	local := peering.Network{Name: vpcID, Region: region}
+	peerings, err := p.List(ctx, local)
+	if err != nil {
+		return nil, err
+	}
+	for _, pcx := range peerings {
+		if pcx.State == peering.StateInactive || pcx.State == peering.StateDeleting {
+			continue
+		}
+		deletions = append(deletions, peeringDeletion(p, local, pcx))
+	}
+
+	natGateways, err := client.ListNatGatewaysInVPC(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, gateway := range natGateways {
+		if gateway.State == types.NatGatewayStateDeleting || gateway.State == types.NatGatewayStateDeleted {
+			continue
+		}
+		id := to.String(gateway.NatGatewayId)
+		ips := []string{}
+		for _, address := range gateway.NatGatewayAddresses {
+			ips = append(ips, to.String(address.PublicIp))
+		}
+		deletions = append(deletions, deletion{
+			kind:   KindNatGateway,
+			name:   tagName(gateway.Tags, id),
+			id:     id,
+			fields: map[string]string{"id": id, "subnetId": to.String(gateway.SubnetId), "elasticIps": strings.Join(ips, ",")},
+			delete: func(ctx context.Context) error { return client.DeleteNatGateway(ctx, id, true) },
+		})
+	}
+
+	enis, err := client.ListNetworkInterfacesInVPC(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, eni := range enis {
+		id := to.String(eni.NetworkInterfaceId)
+		if eni.InterfaceType == types.NetworkInterfaceTypeNatGateway {
+			// deleted along with its nat gateway
+			continue
+		}
+		if eni.Status != types.NetworkInterfaceStatusAvailable {
+			return nil, errors.Errorf("network interface %s (%s) of vpc %s is %s, and must be deleted along with its owner first",
+				id, to.String(eni.Description), vpcID, eni.Status)
+		}
+		deletions = append(deletions, deletion{
+			kind:   KindNetworkInterface,
+			name:   tagName(eni.TagSet, id),
+			id:     id,
+			fields: map[string]string{"id": id, "subnetId": to.String(eni.SubnetId), "description": to.String(eni.Description)},
+			delete: func(ctx context.Context) error { return client.DeleteNetworkInterface(ctx, id) },
+		})
+	}
+
+	subnets, err := client.ListSubnetsInVPC(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, subnet := range subnets {
+		id := to.String(subnet.SubnetId)
+		deletions = append(deletions, deletion{
+			kind:   KindSubnet,
+			name:   tagName(subnet.Tags, id),
+			id:     id,
+			fields: map[string]string{"id": id, "cidrBlock": to.String(subnet.CidrBlock), "availabilityZone": to.String(subnet.AvailabilityZone)},
+			delete: func(ctx context.Context) error { return client.DeleteSubnet(ctx, id) },
+		})
+	}
+
+	tables, err := client.ListRouteTablesInVPC(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if isMainRouteTable(table) {
+			// deleted along with the vpc
+			continue
+		}
+		id := to.String(table.RouteTableId)
+		deletions = append(deletions, deletion{
+			kind:   KindRouteTable,
+			name:   tagName(table.Tags, id),
+			id:     id,
+			fields: map[string]string{"id": id},
+			delete: func(ctx context.Context) error { return client.DeleteRouteTable(ctx, id) },
+		})
+	}
+
+	gateways, err := client.ListInternetGatewaysInVPC(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, gateway := range gateways {
+		id := to.String(gateway.InternetGatewayId)
+		deletions = append(deletions, deletion{
+			kind:   KindInternetGateway,
+			name:   tagName(gateway.Tags, id),
+			id:     id,
+			fields: map[string]string{"id": id, "vpcId": vpcID},
+			delete: func(ctx context.Context) error {
+				if err := client.DetachInternetGateway(ctx, id, vpcID); err != nil {
+					return err
+				}
+				return client.DeleteInternetGateway(ctx, id)
+			},
+		})
+	}
+
+	groups, err := client.ListSecurityGroupsInVPC(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if to.String(group.GroupName) == "default" {
+			// deleted along with the vpc
+			continue
+		}
+		id := to.String(group.GroupId)
+		deletions = append(deletions, deletion{
+			kind:   KindSecurityGroup,
+			name:   tagName(group.Tags, id),
+			id:     id,
+			fields: map[string]string{"id": id, "groupName": to.String(group.GroupName)},
+			delete: func(ctx context.Context) error { return client.DeleteSecurityGroup(ctx, id) },
+		})
+	}
+
+	return append(deletions, deletion{
+		kind:   KindVPC,
+		name:   tagName(vpc.Tags, vpcID),
+		id:     vpcID,
+		fields: map[string]string{"id": vpcID, "cidrBlock": to.String(vpc.CidrBlock)},
+		delete: func(ctx context.Context) error { return client.DeleteVPC(ctx, vpcID) },
+	}), nil
+}
+
+// isMainRouteTable will return whether a route table is the main route table of its vpc
+func isMainRouteTable(table types.RouteTable) bool {
+	for _, association := range table.Associations {
+		if association.Main {
+			return true
+		}
+	}
+	return false
+}
+
+// tagName will return the Name tag of a resource, or its id when it has none
+func tagName(tags []types.Tag, id string) string {
+	for _, tag := range tags {
+		if to.String(tag.Key) == "Name" && to.String(tag.Value) != "" {
+			return to.String(tag.Value)
+		}
+	}
+	return id
+}
+
+// nameTags will return the tag specification of a resource, with its Name tag along with the given tags
+func nameTags(resourceType types.ResourceType, name string, t tags.Tags) []types.TagSpecification {
+	t = tags.Merge(t, tags.Tags{"Name": name})
+	return []types.TagSpecification{{ResourceType: resourceType, Tags: t.AWS()}}
+}
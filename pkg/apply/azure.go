@@ -0,0 +1,403 @@
+package apply
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2020-11-01/network"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+
+	azure_network "github.com/naemono/go-cloud-actions/pkg/network/azure"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	azure_resources "github.com/naemono/go-cloud-actions/pkg/resources/azure"
+	azure_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/azure"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+// resourceGroupSpec is the spec of a ResourceGroup
+type resourceGroupSpec struct {
+	SubscriptionID string            `json:"subscriptionId,omitempty"`
+	Location       string            `json:"location"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+func (s *resourceGroupSpec) fields() map[string]string {
+	return map[string]string{"location": normalizeLocation(s.Location)}
+}
+
+func (s *resourceGroupSpec) observe(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.azureResources(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	group, err := client.GetResourceGroup(ctx, name)
+	if err == azure_resources.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state{
+		fields:  map[string]string{"location": normalizeLocation(to.String(group.Location))},
+		outputs: map[string]string{"id": to.String(group.ID), "name": name, "location": to.String(group.Location)},
+	}, nil
+}
+
+func (s *resourceGroupSpec) create(ctx context.Context, c *clients, name string) (*state, error) {
+	if s.Location == "" {
+		return nil, errors.New("location cannot be empty")
+	}
+	client, err := c.azureResources(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	group, err := client.CreateResourceGroup(ctx, name, s.Location, c.createTags(s.Tags))
+	if err != nil {
+		return nil, err
+	}
+	return &state{
+		outputs: map[string]string{"id": to.String(group.ID), "name": name, "location": to.String(group.Location)},
+	}, nil
+}
+
+func (s *resourceGroupSpec) update(ctx context.Context, c *clients, name string, changes []plan.Change) (*state, error) {
+	return nil, immutable(changes)
+}
+
+func (s *resourceGroupSpec) destroy(ctx context.Context, c *clients, name string, actual *state) ([]deletion, error) {
+	client, err := c.azureResources(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	group, err := client.GetResourceGroup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return resourceGroupDeletions(ctx, c, s.SubscriptionID, group)
+}
+
+// resourceGroupDeletions will return the deletion of a resource group, after its container groups and network
+// profiles.  Azure deletes every other resource of the group along with it.
+func resourceGroupDeletions(ctx context.Context, c *clients, subscription string, group resources.Group) ([]deletion, error) {
+	name := to.String(group.Name)
+	deletions, err := containerGroupDeletions(ctx, c, subscription, name, "")
+	if err != nil {
+		return nil, err
+	}
+	networkClient, err := c.azureNetwork(subscription)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := networkClient.ListNetworkProfiles(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		deletions = append(deletions, networkProfileDeletion(networkClient, name, profile.ID, profile.Location, to.String(profile.Name)))
+	}
+	client, err := c.azureResources(subscription)
+	if err != nil {
+		return nil, err
+	}
+	return append(deletions, deletion{
+		kind:   KindResourceGroup,
+		name:   name,
+		id:     to.String(group.ID),
+		fields: map[string]string{"id": to.String(group.ID), "location": to.String(group.Location)},
+		delete: func(ctx context.Context) error { return client.DeleteResourceGroup(ctx, name) },
+	}), nil
+}
+
+// networkProfileSpec is the spec of a NetworkProfile, whose vnet and subnet are created along with it when missing
+type networkProfileSpec struct {
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+	ResourceGroup  string `json:"resourceGroup"`
+	Location       string `json:"location"`
+	VnetName       string `json:"vnetName"`
+	VnetCIDR       string `json:"vnetCidr,omitempty"`
+	SubnetName     string `json:"subnetName"`
+	SubnetCIDR     string `json:"subnetCidr,omitempty"`
+	// SubnetPrefixLength is the prefix length of the next free block of the vnet given to a created subnet without
+	// a SubnetCIDR
+	SubnetPrefixLength int               `json:"subnetPrefixLength,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+}
+
+func (s *networkProfileSpec) fields() map[string]string {
+	return map[string]string{
+		"location":   normalizeLocation(s.Location),
+		"vnetName":   s.VnetName,
+		"subnetName": s.SubnetName,
+	}
+}
+
+func (s *networkProfileSpec) observe(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.azureNetwork(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := client.GetNetworkProfile(ctx, s.ResourceGroup, name)
+	if err == azure_network.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	subnetID := profileSubnetID(profile)
+	return &state{
+		fields: map[string]string{
+			"location":   normalizeLocation(to.String(profile.Location)),
+			"vnetName":   idSegment(subnetID, "virtualNetworks"),
+			"subnetName": idSegment(subnetID, "subnets"),
+		},
+		outputs: map[string]string{"id": to.String(profile.ID), "name": name, "subnetId": subnetID},
+	}, nil
+}
+
+func (s *networkProfileSpec) create(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.azureNetwork(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = client.CreateNetworkProfile(ctx, azure_network.NetworkProfileRequest{
+		Name:               name,
+		ResourceGroupName:  s.ResourceGroup,
+		Location:           s.Location,
+		VnetName:           s.VnetName,
+		VnetAddressCIDR:    s.VnetCIDR,
+		SubnetName:         s.SubnetName,
+		SubnetAddressCIDR:  s.SubnetCIDR,
+		SubnetPrefixLength: s.SubnetPrefixLength,
+		Tags:               c.createTags(s.Tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return observeCreated(ctx, s, c, name)
+}
+
+func (s *networkProfileSpec) update(ctx context.Context, c *clients, name string, changes []plan.Change) (*state, error) {
+	return nil, immutable(changes)
+}
+
+// destroy will delete the network profile after the container groups of its resource group using it.  Its vnet and
+// subnet, which may be shared, are kept.
+func (s *networkProfileSpec) destroy(ctx context.Context, c *clients, name string, actual *state) ([]deletion, error) {
+	id := actual.outputs["id"]
+	deletions, err := containerGroupDeletions(ctx, c, s.SubscriptionID, s.ResourceGroup, id)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.azureNetwork(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	return append(deletions, networkProfileDeletion(client, s.ResourceGroup, &id, to.StringPtr(s.Location), name)), nil
+}
+
+func networkProfileDeletion(client *azure_network.Client, resourceGroup string, id, location *string, name string) deletion {
+	return deletion{
+		kind:   KindNetworkProfile,
+		name:   name,
+		id:     to.String(id),
+		fields: map[string]string{"id": to.String(id), "location": to.String(location)},
+		delete: func(ctx context.Context) error { return client.DeleteNetworkProfile(ctx, resourceGroup, name) },
+	}
+}
+
+// profileSubnetID will return the id of the subnet of the first ip configuration of a network profile
+func profileSubnetID(profile network.Profile) string {
+	if profile.ProfilePropertiesFormat == nil || profile.ContainerNetworkInterfaceConfigurations == nil {
+		return ""
+	}
+	for _, nic := range *profile.ContainerNetworkInterfaceConfigurations {
+		if nic.ContainerNetworkInterfaceConfigurationPropertiesFormat == nil || nic.IPConfigurations == nil {
+			continue
+		}
+		for _, ip := range *nic.IPConfigurations {
+			if ip.IPConfigurationProfilePropertiesFormat != nil && ip.Subnet != nil {
+				return to.String(ip.Subnet.ID)
+			}
+		}
+	}
+	return ""
+}
+
+// containerGroupSpec is the spec of a ContainerGroup, with the same properties as the file of
+// compute azure create-container-instance
+type containerGroupSpec struct {
+	SubscriptionID string                                     `json:"subscriptionId,omitempty"`
+	ResourceGroup  string                                     `json:"resourceGroup"`
+	Location       string                                     `json:"location"`
+	Properties     containerinstance.ContainerGroupProperties `json:"properties"`
+	Tags           map[string]string                          `json:"tags,omitempty"`
+}
+
+func (s *containerGroupSpec) fields() map[string]string {
+	return map[string]string{
+		"location":   normalizeLocation(s.Location),
+		"osType":     string(s.Properties.OsType),
+		"containers": containerImages(s.Properties.Containers),
+	}
+}
+
+func (s *containerGroupSpec) observe(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.azureServerless(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	cg, err := client.GetContainerGroup(ctx, s.ResourceGroup, name)
+	if err == azure_serverless.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return containerGroupState(cg), nil
+}
+
+func (s *containerGroupSpec) create(ctx context.Context, c *clients, name string) (*state, error) {
+	return s.createOrUpdate(ctx, c, name, c.createTags(s.Tags))
+}
+
+// update will update the containers of a container group in place, which azure supports for everything but the
+// location and os type, keeping the time it was created at
+func (s *containerGroupSpec) update(ctx context.Context, c *clients, name string, changes []plan.Change) (*state, error) {
+	for _, change := range changes {
+		if change.Field != "containers" {
+			return nil, immutable(changes)
+		}
+	}
+	client, err := c.azureServerless(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := client.GetContainerGroup(ctx, s.ResourceGroup, name)
+	if err != nil {
+		return nil, err
+	}
+	t := c.createTags(s.Tags)
+	if createdAt, ok := existing.Tags[tags.KeyCreatedAt]; ok {
+		t[tags.KeyCreatedAt] = to.String(createdAt)
+	}
+	return s.createOrUpdate(ctx, c, name, t)
+}
+
+func (s *containerGroupSpec) createOrUpdate(ctx context.Context, c *clients, name string, t tags.Tags) (*state, error) {
+	client, err := c.azureServerless(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	cg, err := client.CreateContainerGroup(ctx, azure_serverless.CreateContainerRequest{
+		ContainerGroupName:       name,
+		Location:                 s.Location,
+		ResourceGroupName:        s.ResourceGroup,
+		ContainerGroupProperties: s.Properties,
+		Tags:                     t,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return containerGroupState(cg), nil
+}
+
+func (s *containerGroupSpec) destroy(ctx context.Context, c *clients, name string, actual *state) ([]deletion, error) {
+	client, err := c.azureServerless(s.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	return []deletion{containerGroupDeletion(client, s.ResourceGroup, name, actual.outputs)}, nil
+}
+
+// containerGroupDeletions will return the deletion of the container groups of a resource group, or only of the ones
+// using the network profile id when given
+func containerGroupDeletions(ctx context.Context, c *clients, subscription, resourceGroup, networkProfileID string) ([]deletion, error) {
+	client, err := c.azureServerless(subscription)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := client.ListContainerGroups(ctx, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	deletions := []deletion{}
+	for _, cg := range groups {
+		if networkProfileID != "" && !strings.EqualFold(containerGroupNetworkProfile(cg), networkProfileID) {
+			continue
+		}
+		deletions = append(deletions, containerGroupDeletion(client, resourceGroup, to.String(cg.Name), containerGroupState(cg).outputs))
+	}
+	return deletions, nil
+}
+
+func containerGroupDeletion(client *azure_serverless.Client, resourceGroup, name string, outputs map[string]string) deletion {
+	return deletion{
+		kind:   KindContainerGroup,
+		name:   name,
+		id:     outputs["id"],
+		fields: map[string]string{"id": outputs["id"], "ipAddress": outputs["ipAddress"]},
+		delete: func(ctx context.Context) error { return client.DeleteContainerGroup(ctx, resourceGroup, name) },
+	}
+}
+
+// containerGroupNetworkProfile will return the id of the network profile of a container group, if any
+func containerGroupNetworkProfile(cg containerinstance.ContainerGroup) string {
+	if cg.ContainerGroupProperties == nil || cg.NetworkProfile == nil {
+		return ""
+	}
+	return to.String(cg.NetworkProfile.ID)
+}
+
+func containerGroupState(cg containerinstance.ContainerGroup) *state {
+	st := &state{
+		fields:  map[string]string{"location": normalizeLocation(to.String(cg.Location))},
+		outputs: map[string]string{"id": to.String(cg.ID), "name": to.String(cg.Name)},
+	}
+	if props := cg.ContainerGroupProperties; props != nil {
+		st.fields["osType"] = string(props.OsType)
+		st.fields["containers"] = containerImages(props.Containers)
+		if props.IPAddress != nil {
+			st.outputs["ipAddress"] = to.String(props.IPAddress.IP)
+			st.outputs["fqdn"] = to.String(props.IPAddress.Fqdn)
+		}
+	}
+	return st
+}
+
+// containerImages will return the containers as a sorted, comparable list of {name}={image}
+func containerImages(containers *[]containerinstance.Container) string {
+	if containers == nil {
+		return ""
+	}
+	images := []string{}
+	for _, container := range *containers {
+		image := ""
+		if container.ContainerProperties != nil {
+			image = to.String(container.Image)
+		}
+		images = append(images, to.String(container.Name)+"="+image)
+	}
+	sort.Strings(images)
+	return strings.Join(images, ",")
+}
+
+// normalizeLocation will return an azure location as returned by azure, which lowercases and removes the
+// spaces of display names such as "East US"
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
+
+// idSegment will return the segment following key in an azure resource id
+func idSegment(id, key string) string {
+	parts := strings.Split(id, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if strings.EqualFold(parts[i], key) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
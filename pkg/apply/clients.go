@@ -0,0 +1,170 @@
+package apply
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	azure_auth "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	azure_network "github.com/naemono/go-cloud-actions/pkg/network/azure"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	peering_aws "github.com/naemono/go-cloud-actions/pkg/peering/aws"
+	peering_azure "github.com/naemono/go-cloud-actions/pkg/peering/azure"
+	peering_google "github.com/naemono/go-cloud-actions/pkg/peering/google"
+	azure_resources "github.com/naemono/go-cloud-actions/pkg/resources/azure"
+	azure_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/azure"
+	google_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/google"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+// clients lazily creates, and caches, the provider clients of an applier, so that a manifest only needs the
+// credentials of the providers it uses, and every azure client of a subscription shares the same tokens
+type clients struct {
+	Config
+	mu        sync.Mutex
+	factories map[string]*azure_auth.ClientFactory
+	awsClient map[string]*aws_network.Client
+	gke       *google_serverless.Client
+	peerers   map[string]peering.Peerer
+}
+
+// createTags will return the tags of a created resource, those of the config overridden by those of its spec
+func (c *clients) createTags(spec map[string]string) tags.Tags {
+	return tags.Merge(c.Tags, spec)
+}
+
+func newClients(conf Config) *clients {
+	return &clients{
+		Config:    conf,
+		factories: map[string]*azure_auth.ClientFactory{},
+		awsClient: map[string]*aws_network.Client{},
+		peerers:   map[string]peering.Peerer{},
+	}
+}
+
+// azureAuth will return the azure auth config, for subscription when given, authorized for remoteTenant as well
+// when given
+func (c *clients) azureAuth(subscription, remoteTenant string) azure_auth.AuthConfig {
+	conf := c.Azure
+	if subscription != "" {
+		conf.SubscriptionID = subscription
+	}
+	if remoteTenant != "" {
+		conf.AuxTenantIDs = []string{remoteTenant}
+	}
+	return conf
+}
+
+func (c *clients) azureFactory(subscription, remoteTenant string) (*azure_auth.ClientFactory, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := subscription + "/" + remoteTenant
+	if f, ok := c.factories[key]; ok {
+		return f, nil
+	}
+	f, err := azure_auth.NewClientFactory(azure_auth.FactoryConfig{
+		AuthConfig: c.azureAuth(subscription, remoteTenant),
+		Logger:     c.Logger,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get new azure client factory")
+	}
+	c.factories[key] = f
+	return f, nil
+}
+
+func (c *clients) azureResources(subscription string) (*azure_resources.Client, error) {
+	f, err := c.azureFactory(subscription, "")
+	if err != nil {
+		return nil, err
+	}
+	return azure_resources.New(azure_resources.Config{AuthConfig: f.AuthConfig, Logger: c.Logger, Factory: f})
+}
+
+func (c *clients) azureNetwork(subscription string) (*azure_network.Client, error) {
+	f, err := c.azureFactory(subscription, "")
+	if err != nil {
+		return nil, err
+	}
+	return azure_network.New(azure_network.Config{AuthConfig: f.AuthConfig, Logger: c.Logger, Factory: f})
+}
+
+func (c *clients) azureServerless(subscription string) (*azure_serverless.Client, error) {
+	f, err := c.azureFactory(subscription, "")
+	if err != nil {
+		return nil, err
+	}
+	return azure_serverless.New(azure_serverless.Config{AuthConfig: f.AuthConfig, Logger: c.Logger, Factory: f})
+}
+
+// awsNetwork will return the aws network client of region, defaulting to the region of the auth config
+func (c *clients) awsNetwork(region string) (*aws_network.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conf := c.AWS
+	if region != "" {
+		conf.Region = region
+	}
+	if conf.Region == "" {
+		return nil, errors.New("aws region must be given in the spec or the auth config")
+	}
+	if client, ok := c.awsClient[conf.Region]; ok {
+		return client, nil
+	}
+	client, err := aws_network.New(aws_network.Config{AuthConfig: conf, Logger: c.Logger})
+	if err != nil {
+		return nil, err
+	}
+	c.awsClient[conf.Region] = client
+	return client, nil
+}
+
+func (c *clients) googleContainers() (*google_serverless.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gke != nil {
+		return c.gke, nil
+	}
+	client, err := google_serverless.New(google_serverless.Config{AuthConfig: c.Google, Logger: c.Logger})
+	if err != nil {
+		return nil, err
+	}
+	c.gke = client
+	return client, nil
+}
+
+// peerer will return the peerer of provider.  The azure peerer is authorized for remoteTenant as well when given,
+// and the aws peerer operates in region.
+func (c *clients) peerer(provider peering.Provider, region, remoteTenant string) (peering.Peerer, error) {
+	if provider == peering.ProviderAzure {
+		f, err := c.azureFactory("", remoteTenant)
+		if err != nil {
+			return nil, err
+		}
+		return peering_azure.NewPeerer(peering_azure.Config{AuthConfig: f.AuthConfig, Logger: c.Logger, Factory: f})
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := string(provider) + "/" + region
+	if p, ok := c.peerers[key]; ok {
+		return p, nil
+	}
+	var p peering.Peerer
+	var err error
+	switch provider {
+	case peering.ProviderAWS:
+		conf := c.AWS
+		if region != "" {
+			conf.Region = region
+		}
+		p, err = peering_aws.NewPeerer(peering_aws.Config{AuthConfig: conf, Logger: c.Logger})
+	case peering.ProviderGoogle:
+		p, err = peering_google.NewPeerer(peering_google.Config{AuthConfig: c.Google, Logger: c.Logger})
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.peerers[key] = p
+	return p, nil
+}
@@ -0,0 +1,191 @@
+package apply
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+)
+
+// The kinds of aws resources which are never part of a manifest, such as the dependents discovered when destroying
+// a resource
+const (
+	// KindRouteTable is an aws route table
+	KindRouteTable Kind = "RouteTable"
+	// KindInternetGateway is an aws internet gateway
+	KindInternetGateway Kind = "InternetGateway"
+	// KindNatGateway is an aws nat gateway
+	KindNatGateway Kind = "NatGateway"
+	// KindSecurityGroup is an aws security group
+	KindSecurityGroup Kind = "SecurityGroup"
+	// KindNetworkInterface is an aws network interface
+	KindNetworkInterface Kind = "NetworkInterface"
+)
+
+// deletion is the deletion of a single existing resource
+type deletion struct {
+	kind Kind
+	name string
+	// id uniquely identifies the resource within its kind, so that a dependent of several destroyed resources is
+	// only deleted once
+	id string
+	// fields are the fields the deletion is planned with
+	fields map[string]string
+	delete func(ctx context.Context) error
+}
+
+func (d deletion) key() string {
+	return string(d.kind) + "/" + strings.ToLower(d.id)
+}
+
+// Selector selects the resources to destroy by their tags: the aws vpcs of the region of the auth config, or the
+// azure resource groups of the subscription of the auth config, having every one of the tags
+type Selector struct {
+	Provider peering.Provider
+	Tags     map[string]string
+}
+
+// Teardown is the deletion of a set of resources along with their dependents, in reverse dependency order
+type Teardown struct {
+	logger    *logrus.Entry
+	deletions []deletion
+	seen      map[string]bool
+}
+
+func (a *Applier) newTeardown() *Teardown {
+	return &Teardown{logger: a.Logger, seen: map[string]bool{}}
+}
+
+// add will add deletions, skipping the resources which are already deleted by the teardown
+func (t *Teardown) add(deletions ...deletion) {
+	for _, d := range deletions {
+		if t.seen[d.key()] {
+			continue
+		}
+		t.seen[d.key()] = true
+		t.deletions = append(t.deletions, d)
+	}
+}
+
+// Plan will return the deletions of the teardown, in the order they are made
+func (t *Teardown) Plan() plan.Plan {
+	var p plan.Plan
+	for _, d := range t.deletions {
+		p.Add(plan.Delete(string(d.kind), d.name, d.fields))
+	}
+	return p
+}
+
+// Run will delete every resource of the teardown in order, returning the result of every deleted resource.  On
+// failure, the results of the resources deleted so far are returned along with the error.
+func (t *Teardown) Run(ctx context.Context) ([]Result, error) {
+	results := make([]Result, 0, len(t.deletions))
+	for _, d := range t.deletions {
+		t.logger.Infof("deleting %s/%s", d.kind, d.name)
+		if err := d.delete(ctx); err != nil {
+			return results, errors.Wrapf(err, "failed to delete %s/%s", d.kind, d.name)
+		}
+		results = append(results, Result{
+			Kind:    d.kind,
+			Name:    d.name,
+			Action:  plan.ActionDelete,
+			Outputs: map[string]string{"id": d.id},
+		})
+	}
+	return results, nil
+}
+
+// Teardown will discover the existing resources of a manifest along with their dependents, returning their
+// deletion in reverse dependency order.  Resources which do not exist, or reference a resource which does not
+// exist, are left out.
+func (a *Applier) Teardown(ctx context.Context, resources []Resource) (*Teardown, error) {
+	ordered, err := Order(resources)
+	if err != nil {
+		return nil, err
+	}
+	// the outputs of resources which do not exist are nil, and their dependents are left out
+	outputs := map[string]map[string]string{}
+	kinds := make([]kind, len(ordered))
+	states := make([]*state, len(ordered))
+	for i, r := range ordered {
+		if dependsOnCreated(r, outputs) {
+			outputs[r.ID()] = nil
+			continue
+		}
+		k, err := decode(r, outputs)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := k.observe(ctx, a.clients, r.Metadata.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %s", r.ID())
+		}
+		if actual == nil {
+			outputs[r.ID()] = nil
+			continue
+		}
+		kinds[i], states[i], outputs[r.ID()] = k, actual, actual.outputs
+	}
+	t := a.newTeardown()
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if states[i] == nil {
+			continue
+		}
+		deletions, err := kinds[i].destroy(ctx, a.clients, ordered[i].Metadata.Name, states[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to discover dependents of %s", ordered[i].ID())
+		}
+		t.add(deletions...)
+	}
+	return t, nil
+}
+
+// TeardownSelector will discover the resources selected by their tags along with their dependents, returning their
+// deletion in reverse dependency order
+func (a *Applier) TeardownSelector(ctx context.Context, selector Selector) (*Teardown, error) {
+	if len(selector.Tags) == 0 {
+		return nil, errors.New("selector tags cannot be empty")
+	}
+	t := a.newTeardown()
+	switch selector.Provider {
+	case peering.ProviderAWS:
+		client, err := a.clients.awsNetwork("")
+		if err != nil {
+			return nil, err
+		}
+		vpcs, err := client.FindVPCsByTags(ctx, selector.Tags)
+		if err != nil {
+			return nil, err
+		}
+		for _, vpc := range vpcs {
+			deletions, err := vpcDeletions(ctx, a.clients, "", vpc)
+			if err != nil {
+				return nil, err
+			}
+			t.add(deletions...)
+		}
+	case peering.ProviderAzure:
+		client, err := a.clients.azureResources("")
+		if err != nil {
+			return nil, err
+		}
+		groups, err := client.ListResourceGroups(ctx, selector.Tags)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			deletions, err := resourceGroupDeletions(ctx, a.clients, "", group)
+			if err != nil {
+				return nil, err
+			}
+			t.add(deletions...)
+		}
+	default:
+		return nil, errors.Errorf("selecting resources by tags is unsupported for provider %q, must be one of aws, azure", selector.Provider)
+	}
+	return t, nil
+}
@@ -0,0 +1,145 @@
+package apply
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+	google_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/google"
+	"google.golang.org/api/container/v1"
+)
+
+// gkeClusterSpec is the spec of a GKECluster
+type gkeClusterSpec struct {
+	ProjectID       string `json:"projectId"`
+	Location        string `json:"location"`
+	Network         string `json:"network,omitempty"`
+	Subnetwork      string `json:"subnetwork,omitempty"`
+	ClusterIpv4Cidr string `json:"clusterIpv4Cidr,omitempty"`
+	// PodsRange and ServicesRange are either the names of secondary ranges of the subnetwork, or cidrs
+	PodsRange     string `json:"podsRange,omitempty"`
+	ServicesRange string `json:"servicesRange,omitempty"`
+	Description   string `json:"description,omitempty"`
+	// Labels are applied to the cluster along with the tags of the applier's config
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func (s *gkeClusterSpec) fields() map[string]string {
+	return map[string]string{
+		"network":         s.Network,
+		"subnetwork":      s.Subnetwork,
+		"clusterIpv4Cidr": s.ClusterIpv4Cidr,
+		"podsRange":       s.PodsRange,
+		"servicesRange":   s.ServicesRange,
+		"description":     s.Description,
+	}
+}
+
+func (s *gkeClusterSpec) observe(ctx context.Context, c *clients, name string) (*state, error) {
+	if s.ProjectID == "" || s.Location == "" {
+		return nil, errors.New("project id and location cannot be empty")
+	}
+	client, err := c.googleContainers()
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := client.GetCluster(ctx, s.ProjectID, s.Location, name)
+	if err == google_serverless.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.clusterState(cluster), nil
+}
+
+// create will request a new cluster, which is still provisioning once created
+func (s *gkeClusterSpec) create(ctx context.Context, c *clients, name string) (*state, error) {
+	client, err := c.googleContainers()
+	if err != nil {
+		return nil, err
+	}
+	_, err = client.CreateCluster(ctx, google_serverless.CreateClusterRequest{
+		ClusterCommon: google_serverless.ClusterCommon{
+			ProjectID:   s.ProjectID,
+			NetworkName: s.Network,
+		},
+		Subnetwork:      s.Subnetwork,
+		ClusterIpv4Cidr: s.ClusterIpv4Cidr,
+		PodsRange:       s.PodsRange,
+		ServicesRange:   s.ServicesRange,
+		Description:     s.Description,
+		Location:        s.Location,
+		Name:            name,
+		Tags:            c.createTags(s.Labels),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return observeCreated(ctx, s, c, name)
+}
+
+func (s *gkeClusterSpec) update(ctx context.Context, c *clients, name string, changes []plan.Change) (*state, error) {
+	return nil, immutable(changes)
+}
+
+// destroy will request the deletion of the cluster, which continues in the background
+func (s *gkeClusterSpec) destroy(ctx context.Context, c *clients, name string, actual *state) ([]deletion, error) {
+	client, err := c.googleContainers()
+	if err != nil {
+		return nil, err
+	}
+	return []deletion{{
+		kind:   KindGKECluster,
+		name:   name,
+		id:     actual.outputs["id"],
+		fields: map[string]string{"id": actual.outputs["id"], "status": actual.outputs["status"], "location": actual.outputs["location"]},
+		delete: func(ctx context.Context) error {
+			_, err := client.DeleteCluster(ctx, s.ProjectID, s.Location, name)
+			return err
+		},
+	}}, nil
+}
+
+// clusterState will return the state of a cluster, whose pods and services ranges are the names of their secondary
+// ranges, or their cidrs when the spec gives cidrs
+func (s *gkeClusterSpec) clusterState(cluster *container.Cluster) *state {
+	var podsRange, servicesRange string
+	if policy := cluster.IpAllocationPolicy; policy != nil {
+		podsRange = allocatedRange(s.PodsRange, policy.ClusterSecondaryRangeName, policy.ClusterIpv4CidrBlock)
+		servicesRange = allocatedRange(s.ServicesRange, policy.ServicesSecondaryRangeName, policy.ServicesIpv4CidrBlock)
+	}
+	return &state{
+		fields: map[string]string{
+			"network":         cluster.Network,
+			"subnetwork":      cluster.Subnetwork,
+			"clusterIpv4Cidr": cluster.ClusterIpv4Cidr,
+			"podsRange":       podsRange,
+			"servicesRange":   servicesRange,
+			"description":     cluster.Description,
+		},
+		outputs: map[string]string{
+			"id":       cluster.SelfLink,
+			"name":     cluster.Name,
+			"endpoint": cluster.Endpoint,
+			"selfLink": cluster.SelfLink,
+			"status":   cluster.Status,
+			"location": cluster.Location,
+		},
+	}
+}
+
+// allocatedRange will return the allocated range in the form of the desired one: the name of a secondary range, a
+// cidr, or only the netmask of a cidr
+func allocatedRange(desired, name, cidr string) string {
+	switch {
+	case !google_serverless.IsCIDR(desired):
+		return name
+	case strings.HasPrefix(desired, "/") && strings.Contains(cidr, "/"):
+		return cidr[strings.Index(cidr, "/"):]
+	default:
+		return cidr
+	}
+}
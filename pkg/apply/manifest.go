@@ -0,0 +1,243 @@
+package apply
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Kind is the kind of a manifest resource
+type Kind string
+
+const (
+	// KindResourceGroup is an azure resource group
+	KindResourceGroup Kind = "ResourceGroup"
+	// KindNetworkProfile is an azure network profile, along with its vnet and delegated subnet
+	KindNetworkProfile Kind = "NetworkProfile"
+	// KindVPC is an aws vpc, identified by its Name tag
+	KindVPC Kind = "VPC"
+	// KindSubnet is an aws subnet, identified by its cidr block within its vpc
+	KindSubnet Kind = "Subnet"
+	// KindPeering is a peering in any public cloud
+	KindPeering Kind = "Peering"
+	// KindContainerGroup is an azure container instances container group
+	KindContainerGroup Kind = "ContainerGroup"
+	// KindGKECluster is a google gke autopilot cluster
+	KindGKECluster Kind = "GKECluster"
+)
+
+// Kinds are all supported kinds, in the order they are documented
+var Kinds = []Kind{KindResourceGroup, KindNetworkProfile, KindVPC, KindSubnet, KindPeering, KindContainerGroup, KindGKECluster}
+
+// Resource is a single document of a manifest
+type Resource struct {
+	APIVersion string                 `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Kind       Kind                   `json:"kind" yaml:"kind"`
+	Metadata   Metadata               `json:"metadata" yaml:"metadata"`
+	Spec       map[string]interface{} `json:"spec" yaml:"spec"`
+}
+
+// Metadata identifies a resource within a manifest
+type Metadata struct {
+	Name string `json:"name" yaml:"name"`
+	// DependsOn are resources, as {kind}/{name}, which must be applied first, in addition to the referenced ones
+	DependsOn []string `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+}
+
+// ID will return the {kind}/{name} identifying the resource within a manifest
+func (r Resource) ID() string {
+	return fmt.Sprintf("%s/%s", r.Kind, r.Metadata.Name)
+}
+
+// referencePattern matches ${{kind}/{name}.{output}}, a reference to an output of another resource
+var referencePattern = regexp.MustCompile(`\$\{([A-Za-z]+/[^.}]+)\.([A-Za-z0-9]+)\}`)
+
+// knownAfterApply is the value of the outputs of resources which would be created, when planning
+const knownAfterApply = "(known after apply)"
+
+// ReadFile will read and parse the manifest at path, or stdin when path is -
+func ReadFile(path string) ([]Resource, error) {
+	if path == "-" {
+		return Parse(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open manifest %s", path)
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse will parse a multi-document yaml manifest, ensuring every resource is of a supported kind, and
+// uniquely named within its kind
+func Parse(r io.Reader) ([]Resource, error) {
+	var resources []Resource
+	seen := map[string]bool{}
+	decoder := yaml.NewDecoder(r)
+	for i := 1; ; i++ {
+		var res Resource
+		err := decoder.Decode(&res)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse manifest document %d", i)
+		}
+		if res.Kind == "" && res.Metadata.Name == "" && len(res.Spec) == 0 {
+			// empty document, such as a trailing ---
+			continue
+		}
+		if !isKind(res.Kind) {
+			return nil, errors.Errorf("manifest document %d has unsupported kind %q", i, res.Kind)
+		}
+		if res.Metadata.Name == "" {
+			return nil, errors.Errorf("manifest document %d (%s) has no metadata.name", i, res.Kind)
+		}
+		if seen[res.ID()] {
+			return nil, errors.Errorf("%s is defined more than once", res.ID())
+		}
+		seen[res.ID()] = true
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+func isKind(k Kind) bool {
+	for _, kind := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencies will return the ids of the resources referenced by the spec of r, along with its dependsOn
+func (r Resource) dependencies() []string {
+	deps := map[string]bool{}
+	for _, d := range r.Metadata.DependsOn {
+		deps[d] = true
+	}
+	walkStrings(r.Spec, func(s string) {
+		for _, m := range referencePattern.FindAllStringSubmatch(s, -1) {
+			deps[m[1]] = true
+		}
+	})
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func walkStrings(v interface{}, fn func(string)) {
+	switch t := v.(type) {
+	case string:
+		fn(t)
+	case map[string]interface{}:
+		for _, e := range t {
+			walkStrings(e, fn)
+		}
+	case []interface{}:
+		for _, e := range t {
+			walkStrings(e, fn)
+		}
+	}
+}
+
+// Order will sort resources so that every resource follows the resources it depends on, keeping the manifest
+// order otherwise.  Dependencies missing from the manifest, and dependency cycles, are errors.
+func Order(resources []Resource) ([]Resource, error) {
+	index := map[string]int{}
+	for i, r := range resources {
+		index[r.ID()] = i
+	}
+	pending := make([]int, len(resources))
+	dependents := make([][]int, len(resources))
+	for i, r := range resources {
+		for _, d := range r.dependencies() {
+			j, ok := index[d]
+			if !ok {
+				return nil, errors.Errorf("%s depends on %s, which is not in the manifest", r.ID(), d)
+			}
+			if j == i {
+				return nil, errors.Errorf("%s depends on itself", r.ID())
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+	ordered := make([]Resource, 0, len(resources))
+	done := make([]bool, len(resources))
+	for len(ordered) < len(resources) {
+		next := -1
+		for i := range resources {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var cycle []string
+			for i, r := range resources {
+				if !done[i] {
+					cycle = append(cycle, r.ID())
+				}
+			}
+			return nil, errors.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+		}
+		done[next] = true
+		ordered = append(ordered, resources[next])
+		for _, d := range dependents[next] {
+			pending[d]--
+		}
+	}
+	return ordered, nil
+}
+
+// resolve will return a copy of the spec of r, with every reference replaced by the output it references.  The
+// outputs of resources which are only planned are nil, and resolve as knownAfterApply.
+func (r Resource) resolve(outputs map[string]map[string]string) (map[string]interface{}, error) {
+	var err error
+	replace := func(s string) string {
+		return referencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+			m := referencePattern.FindStringSubmatch(ref)
+			if values, ok := outputs[m[1]]; ok && values == nil {
+				return knownAfterApply
+			}
+			value, ok := outputs[m[1]][m[2]]
+			if !ok && err == nil {
+				err = errors.Errorf("%s references unknown output %s of %s", r.ID(), m[2], m[1])
+			}
+			return value
+		})
+	}
+	spec, _ := resolveValue(r.Spec, replace).(map[string]interface{})
+	return spec, err
+}
+
+func resolveValue(v interface{}, replace func(string) string) interface{} {
+	switch t := v.(type) {
+	case string:
+		return replace(t)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = resolveValue(e, replace)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, e := range t {
+			l[i] = resolveValue(e, replace)
+		}
+		return l
+	}
+	return v
+}
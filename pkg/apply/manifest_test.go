@@ -0,0 +1,215 @@
+package apply
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func resource(kind Kind, name string, spec map[string]interface{}, dependsOn ...string) Resource {
+	return Resource{Kind: kind, Metadata: Metadata{Name: name, DependsOn: dependsOn}, Spec: spec}
+}
+
+func ids(resources []Resource) []string {
+	result := make([]string, 0, len(resources))
+	for _, r := range resources {
+		result = append(result, r.ID())
+	}
+	return result
+}
+
+func TestReferencePattern(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want [][]string
+	}{
+		{name: "reference", s: "${VPC/main.id}", want: [][]string{{"${VPC/main.id}", "VPC/main", "id"}}},
+		{name: "embedded references", s: "a-${VPC/main.id}-${Subnet/web-1.cidr}", want: [][]string{
+			{"${VPC/main.id}", "VPC/main", "id"},
+			{"${Subnet/web-1.cidr}", "Subnet/web-1", "cidr"},
+		}},
+		{name: "no output", s: "${VPC/main}"},
+		{name: "no kind", s: "${main.id}"},
+		{name: "not a reference", s: "$VPC/main.id"},
+		{name: "output of a nested field", s: "${VPC/main.tags.name}"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := referencePattern.FindAllStringSubmatch(tt.s, -1)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAllStringSubmatch(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []Resource
+		want      []string
+		wantErr   string
+	}{
+		{
+			name: "manifest order without dependencies",
+			resources: []Resource{
+				resource(KindVPC, "b", nil),
+				resource(KindVPC, "a", nil),
+			},
+			want: []string{"VPC/b", "VPC/a"},
+		},
+		{
+			name: "references first",
+			resources: []Resource{
+				resource(KindSubnet, "web", map[string]interface{}{"vpcId": "${VPC/main.id}"}),
+				resource(KindVPC, "main", nil),
+			},
+			want: []string{"VPC/main", "Subnet/web"},
+		},
+		{
+			name: "nested references and dependsOn",
+			resources: []Resource{
+				resource(KindPeering, "p", map[string]interface{}{
+					"network": map[string]interface{}{"name": "${VPC/a.id}"},
+					"remote":  []interface{}{"${VPC/b.id}"},
+				}, "Subnet/s"),
+				resource(KindSubnet, "s", nil),
+				resource(KindVPC, "a", nil),
+				resource(KindVPC, "b", nil),
+			},
+			want: []string{"Subnet/s", "VPC/a", "VPC/b", "Peering/p"},
+		},
+		{
+			name: "chain",
+			resources: []Resource{
+				resource(KindContainerGroup, "c", map[string]interface{}{"profile": "${NetworkProfile/n.id}"}),
+				resource(KindNetworkProfile, "n", map[string]interface{}{"group": "${ResourceGroup/g.name}"}),
+				resource(KindResourceGroup, "g", nil),
+			},
+			want: []string{"ResourceGroup/g", "NetworkProfile/n", "ContainerGroup/c"},
+		},
+		{
+			name: "unknown reference",
+			resources: []Resource{
+				resource(KindSubnet, "web", map[string]interface{}{"vpcId": "${VPC/missing.id}"}),
+			},
+			wantErr: "Subnet/web depends on VPC/missing, which is not in the manifest",
+		},
+		{
+			name: "unknown dependsOn",
+			resources: []Resource{
+				resource(KindSubnet, "web", nil, "VPC/missing"),
+			},
+			wantErr: "Subnet/web depends on VPC/missing, which is not in the manifest",
+		},
+		{
+			name: "self reference",
+			resources: []Resource{
+				resource(KindVPC, "main", map[string]interface{}{"name": "${VPC/main.id}"}),
+			},
+			wantErr: "VPC/main depends on itself",
+		},
+		{
+			name: "cycle",
+			resources: []Resource{
+				resource(KindVPC, "free", nil),
+				resource(KindVPC, "a", map[string]interface{}{"x": "${VPC/b.id}"}),
+				resource(KindVPC, "b", nil, "VPC/a"),
+			},
+			wantErr: "dependency cycle between VPC/a, VPC/b",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Order(tt.resources)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Order() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Order() error = %v", err)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("Order() = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	outputs := map[string]map[string]string{
+		"VPC/main":     {"id": "vpc-1", "cidr": "10.0.0.0/16"},
+		"VPC/planned":  nil,
+		"Subnet/empty": {},
+	}
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "references",
+			spec: map[string]interface{}{
+				"vpcId": "${VPC/main.id}",
+				"name":  "subnet-of-${VPC/main.id}",
+				"count": 2,
+				"nested": map[string]interface{}{
+					"cidrs": []interface{}{"${VPC/main.cidr}", "192.168.0.0/16"},
+				},
+			},
+			want: map[string]interface{}{
+				"vpcId": "vpc-1",
+				"name":  "subnet-of-vpc-1",
+				"count": 2,
+				"nested": map[string]interface{}{
+					"cidrs": []interface{}{"10.0.0.0/16", "192.168.0.0/16"},
+				},
+			},
+		},
+		{
+			name: "planned output",
+			spec: map[string]interface{}{"vpcId": "${VPC/planned.id}"},
+			want: map[string]interface{}{"vpcId": knownAfterApply},
+		},
+		{
+			name:    "unknown output",
+			spec:    map[string]interface{}{"vpcId": "${VPC/main.arn}"},
+			wantErr: "Subnet/s references unknown output arn of VPC/main",
+		},
+		{
+			name:    "output of unknown resource",
+			spec:    map[string]interface{}{"vpcId": "${VPC/other.id}"},
+			wantErr: "Subnet/s references unknown output id of VPC/other",
+		},
+		{
+			name:    "resource without outputs",
+			spec:    map[string]interface{}{"id": "${Subnet/empty.id}"},
+			wantErr: "Subnet/s references unknown output id of Subnet/empty",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := resource(KindSubnet, "s", tt.spec)
+			got, err := r.resolve(outputs)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
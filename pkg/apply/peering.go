@@ -0,0 +1,194 @@
+package apply
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+	"github.com/naemono/go-cloud-actions/pkg/plan"
+)
+
+// peeringSpec is the spec of a Peering, whose networks are given in the formats of peering.ParseNetwork
+type peeringSpec struct {
+	Provider       string `json:"provider"`
+	Network        string `json:"network"`
+	RemoteNetwork  string `json:"remoteNetwork"`
+	Region         string `json:"region,omitempty"`
+	RemoteRegion   string `json:"remoteRegion,omitempty"`
+	RemoteTenantID string `json:"remoteTenantId,omitempty"`
+	// AllowOverlap creates the peering even when the address spaces of the networks overlap
+	AllowOverlap bool `json:"allowOverlap,omitempty"`
+	// Tags are applied to aws peerings along with the tags of the applier's config
+	Tags map[string]string `json:"tags,omitempty"`
+	peering.RouteExchange
+}
+
+func (s *peeringSpec) fields() map[string]string {
+	fields := map[string]string{"remoteNetwork": networkName(s.RemoteNetwork)}
+	for field, value := range routeFields(peering.Provider(strings.ToLower(s.Provider)), s.RouteExchange) {
+		fields[field] = value
+	}
+	return fields
+}
+
+// routeFields will return the comparable route exchange flags supported by provider
+func routeFields(provider peering.Provider, r peering.RouteExchange) map[string]string {
+	switch provider {
+	case peering.ProviderAzure:
+		return map[string]string{
+			"allowForwardedTraffic": boolField(r.AllowForwardedTraffic),
+			"allowGatewayTransit":   boolField(r.AllowGatewayTransit),
+			"useRemoteGateways":     boolField(r.UseRemoteGateways),
+		}
+	case peering.ProviderGoogle:
+		return map[string]string{
+			"importCustomRoutes": boolField(r.ImportCustomRoutes),
+			"exportCustomRoutes": boolField(r.ExportCustomRoutes),
+		}
+	}
+	return nil
+}
+
+// networkName will return the name of a network from any of its provider specific references, which is
+// the last segment of azure ids, google urls and aws {region}/{account}/{vpc-id} alike
+func networkName(ref string) string {
+	return strings.ToLower(path.Base(strings.TrimRight(ref, "/")))
+}
+
+// peerer will return the peerer of the spec's provider, and the local network, defaulting the azure
+// subscription and aws region from the auth config
+func (s *peeringSpec) peerer(c *clients) (peering.Peerer, peering.Network, error) {
+	var local peering.Network
+	provider, err := peering.ParseProvider(s.Provider)
+	if err != nil {
+		return nil, local, err
+	}
+	if local, err = peering.ParseNetwork(provider, s.Network); err != nil {
+		return nil, local, err
+	}
+	if s.RemoteNetwork == "" {
+		return nil, local, errors.New("remote network cannot be empty")
+	}
+	if provider == peering.ProviderAzure && local.Account == "" {
+		local.Account = c.Azure.SubscriptionID
+	}
+	if provider == peering.ProviderAWS {
+		local.Region = s.Region
+		if local.Region == "" {
+			local.Region = c.AWS.Region
+		}
+	}
+	p, err := c.peerer(provider, local.Region, s.RemoteTenantID)
+	return p, local, err
+}
+
+// observe will get the peering, treating inactive peerings, which can only be recreated, as missing
+func (s *peeringSpec) observe(ctx context.Context, c *clients, name string) (*state, error) {
+	p, local, err := s.peerer(c)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.Get(ctx, local, name)
+	if err == peering.ErrNotFound || (err == nil && result.State == peering.StateInactive) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return peeringState(result), nil
+}
+
+func (s *peeringSpec) create(ctx context.Context, c *clients, name string) (*state, error) {
+	p, local, err := s.peerer(c)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := peering.ParseNetwork(peering.Provider(strings.ToLower(s.Provider)), s.RemoteNetwork)
+	if err != nil {
+		return nil, err
+	}
+	remote.Region = s.RemoteRegion
+	result, err := p.Create(ctx, peering.CreateRequest{
+		Name:          name,
+		Network:       local,
+		RemoteNetwork: remote,
+		RouteExchange: s.RouteExchange,
+		AllowOverlap:  s.AllowOverlap,
+		Tags:          c.createTags(s.Tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return peeringState(result), nil
+}
+
+func (s *peeringSpec) update(ctx context.Context, c *clients, name string, changes []plan.Change) (*state, error) {
+	return nil, immutable(changes)
+}
+
+func (s *peeringSpec) destroy(ctx context.Context, c *clients, name string, actual *state) ([]deletion, error) {
+	p, local, err := s.peerer(c)
+	if err != nil {
+		return nil, err
+	}
+	provider, _ := peering.ParseProvider(s.Provider)
+	return []deletion{peeringDeletion(p, local, peering.Peering{
+		Provider:      provider,
+		ID:            actual.outputs["id"],
+		Name:          actual.outputs["name"],
+		State:         peering.State(actual.outputs["state"]),
+		RemoteNetwork: actual.outputs["remoteNetwork"],
+	})}, nil
+}
+
+// routeDeleter is implemented by the peerers whose peerings are routed through, whose routes must be deleted
+// along with them
+type routeDeleter interface {
+	DeleteRoutes(ctx context.Context, id string) error
+}
+
+// peeringDeletion will return the deletion of the local half of an existing peering
+func peeringDeletion(p peering.Peerer, local peering.Network, pcx peering.Peering) deletion {
+	ref := pcx.Name
+	if pcx.Provider == peering.ProviderAWS || ref == "" {
+		// aws Name tags are not unique
+		ref = pcx.ID
+	}
+	name := pcx.Name
+	if name == "" {
+		name = pcx.ID
+	}
+	return deletion{
+		kind:   KindPeering,
+		name:   name,
+		id:     pcx.ID,
+		fields: map[string]string{"id": pcx.ID, "state": string(pcx.State), "remoteNetwork": pcx.RemoteNetwork},
+		delete: func(ctx context.Context) error {
+			if r, ok := p.(routeDeleter); ok {
+				if err := r.DeleteRoutes(ctx, pcx.ID); err != nil {
+					return err
+				}
+			}
+			return p.Delete(ctx, local, ref)
+		},
+	}
+}
+
+func peeringState(p peering.Peering) *state {
+	fields := map[string]string{"remoteNetwork": networkName(p.RemoteNetwork)}
+	for field, value := range routeFields(p.Provider, p.RouteExchange) {
+		fields[field] = value
+	}
+	return &state{
+		fields: fields,
+		outputs: map[string]string{
+			"id":            p.ID,
+			"name":          p.Name,
+			"state":         string(p.State),
+			"remoteNetwork": p.RemoteNetwork,
+		},
+	}
+}
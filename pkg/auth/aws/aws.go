@@ -1,27 +1,161 @@
 package aws
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/pkg/errors"
 )
 
-// AuthConfig is the authentication configuration for aws
+// DefaultRoleSessionName is the session name of assumed roles when none is given
+const DefaultRoleSessionName = "go-cloud-actions"
+
+// AuthConfig is the authentication configuration for aws.  The base credentials are, in order of precedence,
+// the static keys, the SSO account and role, or the default chain of the profile (environment, shared files,
+// SSO and role profiles, instance roles).  Roles are then assumed in order, each with the credentials of the
+// previous one, the first by web identity when WebIdentityTokenFile is given.
 type AuthConfig struct {
 	Profile string
 	Region  string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	SSOStartURL  string
+	SSORegion    string
+	SSOAccountID string
+	SSORoleName  string
+
+	// AssumeRoles is the chain of roles to assume, such as a role in an organization's identity account,
+	// followed by a role in the target account
+	AssumeRoles          []AssumeRole
+	WebIdentityTokenFile string
+	// MFASerial is the serial number or arn of the mfa device required to assume the first role
+	MFASerial string
+	// MFATokenCode is the current mfa code, which is prompted for on the terminal when empty
+	MFATokenCode string
 }
 
-// NewEc2Client will return a new configured ec2 client
-func NewEc2Client(auth AuthConfig) (*ec2.Client, error) {
-	cfg, err := config.LoadDefaultConfig(context.Background(),
+// AssumeRole is a role to assume
+type AssumeRole struct {
+	RoleARN     string
+	ExternalID  string
+	SessionName string
+	Duration    time.Duration
+}
+
+// Equal will return whether both configurations authenticate the same way
+func (a AuthConfig) Equal(b AuthConfig) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// NewConfig will return an aws config with the configured region and credentials, from which any aws service
+// client can be created
+func NewConfig(ctx context.Context, auth AuthConfig) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{
 		config.WithRegion(auth.Region),
 		config.WithSharedConfigProfile(auth.Profile),
-	)
+		config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			// prompt for the mfa code of role profiles requiring mfa on stderr, as stdout holds results
+			o.TokenProvider = mfaTokenProvider(auth.MFATokenCode)
+		}),
+	}
+	if auth.AccessKeyID != "" || auth.SecretAccessKey != "" {
+		if auth.AccessKeyID == "" || auth.SecretAccessKey == "" {
+			return aws.Config{}, errors.New("aws access key id and secret access key must be given together")
+		}
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(auth.AccessKeyID, auth.SecretAccessKey, auth.SessionToken)))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return cfg, errors.Wrap(err, "failed to load aws config")
+	}
+	if auth.SSOStartURL != "" && auth.AccessKeyID == "" {
+		if auth.SSOAccountID == "" || auth.SSORoleName == "" {
+			return cfg, errors.New("aws sso account id and role name must be given with an sso start url")
+		}
+		ssoCfg := cfg.Copy()
+		if auth.SSORegion != "" {
+			ssoCfg.Region = auth.SSORegion
+		}
+		cfg.Credentials = aws.NewCredentialsCache(
+			ssocreds.New(sso.NewFromConfig(ssoCfg), auth.SSOAccountID, auth.SSORoleName, auth.SSOStartURL))
+	}
+	if auth.WebIdentityTokenFile != "" && len(auth.AssumeRoles) == 0 {
+		return cfg, errors.New("aws role arn must be given with a web identity token file")
+	}
+	for i, role := range auth.AssumeRoles {
+		if role.RoleARN == "" {
+			return cfg, errors.Errorf("aws role arn %d of the role chain cannot be empty", i+1)
+		}
+		sessionName := role.SessionName
+		if sessionName == "" {
+			sessionName = DefaultRoleSessionName
+		}
+		client := sts.NewFromConfig(cfg)
+		var provider aws.CredentialsProvider
+		if i == 0 && auth.WebIdentityTokenFile != "" {
+			provider = stscreds.NewWebIdentityRoleProvider(client, role.RoleARN, stscreds.IdentityTokenFile(auth.WebIdentityTokenFile),
+				func(o *stscreds.WebIdentityRoleOptions) {
+					o.RoleSessionName = sessionName
+				})
+		} else {
+			first := i == 0
+			provider = stscreds.NewAssumeRoleProvider(client, role.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+				o.RoleSessionName = sessionName
+				if role.ExternalID != "" {
+					o.ExternalID = aws.String(role.ExternalID)
+				}
+				if role.Duration > 0 {
+					o.Duration = role.Duration
+				}
+				if first && auth.MFASerial != "" {
+					o.SerialNumber = aws.String(auth.MFASerial)
+					o.TokenProvider = mfaTokenProvider(auth.MFATokenCode)
+				}
+			})
+		}
+		cfg = cfg.Copy()
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
+
+// NewEc2Client will return a new configured ec2 client
+func NewEc2Client(auth AuthConfig) (*ec2.Client, error) {
+	cfg, err := NewConfig(context.Background(), auth)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get ec2 credentials provider from credentials")
 	}
 	return ec2.NewFromConfig(cfg), nil
 }
+
+// mfaTokenProvider will return the given mfa code, or prompt for it on the terminal when empty
+func mfaTokenProvider(code string) func() (string, error) {
+	return func() (string, error) {
+		if code != "" {
+			return code, nil
+		}
+		fmt.Fprint(os.Stderr, "Assume Role MFA token code: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read mfa token code")
+		}
+		return strings.TrimSpace(line), nil
+	}
+}
@@ -0,0 +1,56 @@
+package azure
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// environmentAliases are the short names of the azure clouds, in addition to the sdk names such as AzureUSGovernmentCloud
+var environmentAliases = map[string]string{
+	"public":       azure.PublicCloud.Name,
+	"usgovernment": azure.USGovernmentCloud.Name,
+	"usgov":        azure.USGovernmentCloud.Name,
+	"china":        azure.ChinaCloud.Name,
+	"german":       azure.GermanCloud.Name,
+}
+
+// ParseEnvironment will return the azure cloud environment named by s, which is either a short name (public,
+// usgovernment, china, german), an sdk name (AzurePublicCloud, AzureUSGovernmentCloud, ...), or the path to an
+// environment json file, such as the one describing an Azure Stack hub.  An empty name is the public cloud.
+func ParseEnvironment(s string) (azure.Environment, error) {
+	if s == "" {
+		return azure.PublicCloud, nil
+	}
+	if name, ok := environmentAliases[strings.ToLower(s)]; ok {
+		return azure.EnvironmentFromName(name)
+	}
+	if env, err := azure.EnvironmentFromName(s); err == nil {
+		return env, nil
+	}
+	if _, err := os.Stat(s); err != nil {
+		return azure.Environment{}, errors.Errorf("unknown azure environment %q, must be one of public, usgovernment, china, german or an environment json file", s)
+	}
+	env, err := azure.EnvironmentFromFile(s)
+	if err != nil {
+		return env, errors.Wrapf(err, "failed to load azure environment file %s", s)
+	}
+	return env, nil
+}
+
+// environment will return the configured azure cloud environment
+func (c AuthConfig) environment() (azure.Environment, error) {
+	return ParseEnvironment(c.Environment)
+}
+
+// mgmtResource will return the token audience of the resource manager of env, which differs from its endpoint
+// in some custom environments
+func mgmtResource(env azure.Environment) string {
+	if env.TokenAudience != "" {
+		return env.TokenAudience
+	}
+	return env.ResourceManagerEndpoint
+}
@@ -0,0 +1,245 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Azure/azure-sdk-for-go/services/authorization/mgmt/2015-07-01/authorization"
+	"github.com/Azure/azure-sdk-for-go/services/containerinstance/mgmt/2020-11-01/containerinstance"
+	"github.com/Azure/azure-sdk-for-go/services/graphrbac/1.6/graphrbac"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2020-11-01/network"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+const (
+	// DefaultRetryAttempts is the number of attempts of throttled and failed requests
+	DefaultRetryAttempts = 5
+	// DefaultRetryDuration is the initial backoff between attempts, doubled on every attempt, unless the
+	// response asks for a longer wait with Retry-After
+	DefaultRetryDuration = 2 * time.Second
+)
+
+var userAgent = fmt.Sprintf("Go-Cloud-Actions-v%s", "0.1.0")
+
+// FactoryConfig is the configuration of an azure client factory
+type FactoryConfig struct {
+	AuthConfig
+	Logger *logrus.Entry
+	// RetryAttempts is the number of attempts of throttled (429) and failed (5xx) requests, defaulting to DefaultRetryAttempts
+	RetryAttempts int
+	// RetryDuration is the initial backoff between attempts, defaulting to DefaultRetryDuration
+	RetryDuration time.Duration
+}
+
+// ClientFactory hands out azure clients which share one sender, with retries and request logging, and one
+// cached authorizer per resource (management and graph), so that every client of a workflow reuses the same
+// tokens, which are refreshed as they are about to expire
+type ClientFactory struct {
+	FactoryConfig
+	env         azure.Environment
+	sender      autorest.Sender
+	mu          sync.Mutex
+	authorizers map[string]autorest.Authorizer
+}
+
+// NewClientFactory will return a new azure client factory.  No token is acquired until the first client is requested.
+func NewClientFactory(conf FactoryConfig) (*ClientFactory, error) {
+	env, err := conf.environment()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = ParseMethod(string(conf.Method)); err != nil {
+		return nil, err
+	}
+	if conf.Logger == nil {
+		conf.Logger = logrus.NewEntry(logrus.New())
+		conf.Logger.Logger.SetLevel(logrus.InfoLevel)
+		conf.Logger.Logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+	if conf.RetryAttempts <= 0 {
+		conf.RetryAttempts = DefaultRetryAttempts
+	}
+	if conf.RetryDuration <= 0 {
+		conf.RetryDuration = DefaultRetryDuration
+	}
+	return &ClientFactory{
+		FactoryConfig: conf,
+		env:           env,
+		sender: autorest.DecorateSender(autorest.CreateSender(),
+			withRequestLogging(conf.Logger),
+			autorest.DoRetryForStatusCodes(conf.RetryAttempts, conf.RetryDuration, autorest.StatusCodesForRetry...),
+		),
+		authorizers: map[string]autorest.Authorizer{},
+	}, nil
+}
+
+// Environment will return the azure cloud environment of the factory
+func (f *ClientFactory) Environment() azure.Environment {
+	return f.env
+}
+
+// GroupsClient will return a new azure resource groups client
+func (f *ClientFactory) GroupsClient() (resources.GroupsClient, error) {
+	client := resources.NewGroupsClientWithBaseURI(f.env.ResourceManagerEndpoint, f.SubscriptionID)
+	err := f.configure(&client.Client, f.mgmtAuthorizer)
+	return client, err
+}
+
+// VirtualNetworkPeeringsClient will return a new azure virtual network peerings client, which is authorized
+// for AuxTenantIDs as well when given
+func (f *ClientFactory) VirtualNetworkPeeringsClient() (network.VirtualNetworkPeeringsClient, error) {
+	client := network.NewVirtualNetworkPeeringsClientWithBaseURI(f.env.ResourceManagerEndpoint, f.SubscriptionID)
+	err := f.configure(&client.Client, f.mgmtAuthorizer)
+	return client, err
+}
+
+// ApplicationsClient will return a new azure graph applications client
+func (f *ClientFactory) ApplicationsClient() (graphrbac.ApplicationsClient, error) {
+	client := graphrbac.NewApplicationsClientWithBaseURI(f.env.GraphEndpoint, f.TenantID)
+	err := f.configure(&client.Client, f.graphAuthorizer)
+	return client, err
+}
+
+// ServicePrincipalsClient will return a new azure graph service principals client
+func (f *ClientFactory) ServicePrincipalsClient() (graphrbac.ServicePrincipalsClient, error) {
+	client := graphrbac.NewServicePrincipalsClientWithBaseURI(f.env.GraphEndpoint, f.TenantID)
+	err := f.configure(&client.Client, f.graphAuthorizer)
+	return client, err
+}
+
+// RoleDefinitionsClient will return a new azure role definitions client
+func (f *ClientFactory) RoleDefinitionsClient() (authorization.RoleDefinitionsClient, error) {
+	client := authorization.NewRoleDefinitionsClientWithBaseURI(f.env.ResourceManagerEndpoint, f.SubscriptionID)
+	err := f.configure(&client.Client, f.mgmtAuthorizer)
+	return client, err
+}
+
+// ContainerGroupsClient will return a new azure container groups client
+func (f *ClientFactory) ContainerGroupsClient() (containerinstance.ContainerGroupsClient, error) {
+	client := containerinstance.NewContainerGroupsClientWithBaseURI(f.env.ResourceManagerEndpoint, f.SubscriptionID)
+	err := f.configure(&client.Client, f.mgmtAuthorizer)
+	return client, err
+}
+
+// ContainersClient will return a new azure container instance containers client
+func (f *ClientFactory) ContainersClient() (containerinstance.ContainersClient, error) {
+	client := containerinstance.NewContainersClientWithBaseURI(f.env.ResourceManagerEndpoint, f.SubscriptionID)
+	err := f.configure(&client.Client, f.mgmtAuthorizer)
+	return client, err
+}
+
+// NetworkProfilesClient will return a new azure network profiles client
+func (f *ClientFactory) NetworkProfilesClient() (network.ProfilesClient, error) {
+	client := network.NewProfilesClientWithBaseURI(f.env.ResourceManagerEndpoint, f.SubscriptionID)
+	err := f.configure(&client.Client, f.mgmtAuthorizer)
+	return client, err
+}
+
+// VirtualNetworksClient will return a new azure virtual networks client
+func (f *ClientFactory) VirtualNetworksClient() (network.VirtualNetworksClient, error) {
+	client := network.NewVirtualNetworksClientWithBaseURI(f.env.ResourceManagerEndpoint, f.SubscriptionID)
+	err := f.configure(&client.Client, f.mgmtAuthorizer)
+	return client, err
+}
+
+// SubnetsClient will return a new azure network subnets client
+func (f *ClientFactory) SubnetsClient() (network.SubnetsClient, error) {
+	client := network.NewSubnetsClientWithBaseURI(f.env.ResourceManagerEndpoint, f.SubscriptionID)
+	err := f.configure(&client.Client, f.mgmtAuthorizer)
+	return client, err
+}
+
+// configure will set the shared authorizer, sender and user agent of an sdk client
+func (f *ClientFactory) configure(client *autorest.Client, authorizer func() (autorest.Authorizer, error)) error {
+	a, err := authorizer()
+	if err != nil {
+		return errors.Wrap(err, "failed to get new azure authorizer")
+	}
+	client.Authorizer = a
+	client.Sender = f.sender
+	client.UserAgent = userAgent
+	return nil
+}
+
+// mgmtAuthorizer will return the cached resource manager authorizer
+func (f *ClientFactory) mgmtAuthorizer() (autorest.Authorizer, error) {
+	resource := f.Resource
+	if resource == "" {
+		resource = mgmtResource(f.env)
+	}
+	if len(f.AuxTenantIDs) > 0 {
+		return f.authorizer(resource, func() (autorest.Authorizer, error) {
+			return newMultiTenantAuthorizer(f.AuthConfig, f.env, resource)
+		})
+	}
+	return f.authorizer(resource, func() (autorest.Authorizer, error) {
+		return newTokenAuthorizer(f.AuthConfig, f.env, resource)
+	})
+}
+
+// graphAuthorizer will return the cached graph authorizer
+func (f *ClientFactory) graphAuthorizer() (autorest.Authorizer, error) {
+	return f.authorizer(f.env.GraphEndpoint, func() (autorest.Authorizer, error) {
+		return newTokenAuthorizer(f.AuthConfig, f.env, f.env.GraphEndpoint)
+	})
+}
+
+// authorizer will return the cached authorizer of resource, creating it on first use
+func (f *ClientFactory) authorizer(resource string, create func() (autorest.Authorizer, error)) (autorest.Authorizer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if a, ok := f.authorizers[resource]; ok {
+		return a, nil
+	}
+	f.Logger.Debugf("acquiring azure token for %s", resource)
+	a, err := create()
+	if err != nil {
+		return nil, err
+	}
+	f.authorizers[resource] = a
+	return a, nil
+}
+
+// withRequestLogging will log every request and response at debug level, without the authorization header
+func withRequestLogging(logger *logrus.Entry) autorest.SendDecorator {
+	return func(s autorest.Sender) autorest.Sender {
+		return autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
+			if !logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+				return s.Do(r)
+			}
+			// dump request to wire format, with a copy of the request, so the bearer token is not logged
+			redacted := r.Clone(r.Context())
+			if redacted.Header.Get("Authorization") != "" {
+				redacted.Header.Set("Authorization", "REDACTED")
+			}
+			if dump, err := httputil.DumpRequestOut(redacted, false); err == nil {
+				logger.Debugf("AzureRM Request: \n%s\n", dump)
+			} else {
+				// fallback to basic message
+				logger.Debugf("AzureRM Request: %s to %s\n", r.Method, r.URL)
+			}
+			resp, err := s.Do(r)
+			if resp != nil {
+				// dump response to wire format
+				if dump, err := httputil.DumpResponse(resp, true); err == nil {
+					logger.Debugf("AzureRM Response for %s: \n%s\n", r.URL, dump)
+				} else {
+					// fallback to basic message
+					logger.Debugf("AzureRM Response: %s for %s\n", resp.Status, r.URL)
+				}
+			} else {
+				logger.Debugf("Request to %s completed with no response", r.URL)
+			}
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package azure
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/cli"
+)
+
+// Method is the method used to authenticate against azure
+type Method string
+
+const (
+	// MethodClientSecret authenticates as a service principal with ClientID and ClientSecret
+	MethodClientSecret Method = "client-secret"
+	// MethodClientCertificate authenticates as a service principal with ClientID, and the pkcs12
+	// certificate at CertificatePath, protected by CertificatePassword
+	MethodClientCertificate Method = "client-certificate"
+	// MethodManagedIdentity authenticates as the managed identity of the host, or the user assigned
+	// identity with ClientID when given
+	MethodManagedIdentity Method = "managed-identity"
+	// MethodDeviceCode authenticates as a user, who signs in on another device with the printed code
+	MethodDeviceCode Method = "device-code"
+	// MethodCLI reuses the tokens of the signed in azure cli (az login)
+	MethodCLI Method = "cli"
+	// MethodWorkloadIdentity authenticates as the service principal ClientID with the federated token
+	// read from FederatedTokenFile, such as a kubernetes service account token
+	MethodWorkloadIdentity Method = "workload-identity"
+)
+
+const (
+	// azureCLIClientID is the public client id of the azure cli, used for device code sign in when no
+	// client id is given
+	azureCLIClientID = "04b07795-8ddb-461a-bbee-02f9e1bf7b46"
+	// jwtBearerAssertionType is the client assertion type of a federated token
+	jwtBearerAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	// tokenRefreshWindow is how long before expiry cli tokens are refreshed
+	tokenRefreshWindow = 2 * time.Minute
+)
+
+var (
+	// deviceTokens are the tokens of completed device code sign ins, by tenant and client, so that a user
+	// is prompted only once, while each resource's token is exchanged from the same refresh token
+	deviceTokens   = map[string]adal.Token{}
+	deviceTokensMu sync.Mutex
+)
+
+// ParseMethod will parse an authentication method name, defaulting to MethodClientSecret when empty
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(strings.ToLower(s)); m {
+	case "":
+		return MethodClientSecret, nil
+	case MethodClientSecret, MethodClientCertificate, MethodManagedIdentity, MethodDeviceCode, MethodCLI, MethodWorkloadIdentity:
+		return m, nil
+	}
+	return "", fmt.Errorf("unsupported azure auth method %q, must be one of %s, %s, %s, %s, %s, %s",
+		s, MethodClientSecret, MethodClientCertificate, MethodManagedIdentity, MethodDeviceCode, MethodCLI, MethodWorkloadIdentity)
+}
+
+// newTokenAuthorizer will return a bearer authorizer for resource, authenticating with the configured method
+func newTokenAuthorizer(conf AuthConfig, env azure.Environment, resource string) (autorest.Authorizer, error) {
+	provider, err := newTokenProvider(conf, env, resource)
+	if err != nil {
+		return nil, err
+	}
+	return autorest.NewBearerAuthorizer(provider), nil
+}
+
+// newTokenProvider will return a refreshing token provider for resource, authenticating with the configured method
+func newTokenProvider(conf AuthConfig, env azure.Environment, resource string) (adal.OAuthTokenProvider, error) {
+	method, err := ParseMethod(string(conf.Method))
+	if err != nil {
+		return nil, err
+	}
+	if method == MethodWorkloadIdentity {
+		conf = workloadIdentityDefaults(conf)
+	}
+	switch method {
+	case MethodManagedIdentity:
+		token, err := adal.NewServicePrincipalTokenFromManagedIdentity(resource, &adal.ManagedIdentityOptions{
+			ClientID: conf.ClientID,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate new azure managed identity token")
+		}
+		return token, nil
+	case MethodCLI:
+		return newCLITokenProvider(resource)
+	}
+	oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, conf.TenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new azure oauth config")
+	}
+	switch method {
+	case MethodClientCertificate:
+		certificate, privateKey, err := readCertificate(conf)
+		if err != nil {
+			return nil, err
+		}
+		token, err := adal.NewServicePrincipalTokenFromCertificate(*oauthConfig, conf.ClientID, certificate, privateKey, resource)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate new azure service principal token from certificate")
+		}
+		return token, nil
+	case MethodDeviceCode:
+		return newDeviceCodeToken(conf, *oauthConfig, resource)
+	case MethodWorkloadIdentity:
+		if conf.FederatedTokenFile == "" {
+			return nil, errors.New("federated token file cannot be empty with workload identity auth")
+		}
+		token, err := adal.NewServicePrincipalTokenWithSecret(*oauthConfig, conf.ClientID, resource, &federatedTokenSecret{path: conf.FederatedTokenFile})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate new azure service principal token from federated token")
+		}
+		return token, nil
+	}
+	token, err := adal.NewServicePrincipalToken(*oauthConfig, conf.ClientID, conf.ClientSecret, resource)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new azure service principal token")
+	}
+	return token, nil
+}
+
+// newMultiTenantAuthorizer will return an authorizer for resource in the primary tenant, with auxiliary tokens
+// for AuxTenantIDs, which is only supported by the service principal methods
+func newMultiTenantAuthorizer(conf AuthConfig, env azure.Environment, resource string) (autorest.Authorizer, error) {
+	method, err := ParseMethod(string(conf.Method))
+	if err != nil {
+		return nil, err
+	}
+	oauth, err := adal.NewMultiTenantOAuthConfig(env.ActiveDirectoryEndpoint, conf.TenantID, conf.AuxTenantIDs, adal.OAuthOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get new multi-tenant oauth configuration")
+	}
+	var token *adal.MultiTenantServicePrincipalToken
+	switch method {
+	case MethodClientSecret:
+		token, err = adal.NewMultiTenantServicePrincipalToken(oauth, conf.ClientID, conf.ClientSecret, resource)
+	case MethodClientCertificate:
+		certificate, privateKey, certErr := readCertificate(conf)
+		if certErr != nil {
+			return nil, certErr
+		}
+		token, err = adal.NewMultiTenantServicePrincipalTokenFromCertificate(oauth, conf.ClientID, certificate, privateKey, resource)
+	default:
+		return nil, errors.Errorf("azure auth method %s does not support auxiliary tenants", method)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new multi-tenant service principal token")
+	}
+	return autorest.NewMultiTenantServicePrincipalTokenAuthorizer(token), nil
+}
+
+// workloadIdentityDefaults will fill the client, tenant and token file of workload identity auth from the
+// environment injected by the azure workload identity webhook, when not configured
+func workloadIdentityDefaults(conf AuthConfig) AuthConfig {
+	for value, env := range map[*string]string{
+		&conf.ClientID:           "AZURE_CLIENT_ID",
+		&conf.TenantID:           "AZURE_TENANT_ID",
+		&conf.FederatedTokenFile: "AZURE_FEDERATED_TOKEN_FILE",
+	} {
+		if *value == "" {
+			*value = os.Getenv(env)
+		}
+	}
+	return conf
+}
+
+// readCertificate will read the pkcs12 certificate and private key of client certificate auth
+func readCertificate(conf AuthConfig) (*x509.Certificate, *rsa.PrivateKey, error) {
+	if conf.CertificatePath == "" {
+		return nil, nil, errors.New("certificate path cannot be empty with client certificate auth")
+	}
+	data, err := ioutil.ReadFile(conf.CertificatePath)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read certificate %s", conf.CertificatePath)
+	}
+	certificate, privateKey, err := adal.DecodePfxCertificateData(data, conf.CertificatePassword)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to decode pkcs12 certificate %s", conf.CertificatePath)
+	}
+	return certificate, privateKey, nil
+}
+
+// newDeviceCodeToken will prompt the user to sign in with a device code once per tenant and client, and
+// exchange the resulting refresh token for a token of resource
+func newDeviceCodeToken(conf AuthConfig, oauthConfig adal.OAuthConfig, resource string) (*adal.ServicePrincipalToken, error) {
+	clientID := conf.ClientID
+	if clientID == "" {
+		clientID = azureCLIClientID
+	}
+	deviceTokensMu.Lock()
+	defer deviceTokensMu.Unlock()
+	key := conf.TenantID + "/" + clientID
+	if token, ok := deviceTokens[key]; ok {
+		spt, err := adal.NewServicePrincipalTokenFromManualToken(oauthConfig, clientID, resource, token)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate new azure token from device code sign in")
+		}
+		if err = spt.RefreshExchange(resource); err != nil {
+			return nil, errors.Wrapf(err, "failed to exchange device code sign in for a token of %s", resource)
+		}
+		return spt, nil
+	}
+	client := &autorest.Client{}
+	code, err := adal.InitiateDeviceAuth(client, oauthConfig, clientID, resource)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start device code sign in")
+	}
+	fmt.Fprintln(os.Stderr, *code.Message)
+	token, err := adal.WaitForUserCompletion(client, code)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to finish device code sign in")
+	}
+	deviceTokens[key] = *token
+	spt, err := adal.NewServicePrincipalTokenFromManualToken(oauthConfig, clientID, resource, *token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new azure token from device code sign in")
+	}
+	return spt, nil
+}
+
+// federatedTokenSecret authenticates a service principal with a federated token, read from a file on
+// every refresh, as the token is rotated by its issuer
+type federatedTokenSecret struct {
+	path string
+}
+
+// SetAuthenticationValues will set the federated token as the client assertion of a token request
+func (s *federatedTokenSecret) SetAuthenticationValues(spt *adal.ServicePrincipalToken, v *url.Values) error {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read federated token file %s", s.path)
+	}
+	v.Set("client_assertion_type", jwtBearerAssertionType)
+	v.Set("client_assertion", strings.TrimSpace(string(b)))
+	return nil
+}
+
+// cliTokenProvider provides the tokens of the signed in azure cli, fetching a new token from the cli as
+// the current token is about to expire
+type cliTokenProvider struct {
+	resource string
+	mu       sync.Mutex
+	token    adal.Token
+}
+
+func newCLITokenProvider(resource string) (*cliTokenProvider, error) {
+	p := &cliTokenProvider{resource: resource}
+	if err := p.RefreshWithContext(context.Background()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// OAuthToken will return the current access token
+func (p *cliTokenProvider) OAuthToken() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.token.OAuthToken()
+}
+
+// EnsureFreshWithContext will fetch a new token from the cli when the current token is about to expire
+func (p *cliTokenProvider) EnsureFreshWithContext(ctx context.Context) error {
+	p.mu.Lock()
+	fresh := !p.token.WillExpireIn(tokenRefreshWindow)
+	p.mu.Unlock()
+	if fresh {
+		return nil
+	}
+	return p.RefreshWithContext(ctx)
+}
+
+// RefreshWithContext will fetch a new token from the cli
+func (p *cliTokenProvider) RefreshWithContext(ctx context.Context) error {
+	return p.RefreshExchangeWithContext(ctx, p.resource)
+}
+
+// RefreshExchangeWithContext will fetch a new token of resource from the cli
+func (p *cliTokenProvider) RefreshExchangeWithContext(ctx context.Context, resource string) error {
+	cliToken, err := cli.GetTokenFromCLI(resource)
+	if err != nil {
+		return errors.Wrap(err, "failed to get token from azure cli, is it signed in with az login?")
+	}
+	token, err := cliToken.ToADALToken()
+	if err != nil {
+		return errors.Wrap(err, "failed to convert azure cli token")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resource = resource
+	p.token = token
+	return nil
+}
@@ -2,33 +2,191 @@ package google
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
 
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/container/v1"
 	"google.golang.org/api/option"
+	"google.golang.org/api/transport"
 )
 
-// AuthConfig is the configuration required to generate any google api client
+// Method is the method used to authenticate against google
+type Method string
+
+const (
+	// MethodCredentialsFile authenticates with the service account key file at CredentialsFilePath
+	MethodCredentialsFile Method = "credentials-file"
+	// MethodADC authenticates with the application default credentials: $GOOGLE_APPLICATION_CREDENTIALS, the
+	// gcloud auth application-default login credentials, or the metadata server of the host
+	MethodADC Method = "adc"
+	// MethodExternalAccount authenticates with the external account (workload identity federation) credential
+	// configuration file at ExternalAccountFilePath
+	MethodExternalAccount Method = "external-account"
+	// MethodAccessToken authenticates with a raw oauth2 access token, read from the environment variable AccessTokenEnv
+	MethodAccessToken Method = "access-token"
+)
+
+// CloudPlatformScope is the oauth2 scope of tokens minted for the apis of gke clusters
+const CloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// DefaultAccessTokenEnv is the environment variable holding the access token of MethodAccessToken when none is given
+const DefaultAccessTokenEnv = "GOOGLE_OAUTH_ACCESS_TOKEN"
+
+// AuthConfig is the configuration required to generate any google api client.  The credentials of the
+// method are impersonating ImpersonateServiceAccount when given, through the chain of ImpersonateDelegates.
 type AuthConfig struct {
 	CredentialsFilePath string
+	// Method is the authentication method, defaulting to MethodCredentialsFile when CredentialsFilePath
+	// is given, and MethodADC otherwise
+	Method                    Method
+	ExternalAccountFilePath   string
+	AccessTokenEnv            string
+	ImpersonateServiceAccount string
+	ImpersonateDelegates      []string
+}
+
+// ParseMethod will parse an authentication method name
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(strings.ToLower(s)); m {
+	case "", MethodCredentialsFile, MethodADC, MethodExternalAccount, MethodAccessToken:
+		return m, nil
+	}
+	return "", fmt.Errorf("unsupported google auth method %q, must be one of %s, %s, %s, %s",
+		s, MethodCredentialsFile, MethodADC, MethodExternalAccount, MethodAccessToken)
+}
+
+// ClientOptions will return the client options authenticating any google api client with the configured method
+func (c AuthConfig) ClientOptions() ([]option.ClientOption, error) {
+	method, err := ParseMethod(string(c.Method))
+	if err != nil {
+		return nil, err
+	}
+	if method == "" {
+		method = MethodADC
+		if c.CredentialsFilePath != "" {
+			method = MethodCredentialsFile
+		}
+	}
+	var opts []option.ClientOption
+	switch method {
+	case MethodCredentialsFile:
+		if c.CredentialsFilePath == "" {
+			return nil, errors.New("google credentials file path cannot be empty with credentials-file auth")
+		}
+		opts = append(opts, option.WithCredentialsFile(c.CredentialsFilePath))
+	case MethodExternalAccount:
+		if err = validateExternalAccountFile(c.ExternalAccountFilePath); err != nil {
+			return nil, err
+		}
+		opts = append(opts, option.WithCredentialsFile(c.ExternalAccountFilePath))
+	case MethodAccessToken:
+		env := c.AccessTokenEnv
+		if env == "" {
+			env = DefaultAccessTokenEnv
+		}
+		token := os.Getenv(env)
+		if token == "" {
+			return nil, errors.Errorf("google access token environment variable %s cannot be empty with access-token auth", env)
+		}
+		opts = append(opts, option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	}
+	if c.ImpersonateServiceAccount != "" {
+		opts = append(opts, option.ImpersonateCredentials(c.ImpersonateServiceAccount, c.ImpersonateDelegates...))
+	}
+	return opts, nil
+}
+
+// validateExternalAccountFile will ensure the file is an external account credential configuration, as a service
+// account key would otherwise be silently accepted
+func validateExternalAccountFile(path string) error {
+	if path == "" {
+		return errors.New("google external account file path cannot be empty with external-account auth")
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read google external account file %s", path)
+	}
+	var f struct {
+		Type string `json:"type"`
+	}
+	if err = json.Unmarshal(b, &f); err != nil {
+		return errors.Wrapf(err, "failed to parse google external account file %s", path)
+	}
+	if f.Type != "external_account" {
+		return errors.Errorf("google external account file %s has type %q, not external_account", path, f.Type)
+	}
+	return nil
 }
 
 // NewNetworkClient will return a new google network client with a given configuration
 func NewNetworkClient(ctx context.Context, conf AuthConfig) (*compute.NetworksService, error) {
-	svc, err := compute.NewService(ctx, option.WithCredentialsFile(conf.CredentialsFilePath))
+	opts, err := conf.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	svc, err := compute.NewService(ctx, opts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate new google network service")
 	}
 	return compute.NewNetworksService(svc), nil
 }
 
+// NewSubnetworksClient will return a new google subnetworks client with a given configuration
+func NewSubnetworksClient(ctx context.Context, conf AuthConfig) (*compute.SubnetworksService, error) {
+	opts, err := conf.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	svc, err := compute.NewService(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new google subnetworks service")
+	}
+	return compute.NewSubnetworksService(svc), nil
+}
+
+// NewInstanceGroupManagersClient will return a new google instance group managers client with a given configuration
+func NewInstanceGroupManagersClient(ctx context.Context, conf AuthConfig) (*compute.InstanceGroupManagersService, error) {
+	opts, err := conf.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	svc, err := compute.NewService(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new google instance group managers service")
+	}
+	return compute.NewInstanceGroupManagersService(svc), nil
+}
+
 // NewContainersClient will return a new google containers (gke) client with a given configuration
 func NewContainersClient(ctx context.Context, conf AuthConfig) (*container.ProjectsService, error) {
-	svc, err := container.NewService(ctx, option.WithCredentialsFile(conf.CredentialsFilePath))
+	opts, err := conf.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	svc, err := container.NewService(ctx, opts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate new google containers service")
 	}
 	return container.NewProjectsService(svc), nil
 }
+
+// TokenSource will return a source of oauth2 access tokens of the given scopes, minted from the credentials of the
+// configured method
+func TokenSource(ctx context.Context, conf AuthConfig, scopes ...string) (oauth2.TokenSource, error) {
+	opts, err := conf.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	creds, err := transport.Creds(ctx, append(opts, option.WithScopes(scopes...))...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find google credentials")
+	}
+	return creds.TokenSource, nil
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix is the prefix of environment variables overriding any flag or context value, such that
+	// --client-secret can be given as CLOUD_CLIENT_SECRET
+	EnvPrefix = "CLOUD"
+	// DefaultFileName is the name of the config file within the config directory
+	DefaultFileName = "config.yaml"
+)
+
+// Context is a named set of credentials and defaults for a single public cloud account.  Keys match the
+// names of the flags they provide defaults for.
+type Context struct {
+	Provider                  string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	SubscriptionID            string `yaml:"subscription-id,omitempty" json:"subscription-id,omitempty"`
+	TenantID                  string `yaml:"tenant-id,omitempty" json:"tenant-id,omitempty"`
+	ClientID                  string `yaml:"client-id,omitempty" json:"client-id,omitempty"`
+	ClientSecret              string `yaml:"client-secret,omitempty" json:"client-secret,omitempty"`
+	AuthMethod                string `yaml:"auth-method,omitempty" json:"auth-method,omitempty"`
+	CertificatePath           string `yaml:"certificate-path,omitempty" json:"certificate-path,omitempty"`
+	FederatedTokenFile        string `yaml:"federated-token-file,omitempty" json:"federated-token-file,omitempty"`
+	AzureEnvironment          string `yaml:"azure-environment,omitempty" json:"azure-environment,omitempty"`
+	Profile                   string `yaml:"profile,omitempty" json:"profile,omitempty"`
+	Region                    string `yaml:"region,omitempty" json:"region,omitempty"`
+	RoleARN                   string `yaml:"role-arn,omitempty" json:"role-arn,omitempty"`
+	MFASerial                 string `yaml:"mfa-serial,omitempty" json:"mfa-serial,omitempty"`
+	SSOStartURL               string `yaml:"sso-start-url,omitempty" json:"sso-start-url,omitempty"`
+	SSORegion                 string `yaml:"sso-region,omitempty" json:"sso-region,omitempty"`
+	SSOAccountID              string `yaml:"sso-account-id,omitempty" json:"sso-account-id,omitempty"`
+	SSORoleName               string `yaml:"sso-role-name,omitempty" json:"sso-role-name,omitempty"`
+	GoogleCredentialsFilePath string `yaml:"google-credentials-file-path,omitempty" json:"google-credentials-file-path,omitempty"`
+	GoogleAuthMethod          string `yaml:"google-auth-method,omitempty" json:"google-auth-method,omitempty"`
+	GoogleExternalAccountFile string `yaml:"google-external-account-file,omitempty" json:"google-external-account-file,omitempty"`
+	GoogleImpersonateAccount  string `yaml:"google-impersonate-service-account,omitempty" json:"google-impersonate-service-account,omitempty"`
+	Tags                      string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	TagsFile                  string `yaml:"tags-file,omitempty" json:"tags-file,omitempty"`
+	Owner                     string `yaml:"owner,omitempty" json:"owner,omitempty"`
+}
+
+// Values will return the non-empty values of the context, keyed by flag name
+func (c Context) Values() map[string]interface{} {
+	values := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"provider":                           c.Provider,
+		"subscription-id":                    c.SubscriptionID,
+		"tenant-id":                          c.TenantID,
+		"client-id":                          c.ClientID,
+		"client-secret":                      c.ClientSecret,
+		"auth-method":                        c.AuthMethod,
+		"certificate-path":                   c.CertificatePath,
+		"federated-token-file":               c.FederatedTokenFile,
+		"azure-environment":                  c.AzureEnvironment,
+		"profile":                            c.Profile,
+		"region":                             c.Region,
+		"role-arn":                           c.RoleARN,
+		"mfa-serial":                         c.MFASerial,
+		"sso-start-url":                      c.SSOStartURL,
+		"sso-region":                         c.SSORegion,
+		"sso-account-id":                     c.SSOAccountID,
+		"sso-role-name":                      c.SSORoleName,
+		"google-credentials-file-path":       c.GoogleCredentialsFilePath,
+		"google-auth-method":                 c.GoogleAuthMethod,
+		"google-external-account-file":       c.GoogleExternalAccountFile,
+		"google-impersonate-service-account": c.GoogleImpersonateAccount,
+		"tags":                               c.Tags,
+		"tags-file":                          c.TagsFile,
+		"owner":                              c.Owner,
+	} {
+		if value != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// Config is the cloud cli configuration file
+type Config struct {
+	CurrentContext string             `yaml:"current-context,omitempty"`
+	Contexts       map[string]Context `yaml:"contexts,omitempty"`
+}
+
+// DefaultPath will return the default config file path, $XDG_CONFIG_HOME/cloud/config.yaml, which is
+// ~/.config/cloud/config.yaml on most systems
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to find home directory")
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "cloud", DefaultFileName), nil
+}
+
+// Path will return the given path, or the default config file path when empty
+func Path(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	return DefaultPath()
+}
+
+// Load will load the config file at path.  A missing file is an empty config.
+func Load(path string) (*Config, error) {
+	conf := &Config{Contexts: map[string]Context{}}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return conf, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read config file %s", path)
+	}
+	if err = yaml.Unmarshal(b, conf); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse config file %s", path)
+	}
+	if conf.Contexts == nil {
+		conf.Contexts = map[string]Context{}
+	}
+	return conf, nil
+}
+
+// Save will write the config file to path, readable only by the current user, as contexts may hold secrets
+func (c *Config) Save(path string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal config")
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrapf(err, "failed to create config directory for %s", path)
+	}
+	if err = ioutil.WriteFile(path, b, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write config file %s", path)
+	}
+	return nil
+}
+
+// Context will return the named context, or the current context when name is empty.  No context is
+// returned when name is empty, and there is no current context.
+func (c *Config) Context(name string) (Context, bool, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return Context{}, false, nil
+	}
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return Context{}, false, errors.Errorf("context %s not found", name)
+	}
+	return ctx, true, nil
+}
+
+// UseContext will set the current context to the named context, which must exist
+func (c *Config) UseContext(name string) error {
+	if _, ok := c.Contexts[name]; !ok {
+		return errors.Errorf("context %s not found", name)
+	}
+	c.CurrentContext = name
+	return nil
+}
+
+// ContextNames will return the names of all contexts, sorted
+func (c *Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
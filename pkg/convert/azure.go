@@ -0,0 +1,385 @@
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	azure_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/azure"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+const (
+	// defaultCPU and defaultMemoryGB are the resources requested by containers requesting none, which container
+	// groups require
+	defaultCPU      = 1
+	defaultMemoryGB = 1.5
+)
+
+// ToContainerGroup will translate a Deployment, with the ConfigMaps of its volumes and environment variables, into
+// the request of a container group, returning warnings about the fields which could not be translated.  ConfigMap
+// volumes become secret volumes, and the labels of the Deployment become the tags of the group.
+func ToContainerGroup(deployment Deployment, configMaps []ConfigMap, opts AzureOptions) (azure_serverless.CreateContainerRequest, []string, error) {
+	var w warnings
+	name := opts.Name
+	if name == "" {
+		name = deployment.Metadata.Name
+	}
+	req := azure_serverless.CreateContainerRequest{
+		ContainerGroupName: name,
+		Location:           opts.Location,
+		ResourceGroupName:  opts.ResourceGroup,
+	}
+	if len(deployment.Metadata.Labels) > 0 {
+		req.Tags = tags.Tags(deployment.Metadata.Labels)
+	}
+	spec := deployment.Spec
+	if spec.Replicas != nil && *spec.Replicas > 1 {
+		w.add("deployment %s: %d replicas cannot be converted, a container group runs a single replica", deployment.Metadata.Name, *spec.Replicas)
+	}
+	w.addExtra("deployment "+deployment.Metadata.Name, spec.Extra)
+	pod := spec.Template.Spec
+	w.addExtra("pod template", pod.Extra)
+
+	cms := map[string]ConfigMap{}
+	for _, cm := range configMaps {
+		cms[cm.Metadata.Name] = cm
+	}
+	volumes, err := containerGroupVolumes(pod.Volumes, cms, &w)
+	if err != nil {
+		return req, nil, err
+	}
+	var (
+		containers []containerinstance.Container
+		ports      []containerinstance.Port
+	)
+	for _, c := range pod.Containers {
+		container, err := containerGroupContainer(c, volumes, cms, &w)
+		if err != nil {
+			return req, nil, err
+		}
+		containers = append(containers, container)
+		if container.Ports == nil {
+			continue
+		}
+		for _, p := range *container.Ports {
+			ports = append(ports, containerinstance.Port{
+				Protocol: containerinstance.ContainerGroupNetworkProtocol(p.Protocol),
+				Port:     p.Port,
+			})
+		}
+	}
+
+	props := containerinstance.ContainerGroupProperties{
+		Containers: &containers,
+		OsType:     containerinstance.Linux,
+	}
+	switch pod.RestartPolicy {
+	case "", string(containerinstance.Always):
+	case string(containerinstance.OnFailure), string(containerinstance.Never):
+		props.RestartPolicy = containerinstance.ContainerGroupRestartPolicy(pod.RestartPolicy)
+	default:
+		w.add("pod template: restart policy %s cannot be converted, and was dropped", pod.RestartPolicy)
+	}
+	if len(volumes) > 0 {
+		groupVolumes := make([]containerinstance.Volume, 0, len(volumes))
+		for _, name := range sortedVolumeNames(volumes) {
+			groupVolumes = append(groupVolumes, volumes[name])
+		}
+		props.Volumes = &groupVolumes
+	}
+	if opts.IPAddressType != "" {
+		props.IPAddress = &containerinstance.IPAddress{
+			Type:  containerinstance.ContainerGroupIPAddressType(opts.IPAddressType),
+			Ports: &ports,
+		}
+	}
+	req.ContainerGroupProperties = props
+	return req, w, nil
+}
+
+// containerGroupVolumes will translate the ConfigMap and empty directory volumes of a pod into the volumes of a
+// container group by name, warning about and dropping the others
+func containerGroupVolumes(volumes []Volume, configMaps map[string]ConfigMap, w *warnings) (map[string]containerinstance.Volume, error) {
+	groupVolumes := map[string]containerinstance.Volume{}
+	for _, v := range volumes {
+		of := "volume " + v.Name
+		w.addExtra(of, v.Extra)
+		switch {
+		case v.EmptyDir != nil:
+			w.addExtra(of+" emptyDir", v.EmptyDir.Extra)
+			groupVolumes[v.Name] = containerinstance.Volume{Name: to.StringPtr(v.Name), EmptyDir: map[string]interface{}{}}
+		case v.ConfigMap != nil:
+			w.addExtra(of+" configMap", v.ConfigMap.Extra)
+			cm, ok := configMaps[v.ConfigMap.Name]
+			if !ok {
+				return nil, errors.Errorf("ConfigMap %s of volume %s was not given", v.ConfigMap.Name, v.Name)
+			}
+			secret, err := configMapSecret(cm, v.ConfigMap.Items, of, w)
+			if err != nil {
+				return nil, err
+			}
+			groupVolumes[v.Name] = containerinstance.Volume{Name: to.StringPtr(v.Name), Secret: secret}
+		}
+	}
+	return groupVolumes, nil
+}
+
+// configMapSecret will return the base64 encoded files of a secret volume holding the keys of a ConfigMap, or only
+// the given items
+func configMapSecret(cm ConfigMap, items []KeyToPath, of string, w *warnings) (map[string]*string, error) {
+	files := map[string]string{}
+	for k, v := range cm.Data {
+		files[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	for k, v := range cm.BinaryData {
+		files[k] = v
+	}
+	secret := map[string]*string{}
+	if len(items) == 0 {
+		for k, v := range files {
+			secret[k] = to.StringPtr(v)
+		}
+		return secret, nil
+	}
+	for _, item := range items {
+		v, ok := files[item.Key]
+		if !ok {
+			return nil, errors.Errorf("key %s of %s is not in ConfigMap %s", item.Key, of, cm.Metadata.Name)
+		}
+		if strings.Contains(item.Path, "/") {
+			w.add("%s: path %s of key %s cannot be converted, secret volumes cannot have directories", of, item.Path, item.Key)
+			continue
+		}
+		secret[item.Path] = to.StringPtr(v)
+	}
+	return secret, nil
+}
+
+// containerGroupContainer will translate a container of a pod into a container of a container group, whose
+// environment variables may come from the given ConfigMaps, and whose mounts are of the given volumes
+func containerGroupContainer(c Container, volumes map[string]containerinstance.Volume, configMaps map[string]ConfigMap, w *warnings) (containerinstance.Container, error) {
+	of := "container " + c.Name
+	w.addExtra(of, c.Extra)
+	props := &containerinstance.ContainerProperties{Image: to.StringPtr(c.Image)}
+
+	// container groups only run a command, so the arguments only apply to a given command
+	if len(c.Command) > 0 {
+		command := append(append([]string{}, c.Command...), c.Args...)
+		props.Command = &command
+	} else if len(c.Args) > 0 {
+		w.add("%s: args without a command cannot be converted, and were dropped", of)
+	}
+
+	var env []containerinstance.EnvironmentVariable
+	for _, e := range c.Env {
+		value := e.Value
+		if e.ValueFrom != nil {
+			w.addExtra(of+" env "+e.Name, e.ValueFrom.Extra)
+			ref := e.ValueFrom.ConfigMapKeyRef
+			if ref == nil {
+				continue
+			}
+			cm, ok := configMaps[ref.Name]
+			if !ok {
+				return containerinstance.Container{}, errors.Errorf("ConfigMap %s of env %s of %s was not given", ref.Name, e.Name, of)
+			}
+			if value, ok = cm.Data[ref.Key]; !ok {
+				return containerinstance.Container{}, errors.Errorf("key %s of env %s of %s is not in ConfigMap %s", ref.Key, e.Name, of, ref.Name)
+			}
+		}
+		env = append(env, containerinstance.EnvironmentVariable{Name: to.StringPtr(e.Name), Value: to.StringPtr(value)})
+	}
+	if len(env) > 0 {
+		props.EnvironmentVariables = &env
+	}
+
+	var ports []containerinstance.ContainerPort
+	for _, p := range c.Ports {
+		w.addExtra(of+" port "+p.Name, p.Extra)
+		protocol := strings.ToUpper(p.Protocol)
+		switch protocol {
+		case "":
+			protocol = string(containerinstance.ContainerNetworkProtocolTCP)
+		case string(containerinstance.ContainerNetworkProtocolTCP), string(containerinstance.ContainerNetworkProtocolUDP):
+		default:
+			w.add("%s: port %d of protocol %s cannot be converted, and was dropped", of, p.ContainerPort, p.Protocol)
+			continue
+		}
+		ports = append(ports, containerinstance.ContainerPort{
+			Protocol: containerinstance.ContainerNetworkProtocol(protocol),
+			Port:     to.Int32Ptr(p.ContainerPort),
+		})
+	}
+	if len(ports) > 0 {
+		props.Ports = &ports
+	}
+
+	resources, err := containerGroupResources(c.Resources, of, w)
+	if err != nil {
+		return containerinstance.Container{}, err
+	}
+	props.Resources = resources
+
+	var mounts []containerinstance.VolumeMount
+	for _, m := range c.VolumeMounts {
+		w.addExtra(of+" volume mount "+m.Name, m.Extra)
+		if _, ok := volumes[m.Name]; !ok {
+			w.add("%s: mount of volume %s at %s was dropped along with its volume", of, m.Name, m.MountPath)
+			continue
+		}
+		mount := containerinstance.VolumeMount{Name: to.StringPtr(m.Name), MountPath: to.StringPtr(m.MountPath)}
+		if m.ReadOnly {
+			mount.ReadOnly = to.BoolPtr(true)
+		}
+		mounts = append(mounts, mount)
+	}
+	if len(mounts) > 0 {
+		props.VolumeMounts = &mounts
+	}
+	return containerinstance.Container{Name: to.StringPtr(c.Name), ContainerProperties: props}, nil
+}
+
+// containerGroupResources will translate the cpu and memory of a container, which must request resources, from its
+// limits when it requests none, or the defaults when it has neither
+func containerGroupResources(resources ResourceRequirements, of string, w *warnings) (*containerinstance.ResourceRequirements, error) {
+	requests, limits := resources.Requests, resources.Limits
+	dropped := map[string]bool{}
+	for _, r := range []map[string]string{requests, limits} {
+		for name := range r {
+			if name != "cpu" && name != "memory" {
+				dropped[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(dropped))
+	for name := range dropped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w.add("%s: %s resources cannot be converted, and were dropped", of, name)
+	}
+	cpu, memory := requests["cpu"], requests["memory"]
+	if cpu == "" {
+		cpu = limits["cpu"]
+	}
+	if memory == "" {
+		memory = limits["memory"]
+	}
+	result := &containerinstance.ResourceRequirements{Requests: &containerinstance.ResourceRequests{}}
+	if cpu == "" {
+		w.add("%s: requests %v cpus, as containers of container groups must request cpus", of, defaultCPU)
+		result.Requests.CPU = to.Float64Ptr(defaultCPU)
+	} else {
+		cores, err := cpuCores(cpu)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cpu of %s", of)
+		}
+		result.Requests.CPU = to.Float64Ptr(cores)
+	}
+	if memory == "" {
+		w.add("%s: requests %vGB of memory, as containers of container groups must request memory", of, defaultMemoryGB)
+		result.Requests.MemoryInGB = to.Float64Ptr(defaultMemoryGB)
+	} else {
+		gb, err := memoryGB(memory)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid memory of %s", of)
+		}
+		result.Requests.MemoryInGB = to.Float64Ptr(gb)
+	}
+	if limits["cpu"] == "" && limits["memory"] == "" {
+		return result, nil
+	}
+	result.Limits = &containerinstance.ResourceLimits{}
+	if limits["cpu"] != "" {
+		cpu, err := cpuCores(limits["cpu"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cpu limit of %s", of)
+		}
+		result.Limits.CPU = to.Float64Ptr(cpu)
+	}
+	if limits["memory"] != "" {
+		memory, err := memoryGB(limits["memory"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid memory limit of %s", of)
+		}
+		result.Limits.MemoryInGB = to.Float64Ptr(memory)
+	}
+	return result, nil
+}
+
+func sortedVolumeNames(volumes map[string]containerinstance.Volume) []string {
+	names := make([]string, 0, len(volumes))
+	for name := range volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseContainerGroup will parse the request of a container group from a yaml or json file, in the format of the
+// azure container group yaml files
+func ParseContainerGroup(b []byte) (azure_serverless.CreateContainerRequest, error) {
+	var req azure_serverless.CreateContainerRequest
+	temp := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &temp); err != nil {
+		return req, errors.Wrap(err, "failed to encode containers yaml file into valid map")
+	}
+	b, err := json.Marshal(&temp)
+	if err != nil {
+		return req, errors.Wrap(err, "failed to marshal map back to json")
+	}
+	if err = json.Unmarshal(b, &req); err != nil {
+		return req, errors.Wrap(err, "failed to encode containers yaml file into valid json")
+	}
+	return req, nil
+}
+
+// MarshalContainerGroup will marshal the request of a container group as a yaml file, in the format of the azure
+// container group yaml files
+func MarshalContainerGroup(req azure_serverless.CreateContainerRequest) ([]byte, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal container group")
+	}
+	temp := map[string]interface{}{}
+	if err = json.Unmarshal(b, &temp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal container group")
+	}
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err = enc.Encode(dropNulls(temp)); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal container group yaml")
+	}
+	if err = enc.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal container group yaml")
+	}
+	return []byte(buf.String()), nil
+}
+
+// dropNulls will drop the null values of maps, such as of the volumes which are not secret volumes
+func dropNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if item == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = dropNulls(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = dropNulls(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,440 @@
+package convert
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func readExample(t *testing.T, path string) []byte {
+	t.Helper()
+	b, err := ioutil.ReadFile("../../examples/" + path)
+	if err != nil {
+		t.Fatalf("failed to read example: %v", err)
+	}
+	return b
+}
+
+func groupContainer(t *testing.T, props containerinstance.ContainerGroupProperties, name string) containerinstance.ContainerProperties {
+	t.Helper()
+	if props.Containers != nil {
+		for _, c := range *props.Containers {
+			if to.String(c.Name) == name {
+				return *c.ContainerProperties
+			}
+		}
+	}
+	t.Fatalf("container group has no container %s", name)
+	return containerinstance.ContainerProperties{}
+}
+
+func envValues(env *[]containerinstance.EnvironmentVariable) map[string]string {
+	if env == nil {
+		return nil
+	}
+	values := map[string]string{}
+	for _, e := range *env {
+		values[to.String(e.Name)] = to.String(e.Value)
+	}
+	return values
+}
+
+func TestToContainerGroupExample(t *testing.T) {
+	deployment, configMaps, parseWarnings, err := ParseKubernetes(readExample(t, "google/deployment.yaml"))
+	if err != nil {
+		t.Fatalf("ParseKubernetes() error = %v", err)
+	}
+	if len(parseWarnings) > 0 {
+		t.Errorf("ParseKubernetes() warnings = %q, want none", parseWarnings)
+	}
+	req, warnings, err := ToContainerGroup(deployment, configMaps, AzureOptions{
+		ResourceGroup: "group",
+		Location:      "eastus",
+		IPAddressType: "Public",
+	})
+	if err != nil {
+		t.Fatalf("ToContainerGroup() error = %v", err)
+	}
+	wantWarnings := []string{
+		"container postgres-exporter: ephemeral-storage resources cannot be converted, and were dropped",
+		"container grafana-agent: ephemeral-storage resources cannot be converted, and were dropped",
+	}
+	if !reflect.DeepEqual([]string(warnings), wantWarnings) {
+		t.Errorf("ToContainerGroup() warnings = %q, want %q", warnings, wantWarnings)
+	}
+	if req.ContainerGroupName != "postgres-container-group" || req.ResourceGroupName != "group" || req.Location != "eastus" {
+		t.Errorf("ToContainerGroup() = %s in %s of %s", req.ContainerGroupName, req.ResourceGroupName, req.Location)
+	}
+	if want := map[string]string{"app": "postgres-container-group"}; !reflect.DeepEqual(map[string]string(req.Tags), want) {
+		t.Errorf("ToContainerGroup() tags = %v, want %v", req.Tags, want)
+	}
+	props := req.ContainerGroupProperties
+	if props.OsType != containerinstance.Linux || props.RestartPolicy != "" {
+		t.Errorf("ToContainerGroup() os type = %s, restart policy = %s", props.OsType, props.RestartPolicy)
+	}
+
+	exporter := groupContainer(t, props, "postgres-exporter")
+	if to.String(exporter.Image) != "quay.io/prometheuscommunity/postgres-exporter:latest" || exporter.Command != nil {
+		t.Errorf("postgres-exporter image = %s, command = %v", to.String(exporter.Image), exporter.Command)
+	}
+	if env := envValues(exporter.EnvironmentVariables); !strings.HasPrefix(env["DATA_SOURCE_NAME"], "postgresql://") {
+		t.Errorf("postgres-exporter env = %v", env)
+	}
+	if r := exporter.Resources; to.Float64(r.Requests.CPU) != 0.01 || to.Float64(r.Requests.MemoryInGB) != 0.1 ||
+		to.Float64(r.Limits.CPU) != 0.01 || to.Float64(r.Limits.MemoryInGB) != 0.1 {
+		t.Errorf("postgres-exporter resources = %+v, %+v", *r.Requests, *r.Limits)
+	}
+
+	agent := groupContainer(t, props, "grafana-agent")
+	wantCommand := []string{"/bin/agent", "--config.file=/etc/agent/agent.yaml", "--prometheus.wal-directory=/tmp/data"}
+	if agent.Command == nil || !reflect.DeepEqual(*agent.Command, wantCommand) {
+		t.Errorf("grafana-agent command = %v, want %v", agent.Command, wantCommand)
+	}
+	if r := agent.Resources; to.Float64(r.Requests.CPU) != 0.02 || to.Float64(r.Requests.MemoryInGB) != 0.5 {
+		t.Errorf("grafana-agent requests = %+v", *r.Requests)
+	}
+	if agent.VolumeMounts == nil || len(*agent.VolumeMounts) != 1 ||
+		to.String((*agent.VolumeMounts)[0].Name) != "config-volume" || to.String((*agent.VolumeMounts)[0].MountPath) != "/etc/agent" {
+		t.Errorf("grafana-agent volume mounts = %v", agent.VolumeMounts)
+	}
+
+	if props.Volumes == nil || len(*props.Volumes) != 1 {
+		t.Fatalf("ToContainerGroup() volumes = %v, want the config volume", props.Volumes)
+	}
+	volume := (*props.Volumes)[0]
+	agentYAML := configMaps[0].Data["agent.yaml"]
+	if to.String(volume.Name) != "config-volume" || to.String(volume.Secret["agent.yaml"]) != base64.StdEncoding.EncodeToString([]byte(agentYAML)) {
+		t.Errorf("ToContainerGroup() volume = %s of %v", to.String(volume.Name), volume.Secret)
+	}
+
+	if props.IPAddress == nil || props.IPAddress.Type != containerinstance.Public {
+		t.Fatalf("ToContainerGroup() ip address = %v, want a public one", props.IPAddress)
+	}
+	var ports []int32
+	for _, p := range *props.IPAddress.Ports {
+		if p.Protocol != containerinstance.TCP {
+			t.Errorf("port %d protocol = %s, want TCP", to.Int32(p.Port), p.Protocol)
+		}
+		ports = append(ports, to.Int32(p.Port))
+	}
+	if want := []int32{9187, 8080}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("ToContainerGroup() ports = %v, want %v", ports, want)
+	}
+
+	// the container group file converts back into the deployment, but for its quantities rounded to those of
+	// container groups, and the ip address
+	b, err := MarshalContainerGroup(req)
+	if err != nil {
+		t.Fatalf("MarshalContainerGroup() error = %v", err)
+	}
+	parsed, err := ParseContainerGroup(b)
+	if err != nil {
+		t.Fatalf("ParseContainerGroup() error = %v", err)
+	}
+	back, backConfigMaps, backWarnings, err := ToKubernetes(parsed, KubernetesOptions{Namespace: "dbasvcs"})
+	if err != nil {
+		t.Fatalf("ToKubernetes() error = %v", err)
+	}
+	wantWarnings = []string{
+		"container group postgres-container-group: the Public ip address cannot be converted, expose the deployment with a service",
+	}
+	if !reflect.DeepEqual([]string(backWarnings), wantWarnings) {
+		t.Errorf("ToKubernetes() warnings = %q, want %q", backWarnings, wantWarnings)
+	}
+	if len(backConfigMaps) != 1 || backConfigMaps[0].Data["agent.yaml"] != agentYAML {
+		t.Errorf("ToKubernetes() ConfigMaps = %+v, want the agent config", backConfigMaps)
+	}
+	backPod := back.Spec.Template.Spec
+	wantVolumes := []Volume{{Name: "config-volume", ConfigMap: &ConfigMapVolumeSource{Name: "postgres-container-group-config-volume"}}}
+	if !reflect.DeepEqual(backPod.Volumes, wantVolumes) {
+		t.Errorf("ToKubernetes() volumes = %+v, want %+v", backPod.Volumes, wantVolumes)
+	}
+	if len(backPod.Containers) != 2 {
+		t.Fatalf("ToKubernetes() containers = %+v", backPod.Containers)
+	}
+	pod := deployment.Spec.Template.Spec
+	for i, c := range backPod.Containers {
+		original := pod.Containers[i]
+		if c.Name != original.Name || c.Image != original.Image || !reflect.DeepEqual(c.Command, original.Command) ||
+			!reflect.DeepEqual(c.Env, original.Env) || !reflect.DeepEqual(c.VolumeMounts, original.VolumeMounts) {
+			t.Errorf("ToKubernetes() container = %+v, want %+v", c, original)
+		}
+		if c.Ports[0].ContainerPort != original.Ports[0].ContainerPort {
+			t.Errorf("ToKubernetes() container %s ports = %+v, want %+v", c.Name, c.Ports, original.Ports)
+		}
+	}
+	wantResources := ResourceRequirements{
+		Requests: map[string]string{"cpu": "10m", "memory": "102Mi"},
+		Limits:   map[string]string{"cpu": "10m", "memory": "102Mi"},
+	}
+	if !reflect.DeepEqual(backPod.Containers[0].Resources, wantResources) {
+		t.Errorf("ToKubernetes() resources = %+v, want %+v", backPod.Containers[0].Resources, wantResources)
+	}
+}
+
+func TestToContainerGroupEnv(t *testing.T) {
+	configMaps := []ConfigMap{{Metadata: ObjectMeta{Name: "settings"}, Data: map[string]string{"level": "debug"}}}
+	tests := []struct {
+		name         string
+		env          []EnvVar
+		want         map[string]string
+		wantWarnings []string
+		wantErr      string
+	}{
+		{
+			name: "values",
+			env:  []EnvVar{{Name: "A", Value: "1"}, {Name: "EMPTY"}},
+			want: map[string]string{"A": "1", "EMPTY": ""},
+		},
+		{
+			name: "value from a ConfigMap",
+			env:  []EnvVar{{Name: "LEVEL", ValueFrom: &EnvVarSource{ConfigMapKeyRef: &KeySelector{Name: "settings", Key: "level"}}}},
+			want: map[string]string{"LEVEL": "debug"},
+		},
+		{
+			name: "value from a secret",
+			env: []EnvVar{
+				{Name: "A", Value: "1"},
+				{Name: "PASSWORD", ValueFrom: &EnvVarSource{Extra: map[string]interface{}{
+					"secretKeyRef": map[string]interface{}{"name": "db", "key": "password"},
+				}}},
+			},
+			want:         map[string]string{"A": "1"},
+			wantWarnings: []string{"container c env PASSWORD: secretKeyRef cannot be converted, and was dropped"},
+		},
+		{
+			name:    "ConfigMap not given",
+			env:     []EnvVar{{Name: "LEVEL", ValueFrom: &EnvVarSource{ConfigMapKeyRef: &KeySelector{Name: "other", Key: "level"}}}},
+			wantErr: "ConfigMap other of env LEVEL of container c was not given",
+		},
+		{
+			name:    "key not in the ConfigMap",
+			env:     []EnvVar{{Name: "LEVEL", ValueFrom: &EnvVarSource{ConfigMapKeyRef: &KeySelector{Name: "settings", Key: "verbosity"}}}},
+			wantErr: "key verbosity of env LEVEL of container c is not in ConfigMap settings",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := Deployment{Metadata: ObjectMeta{Name: "d"}}
+			deployment.Spec.Template.Spec.Containers = []Container{{
+				Name:      "c",
+				Env:       tt.env,
+				Resources: ResourceRequirements{Requests: map[string]string{"cpu": "1", "memory": "1Gi"}},
+			}}
+			req, warnings, err := ToContainerGroup(deployment, configMaps, AzureOptions{})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ToContainerGroup() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToContainerGroup() error = %v", err)
+			}
+			if got := envValues(groupContainer(t, req.ContainerGroupProperties, "c").EnvironmentVariables); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToContainerGroup() env = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual([]string(warnings), tt.wantWarnings) {
+				t.Errorf("ToContainerGroup() warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestToContainerGroupVolumes(t *testing.T) {
+	configMaps := []ConfigMap{{
+		Metadata:   ObjectMeta{Name: "files"},
+		Data:       map[string]string{"a.conf": "a", "b.conf": "b"},
+		BinaryData: map[string]string{"c.bin": "AAE="},
+	}}
+	encoded := func(s string) *string {
+		return to.StringPtr(base64.StdEncoding.EncodeToString([]byte(s)))
+	}
+	tests := []struct {
+		name         string
+		volume       Volume
+		want         containerinstance.Volume
+		dropped      bool
+		wantWarnings []string
+		wantErr      string
+	}{
+		{
+			name:   "all keys of a ConfigMap",
+			volume: Volume{Name: "v", ConfigMap: &ConfigMapVolumeSource{Name: "files"}},
+			want: containerinstance.Volume{Name: to.StringPtr("v"), Secret: map[string]*string{
+				"a.conf": encoded("a"), "b.conf": encoded("b"), "c.bin": to.StringPtr("AAE="),
+			}},
+		},
+		{
+			name: "items of a ConfigMap",
+			volume: Volume{Name: "v", ConfigMap: &ConfigMapVolumeSource{Name: "files", Items: []KeyToPath{
+				{Key: "a.conf", Path: "renamed.conf"},
+				{Key: "b.conf", Path: "nested/b.conf"},
+			}}},
+			want:         containerinstance.Volume{Name: to.StringPtr("v"), Secret: map[string]*string{"renamed.conf": encoded("a")}},
+			wantWarnings: []string{"volume v: path nested/b.conf of key b.conf cannot be converted, secret volumes cannot have directories"},
+		},
+		{
+			name: "fields of a ConfigMap volume",
+			volume: Volume{Name: "v", ConfigMap: &ConfigMapVolumeSource{Name: "files", Items: []KeyToPath{{Key: "c.bin", Path: "c.bin"}},
+				Extra: map[string]interface{}{"defaultMode": 0400, "optional": true}}},
+			want: containerinstance.Volume{Name: to.StringPtr("v"), Secret: map[string]*string{"c.bin": to.StringPtr("AAE=")}},
+			wantWarnings: []string{
+				"volume v configMap: defaultMode cannot be converted, and was dropped",
+				"volume v configMap: optional cannot be converted, and was dropped",
+			},
+		},
+		{
+			name:   "empty directory",
+			volume: Volume{Name: "v", EmptyDir: &EmptyDirVolumeSource{Extra: map[string]interface{}{"medium": "Memory"}}},
+			want:   containerinstance.Volume{Name: to.StringPtr("v"), EmptyDir: map[string]interface{}{}},
+			wantWarnings: []string{
+				"volume v emptyDir: medium cannot be converted, and was dropped",
+			},
+		},
+		{
+			name:    "other volumes are dropped",
+			volume:  Volume{Name: "v", Extra: map[string]interface{}{"hostPath": map[string]interface{}{"path": "/data"}}},
+			dropped: true,
+			wantWarnings: []string{
+				"volume v: hostPath cannot be converted, and was dropped",
+				"container c: mount of volume v at /data was dropped along with its volume",
+			},
+		},
+		{
+			name:    "ConfigMap not given",
+			volume:  Volume{Name: "v", ConfigMap: &ConfigMapVolumeSource{Name: "other"}},
+			wantErr: "ConfigMap other of volume v was not given",
+		},
+		{
+			name:    "item not in the ConfigMap",
+			volume:  Volume{Name: "v", ConfigMap: &ConfigMapVolumeSource{Name: "files", Items: []KeyToPath{{Key: "d.conf", Path: "d.conf"}}}},
+			wantErr: "key d.conf of volume v is not in ConfigMap files",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := Deployment{Metadata: ObjectMeta{Name: "d"}}
+			deployment.Spec.Template.Spec = PodSpec{
+				Containers: []Container{{
+					Name:         "c",
+					Resources:    ResourceRequirements{Requests: map[string]string{"cpu": "1", "memory": "1Gi"}},
+					VolumeMounts: []VolumeMount{{Name: "v", MountPath: "/data", ReadOnly: true}},
+				}},
+				Volumes: []Volume{tt.volume},
+			}
+			req, warnings, err := ToContainerGroup(deployment, configMaps, AzureOptions{})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ToContainerGroup() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToContainerGroup() error = %v", err)
+			}
+			if !reflect.DeepEqual([]string(warnings), tt.wantWarnings) {
+				t.Errorf("ToContainerGroup() warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+			props := req.ContainerGroupProperties
+			mounts := groupContainer(t, props, "c").VolumeMounts
+			if tt.dropped {
+				if props.Volumes != nil || mounts != nil {
+					t.Errorf("ToContainerGroup() volumes = %v, mounts = %v, want none", props.Volumes, mounts)
+				}
+				return
+			}
+			if props.Volumes == nil || !reflect.DeepEqual(*props.Volumes, []containerinstance.Volume{tt.want}) {
+				t.Errorf("ToContainerGroup() volumes = %v, want %+v", props.Volumes, tt.want)
+			}
+			wantMounts := []containerinstance.VolumeMount{{Name: to.StringPtr("v"), MountPath: to.StringPtr("/data"), ReadOnly: to.BoolPtr(true)}}
+			if mounts == nil || !reflect.DeepEqual(*mounts, wantMounts) {
+				t.Errorf("ToContainerGroup() mounts = %v, want %v", mounts, wantMounts)
+			}
+		})
+	}
+}
+
+func TestToContainerGroup(t *testing.T) {
+	replicas := int32(3)
+	deployment := Deployment{Metadata: ObjectMeta{Name: "d"}}
+	deployment.Spec = DeploymentSpec{
+		Replicas: &replicas,
+		Extra:    map[string]interface{}{"strategy": map[string]interface{}{}, "minReadySeconds": 5},
+	}
+	deployment.Spec.Template.Spec = PodSpec{
+		RestartPolicy: "OnFailure",
+		Extra:         map[string]interface{}{"nodeSelector": map[string]interface{}{"pool": "a"}},
+		Containers: []Container{
+			{
+				Name:  "defaults",
+				Image: "app",
+				Args:  []string{"--verbose"},
+				Ports: []ContainerPort{{ContainerPort: 53, Protocol: "udp"}, {ContainerPort: 132, Protocol: "SCTP"}},
+			},
+			{
+				Name:      "limited",
+				Image:     "app",
+				Command:   []string{"app"},
+				Args:      []string{"--verbose"},
+				Resources: ResourceRequirements{Limits: map[string]string{"cpu": "1500m", "memory": "2Gi", "nvidia.com/gpu": "1"}},
+				Extra:     map[string]interface{}{"livenessProbe": map[string]interface{}{"exec": "true"}},
+			},
+		},
+	}
+	req, warnings, err := ToContainerGroup(deployment, nil, AzureOptions{Name: "group"})
+	if err != nil {
+		t.Fatalf("ToContainerGroup() error = %v", err)
+	}
+	wantWarnings := []string{
+		"deployment d: 3 replicas cannot be converted, a container group runs a single replica",
+		"deployment d: minReadySeconds cannot be converted, and was dropped",
+		"pod template: nodeSelector cannot be converted, and was dropped",
+		"container defaults: args without a command cannot be converted, and were dropped",
+		"container defaults: port 132 of protocol SCTP cannot be converted, and was dropped",
+		"container defaults: requests 1 cpus, as containers of container groups must request cpus",
+		"container defaults: requests 1.5GB of memory, as containers of container groups must request memory",
+		"container limited: livenessProbe cannot be converted, and was dropped",
+		"container limited: nvidia.com/gpu resources cannot be converted, and were dropped",
+	}
+	if !reflect.DeepEqual([]string(warnings), wantWarnings) {
+		t.Errorf("ToContainerGroup() warnings = %q, want %q", warnings, wantWarnings)
+	}
+	props := req.ContainerGroupProperties
+	if req.ContainerGroupName != "group" || props.RestartPolicy != containerinstance.OnFailure || props.IPAddress != nil {
+		t.Errorf("ToContainerGroup() = %s, restart policy %s, ip address %v", req.ContainerGroupName, props.RestartPolicy, props.IPAddress)
+	}
+
+	defaults := groupContainer(t, props, "defaults")
+	if defaults.Command != nil {
+		t.Errorf("defaults command = %v, want none", *defaults.Command)
+	}
+	wantPorts := []containerinstance.ContainerPort{{Protocol: containerinstance.ContainerNetworkProtocolUDP, Port: to.Int32Ptr(53)}}
+	if defaults.Ports == nil || !reflect.DeepEqual(*defaults.Ports, wantPorts) {
+		t.Errorf("defaults ports = %v, want %v", defaults.Ports, wantPorts)
+	}
+	if r := defaults.Resources; to.Float64(r.Requests.CPU) != defaultCPU || to.Float64(r.Requests.MemoryInGB) != defaultMemoryGB || r.Limits != nil {
+		t.Errorf("defaults resources = %+v, limits %v", *r.Requests, r.Limits)
+	}
+
+	limited := groupContainer(t, props, "limited")
+	if want := []string{"app", "--verbose"}; limited.Command == nil || !reflect.DeepEqual(*limited.Command, want) {
+		t.Errorf("limited command = %v, want %v", limited.Command, want)
+	}
+	if r := limited.Resources; to.Float64(r.Requests.CPU) != 1.5 || to.Float64(r.Requests.MemoryInGB) != 2 ||
+		to.Float64(r.Limits.CPU) != 1.5 || to.Float64(r.Limits.MemoryInGB) != 2 {
+		t.Errorf("limited resources = %+v, %+v", *r.Requests, *r.Limits)
+	}
+
+	deployment.Spec.Template.Spec.Containers[0].Resources.Requests = map[string]string{"cpu": "lots"}
+	if _, _, err = ToContainerGroup(deployment, nil, AzureOptions{}); err == nil || err.Error() != `invalid cpu of container defaults: invalid quantity "lots"` {
+		t.Errorf("ToContainerGroup() error = %v, want the invalid cpu", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Package convert translates between a kubernetes Deployment, along with the ConfigMaps of its volumes, and an
+// azure container group, warning about the fields which cannot be translated
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AzureOptions are the fields of a container group which a Deployment does not hold
+type AzureOptions struct {
+	// Name is the name of the container group, that of the Deployment when empty
+	Name          string
+	ResourceGroup string
+	Location      string
+	// IPAddressType exposes the ports of the containers on a Public or Private ip address, or none when empty
+	IPAddressType string
+}
+
+// KubernetesOptions are the fields of a Deployment which a container group does not hold
+type KubernetesOptions struct {
+	// Name is the name of the Deployment, that of the container group when empty
+	Name      string
+	Namespace string
+}
+
+// warnings are the fields which could not be translated
+type warnings []string
+
+func (w *warnings) add(format string, args ...interface{}) {
+	*w = append(*w, fmt.Sprintf(format, args...))
+}
+
+// addExtra will warn about the unconvertible fields of extra, which are not empty, in order
+func (w *warnings) addExtra(of string, extra map[string]interface{}) {
+	var fields []string
+	for field, value := range extra {
+		if !isEmpty(value) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		w.add("%s: %s cannot be converted, and was dropped", of, field)
+	}
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// dnsLabel will return a name as a lowercase dns label, as are the names of kubernetes objects and containers
+func dnsLabel(name string) string {
+	label := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-':
+			return r
+		}
+		return '-'
+	}, name)
+	if len(label) > 63 {
+		label = label[:63]
+	}
+	return strings.Trim(label, "-")
+}
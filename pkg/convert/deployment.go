@@ -0,0 +1,231 @@
+package convert
+
+import (
+	"encoding/base64"
+	"unicode/utf8"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+
+	azure_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/azure"
+)
+
+// labelApp is the label selecting the pods of converted Deployments
+const labelApp = "app"
+
+// ToKubernetes will translate the request of a container group into a Deployment, and the ConfigMaps of its secret
+// volumes, returning warnings about the fields which could not be translated.  The tags of the group become the
+// annotations of the Deployment, as their values, such as the created-at time, are not always valid label values.
+func ToKubernetes(req azure_serverless.CreateContainerRequest, opts KubernetesOptions) (Deployment, []ConfigMap, []string, error) {
+	var w warnings
+	name := opts.Name
+	if name == "" {
+		name = dnsLabel(req.ContainerGroupName)
+		if name != req.ContainerGroupName {
+			w.add("container group %s: named %s, as kubernetes names are lowercase dns labels", req.ContainerGroupName, name)
+		}
+	}
+	if name == "" {
+		return Deployment{}, nil, nil, errors.New("the name of the container group cannot be empty")
+	}
+	labels := map[string]string{labelApp: name}
+	deployment := Deployment{
+		APIVersion: "apps/v1",
+		Kind:       kindDeployment,
+		Metadata: ObjectMeta{
+			Name:      name,
+			Namespace: opts.Namespace,
+			Labels:    labels,
+		},
+		Spec: DeploymentSpec{
+			Replicas: to.Int32Ptr(1),
+			Selector: &LabelSelector{MatchLabels: labels},
+			Template: PodTemplateSpec{Metadata: ObjectMeta{Labels: labels}},
+		},
+	}
+	if len(req.Tags) > 0 {
+		deployment.Metadata.Annotations = map[string]string(req.Tags)
+	}
+
+	props := req.ContainerGroupProperties
+	of := "container group " + req.ContainerGroupName
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"imageRegistryCredentials", props.ImageRegistryCredentials != nil},
+		{"diagnostics", props.Diagnostics != nil},
+		{"networkProfile", props.NetworkProfile != nil},
+		{"dnsConfig", props.DNSConfig != nil},
+		{"encryptionProperties", props.EncryptionProperties != nil},
+		{"initContainers", props.InitContainers != nil},
+		{"sku", props.Sku != ""},
+	} {
+		if field.set {
+			w.add("%s: %s cannot be converted, and was dropped", of, field.name)
+		}
+	}
+	if props.IPAddress != nil {
+		w.add("%s: the %s ip address cannot be converted, expose the deployment with a service", of, props.IPAddress.Type)
+	}
+	if props.OsType == containerinstance.Windows {
+		w.add("%s: windows containers require a nodeSelector of the windows nodes of the cluster", of)
+	}
+	if props.RestartPolicy != "" && props.RestartPolicy != containerinstance.Always {
+		w.add("%s: restart policy %s cannot be converted, the pods of deployments always restart", of, props.RestartPolicy)
+	}
+
+	pod := &deployment.Spec.Template.Spec
+	var configMaps []ConfigMap
+	volumes := map[string]bool{}
+	if props.Volumes != nil {
+		for _, v := range *props.Volumes {
+			volumeName := to.String(v.Name)
+			volume := Volume{Name: volumeName}
+			switch {
+			case v.Secret != nil:
+				cm, err := secretConfigMap(name+"-"+volumeName, opts.Namespace, v.Secret)
+				if err != nil {
+					return deployment, nil, nil, errors.Wrapf(err, "invalid secret volume %s", volumeName)
+				}
+				configMaps = append(configMaps, cm)
+				volume.ConfigMap = &ConfigMapVolumeSource{Name: cm.Metadata.Name}
+			case v.EmptyDir != nil:
+				volume.EmptyDir = &EmptyDirVolumeSource{}
+			default:
+				w.add("%s: volume %s cannot be converted, only secret and emptyDir volumes can, and was dropped", of, volumeName)
+				continue
+			}
+			volumes[volumeName] = true
+			pod.Volumes = append(pod.Volumes, volume)
+		}
+	}
+	if props.Containers != nil {
+		for _, c := range *props.Containers {
+			pod.Containers = append(pod.Containers, kubernetesContainer(c, volumes, &w))
+		}
+	}
+	return deployment, configMaps, w, nil
+}
+
+// secretConfigMap will return a ConfigMap of the base64 encoded files of a secret volume, whose files which are not
+// text are binary data
+func secretConfigMap(name, namespace string, secret map[string]*string) (ConfigMap, error) {
+	cm := ConfigMap{
+		APIVersion: "v1",
+		Kind:       kindConfigMap,
+		Metadata:   ObjectMeta{Name: name, Namespace: namespace},
+	}
+	for file, value := range secret {
+		b, err := base64.StdEncoding.DecodeString(to.String(value))
+		if err != nil {
+			return cm, errors.Wrapf(err, "file %s is not base64 encoded", file)
+		}
+		if !utf8.Valid(b) {
+			if cm.BinaryData == nil {
+				cm.BinaryData = map[string]string{}
+			}
+			cm.BinaryData[file] = to.String(value)
+			continue
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[file] = string(b)
+	}
+	return cm, nil
+}
+
+// kubernetesContainer will translate a container of a container group into a container of a pod, whose mounts are
+// of the given volumes
+func kubernetesContainer(c containerinstance.Container, volumes map[string]bool, w *warnings) Container {
+	container := Container{Name: dnsLabel(to.String(c.Name))}
+	of := "container " + to.String(c.Name)
+	if container.Name != to.String(c.Name) {
+		w.add("%s: named %s, as kubernetes names are lowercase dns labels", of, container.Name)
+	}
+	props := c.ContainerProperties
+	if props == nil {
+		return container
+	}
+	container.Image = to.String(props.Image)
+	if props.Command != nil {
+		container.Command = *props.Command
+	}
+	if props.EnvironmentVariables != nil {
+		for _, e := range *props.EnvironmentVariables {
+			value := to.String(e.Value)
+			if e.SecureValue != nil {
+				w.add("%s: secure env %s is written in plain text, move it to a secret", of, to.String(e.Name))
+				value = to.String(e.SecureValue)
+			}
+			container.Env = append(container.Env, EnvVar{Name: to.String(e.Name), Value: value})
+		}
+	}
+	if props.Ports != nil {
+		for _, p := range *props.Ports {
+			port := ContainerPort{ContainerPort: to.Int32(p.Port)}
+			if p.Protocol == containerinstance.ContainerNetworkProtocolUDP {
+				port.Protocol = string(p.Protocol)
+			}
+			container.Ports = append(container.Ports, port)
+		}
+	}
+	if props.Resources != nil {
+		container.Resources = kubernetesResources(*props.Resources, of, w)
+	}
+	if props.VolumeMounts != nil {
+		for _, m := range *props.VolumeMounts {
+			if !volumes[to.String(m.Name)] {
+				w.add("%s: mount of volume %s at %s was dropped along with its volume", of, to.String(m.Name), to.String(m.MountPath))
+				continue
+			}
+			container.VolumeMounts = append(container.VolumeMounts, VolumeMount{
+				Name:      to.String(m.Name),
+				MountPath: to.String(m.MountPath),
+				ReadOnly:  to.Bool(m.ReadOnly),
+			})
+		}
+	}
+	if props.LivenessProbe != nil {
+		w.add("%s: livenessProbe cannot be converted, and was dropped", of)
+	}
+	if props.ReadinessProbe != nil {
+		w.add("%s: readinessProbe cannot be converted, and was dropped", of)
+	}
+	return container
+}
+
+// kubernetesResources will translate the cpu and memory requested by a container of a container group, and
+// limiting it
+func kubernetesResources(resources containerinstance.ResourceRequirements, of string, w *warnings) ResourceRequirements {
+	var result ResourceRequirements
+	if r := resources.Requests; r != nil {
+		result.Requests = quantities(r.CPU, r.MemoryInGB)
+		if r.Gpu != nil {
+			w.add("%s: gpu requests cannot be converted, and were dropped", of)
+		}
+	}
+	if l := resources.Limits; l != nil {
+		result.Limits = quantities(l.CPU, l.MemoryInGB)
+		if l.Gpu != nil {
+			w.add("%s: gpu limits cannot be converted, and were dropped", of)
+		}
+	}
+	return result
+}
+
+func quantities(cpu, memoryInGB *float64) map[string]string {
+	q := map[string]string{}
+	if cpu != nil {
+		q["cpu"] = cpuQuantity(*cpu)
+	}
+	if memoryInGB != nil {
+		q["memory"] = memoryQuantity(*memoryInGB)
+	}
+	if len(q) == 0 {
+		return nil
+	}
+	return q
+}
@@ -0,0 +1,245 @@
+package convert
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	azure_serverless "github.com/naemono/go-cloud-actions/pkg/serverless/azure"
+)
+
+func TestToKubernetesExample(t *testing.T) {
+	req, err := ParseContainerGroup(readExample(t, "azure/container_instances/containers.yaml"))
+	if err != nil {
+		t.Fatalf("ParseContainerGroup() error = %v", err)
+	}
+	deployment, configMaps, warnings, err := ToKubernetes(req, KubernetesOptions{Namespace: "monitoring"})
+	if err != nil {
+		t.Fatalf("ToKubernetes() error = %v", err)
+	}
+	wantWarnings := []string{
+		"container group PostgresContainerGroup: named postgrescontainergroup, as kubernetes names are lowercase dns labels",
+		"container group PostgresContainerGroup: networkProfile cannot be converted, and was dropped",
+		"container group PostgresContainerGroup: the Private ip address cannot be converted, expose the deployment with a service",
+	}
+	if !reflect.DeepEqual([]string(warnings), wantWarnings) {
+		t.Errorf("ToKubernetes() warnings = %q, want %q", warnings, wantWarnings)
+	}
+	if configMaps != nil {
+		t.Errorf("ToKubernetes() ConfigMaps = %+v, want none", configMaps)
+	}
+	labels := map[string]string{"app": "postgrescontainergroup"}
+	wantMetadata := ObjectMeta{
+		Name:        "postgrescontainergroup",
+		Namespace:   "monitoring",
+		Labels:      labels,
+		Annotations: map[string]string{"exampleTag": "tutorial"},
+	}
+	if !reflect.DeepEqual(deployment.Metadata, wantMetadata) {
+		t.Errorf("ToKubernetes() metadata = %+v, want %+v", deployment.Metadata, wantMetadata)
+	}
+	if to.Int32(deployment.Spec.Replicas) != 1 || !reflect.DeepEqual(deployment.Spec.Selector.MatchLabels, labels) ||
+		!reflect.DeepEqual(deployment.Spec.Template.Metadata.Labels, labels) {
+		t.Errorf("ToKubernetes() spec = %+v", deployment.Spec)
+	}
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) != 2 {
+		t.Fatalf("ToKubernetes() containers = %+v, want 2", containers)
+	}
+	requests := ResourceRequirements{Requests: map[string]string{"cpu": "1", "memory": "1536Mi"}}
+	exporter := containers[0]
+	if exporter.Name != "pgexporter" || exporter.Image != "quay.io/prometheuscommunity/postgres-exporter:latest" ||
+		exporter.Command != nil || len(exporter.Env) != 1 || exporter.Env[0].Name != "DATA_SOURCE_NAME" ||
+		!reflect.DeepEqual(exporter.Ports, []ContainerPort{{ContainerPort: 9187}}) || !reflect.DeepEqual(exporter.Resources, requests) {
+		t.Errorf("ToKubernetes() pgexporter = %+v", exporter)
+	}
+	agent := containers[1]
+	if agent.Name != "grafana-agent" || len(agent.Command) != 3 || agent.Command[0] != "/bin/bash" ||
+		len(agent.Env) != 1 || agent.Env[0].Name != "CONFIG" || !strings.HasPrefix(agent.Env[0].Value, "c2VydmVy") ||
+		!reflect.DeepEqual(agent.Ports, []ContainerPort{{ContainerPort: 8080}}) || !reflect.DeepEqual(agent.Resources, requests) {
+		t.Errorf("ToKubernetes() grafana-agent = %+v", agent)
+	}
+
+	// the deployment file converts back into the container group, but for the fields warned about
+	b, err := MarshalKubernetes(deployment, configMaps)
+	if err != nil {
+		t.Fatalf("MarshalKubernetes() error = %v", err)
+	}
+	parsed, parsedConfigMaps, parseWarnings, err := ParseKubernetes(b)
+	if err != nil {
+		t.Fatalf("ParseKubernetes() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed, deployment) || len(parseWarnings) > 0 {
+		t.Errorf("ParseKubernetes() = %+v, warnings %q, want %+v", parsed, parseWarnings, deployment)
+	}
+	back, backWarnings, err := ToContainerGroup(parsed, parsedConfigMaps, AzureOptions{
+		Name:          req.ContainerGroupName,
+		ResourceGroup: req.ResourceGroupName,
+		Location:      req.Location,
+		IPAddressType: "Private",
+	})
+	if err != nil {
+		t.Fatalf("ToContainerGroup() error = %v", err)
+	}
+	if len(backWarnings) > 0 {
+		t.Errorf("ToContainerGroup() warnings = %q, want none", backWarnings)
+	}
+	props, backProps := req.ContainerGroupProperties, back.ContainerGroupProperties
+	if back.ContainerGroupName != req.ContainerGroupName || back.Location != req.Location || backProps.OsType != props.OsType {
+		t.Errorf("ToContainerGroup() = %s in %s of %s", back.ContainerGroupName, back.Location, backProps.OsType)
+	}
+	// the ports of the containers are converted back with their default protocol
+	for _, c := range *props.Containers {
+		for i := range *c.Ports {
+			if (*c.Ports)[i].Protocol == "" {
+				(*c.Ports)[i].Protocol = containerinstance.ContainerNetworkProtocolTCP
+			}
+		}
+	}
+	if !reflect.DeepEqual(*backProps.Containers, *props.Containers) {
+		t.Errorf("ToContainerGroup() containers = %+v, want %+v", *backProps.Containers, *props.Containers)
+	}
+	ports := func(ip *containerinstance.IPAddress) map[int32]string {
+		result := map[int32]string{}
+		for _, p := range *ip.Ports {
+			result[to.Int32(p.Port)] = strings.ToUpper(string(p.Protocol))
+		}
+		return result
+	}
+	if got, want := ports(backProps.IPAddress), ports(props.IPAddress); backProps.IPAddress.Type != props.IPAddress.Type || !reflect.DeepEqual(got, want) {
+		t.Errorf("ToContainerGroup() %s ip address ports = %v, want %v", backProps.IPAddress.Type, got, want)
+	}
+}
+
+func TestToKubernetes(t *testing.T) {
+	binary := base64.StdEncoding.EncodeToString([]byte{0xff, 0x00})
+	req := azure_serverless.CreateContainerRequest{
+		ContainerGroupName: "group",
+		ContainerGroupProperties: containerinstance.ContainerGroupProperties{
+			OsType:        containerinstance.Windows,
+			RestartPolicy: containerinstance.Never,
+			Volumes: &[]containerinstance.Volume{
+				{Name: to.StringPtr("files"), Secret: map[string]*string{
+					"a.conf": to.StringPtr(base64.StdEncoding.EncodeToString([]byte("a"))),
+					"b.bin":  to.StringPtr(binary),
+				}},
+				{Name: to.StringPtr("scratch"), EmptyDir: map[string]interface{}{}},
+				{Name: to.StringPtr("share"), AzureFile: &containerinstance.AzureFileVolume{ShareName: to.StringPtr("s")}},
+			},
+			Containers: &[]containerinstance.Container{{
+				Name: to.StringPtr("App_1"),
+				ContainerProperties: &containerinstance.ContainerProperties{
+					Image: to.StringPtr("app"),
+					EnvironmentVariables: &[]containerinstance.EnvironmentVariable{
+						{Name: to.StringPtr("A"), Value: to.StringPtr("1")},
+						{Name: to.StringPtr("PASSWORD"), SecureValue: to.StringPtr("secret")},
+					},
+					Ports: &[]containerinstance.ContainerPort{
+						{Port: to.Int32Ptr(53), Protocol: containerinstance.ContainerNetworkProtocolUDP},
+						{Port: to.Int32Ptr(80), Protocol: containerinstance.ContainerNetworkProtocolTCP},
+					},
+					Resources: &containerinstance.ResourceRequirements{
+						Requests: &containerinstance.ResourceRequests{CPU: to.Float64Ptr(0.5), MemoryInGB: to.Float64Ptr(1)},
+						Limits: &containerinstance.ResourceLimits{CPU: to.Float64Ptr(2),
+							Gpu: &containerinstance.GpuResource{Count: to.Int32Ptr(1), Sku: containerinstance.K80}},
+					},
+					VolumeMounts: &[]containerinstance.VolumeMount{
+						{Name: to.StringPtr("files"), MountPath: to.StringPtr("/etc/app"), ReadOnly: to.BoolPtr(true)},
+						{Name: to.StringPtr("scratch"), MountPath: to.StringPtr("/tmp")},
+						{Name: to.StringPtr("share"), MountPath: to.StringPtr("/share")},
+					},
+					LivenessProbe: &containerinstance.ContainerProbe{},
+				},
+			}},
+		},
+	}
+	deployment, configMaps, warnings, err := ToKubernetes(req, KubernetesOptions{Name: "app", Namespace: "ns"})
+	if err != nil {
+		t.Fatalf("ToKubernetes() error = %v", err)
+	}
+	wantWarnings := []string{
+		"container group group: windows containers require a nodeSelector of the windows nodes of the cluster",
+		"container group group: restart policy Never cannot be converted, the pods of deployments always restart",
+		"container group group: volume share cannot be converted, only secret and emptyDir volumes can, and was dropped",
+		"container App_1: named app-1, as kubernetes names are lowercase dns labels",
+		"container App_1: secure env PASSWORD is written in plain text, move it to a secret",
+		"container App_1: gpu limits cannot be converted, and were dropped",
+		"container App_1: mount of volume share at /share was dropped along with its volume",
+		"container App_1: livenessProbe cannot be converted, and was dropped",
+	}
+	if !reflect.DeepEqual([]string(warnings), wantWarnings) {
+		t.Errorf("ToKubernetes() warnings = %q, want %q", warnings, wantWarnings)
+	}
+	wantConfigMaps := []ConfigMap{{
+		APIVersion: "v1",
+		Kind:       kindConfigMap,
+		Metadata:   ObjectMeta{Name: "app-files", Namespace: "ns"},
+		Data:       map[string]string{"a.conf": "a"},
+		BinaryData: map[string]string{"b.bin": binary},
+	}}
+	if !reflect.DeepEqual(configMaps, wantConfigMaps) {
+		t.Errorf("ToKubernetes() ConfigMaps = %+v, want %+v", configMaps, wantConfigMaps)
+	}
+	pod := deployment.Spec.Template.Spec
+	wantVolumes := []Volume{
+		{Name: "files", ConfigMap: &ConfigMapVolumeSource{Name: "app-files"}},
+		{Name: "scratch", EmptyDir: &EmptyDirVolumeSource{}},
+	}
+	if !reflect.DeepEqual(pod.Volumes, wantVolumes) {
+		t.Errorf("ToKubernetes() volumes = %+v, want %+v", pod.Volumes, wantVolumes)
+	}
+	wantContainers := []Container{{
+		Name:  "app-1",
+		Image: "app",
+		Env:   []EnvVar{{Name: "A", Value: "1"}, {Name: "PASSWORD", Value: "secret"}},
+		Ports: []ContainerPort{{ContainerPort: 53, Protocol: "UDP"}, {ContainerPort: 80}},
+		Resources: ResourceRequirements{
+			Requests: map[string]string{"cpu": "500m", "memory": "1Gi"},
+			Limits:   map[string]string{"cpu": "2"},
+		},
+		VolumeMounts: []VolumeMount{
+			{Name: "files", MountPath: "/etc/app", ReadOnly: true},
+			{Name: "scratch", MountPath: "/tmp"},
+		},
+	}}
+	if !reflect.DeepEqual(pod.Containers, wantContainers) {
+		t.Errorf("ToKubernetes() containers = %+v, want %+v", pod.Containers, wantContainers)
+	}
+}
+
+func TestToKubernetesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     azure_serverless.CreateContainerRequest
+		wantErr string
+	}{
+		{
+			name:    "no name",
+			req:     azure_serverless.CreateContainerRequest{ContainerGroupName: "--"},
+			wantErr: "the name of the container group cannot be empty",
+		},
+		{
+			name: "secret file not base64 encoded",
+			req: azure_serverless.CreateContainerRequest{
+				ContainerGroupName: "group",
+				ContainerGroupProperties: containerinstance.ContainerGroupProperties{
+					Volumes: &[]containerinstance.Volume{{Name: to.StringPtr("files"), Secret: map[string]*string{"a.conf": to.StringPtr("not base64!")}}},
+				},
+			},
+			wantErr: "invalid secret volume files: file a.conf is not base64 encoded",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := ToKubernetes(tt.req, KubernetesOptions{})
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("ToKubernetes() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
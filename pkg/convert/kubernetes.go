@@ -0,0 +1,216 @@
+package convert
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	kindDeployment = "Deployment"
+	kindConfigMap  = "ConfigMap"
+)
+
+// Deployment is a kubernetes apps/v1 Deployment, of the fields which can be converted.  The Extra fields of its
+// parts hold the fields which cannot be converted, which are warned about.
+type Deployment struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   ObjectMeta     `yaml:"metadata"`
+	Spec       DeploymentSpec `yaml:"spec"`
+}
+
+// ObjectMeta is the metadata of a kubernetes object
+type ObjectMeta struct {
+	Name        string            `yaml:"name,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// DeploymentSpec is the spec of a Deployment
+type DeploymentSpec struct {
+	Replicas *int32                 `yaml:"replicas,omitempty"`
+	Selector *LabelSelector         `yaml:"selector,omitempty"`
+	Template PodTemplateSpec        `yaml:"template"`
+	Extra    map[string]interface{} `yaml:",inline"`
+}
+
+// LabelSelector selects the pods of a Deployment by their labels
+type LabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels,omitempty"`
+}
+
+// PodTemplateSpec is the template of the pods of a Deployment
+type PodTemplateSpec struct {
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     PodSpec    `yaml:"spec"`
+}
+
+// PodSpec is the spec of a pod
+type PodSpec struct {
+	Containers    []Container            `yaml:"containers"`
+	Volumes       []Volume               `yaml:"volumes,omitempty"`
+	RestartPolicy string                 `yaml:"restartPolicy,omitempty"`
+	Extra         map[string]interface{} `yaml:",inline"`
+}
+
+// Container is a container of a pod
+type Container struct {
+	Name         string                 `yaml:"name"`
+	Image        string                 `yaml:"image"`
+	Command      []string               `yaml:"command,omitempty"`
+	Args         []string               `yaml:"args,omitempty"`
+	Env          []EnvVar               `yaml:"env,omitempty"`
+	Ports        []ContainerPort        `yaml:"ports,omitempty"`
+	Resources    ResourceRequirements   `yaml:"resources,omitempty"`
+	VolumeMounts []VolumeMount          `yaml:"volumeMounts,omitempty"`
+	Extra        map[string]interface{} `yaml:",inline"`
+}
+
+// EnvVar is an environment variable of a container, with a value, or a value from a ConfigMap
+type EnvVar struct {
+	Name      string        `yaml:"name"`
+	Value     string        `yaml:"value,omitempty"`
+	ValueFrom *EnvVarSource `yaml:"valueFrom,omitempty"`
+}
+
+// EnvVarSource is the source of the value of an environment variable
+type EnvVarSource struct {
+	ConfigMapKeyRef *KeySelector           `yaml:"configMapKeyRef,omitempty"`
+	Extra           map[string]interface{} `yaml:",inline"`
+}
+
+// KeySelector selects a key of a ConfigMap
+type KeySelector struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// ContainerPort is a port exposed by a container
+type ContainerPort struct {
+	Name          string                 `yaml:"name,omitempty"`
+	ContainerPort int32                  `yaml:"containerPort"`
+	Protocol      string                 `yaml:"protocol,omitempty"`
+	Extra         map[string]interface{} `yaml:",inline"`
+}
+
+// ResourceRequirements are the resource quantities, such as 100m or 512Mi, requested by a container and limiting it
+type ResourceRequirements struct {
+	Limits   map[string]string `yaml:"limits,omitempty"`
+	Requests map[string]string `yaml:"requests,omitempty"`
+}
+
+// VolumeMount mounts a volume of a pod into a container
+type VolumeMount struct {
+	Name      string                 `yaml:"name"`
+	MountPath string                 `yaml:"mountPath"`
+	ReadOnly  bool                   `yaml:"readOnly,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+// Volume is a volume of a pod, either of the files of a ConfigMap, or an empty directory
+type Volume struct {
+	Name      string                 `yaml:"name"`
+	ConfigMap *ConfigMapVolumeSource `yaml:"configMap,omitempty"`
+	EmptyDir  *EmptyDirVolumeSource  `yaml:"emptyDir,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+// ConfigMapVolumeSource is a volume of the keys of a ConfigMap, or only of its items when given
+type ConfigMapVolumeSource struct {
+	Name  string                 `yaml:"name"`
+	Items []KeyToPath            `yaml:"items,omitempty"`
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// KeyToPath is the file path of a key of a ConfigMap volume
+type KeyToPath struct {
+	Key  string `yaml:"key"`
+	Path string `yaml:"path"`
+}
+
+// EmptyDirVolumeSource is an empty directory volume
+type EmptyDirVolumeSource struct {
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// ConfigMap is a kubernetes v1 ConfigMap
+type ConfigMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	// BinaryData holds base64 encoded values
+	BinaryData map[string]string `yaml:"binaryData,omitempty"`
+}
+
+// ParseKubernetes will parse the single Deployment, and the ConfigMaps, of multiple yaml documents.  Objects of
+// other kinds are warned about.
+func ParseKubernetes(b []byte) (Deployment, []ConfigMap, []string, error) {
+	var (
+		deployment  Deployment
+		deployments int
+		configMaps  []ConfigMap
+		w           warnings
+	)
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return deployment, nil, nil, errors.Wrap(err, "failed to parse kubernetes yaml")
+		}
+		var object struct {
+			Kind     string     `yaml:"kind"`
+			Metadata ObjectMeta `yaml:"metadata"`
+		}
+		if err = doc.Decode(&object); err != nil {
+			return deployment, nil, nil, errors.Wrap(err, "failed to parse kubernetes object")
+		}
+		switch object.Kind {
+		case kindDeployment:
+			deployments++
+			err = doc.Decode(&deployment)
+		case kindConfigMap:
+			var cm ConfigMap
+			err = doc.Decode(&cm)
+			configMaps = append(configMaps, cm)
+		case "":
+			w.add("a document without a kind was ignored")
+		default:
+			w.add("%s %s was ignored, only a Deployment and its ConfigMaps can be converted", object.Kind, object.Metadata.Name)
+		}
+		if err != nil {
+			return deployment, nil, nil, errors.Wrapf(err, "failed to parse %s %s", object.Kind, object.Metadata.Name)
+		}
+	}
+	if deployments != 1 {
+		return deployment, nil, nil, errors.Errorf("found %d deployments, exactly one must be given", deployments)
+	}
+	return deployment, configMaps, w, nil
+}
+
+// MarshalKubernetes will marshal ConfigMaps, followed by the Deployment using them, as yaml documents
+func MarshalKubernetes(deployment Deployment, configMaps []ConfigMap) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, cm := range configMaps {
+		if err := enc.Encode(cm); err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal ConfigMap %s", cm.Metadata.Name)
+		}
+	}
+	if err := enc.Encode(deployment); err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal Deployment %s", deployment.Metadata.Name)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal kubernetes yaml")
+	}
+	return buf.Bytes(), nil
+}
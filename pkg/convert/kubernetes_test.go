@@ -0,0 +1,58 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKubernetes(t *testing.T) {
+	tests := []struct {
+		name           string
+		yaml           string
+		wantConfigMaps int
+		wantWarnings   []string
+		wantErr        string
+	}{
+		{
+			name:         "other kinds are ignored",
+			yaml:         "kind: Service\nmetadata:\n  name: web\n---\nname: nothing\n---\nkind: Deployment\nmetadata:\n  name: web\n",
+			wantWarnings: []string{"Service web was ignored, only a Deployment and its ConfigMaps can be converted", "a document without a kind was ignored"},
+		},
+		{
+			name:           "ConfigMaps",
+			yaml:           "kind: ConfigMap\nmetadata:\n  name: a\n---\nkind: Deployment\nmetadata:\n  name: web\n---\nkind: ConfigMap\nmetadata:\n  name: b\n",
+			wantConfigMaps: 2,
+		},
+		{
+			name:    "no deployment",
+			yaml:    "kind: ConfigMap\nmetadata:\n  name: a\n",
+			wantErr: "found 0 deployments, exactly one must be given",
+		},
+		{
+			name:    "two deployments",
+			yaml:    "kind: Deployment\nmetadata:\n  name: a\n---\nkind: Deployment\nmetadata:\n  name: b\n",
+			wantErr: "found 2 deployments, exactly one must be given",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, configMaps, warnings, err := ParseKubernetes([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ParseKubernetes() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKubernetes() error = %v", err)
+			}
+			if deployment.Metadata.Name != "web" || len(configMaps) != tt.wantConfigMaps {
+				t.Errorf("ParseKubernetes() = %s with %d ConfigMaps, want web with %d", deployment.Metadata.Name, len(configMaps), tt.wantConfigMaps)
+			}
+			if !reflect.DeepEqual([]string(warnings), tt.wantWarnings) {
+				t.Errorf("ParseKubernetes() warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
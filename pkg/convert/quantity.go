@@ -0,0 +1,73 @@
+package convert
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// gib is the number of bytes of the GB of container group memory
+const gib = 1 << 30
+
+// suffixes are the multipliers of the suffixes of kubernetes quantities, the binary ones first as they share the
+// prefix of the decimal ones
+var suffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50}, {"Ei", 1 << 60},
+	{"m", 1e-3}, {"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15}, {"E", 1e18},
+}
+
+// parseQuantity will parse a kubernetes quantity, such as 100m, 1.5 or 512Mi
+func parseQuantity(q string) (float64, error) {
+	multiplier := 1.0
+	number := strings.TrimSpace(q)
+	for _, s := range suffixes {
+		if strings.HasSuffix(number, s.suffix) {
+			number, multiplier = strings.TrimSuffix(number, s.suffix), s.multiplier
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, errors.Errorf("invalid quantity %q", q)
+	}
+	return value * multiplier, nil
+}
+
+// cpuCores will return a cpu quantity as the cores of a container group, rounded up to hundredths of a core
+func cpuCores(q string) (float64, error) {
+	cores, err := parseQuantity(q)
+	if err != nil {
+		return 0, err
+	}
+	return math.Ceil(cores*100) / 100, nil
+}
+
+// memoryGB will return a memory quantity as the GB of a container group, rounded up to tenths of a GB
+func memoryGB(q string) (float64, error) {
+	bytes, err := parseQuantity(q)
+	if err != nil {
+		return 0, err
+	}
+	return math.Ceil(bytes/gib*10) / 10, nil
+}
+
+// cpuQuantity will return the cores of a container group as a kubernetes cpu quantity, in millicores unless whole
+func cpuQuantity(cores float64) string {
+	if cores == math.Trunc(cores) {
+		return strconv.FormatFloat(cores, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(math.Round(cores*1000), 'f', -1, 64) + "m"
+}
+
+// memoryQuantity will return the GB of a container group as a kubernetes memory quantity, in Mi unless whole
+func memoryQuantity(gb float64) string {
+	if gb == math.Trunc(gb) {
+		return strconv.FormatFloat(gb, 'f', -1, 64) + "Gi"
+	}
+	return strconv.FormatFloat(math.Round(gb*1024), 'f', -1, 64) + "Mi"
+}
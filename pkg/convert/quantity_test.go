@@ -0,0 +1,145 @@
+package convert
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		q       string
+		want    float64
+		wantErr bool
+	}{
+		{q: "1", want: 1},
+		{q: "1.5", want: 1.5},
+		{q: " 2 ", want: 2},
+		{q: "100m", want: 0.1},
+		{q: "2k", want: 2000},
+		{q: "1G", want: 1e9},
+		{q: "2Ki", want: 2048},
+		{q: "512Mi", want: 512 << 20},
+		{q: "1.5Gi", want: 1.5 * (1 << 30)},
+		{q: "", wantErr: true},
+		{q: "Mi", wantErr: true},
+		{q: "-1", wantErr: true},
+		{q: "1Xi", wantErr: true},
+		{q: "one", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.q, func(t *testing.T) {
+			got, err := parseQuantity(tt.q)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseQuantity(%q) = %v, want an error", tt.q, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseQuantity(%q) error = %v", tt.q, err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("parseQuantity(%q) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCPUCores(t *testing.T) {
+	tests := []struct {
+		q    string
+		want float64
+	}{
+		{q: "1", want: 1},
+		{q: "0.5", want: 0.5},
+		{q: "10m", want: 0.01},
+		{q: "20m", want: 0.02},
+		{q: "250m", want: 0.25},
+		{q: "1m", want: 0.01},
+		{q: "1001m", want: 1.01},
+		{q: "1.234", want: 1.24},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.q, func(t *testing.T) {
+			got, err := cpuCores(tt.q)
+			if err != nil {
+				t.Fatalf("cpuCores(%q) error = %v", tt.q, err)
+			}
+			if got != tt.want {
+				t.Errorf("cpuCores(%q) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+	if _, err := cpuCores("lots"); err == nil {
+		t.Errorf("cpuCores(%q) returned no error", "lots")
+	}
+}
+
+func TestMemoryGB(t *testing.T) {
+	tests := []struct {
+		q    string
+		want float64
+	}{
+		{q: "1Gi", want: 1},
+		{q: "1.5Gi", want: 1.5},
+		{q: "1536Mi", want: 1.5},
+		{q: "100Mi", want: 0.1},
+		{q: "500Mi", want: 0.5},
+		{q: "1G", want: 1},
+		{q: "1Mi", want: 0.1},
+		{q: "1025Mi", want: 1.1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.q, func(t *testing.T) {
+			got, err := memoryGB(tt.q)
+			if err != nil {
+				t.Fatalf("memoryGB(%q) error = %v", tt.q, err)
+			}
+			if got != tt.want {
+				t.Errorf("memoryGB(%q) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+	if _, err := memoryGB("lots"); err == nil {
+		t.Errorf("memoryGB(%q) returned no error", "lots")
+	}
+}
+
+func TestCPUQuantity(t *testing.T) {
+	tests := []struct {
+		cores float64
+		want  string
+	}{
+		{cores: 1, want: "1"},
+		{cores: 4, want: "4"},
+		{cores: 0.5, want: "500m"},
+		{cores: 0.01, want: "10m"},
+		{cores: 1.25, want: "1250m"},
+	}
+	for _, tt := range tests {
+		if got := cpuQuantity(tt.cores); got != tt.want {
+			t.Errorf("cpuQuantity(%v) = %s, want %s", tt.cores, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryQuantity(t *testing.T) {
+	tests := []struct {
+		gb   float64
+		want string
+	}{
+		{gb: 1, want: "1Gi"},
+		{gb: 2, want: "2Gi"},
+		{gb: 1.5, want: "1536Mi"},
+		{gb: 0.5, want: "512Mi"},
+		{gb: 0.1, want: "102Mi"},
+	}
+	for _, tt := range tests {
+		if got := memoryQuantity(tt.gb); got != tt.want {
+			t.Errorf("memoryQuantity(%v) = %s, want %s", tt.gb, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/authorization/mgmt/authorization"
 	"github.com/Azure/azure-sdk-for-go/services/graphrbac/1.6/graphrbac"
@@ -22,11 +23,16 @@ var (
 	ErrApplicationAlreadyExists = errors.New("application already exists")
 	// ErrServicePrincipalAlreadyExists is the error when a azure service principal already exists
 	ErrServicePrincipalAlreadyExists = errors.New("service principal already exists")
+	// ErrNotFound is the error when an application or service principal cannot be found
+	ErrNotFound = errors.New("not found")
 )
 
 // Config is the configuration for the azure users client
 type Config struct {
 	azure_auth.AuthConfig
+	Logger *logrus.Entry
+	// Factory is the azure client factory to share with other clients, created from AuthConfig when nil
+	Factory *azure_auth.ClientFactory
 }
 
 // Client is the client for the azure users client
@@ -43,16 +49,29 @@ type ApplicationConfig struct {
 	IdentifierUris          []string
 }
 
-// New will return a new azure identities client
-func New(conf Config) *Client {
-	return &Client{
+// New will return a new azure identities client, whose calls share the tokens of one client factory
+func New(conf Config) (*Client, error) {
+	var err error
+	c := &Client{
 		Config: conf,
 	}
+	if c.Logger == nil {
+		c.Logger = logrus.NewEntry(logrus.New())
+		c.Logger.Logger.SetLevel(logrus.InfoLevel)
+		c.Logger.Logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+	if c.Factory == nil {
+		c.Factory, err = azure_auth.NewClientFactory(azure_auth.FactoryConfig{AuthConfig: conf.AuthConfig, Logger: c.Logger})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get new azure client factory")
+		}
+	}
+	return c, nil
 }
 
 // CreateADApplication creates an Azure Active Directory (AAD) application
 func (c *Client) CreateADApplication(ctx context.Context, appConfig ApplicationConfig) (graphrbac.Application, error) {
-	appClient, err := azure_auth.NewApplicationsClient(c.AuthConfig)
+	appClient, err := c.Factory.ApplicationsClient()
 	if err != nil {
 		return graphrbac.Application{}, errors.Wrap(err, "failed to get new azure applications client")
 	}
@@ -77,7 +96,7 @@ func (c *Client) CreateServicePrincipal(ctx context.Context, appConfig Applicati
 	if appConfig.AppID == nil {
 		return graphrbac.ServicePrincipal{}, fmt.Errorf("app id cannot be empty")
 	}
-	spClient, err := azure_auth.NewServicePrincipalsClient(c.AuthConfig)
+	spClient, err := c.Factory.ServicePrincipalsClient()
 	if err != nil {
 		return graphrbac.ServicePrincipal{}, errors.Wrap(err, "failed to get new azure service principal client")
 	}
@@ -96,9 +115,42 @@ func (c *Client) CreateServicePrincipal(ctx context.Context, appConfig Applicati
 		})
 }
 
+// GetADApplication will get the application with the given display name, returning ErrNotFound if there is none
+func (c *Client) GetADApplication(ctx context.Context, displayName string) (graphrbac.Application, error) {
+	appClient, err := c.Factory.ApplicationsClient()
+	if err != nil {
+		return graphrbac.Application{}, errors.Wrap(err, "failed to get new azure applications client")
+	}
+	res, err := appClient.List(ctx, fmt.Sprintf("displayName eq '%s'", displayName))
+	if err != nil {
+		return graphrbac.Application{}, errors.Wrap(err, "failed to list applications")
+	}
+	if len(res.Values()) == 0 {
+		return graphrbac.Application{}, ErrNotFound
+	}
+	return res.Values()[0], nil
+}
+
+// GetServicePrincipal will get the application service principal with the given display name, returning
+// ErrNotFound if there is none
+func (c *Client) GetServicePrincipal(ctx context.Context, displayName string) (graphrbac.ServicePrincipal, error) {
+	spClient, err := c.Factory.ServicePrincipalsClient()
+	if err != nil {
+		return graphrbac.ServicePrincipal{}, errors.Wrap(err, "failed to get new azure service principal client")
+	}
+	res, err := spClient.List(ctx, fmt.Sprintf("displayname eq '%s' and servicePrincipalType eq 'Application'", displayName))
+	if err != nil {
+		return graphrbac.ServicePrincipal{}, errors.Wrap(err, "failed to list service principals")
+	}
+	if len(res.Values()) == 0 {
+		return graphrbac.ServicePrincipal{}, ErrNotFound
+	}
+	return res.Values()[0], nil
+}
+
 // ListRoleDefinitions will list azure role definitions for a given resource group, and virtual network
 func (c *Client) ListRoleDefinitions(ctx context.Context, rg, vnet string) ([]authorization.RoleDefinition, error) {
-	rdClient, err := azure_auth.NewRoleDefinitionsClient(c.AuthConfig)
+	rdClient, err := c.Factory.RoleDefinitionsClient()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get new role definitions client")
 	}
@@ -117,7 +169,7 @@ func (c *Client) CreateApplicationCredentials(ctx context.Context, appConfig App
 		return "", fmt.Errorf("app id cannot be empty")
 	}
 	password = randomPassword()
-	appClient, err := azure_auth.NewApplicationsClient(c.AuthConfig)
+	appClient, err := c.Factory.ApplicationsClient()
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get new azure applications client")
 	}
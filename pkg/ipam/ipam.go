@@ -0,0 +1,178 @@
+// Package ipam plans ipv4 address blocks, allocating free blocks within parent cidr blocks, and detects overlapping
+// cidr blocks
+package ipam
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrExhausted is the error when a parent cidr block has no free block of the requested size left
+	ErrExhausted = errors.New("no free cidr block")
+)
+
+// block is an ipv4 cidr block, as the integer of its first address and the length of its prefix
+type block struct {
+	start uint64
+	ones  int
+}
+
+func parse(cidr string) (block, error) {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return block{}, errors.Errorf("invalid ipv4 cidr block %q", cidr)
+	}
+	ones, _ := network.Mask.Size()
+	return block{start: uint64(binary.BigEndian.Uint32(network.IP.To4())), ones: ones}, nil
+}
+
+func (b block) size() uint64 {
+	return uint64(1) << uint(32-b.ones)
+}
+
+// end is the integer of the address following the last address of the block
+func (b block) end() uint64 {
+	return b.start + b.size()
+}
+
+func (b block) overlaps(o block) bool {
+	return b.start < o.end() && o.start < b.end()
+}
+
+func (b block) String() string {
+	addr := make(net.IP, 4)
+	binary.BigEndian.PutUint32(addr, uint32(b.start))
+	return fmt.Sprintf("%s/%d", addr, b.ones)
+}
+
+// parseAll will parse the ipv4 cidr blocks of cidrs, skipping empty ones, as of resources without an ipv4 cidr block,
+// and those of other ip versions, which cannot overlap them
+func parseAll(cidrs []string) ([]block, error) {
+	blocks := make([]block, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if cidr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err == nil && !IsIPv4(cidr) {
+			continue
+		}
+		b, err := parse(cidr)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
+}
+
+// IsIPv4 will return whether a cidr block is a valid ipv4 cidr block
+func IsIPv4(cidr string) bool {
+	_, err := parse(cidr)
+	return err == nil
+}
+
+// Overlaps will return whether two cidr blocks, of either ip version, share any address
+func Overlaps(a, b string) (bool, error) {
+	_, x, err := net.ParseCIDR(a)
+	if err != nil {
+		return false, errors.Errorf("invalid cidr block %q", a)
+	}
+	_, y, err := net.ParseCIDR(b)
+	if err != nil {
+		return false, errors.Errorf("invalid cidr block %q", b)
+	}
+	return x.Contains(y.IP) || y.Contains(x.IP), nil
+}
+
+// Validate will return an error when a cidr block is not within parent, or overlaps any of existing
+func Validate(parent, cidr string, existing []string) error {
+	p, err := parse(parent)
+	if err != nil {
+		return err
+	}
+	b, err := parse(cidr)
+	if err != nil {
+		return err
+	}
+	if b.ones < p.ones || b.start < p.start || b.end() > p.end() {
+		return errors.Errorf("cidr block %s is not within %s", b, p)
+	}
+	others, err := parseAll(existing)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if b.overlaps(other) {
+			return errors.Errorf("cidr block %s overlaps existing cidr block %s", b, other)
+		}
+	}
+	return nil
+}
+
+// Next will return the first block with a prefix of prefixLength bits within parent, which overlaps none of
+// existing, returning ErrExhausted when there is none, as when parent is smaller than such a block.  Empty existing
+// blocks, and those of other ip versions, are ignored.
+func Next(parent string, prefixLength int, existing []string) (string, error) {
+	p, err := parse(parent)
+	if err != nil {
+		return "", err
+	}
+	if prefixLength < 0 || prefixLength > 32 {
+		return "", errors.Errorf("invalid prefix length %d, it must be between 0 and 32", prefixLength)
+	}
+	if prefixLength < p.ones {
+		return "", errors.Wrapf(ErrExhausted, "%s is smaller than a /%d block", parent, prefixLength)
+	}
+	others, err := parseAll(existing)
+	if err != nil {
+		return "", err
+	}
+	candidate := block{start: p.start, ones: prefixLength}
+	for candidate.end() <= p.end() {
+		overlapped := false
+		for _, other := range others {
+			if candidate.overlaps(other) {
+				overlapped = true
+				// skip past the overlapped block, to the next boundary of the candidate's size
+				candidate.start = other.end()
+				if rem := (candidate.start - p.start) % candidate.size(); rem != 0 {
+					candidate.start += candidate.size() - rem
+				}
+				break
+			}
+		}
+		if !overlapped {
+			return candidate.String(), nil
+		}
+	}
+	return "", errors.Wrapf(ErrExhausted, "no free /%d block within %s", prefixLength, parent)
+}
+
+// Split will split a cidr block into count equally sized consecutive blocks, as large as possible
+func Split(cidr string, count int) ([]string, error) {
+	p, err := parse(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if count < 1 {
+		return nil, errors.Errorf("cannot split cidr block %s into %d blocks", cidr, count)
+	}
+	bits := 0
+	for 1<<uint(bits) < count {
+		bits++
+	}
+	if p.ones+bits > 32 {
+		return nil, errors.Errorf("cidr block %s is too small to split into %d blocks", cidr, count)
+	}
+	b := block{start: p.start, ones: p.ones + bits}
+	blocks := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		blocks = append(blocks, b.String())
+		b.start += b.size()
+	}
+	return blocks, nil
+}
@@ -0,0 +1,191 @@
+package ipam
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name         string
+		parent       string
+		prefixLength int
+		existing     []string
+		want         string
+		wantErr      string
+		exhausted    bool
+	}{
+		{name: "first block", parent: "10.0.0.0/16", prefixLength: 24, want: "10.0.0.0/24"},
+		{name: "whole parent", parent: "10.0.0.0/16", prefixLength: 16, want: "10.0.0.0/16"},
+		{
+			name:         "gap between existing blocks",
+			parent:       "10.0.0.0/16",
+			prefixLength: 24,
+			existing:     []string{"10.0.0.0/24", "10.0.2.0/24"},
+			want:         "10.0.1.0/24",
+		},
+		{
+			name:         "aligned after an overlapping smaller block",
+			parent:       "10.0.0.0/16",
+			prefixLength: 24,
+			existing:     []string{"10.0.0.0/26"},
+			want:         "10.0.1.0/24",
+		},
+		{
+			name:         "aligned after an overlapping larger block",
+			parent:       "10.0.0.0/16",
+			prefixLength: 22,
+			existing:     []string{"10.0.0.0/23", "10.0.4.128/25"},
+			want:         "10.0.8.0/22",
+		},
+		{
+			name:         "existing blocks outside the parent",
+			parent:       "10.0.0.0/16",
+			prefixLength: 24,
+			existing:     []string{"10.1.0.0/24", "192.168.0.0/16"},
+			want:         "10.0.0.0/24",
+		},
+		{
+			name:         "ipv6 and empty existing blocks are ignored",
+			parent:       "10.0.0.0/16",
+			prefixLength: 24,
+			existing:     []string{"2600:1f18::/56", "", "10.0.0.0/24"},
+			want:         "10.0.1.0/24",
+		},
+		{
+			name:         "exhausted",
+			parent:       "10.0.0.0/23",
+			prefixLength: 24,
+			existing:     []string{"10.0.0.0/24", "10.0.1.0/25"},
+			wantErr:      "no free /24 block within 10.0.0.0/23: no free cidr block",
+			exhausted:    true,
+		},
+		{
+			name:         "exhausted by a block containing the parent",
+			parent:       "10.0.0.0/16",
+			prefixLength: 24,
+			existing:     []string{"10.0.0.0/8"},
+			wantErr:      "no free /24 block within 10.0.0.0/16: no free cidr block",
+			exhausted:    true,
+		},
+		{
+			name:         "prefix shorter than the parent",
+			parent:       "10.0.0.0/16",
+			prefixLength: 15,
+			wantErr:      "10.0.0.0/16 is smaller than a /15 block: no free cidr block",
+			exhausted:    true,
+		},
+		{name: "invalid prefix length", parent: "10.0.0.0/16", prefixLength: 33, wantErr: "invalid prefix length 33, it must be between 0 and 32"},
+		{name: "ipv6 parent", parent: "2600:1f18::/56", prefixLength: 64, wantErr: `invalid ipv4 cidr block "2600:1f18::/56"`},
+		{
+			name:         "invalid existing block",
+			parent:       "10.0.0.0/16",
+			prefixLength: 24,
+			existing:     []string{"10.0.0.0"},
+			wantErr:      `invalid ipv4 cidr block "10.0.0.0"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Next(tt.parent, tt.prefixLength, tt.existing)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Next() error = %v, want %q", err, tt.wantErr)
+				}
+				if exhausted := errors.Cause(err) == ErrExhausted; exhausted != tt.exhausted {
+					t.Errorf("Next() error is ErrExhausted = %t, want %t", exhausted, tt.exhausted)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		parent   string
+		cidr     string
+		existing []string
+		wantErr  string
+	}{
+		{name: "free block", parent: "10.0.0.0/16", cidr: "10.0.1.0/24", existing: []string{"10.0.0.0/24"}},
+		{name: "whole parent", parent: "10.0.0.0/16", cidr: "10.0.0.0/16"},
+		{
+			name:     "ipv6 and empty existing blocks are ignored",
+			parent:   "10.0.0.0/16",
+			cidr:     "10.0.0.0/24",
+			existing: []string{"2600:1f18::/56", ""},
+		},
+		{name: "outside the parent", parent: "10.0.0.0/16", cidr: "10.1.0.0/24", wantErr: "cidr block 10.1.0.0/24 is not within 10.0.0.0/16"},
+		{name: "larger than the parent", parent: "10.0.0.0/16", cidr: "10.0.0.0/15", wantErr: "cidr block 10.0.0.0/15 is not within 10.0.0.0/16"},
+		{
+			name:     "overlapping",
+			parent:   "10.0.0.0/16",
+			cidr:     "10.0.0.0/23",
+			existing: []string{"10.0.1.128/25"},
+			wantErr:  "cidr block 10.0.0.0/23 overlaps existing cidr block 10.0.1.128/25",
+		},
+		{name: "host bits are masked", parent: "10.0.0.0/16", cidr: "10.0.1.7/24", existing: []string{"10.0.1.0/24"}, wantErr: "cidr block 10.0.1.0/24 overlaps existing cidr block 10.0.1.0/24"},
+		{name: "invalid cidr", parent: "10.0.0.0/16", cidr: "10.0.1.0", wantErr: `invalid ipv4 cidr block "10.0.1.0"`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.parent, tt.cidr, tt.existing)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidr    string
+		count   int
+		want    []string
+		wantErr string
+	}{
+		{name: "one block", cidr: "10.0.0.0/16", count: 1, want: []string{"10.0.0.0/16"}},
+		{name: "power of two", cidr: "10.0.0.0/16", count: 4, want: []string{"10.0.0.0/18", "10.0.64.0/18", "10.0.128.0/18", "10.0.192.0/18"}},
+		{name: "rounded up to a power of two", cidr: "10.0.0.0/16", count: 3, want: []string{"10.0.0.0/18", "10.0.64.0/18", "10.0.128.0/18"}},
+		{name: "rounded up by one bit", cidr: "10.0.0.0/24", count: 5, want: []string{"10.0.0.0/27", "10.0.0.32/27", "10.0.0.64/27", "10.0.0.96/27", "10.0.0.128/27"}},
+		{name: "into addresses", cidr: "10.0.0.0/31", count: 2, want: []string{"10.0.0.0/32", "10.0.0.1/32"}},
+		{name: "too small", cidr: "10.0.0.0/31", count: 3, wantErr: "cidr block 10.0.0.0/31 is too small to split into 3 blocks"},
+		{name: "no blocks", cidr: "10.0.0.0/16", count: 0, wantErr: "cannot split cidr block 10.0.0.0/16 into 0 blocks"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Split(tt.cidr, tt.count)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Split() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// Package kubeconfig reads, merges and writes the kubeconfig files of kubectl, keeping the fields it does not know
+package kubeconfig
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ExecAPIVersion is the api version of the exec credentials written by exec credential plugins
+	ExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+	// envKubeconfig is the environment variable holding the path list of kubeconfig files
+	envKubeconfig = "KUBECONFIG"
+)
+
+// Config is a kubeconfig file
+type Config struct {
+	APIVersion     string         `yaml:"apiVersion"`
+	Kind           string         `yaml:"kind"`
+	Clusters       []NamedCluster `yaml:"clusters"`
+	Contexts       []NamedContext `yaml:"contexts"`
+	Users          []NamedUser    `yaml:"users"`
+	CurrentContext string         `yaml:"current-context"`
+	// Extra holds the fields not known to this package, such as preferences
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// NamedCluster is a named cluster of a kubeconfig
+type NamedCluster struct {
+	Name    string  `yaml:"name"`
+	Cluster Cluster `yaml:"cluster"`
+}
+
+// Cluster is the api server of a cluster
+type Cluster struct {
+	Server string `yaml:"server"`
+	// CertificateAuthorityData is the base64 encoded pem ca certificate of the server
+	CertificateAuthorityData string                 `yaml:"certificate-authority-data,omitempty"`
+	Extra                    map[string]interface{} `yaml:",inline"`
+}
+
+// NamedContext is a named context of a kubeconfig
+type NamedContext struct {
+	Name    string  `yaml:"name"`
+	Context Context `yaml:"context"`
+}
+
+// Context is a pair of a cluster and the user authenticating against it
+type Context struct {
+	Cluster   string                 `yaml:"cluster"`
+	User      string                 `yaml:"user"`
+	Namespace string                 `yaml:"namespace,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+// NamedUser is a named user of a kubeconfig
+type NamedUser struct {
+	Name string `yaml:"name"`
+	User User   `yaml:"user"`
+}
+
+// User is the credentials of a user
+type User struct {
+	Exec  *ExecConfig            `yaml:"exec,omitempty"`
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// ExecConfig runs a command printing the exec credential of a user
+type ExecConfig struct {
+	APIVersion  string   `yaml:"apiVersion"`
+	Command     string   `yaml:"command"`
+	Args        []string `yaml:"args,omitempty"`
+	InstallHint string   `yaml:"installHint,omitempty"`
+}
+
+// ExecCredential is the credential printed by exec credential plugins
+type ExecCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+// ExecCredentialStatus holds the token of an exec credential, and the RFC 3339 time it expires at
+type ExecCredentialStatus struct {
+	Token               string `json:"token"`
+	ExpirationTimestamp string `json:"expirationTimestamp,omitempty"`
+}
+
+// Path will return the kubeconfig file of kubectl: the given path, the first of $KUBECONFIG, or ~/.kube/config
+func Path(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if env := filepath.SplitList(os.Getenv(envKubeconfig)); len(env) > 0 && env[0] != "" {
+		return env[0], nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find home directory")
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// Load will load a kubeconfig file, which is empty when it does not exist
+func Load(path string) (*Config, error) {
+	config := &Config{APIVersion: "v1", Kind: "Config"}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read kubeconfig %s", path)
+	}
+	if err = yaml.Unmarshal(b, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse kubeconfig %s", path)
+	}
+	return config, nil
+}
+
+// Write will write a kubeconfig file, readable only by its owner
+func (c *Config) Write(path string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	// indented as kubectl does
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return errors.Wrap(err, "failed to marshal kubeconfig")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create directory of kubeconfig %s", path)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return errors.Wrapf(err, "failed to write kubeconfig %s", path)
+	}
+	return nil
+}
+
+// SetCluster will add a cluster, replacing any of the same name
+func (c *Config) SetCluster(name string, cluster Cluster) {
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == name {
+			c.Clusters[i].Cluster = cluster
+			return
+		}
+	}
+	c.Clusters = append(c.Clusters, NamedCluster{Name: name, Cluster: cluster})
+}
+
+// SetUser will add a user, replacing any of the same name
+func (c *Config) SetUser(name string, user User) {
+	for i := range c.Users {
+		if c.Users[i].Name == name {
+			c.Users[i].User = user
+			return
+		}
+	}
+	c.Users = append(c.Users, NamedUser{Name: name, User: user})
+}
+
+// SetContext will add a context, replacing the cluster and user of any of the same name, while keeping its namespace
+func (c *Config) SetContext(name string, context Context) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			if context.Namespace == "" {
+				context.Namespace = c.Contexts[i].Context.Namespace
+			}
+			c.Contexts[i].Context = context
+			return
+		}
+	}
+	c.Contexts = append(c.Contexts, NamedContext{Name: name, Context: context})
+}
@@ -1,8 +1,13 @@
 package logging
 
-import "github.com/sirupsen/logrus"
+import (
+	"os"
 
-// GetLogger will get the configured logger
+	"github.com/sirupsen/logrus"
+)
+
+// GetLogger will get the configured logger, which writes to stderr so that logs are kept separate
+// from command results printed to stdout
 func GetLogger(loglevel string) *logrus.Entry {
 	logger := logrus.NewEntry(logrus.New())
 	lvl, err := logrus.ParseLevel(loglevel)
@@ -11,5 +16,6 @@ func GetLogger(loglevel string) *logrus.Entry {
 	}
 	logger.Logger.SetLevel(lvl)
 	logger.Logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.Logger.SetOutput(os.Stderr)
 	return logger
 }
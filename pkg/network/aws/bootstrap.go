@@ -0,0 +1,296 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+
+	"github.com/naemono/go-cloud-actions/pkg/ipam"
+)
+
+// NatGatewayMode is how many nat gateways route the private subnets of a bootstrapped vpc to the internet
+type NatGatewayMode string
+
+const (
+	// NatGatewaysNone leaves the private subnets without internet access
+	NatGatewaysNone NatGatewayMode = "none"
+	// NatGatewaysSingle routes every private subnet through a single nat gateway, in the first availability zone
+	NatGatewaysSingle NatGatewayMode = "single"
+	// NatGatewaysPerAZ routes every private subnet through a nat gateway in its own availability zone
+	NatGatewaysPerAZ NatGatewayMode = "per-az"
+)
+
+// defaultRoute is the destination of the routes to the internet
+const defaultRoute = "0.0.0.0/0"
+
+// BootstrapRequest is a request to create a vpc with a public and a private subnet in each of several availability
+// zones
+type BootstrapRequest struct {
+	// Name is the Name tag of the vpc, and the prefix of the Name tags of every other resource
+	Name      string
+	CidrBlock string
+	// AvailabilityZones is the number of availability zones to create subnets in
+	AvailabilityZones int
+	NatGateways       NatGatewayMode
+	// Tags are tags applied to every created resource, in addition to its Name tag
+	Tags []types.Tag
+	// Wait is how long to wait for each of the vpc, subnets and nat gateways to be available
+	Wait time.Duration
+}
+
+// BootstrapZone are the resources of a bootstrapped vpc in a single availability zone
+type BootstrapZone struct {
+	AvailabilityZone  string            `json:"availabilityZone"`
+	PublicSubnet      types.Subnet      `json:"publicSubnet"`
+	PrivateSubnet     types.Subnet      `json:"privateSubnet"`
+	PrivateRouteTable types.RouteTable  `json:"privateRouteTable"`
+	NatGateway        *types.NatGateway `json:"natGateway,omitempty"`
+}
+
+// BootstrapResult are the resources of a bootstrapped vpc
+type BootstrapResult struct {
+	VPC              types.Vpc             `json:"vpc"`
+	InternetGateway  types.InternetGateway `json:"internetGateway"`
+	PublicRouteTable types.RouteTable      `json:"publicRouteTable"`
+	Zones            []BootstrapZone       `json:"zones"`
+}
+
+// Bootstrap will create a vpc with an internet gateway, and a public and a private subnet in each of the first
+// available availability zones, splitting the cidr block of the vpc evenly between them.  Public subnets are routed
+// through the internet gateway, and assign public ips on launch, while private subnets each have their own route
+// table, routed through the nat gateways of the request.  On failure, the resources created so far are returned
+// along with the error.
+func (c *Client) Bootstrap(ctx context.Context, request BootstrapRequest) (BootstrapResult, error) {
+	var result BootstrapResult
+	zones, err := c.BootstrapLayout(ctx, request)
+	if err != nil {
+		return result, err
+	}
+
+	result.VPC, err = c.CreateVPC(ctx, CreateVpcRequest{
+		CidrBlock:         request.CidrBlock,
+		InstanceTenacy:    types.TenancyDefault,
+		TagSpecifications: nameTagSpecifications(types.ResourceTypeVpc, request.Name, request.Tags),
+		Wait:              request.Wait,
+	})
+	if err != nil {
+		return result, err
+	}
+	vpcID := to.String(result.VPC.VpcId)
+	if err = c.enableDNSHostnames(ctx, vpcID); err != nil {
+		return result, err
+	}
+
+	result.InternetGateway, err = c.CreateInternetGateway(ctx, vpcID,
+		nameTagSpecifications(types.ResourceTypeInternetGateway, request.Name+"-igw", request.Tags))
+	if err != nil {
+		return result, err
+	}
+	result.PublicRouteTable, err = c.CreateRouteTable(ctx, vpcID,
+		nameTagSpecifications(types.ResourceTypeRouteTable, request.Name+"-public", request.Tags))
+	if err != nil {
+		return result, err
+	}
+	err = c.CreateRoute(ctx, CreateRouteRequest{
+		RouteTableID:    to.String(result.PublicRouteTable.RouteTableId),
+		DestinationCIDR: defaultRoute,
+		GatewayID:       to.String(result.InternetGateway.InternetGatewayId),
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for _, layout := range zones {
+		z := BootstrapZone{AvailabilityZone: layout.AvailabilityZone}
+		z.PublicSubnet, err = c.bootstrapSubnet(ctx, request, vpcID, z.AvailabilityZone, to.String(layout.PublicSubnet.CidrBlock), "public")
+		if err != nil {
+			return result, err
+		}
+		result.Zones = append(result.Zones, z)
+		if err = c.mapPublicIPOnLaunch(ctx, to.String(z.PublicSubnet.SubnetId)); err != nil {
+			return result, err
+		}
+		if _, err = c.AssociateRouteTable(ctx, to.String(result.PublicRouteTable.RouteTableId), to.String(z.PublicSubnet.SubnetId)); err != nil {
+			return result, err
+		}
+	}
+
+	for i := range result.Zones {
+		z := &result.Zones[i]
+		z.PrivateSubnet, err = c.bootstrapSubnet(ctx, request, vpcID, z.AvailabilityZone, to.String(zones[i].PrivateSubnet.CidrBlock), "private")
+		if err != nil {
+			return result, err
+		}
+		z.PrivateRouteTable, err = c.CreateRouteTable(ctx, vpcID,
+			nameTagSpecifications(types.ResourceTypeRouteTable, fmt.Sprintf("%s-private-%s", request.Name, z.AvailabilityZone), request.Tags))
+		if err != nil {
+			return result, err
+		}
+		if _, err = c.AssociateRouteTable(ctx, to.String(z.PrivateRouteTable.RouteTableId), to.String(z.PrivateSubnet.SubnetId)); err != nil {
+			return result, err
+		}
+		if request.NatGateways == NatGatewaysPerAZ || (request.NatGateways == NatGatewaysSingle && i == 0) {
+			gateway, err := c.CreateNatGateway(ctx, CreateNatGatewayRequest{
+				SubnetID: to.String(z.PublicSubnet.SubnetId),
+				TagSpecifications: nameTagSpecifications(types.ResourceTypeNatgateway,
+					fmt.Sprintf("%s-nat-%s", request.Name, z.AvailabilityZone), request.Tags),
+				Wait: request.Wait,
+			})
+			if err != nil {
+				return result, err
+			}
+			z.NatGateway = &gateway
+		}
+	}
+
+	if request.NatGateways == NatGatewaysNone {
+		return result, nil
+	}
+	for i := range result.Zones {
+		gateway := result.Zones[0].NatGateway
+		if request.NatGateways == NatGatewaysPerAZ {
+			gateway = result.Zones[i].NatGateway
+		}
+		err = c.CreateRoute(ctx, CreateRouteRequest{
+			RouteTableID:    to.String(result.Zones[i].PrivateRouteTable.RouteTableId),
+			DestinationCIDR: defaultRoute,
+			NatGatewayID:    to.String(gateway.NatGatewayId),
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// BootstrapLayout will return the availability zones, along with the cidr blocks of their public and private subnets,
+// Bootstrap would create
+func (c *Client) BootstrapLayout(ctx context.Context, request BootstrapRequest) ([]BootstrapZone, error) {
+	if err := validateBootstrapRequest(request); err != nil {
+		return nil, err
+	}
+	names, err := c.availableZoneNames(ctx, request.AvailabilityZones)
+	if err != nil {
+		return nil, err
+	}
+	cidrs, err := splitCIDR(request.CidrBlock, 2*len(names))
+	if err != nil {
+		return nil, err
+	}
+	zones := make([]BootstrapZone, 0, len(names))
+	for i, name := range names {
+		zones = append(zones, BootstrapZone{
+			AvailabilityZone: name,
+			PublicSubnet:     types.Subnet{AvailabilityZone: to.StringPtr(name), CidrBlock: to.StringPtr(cidrs[i])},
+			PrivateSubnet:    types.Subnet{AvailabilityZone: to.StringPtr(name), CidrBlock: to.StringPtr(cidrs[len(names)+i])},
+		})
+	}
+	return zones, nil
+}
+
+func validateBootstrapRequest(request BootstrapRequest) error {
+	if request.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if request.AvailabilityZones < 1 {
+		return errors.New("at least one availability zone is required")
+	}
+	switch request.NatGateways {
+	case NatGatewaysNone, NatGatewaysSingle, NatGatewaysPerAZ:
+	default:
+		return errors.Errorf("unsupported nat gateways %q, must be one of none, single, per-az", request.NatGateways)
+	}
+	if request.NatGateways != NatGatewaysNone && request.Wait == 0 {
+		// routes can only be created through available nat gateways
+		return errors.New("nat gateways must be waited for")
+	}
+	return nil
+}
+
+func (c *Client) bootstrapSubnet(ctx context.Context, request BootstrapRequest, vpcID, zone, cidr, tier string) (types.Subnet, error) {
+	return c.CreateSubnetInVPC(ctx, CreateVpcSubnetRequest{
+		CidrBlock:        cidr,
+		VPCId:            vpcID,
+		AvailabilityZone: zone,
+		TagSpecifications: nameTagSpecifications(types.ResourceTypeSubnet,
+			fmt.Sprintf("%s-%s-%s", request.Name, tier, zone),
+			append([]types.Tag{{Key: to.StringPtr("Tier"), Value: to.StringPtr(tier)}}, request.Tags...)),
+		Wait: request.Wait,
+	})
+}
+
+// availableZoneNames will return the names of the first count available availability zones of the region
+func (c *Client) availableZoneNames(ctx context.Context, count int) ([]string, error) {
+	zones, err := c.ListAvailabilityZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, zone := range zones {
+		if zone.State == types.AvailabilityZoneStateAvailable && len(names) < count {
+			names = append(names, to.String(zone.ZoneName))
+		}
+	}
+	if len(names) < count {
+		return nil, errors.Errorf("%d availability zones requested, but only %d are available", count, len(names))
+	}
+	return names, nil
+}
+
+func (c *Client) enableDNSHostnames(ctx context.Context, vpcID string) error {
+	_, err := c.ec2Client.ModifyVpcAttribute(ctx, &ec2.ModifyVpcAttributeInput{
+		VpcId:              to.StringPtr(vpcID),
+		EnableDnsHostnames: &types.AttributeBooleanValue{Value: true},
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to enable dns hostnames of vpc %s", vpcID)
+	}
+	return nil
+}
+
+func (c *Client) mapPublicIPOnLaunch(ctx context.Context, subnetID string) error {
+	_, err := c.ec2Client.ModifySubnetAttribute(ctx, &ec2.ModifySubnetAttributeInput{
+		SubnetId:            to.StringPtr(subnetID),
+		MapPublicIpOnLaunch: &types.AttributeBooleanValue{Value: true},
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to map public ips on launch in subnet %s", subnetID)
+	}
+	return nil
+}
+
+// nameTagSpecifications will return the tag specification of a resource, with its Name tag along with the given tags
+func nameTagSpecifications(resourceType types.ResourceType, name string, tags []types.Tag) []types.TagSpecification {
+	spec := types.TagSpecification{
+		ResourceType: resourceType,
+		Tags:         []types.Tag{{Key: to.StringPtr("Name"), Value: to.StringPtr(name)}},
+	}
+	for _, tag := range tags {
+		if to.String(tag.Key) != "Name" {
+			spec.Tags = append(spec.Tags, tag)
+		}
+	}
+	return []types.TagSpecification{spec}
+}
+
+// splitCIDR will split an ipv4 cidr block into count equally sized consecutive subnet cidr blocks, as large as
+// possible
+func splitCIDR(cidr string, count int) ([]string, error) {
+	blocks, err := ipam.Split(cidr, count)
+	if err != nil {
+		return nil, err
+	}
+	// aws subnets are at most /28
+	if _, network, _ := net.ParseCIDR(blocks[0]); network != nil {
+		if ones, _ := network.Mask.Size(); ones > 28 {
+			return nil, errors.Errorf("cidr block %s is too small to split into %d subnets", cidr, count)
+		}
+	}
+	return blocks, nil
+}
@@ -0,0 +1,107 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+)
+
+// CreateInternetGateway will create an internet gateway, and attach it to the given vpc id when not empty
+func (c *Client) CreateInternetGateway(ctx context.Context, vpcID string, tags []types.TagSpecification) (types.InternetGateway, error) {
+	out, err := c.ec2Client.CreateInternetGateway(ctx, &ec2.CreateInternetGatewayInput{
+		TagSpecifications: tags,
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.InternetGateway{}, errors.Wrap(err, "failed to create internet gateway")
+	}
+	if out.InternetGateway == nil {
+		return types.InternetGateway{}, errors.New("internet gateway missing from create response")
+	}
+	gateway := *out.InternetGateway
+	c.Logger.Infof("internet gateway %s created", to.String(gateway.InternetGatewayId))
+	if vpcID == "" {
+		return gateway, nil
+	}
+	if err = c.AttachInternetGateway(ctx, to.String(gateway.InternetGatewayId), vpcID); err != nil {
+		return gateway, err
+	}
+	gateway.Attachments = []types.InternetGatewayAttachment{{VpcId: to.StringPtr(vpcID), State: types.AttachmentStatusAttached}}
+	return gateway, nil
+}
+
+// AttachInternetGateway will attach an internet gateway to the given vpc
+func (c *Client) AttachInternetGateway(ctx context.Context, id, vpcID string) error {
+	_, err := c.ec2Client.AttachInternetGateway(ctx, &ec2.AttachInternetGatewayInput{
+		InternetGatewayId: to.StringPtr(id),
+		VpcId:             to.StringPtr(vpcID),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to attach internet gateway %s to vpc %s", id, vpcID)
+	}
+	c.Logger.Infof("internet gateway %s attached to vpc %s", id, vpcID)
+	return nil
+}
+
+// GetInternetGateway will get a single internet gateway by id
+func (c *Client) GetInternetGateway(ctx context.Context, id string) (types.InternetGateway, error) {
+	out, err := c.ec2Client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+		InternetGatewayIds: []string{id},
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.InternetGateway{}, errors.Wrapf(err, "failed to get internet gateway %s", id)
+	}
+	if len(out.InternetGateways) == 0 {
+		return types.InternetGateway{}, errors.Errorf("internet gateway %s not found", id)
+	}
+	return out.InternetGateways[0], nil
+}
+
+// ListInternetGatewaysInVPC will list the internet gateways attached to a given vpc
+func (c *Client) ListInternetGatewaysInVPC(ctx context.Context, vpcID string) ([]types.InternetGateway, error) {
+	input := &ec2.DescribeInternetGatewaysInput{
+		Filters: []types.Filter{
+			{
+				Name:   to.StringPtr("attachment.vpc-id"),
+				Values: []string{vpcID},
+			},
+		},
+	}
+	var gateways []types.InternetGateway
+	for {
+		out, err := c.ec2Client.DescribeInternetGateways(ctx, input, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list internet gateways in vpc %s", vpcID)
+		}
+		gateways = append(gateways, out.InternetGateways...)
+		if out.NextToken == nil || *out.NextToken == "" {
+			return gateways, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// DetachInternetGateway will detach an internet gateway from the given vpc
+func (c *Client) DetachInternetGateway(ctx context.Context, id, vpcID string) error {
+	_, err := c.ec2Client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
+		InternetGatewayId: to.StringPtr(id),
+		VpcId:             to.StringPtr(vpcID),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to detach internet gateway %s from vpc %s", id, vpcID)
+	}
+	return nil
+}
+
+// DeleteInternetGateway will delete the given, detached, internet gateway id
+func (c *Client) DeleteInternetGateway(ctx context.Context, id string) error {
+	_, err := c.ec2Client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{
+		InternetGatewayId: to.StringPtr(id),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete internet gateway %s", id)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+)
+
+const natGatewayPollInterval = 10 * time.Second
+
+// CreateNatGatewayRequest is a request to create a nat gateway in a public subnet
+type CreateNatGatewayRequest struct {
+	SubnetID string
+	// AllocationID is the elastic ip of the nat gateway, which is allocated, and tagged as the nat gateway, when
+	// empty
+	AllocationID      string
+	TagSpecifications []types.TagSpecification
+	// Wait is how long to wait for the nat gateway to be available, or zero to return it as soon as it is created
+	Wait time.Duration
+}
+
+// CreateNatGateway will create a nat gateway, allocating its elastic ip when none is given
+func (c *Client) CreateNatGateway(ctx context.Context, request CreateNatGatewayRequest) (types.NatGateway, error) {
+	allocationID := request.AllocationID
+	if allocationID == "" {
+		address, err := c.ec2Client.AllocateAddress(ctx, &ec2.AllocateAddressInput{
+			Domain:            types.DomainTypeVpc,
+			TagSpecifications: retagSpecifications(request.TagSpecifications, types.ResourceTypeElasticIp),
+		}, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return types.NatGateway{}, errors.Wrap(err, "failed to allocate elastic ip of nat gateway")
+		}
+		allocationID = to.String(address.AllocationId)
+		c.Logger.Infof("elastic ip %s allocated as %s", to.String(address.PublicIp), allocationID)
+	}
+	out, err := c.ec2Client.CreateNatGateway(ctx, &ec2.CreateNatGatewayInput{
+		SubnetId:          to.StringPtr(request.SubnetID),
+		AllocationId:      to.StringPtr(allocationID),
+		TagSpecifications: request.TagSpecifications,
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.NatGateway{}, errors.Wrapf(err, "failed to create nat gateway in subnet %s", request.SubnetID)
+	}
+	if out.NatGateway == nil {
+		return types.NatGateway{}, errors.New("nat gateway missing from create response")
+	}
+	gateway := *out.NatGateway
+	c.Logger.Infof("nat gateway %s created in subnet %s", to.String(gateway.NatGatewayId), request.SubnetID)
+	if request.Wait == 0 {
+		return gateway, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, request.Wait)
+	defer cancel()
+	return c.WaitForNatGateway(ctx, to.String(gateway.NatGatewayId), types.NatGatewayStateAvailable)
+}
+
+// ListNatGatewaysInVPC will list the nat gateways within a given vpc, including the ones recently deleted
+func (c *Client) ListNatGatewaysInVPC(ctx context.Context, vpcID string) ([]types.NatGateway, error) {
+	return c.describeNatGateways(ctx, &ec2.DescribeNatGatewaysInput{
+		Filter: []types.Filter{
+			{
+				Name:   to.StringPtr("vpc-id"),
+				Values: []string{vpcID},
+			},
+		},
+	})
+}
+
+// GetNatGateway will get a single nat gateway by id
+func (c *Client) GetNatGateway(ctx context.Context, id string) (types.NatGateway, error) {
+	gateways, err := c.describeNatGateways(ctx, &ec2.DescribeNatGatewaysInput{NatGatewayIds: []string{id}})
+	if err != nil {
+		return types.NatGateway{}, err
+	}
+	if len(gateways) == 0 {
+		return types.NatGateway{}, errors.Errorf("nat gateway %s not found", id)
+	}
+	return gateways[0], nil
+}
+
+func (c *Client) describeNatGateways(ctx context.Context, input *ec2.DescribeNatGatewaysInput) ([]types.NatGateway, error) {
+	var gateways []types.NatGateway
+	for {
+		out, err := c.ec2Client.DescribeNatGateways(ctx, input, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list nat gateways")
+		}
+		gateways = append(gateways, out.NatGateways...)
+		if out.NextToken == nil || *out.NextToken == "" {
+			return gateways, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// DeleteNatGateway will delete the given nat gateway id, and wait for it to be deleted, so that its subnet and
+// elastic ip can be deleted in turn.  The elastic ips of the nat gateway are released when release is given.
+func (c *Client) DeleteNatGateway(ctx context.Context, id string, release bool) error {
+	gateway, err := c.GetNatGateway(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = c.ec2Client.DeleteNatGateway(ctx, &ec2.DeleteNatGatewayInput{
+		NatGatewayId: to.StringPtr(id),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete nat gateway %s", id)
+	}
+	if _, err = c.WaitForNatGateway(ctx, id, types.NatGatewayStateDeleted); err != nil {
+		return err
+	}
+	c.Logger.Infof("nat gateway %s deleted", id)
+	if !release {
+		return nil
+	}
+	for _, address := range gateway.NatGatewayAddresses {
+		if address.AllocationId == nil {
+			continue
+		}
+		_, err = c.ec2Client.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{
+			AllocationId: address.AllocationId,
+		}, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return errors.Wrapf(err, "failed to release elastic ip %s of nat gateway %s", to.String(address.AllocationId), id)
+		}
+		c.Logger.Infof("elastic ip %s released", to.String(address.AllocationId))
+	}
+	return nil
+}
+
+// WaitForNatGateway will poll the given nat gateway until it reaches the given state, or fails, or the context is
+// done
+func (c *Client) WaitForNatGateway(ctx context.Context, id string, state types.NatGatewayState) (types.NatGateway, error) {
+	ticker := time.NewTicker(natGatewayPollInterval)
+	defer ticker.Stop()
+	for {
+		gateway, err := c.GetNatGateway(ctx, id)
+		if err != nil {
+			return gateway, err
+		}
+		if gateway.State == state {
+			return gateway, nil
+		}
+		if gateway.State == types.NatGatewayStateFailed {
+			return gateway, errors.Errorf("nat gateway %s failed: %s", id, to.String(gateway.FailureMessage))
+		}
+		c.Logger.Debugf("nat gateway %s is %s, waiting", id, gateway.State)
+		select {
+		case <-ctx.Done():
+			return gateway, errors.Wrapf(ctx.Err(), "timed out waiting for nat gateway %s to be %s", id, state)
+		case <-ticker.C:
+		}
+	}
+}
+
+// retagSpecifications will return the tags of specifications for another resource type, such as the elastic ip
+// created along with a resource
+func retagSpecifications(specifications []types.TagSpecification, resourceType types.ResourceType) []types.TagSpecification {
+	retagged := make([]types.TagSpecification, 0, len(specifications))
+	for _, s := range specifications {
+		retagged = append(retagged, types.TagSpecification{ResourceType: resourceType, Tags: s.Tags})
+	}
+	return retagged
+}
@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+)
+
+// ListNetworkInterfacesInVPC will list the network interfaces (enis) within a given vpc
+func (c *Client) ListNetworkInterfacesInVPC(ctx context.Context, vpcID string) ([]types.NetworkInterface, error) {
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{
+			{
+				Name:   to.StringPtr("vpc-id"),
+				Values: []string{vpcID},
+			},
+		},
+	}
+	var enis []types.NetworkInterface
+	for {
+		out, err := c.ec2Client.DescribeNetworkInterfaces(ctx, input, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list network interfaces in vpc %s", vpcID)
+		}
+		enis = append(enis, out.NetworkInterfaces...)
+		if out.NextToken == nil || *out.NextToken == "" {
+			return enis, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// DeleteNetworkInterface will delete the given, detached, network interface id
+func (c *Client) DeleteNetworkInterface(ctx context.Context, id string) error {
+	_, err := c.ec2Client.DeleteNetworkInterface(ctx, &ec2.DeleteNetworkInterfaceInput{
+		NetworkInterfaceId: to.StringPtr(id),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete network interface %s", id)
+	}
+	return nil
+}
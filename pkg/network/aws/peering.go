@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+)
+
+const peeringPollInterval = 5 * time.Second
+
+// CreateVpcPeeringRequest is a request to create a peering connection between 2 vpcs
+type CreateVpcPeeringRequest struct {
+	VPCId             string
+	PeerVPCId         string
+	PeerOwnerID       string
+	PeerRegion        string
+	TagSpecifications []types.TagSpecification
+}
+
+// CreateVpcPeeringConnection will request a peering connection from a vpc to a peer vpc, which
+// may live in another account or region
+func (c *Client) CreateVpcPeeringConnection(ctx context.Context, request CreateVpcPeeringRequest) (types.VpcPeeringConnection, error) {
+	input := &ec2.CreateVpcPeeringConnectionInput{
+		VpcId:             to.StringPtr(request.VPCId),
+		PeerVpcId:         to.StringPtr(request.PeerVPCId),
+		TagSpecifications: request.TagSpecifications,
+	}
+	if request.PeerOwnerID != "" {
+		input.PeerOwnerId = to.StringPtr(request.PeerOwnerID)
+	}
+	if request.PeerRegion != "" {
+		input.PeerRegion = to.StringPtr(request.PeerRegion)
+	}
+	out, err := c.ec2Client.CreateVpcPeeringConnection(ctx, input, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.VpcPeeringConnection{}, errors.Wrapf(err, "failed to create vpc peering connection from %s to %s", request.VPCId, request.PeerVPCId)
+	}
+	if out.VpcPeeringConnection == nil {
+		return types.VpcPeeringConnection{}, errors.New("vpc peering connection missing from create response")
+	}
+	return *out.VpcPeeringConnection, nil
+}
+
+// ListVpcPeeringConnections will list the peering connections in which the given vpc id is either
+// the requester or the accepter.  An empty vpc id will list all peering connections in the region.
+func (c *Client) ListVpcPeeringConnections(ctx context.Context, vpcID string) ([]types.VpcPeeringConnection, error) {
+	if vpcID == "" {
+		return c.describeVpcPeeringConnections(ctx, &ec2.DescribeVpcPeeringConnectionsInput{})
+	}
+	var (
+		connections []types.VpcPeeringConnection
+		seen        = map[string]bool{}
+	)
+	for _, filter := range []string{"requester-vpc-info.vpc-id", "accepter-vpc-info.vpc-id"} {
+		result, err := c.describeVpcPeeringConnections(ctx, &ec2.DescribeVpcPeeringConnectionsInput{
+			Filters: []types.Filter{
+				{
+					Name:   to.StringPtr(filter),
+					Values: []string{vpcID},
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, conn := range result {
+			if seen[to.String(conn.VpcPeeringConnectionId)] {
+				continue
+			}
+			seen[to.String(conn.VpcPeeringConnectionId)] = true
+			connections = append(connections, conn)
+		}
+	}
+	return connections, nil
+}
+
+// GetVpcPeeringConnection will get a single peering connection by id
+func (c *Client) GetVpcPeeringConnection(ctx context.Context, id string) (types.VpcPeeringConnection, error) {
+	result, err := c.describeVpcPeeringConnections(ctx, &ec2.DescribeVpcPeeringConnectionsInput{
+		VpcPeeringConnectionIds: []string{id},
+	})
+	if err != nil {
+		return types.VpcPeeringConnection{}, err
+	}
+	if len(result) == 0 {
+		return types.VpcPeeringConnection{}, errors.Errorf("vpc peering connection %s not found", id)
+	}
+	return result[0], nil
+}
+
+// DeleteVpcPeeringConnection will delete the given vpc peering connection id
+func (c *Client) DeleteVpcPeeringConnection(ctx context.Context, id string) error {
+	_, err := c.ec2Client.DeleteVpcPeeringConnection(ctx, &ec2.DeleteVpcPeeringConnectionInput{
+		VpcPeeringConnectionId: to.StringPtr(id),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete vpc peering connection %s", id)
+	}
+	c.Logger.Infof("vpc peering connection %s deleted", id)
+	return nil
+}
+
+func (c *Client) describeVpcPeeringConnections(ctx context.Context, input *ec2.DescribeVpcPeeringConnectionsInput) ([]types.VpcPeeringConnection, error) {
+	var connections []types.VpcPeeringConnection
+	for {
+		out, err := c.ec2Client.DescribeVpcPeeringConnections(ctx, input, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list vpc peering connections")
+		}
+		connections = append(connections, out.VpcPeeringConnections...)
+		if out.NextToken == nil || *out.NextToken == "" {
+			return connections, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// AcceptVpcPeeringConnection will accept a pending vpc peering connection request.  The client must be
+// configured with the account and region of the accepting vpc.
+func (c *Client) AcceptVpcPeeringConnection(ctx context.Context, id string) (types.VpcPeeringConnection, error) {
+	out, err := c.ec2Client.AcceptVpcPeeringConnection(ctx, &ec2.AcceptVpcPeeringConnectionInput{
+		VpcPeeringConnectionId: to.StringPtr(id),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.VpcPeeringConnection{}, errors.Wrapf(err, "failed to accept vpc peering connection %s", id)
+	}
+	if out.VpcPeeringConnection == nil {
+		return types.VpcPeeringConnection{}, errors.New("vpc peering connection missing from accept response")
+	}
+	return *out.VpcPeeringConnection, nil
+}
+
+// WaitForVpcPeeringConnection will poll the given vpc peering connection until it reaches one of the given
+// status codes, or a terminal failure status, or the context is done
+func (c *Client) WaitForVpcPeeringConnection(ctx context.Context, id string, codes ...types.VpcPeeringConnectionStateReasonCode) (types.VpcPeeringConnection, error) {
+	ticker := time.NewTicker(peeringPollInterval)
+	defer ticker.Stop()
+	for {
+		conn, err := c.GetVpcPeeringConnection(ctx, id)
+		if err != nil && !isAPIErrorCode(err, "InvalidVpcPeeringConnectionID.NotFound") {
+			return conn, err
+		}
+		if err == nil && conn.Status != nil {
+			for _, code := range codes {
+				if conn.Status.Code == code {
+					return conn, nil
+				}
+			}
+			switch conn.Status.Code {
+			case types.VpcPeeringConnectionStateReasonCodeFailed,
+				types.VpcPeeringConnectionStateReasonCodeRejected,
+				types.VpcPeeringConnectionStateReasonCodeExpired,
+				types.VpcPeeringConnectionStateReasonCodeDeleted:
+				return conn, errors.Errorf("vpc peering connection %s is %s: %s", id, conn.Status.Code, to.String(conn.Status.Message))
+			}
+			c.Logger.Debugf("vpc peering connection %s is %s, waiting", id, conn.Status.Code)
+		}
+		select {
+		case <-ctx.Done():
+			return conn, errors.Wrapf(ctx.Err(), "timed out waiting for vpc peering connection %s", id)
+		case <-ticker.C:
+		}
+	}
+}
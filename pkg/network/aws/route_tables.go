@@ -0,0 +1,251 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/pkg/errors"
+)
+
+// ListRouteTablesInVPC will list the route tables within a given vpc
+func (c *Client) ListRouteTablesInVPC(ctx context.Context, vpcID string) ([]types.RouteTable, error) {
+	input := &ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{
+			{
+				Name:   to.StringPtr("vpc-id"),
+				Values: []string{vpcID},
+			},
+		},
+	}
+	var routeTables []types.RouteTable
+	for {
+		out, err := c.ec2Client.DescribeRouteTables(ctx, input, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list route tables in vpc %s", vpcID)
+		}
+		routeTables = append(routeTables, out.RouteTables...)
+		if out.NextToken == nil || *out.NextToken == "" {
+			return routeTables, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// GetRouteTable will get a single route table by id
+func (c *Client) GetRouteTable(ctx context.Context, id string) (types.RouteTable, error) {
+	out, err := c.ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+		RouteTableIds: []string{id},
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.RouteTable{}, errors.Wrapf(err, "failed to get route table %s", id)
+	}
+	if len(out.RouteTables) == 0 {
+		return types.RouteTable{}, errors.Errorf("route table %s not found", id)
+	}
+	return out.RouteTables[0], nil
+}
+
+// CreateRouteTable will create a route table in the given vpc, with only its local route
+func (c *Client) CreateRouteTable(ctx context.Context, vpcID string, tags []types.TagSpecification) (types.RouteTable, error) {
+	out, err := c.ec2Client.CreateRouteTable(ctx, &ec2.CreateRouteTableInput{
+		VpcId:             to.StringPtr(vpcID),
+		TagSpecifications: tags,
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.RouteTable{}, errors.Wrapf(err, "failed to create route table in vpc %s", vpcID)
+	}
+	if out.RouteTable == nil {
+		return types.RouteTable{}, errors.New("route table missing from create response")
+	}
+	c.Logger.Infof("route table %s created in vpc %s", to.String(out.RouteTable.RouteTableId), vpcID)
+	return *out.RouteTable, nil
+}
+
+// AssociateRouteTable will associate a route table with the given subnet, replacing the main route table of the
+// vpc as the subnet's route table, returning the id of the association
+func (c *Client) AssociateRouteTable(ctx context.Context, id, subnetID string) (string, error) {
+	out, err := c.ec2Client.AssociateRouteTable(ctx, &ec2.AssociateRouteTableInput{
+		RouteTableId: to.StringPtr(id),
+		SubnetId:     to.StringPtr(subnetID),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to associate route table %s with subnet %s", id, subnetID)
+	}
+	c.Logger.Infof("route table %s associated with subnet %s", id, subnetID)
+	return to.String(out.AssociationId), nil
+}
+
+// DisassociateRouteTable will remove the given association of a route table with a subnet, which is then routed by
+// the main route table of its vpc
+func (c *Client) DisassociateRouteTable(ctx context.Context, associationID string) error {
+	_, err := c.ec2Client.DisassociateRouteTable(ctx, &ec2.DisassociateRouteTableInput{
+		AssociationId: to.StringPtr(associationID),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove route table association %s", associationID)
+	}
+	c.Logger.Infof("route table association %s removed", associationID)
+	return nil
+}
+
+// CreateRouteRequest is a request to route a destination cidr through exactly one of an internet gateway or a nat
+// gateway
+type CreateRouteRequest struct {
+	RouteTableID    string
+	DestinationCIDR string
+	GatewayID       string
+	NatGatewayID    string
+	// Replace will point an existing route to the destination cidr through another target at the gateway of the
+	// request, which is otherwise an error
+	Replace bool
+}
+
+// CreateRoute will route the destination cidr through the gateway of the request in the given route table.  An
+// identical, already existing route is not an error, while an existing route through another target, such as a
+// vpc peering connection, is an error unless the request replaces it.
+func (c *Client) CreateRoute(ctx context.Context, request CreateRouteRequest) error {
+	if (request.GatewayID == "") == (request.NatGatewayID == "") {
+		return errors.New("exactly one of an internet gateway or a nat gateway is required")
+	}
+	input := &ec2.CreateRouteInput{
+		RouteTableId:         to.StringPtr(request.RouteTableID),
+		DestinationCidrBlock: to.StringPtr(request.DestinationCIDR),
+	}
+	target := request.GatewayID
+	if request.GatewayID != "" {
+		input.GatewayId = to.StringPtr(request.GatewayID)
+	} else {
+		input.NatGatewayId = to.StringPtr(request.NatGatewayID)
+		target = request.NatGatewayID
+	}
+	_, err := c.ec2Client.CreateRoute(ctx, input, withLogger(newEc2Logger(c.Logger)))
+	if err == nil {
+		c.Logger.Infof("route to %s via %s created in route table %s", request.DestinationCIDR, target, request.RouteTableID)
+		return nil
+	}
+	if !isAPIErrorCode(err, "RouteAlreadyExists") {
+		return errors.Wrapf(err, "failed to create route to %s in route table %s", request.DestinationCIDR, request.RouteTableID)
+	}
+	route, err := c.getRoute(ctx, request.RouteTableID, request.DestinationCIDR)
+	if err != nil {
+		return err
+	}
+	if routeTarget(route) == target {
+		c.Logger.Debugf("route to %s already exists in route table %s", request.DestinationCIDR, request.RouteTableID)
+		return nil
+	}
+	if !request.Replace {
+		return errors.Errorf("route to %s in route table %s already exists via %s, not %s",
+			request.DestinationCIDR, request.RouteTableID, routeTarget(route), target)
+	}
+	_, err = c.ec2Client.ReplaceRoute(ctx, &ec2.ReplaceRouteInput{
+		RouteTableId:         input.RouteTableId,
+		DestinationCidrBlock: input.DestinationCidrBlock,
+		GatewayId:            input.GatewayId,
+		NatGatewayId:         input.NatGatewayId,
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to replace route to %s in route table %s", request.DestinationCIDR, request.RouteTableID)
+	}
+	c.Logger.Infof("route to %s replaced via %s, instead of %s, in route table %s",
+		request.DestinationCIDR, target, routeTarget(route), request.RouteTableID)
+	return nil
+}
+
+// getRoute will get the route to the destination cidr of the given route table
+func (c *Client) getRoute(ctx context.Context, routeTableID, destinationCIDR string) (types.Route, error) {
+	table, err := c.GetRouteTable(ctx, routeTableID)
+	if err != nil {
+		return types.Route{}, err
+	}
+	for _, route := range table.Routes {
+		if to.String(route.DestinationCidrBlock) == destinationCIDR {
+			return route, nil
+		}
+	}
+	return types.Route{}, errors.Errorf("route to %s not found in route table %s", destinationCIDR, routeTableID)
+}
+
+// CreateRouteToVpcPeeringConnection will route the destination cidr through a vpc peering connection in
+// the given route table.  An identical, already existing route is not an error, while an existing route through
+// another target is, as it may route the traffic of another peering.
+func (c *Client) CreateRouteToVpcPeeringConnection(ctx context.Context, routeTableID, destinationCIDR, peeringID string) error {
+	_, err := c.ec2Client.CreateRoute(ctx, &ec2.CreateRouteInput{
+		RouteTableId:           to.StringPtr(routeTableID),
+		DestinationCidrBlock:   to.StringPtr(destinationCIDR),
+		VpcPeeringConnectionId: to.StringPtr(peeringID),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		if !isAPIErrorCode(err, "RouteAlreadyExists") {
+			return errors.Wrapf(err, "failed to create route to %s in route table %s", destinationCIDR, routeTableID)
+		}
+		route, err := c.getRoute(ctx, routeTableID, destinationCIDR)
+		if err != nil {
+			return err
+		}
+		if to.String(route.VpcPeeringConnectionId) != peeringID {
+			return errors.Errorf("route to %s in route table %s already exists via %s, not vpc peering connection %s",
+				destinationCIDR, routeTableID, routeTarget(route), peeringID)
+		}
+		c.Logger.Debugf("route to %s already exists in route table %s", destinationCIDR, routeTableID)
+		return nil
+	}
+	c.Logger.Infof("route to %s via %s created in route table %s", destinationCIDR, peeringID, routeTableID)
+	return nil
+}
+
+// DeleteRoute will delete the route to the destination cidr from the given route table
+func (c *Client) DeleteRoute(ctx context.Context, routeTableID, destinationCIDR string) error {
+	_, err := c.ec2Client.DeleteRoute(ctx, &ec2.DeleteRouteInput{
+		RouteTableId:         to.StringPtr(routeTableID),
+		DestinationCidrBlock: to.StringPtr(destinationCIDR),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete route to %s from route table %s", destinationCIDR, routeTableID)
+	}
+	c.Logger.Infof("route to %s deleted from route table %s", destinationCIDR, routeTableID)
+	return nil
+}
+
+// DeleteRouteTable will delete the given route table id, which must not be the main route table of its vpc, nor
+// be associated with any subnet
+func (c *Client) DeleteRouteTable(ctx context.Context, id string) error {
+	_, err := c.ec2Client.DeleteRouteTable(ctx, &ec2.DeleteRouteTableInput{
+		RouteTableId: to.StringPtr(id),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete route table %s", id)
+	}
+	return nil
+}
+
+// routeTarget will return the id of the target a route is through
+func routeTarget(route types.Route) string {
+	for _, id := range []*string{
+		route.GatewayId,
+		route.NatGatewayId,
+		route.VpcPeeringConnectionId,
+		route.TransitGatewayId,
+		route.NetworkInterfaceId,
+		route.InstanceId,
+		route.EgressOnlyInternetGatewayId,
+		route.LocalGatewayId,
+		route.CarrierGatewayId,
+	} {
+		if to.String(id) != "" {
+			return to.String(id)
+		}
+	}
+	return "an unknown target"
+}
+
+func isAPIErrorCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode() == code
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeEC2 answers ec2 query api actions with canned xml responses, recording the actions called.  The responses to
+// the pages of paginated actions following the first are keyed by {action}?{next token}.
+type fakeEC2 struct {
+	mu        sync.Mutex
+	actions   []string
+	responses map[string]string
+}
+
+func (f *fakeEC2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := r.Form.Get("Action")
+	f.mu.Lock()
+	f.actions = append(f.actions, action)
+	f.mu.Unlock()
+	key := action
+	if token := r.Form.Get("NextToken"); token != "" {
+		key += "?" + token
+	}
+	response, ok := f.responses[key]
+	if !ok {
+		http.Error(w, "unexpected action "+action, http.StatusNotImplemented)
+		return
+	}
+	if strings.HasPrefix(response, "<Response>") {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	w.Write([]byte(response))
+}
+
+func newFakeClient(t *testing.T, f *fakeEC2) *Client {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	logger := logrus.New()
+	logger.SetOutput(new(strings.Builder))
+	return &Client{
+		Config: Config{Logger: logrus.NewEntry(logger)},
+		ec2Client: ec2.New(ec2.Options{
+			Region:      "us-east-1",
+			Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+			EndpointResolver: ec2.EndpointResolverFunc(func(region string, options ec2.EndpointResolverOptions) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: server.URL}, nil
+			}),
+			HTTPClient: server.Client(),
+		}),
+	}
+}
+
+const (
+	routeAlreadyExists = `<Response><Errors><Error><Code>RouteAlreadyExists</Code>` +
+		`<Message>The route identified by 0.0.0.0/0 already exists.</Message></Error></Errors><RequestID>1</RequestID></Response>`
+	routeCreated  = `<CreateRouteResponse><requestId>1</requestId><return>true</return></CreateRouteResponse>`
+	routeReplaced = `<ReplaceRouteResponse><requestId>1</requestId><return>true</return></ReplaceRouteResponse>`
+)
+
+func routeTableWith(target string) string {
+	return `<DescribeRouteTablesResponse><requestId>1</requestId><routeTableSet><item>` +
+		`<routeTableId>rtb-1</routeTableId><routeSet>` +
+		`<item><destinationCidrBlock>10.0.0.0/16</destinationCidrBlock><gatewayId>local</gatewayId></item>` +
+		`<item><destinationCidrBlock>0.0.0.0/0</destinationCidrBlock>` + target + `</item>` +
+		`</routeSet></item></routeTableSet></DescribeRouteTablesResponse>`
+}
+
+func TestCreateRoute(t *testing.T) {
+	tests := []struct {
+		name        string
+		responses   map[string]string
+		replace     bool
+		wantActions []string
+		wantErr     string
+	}{
+		{
+			name:        "created",
+			responses:   map[string]string{"CreateRoute": routeCreated},
+			wantActions: []string{"CreateRoute"},
+		},
+		{
+			name: "identical route exists",
+			responses: map[string]string{
+				"CreateRoute":         routeAlreadyExists,
+				"DescribeRouteTables": routeTableWith("<gatewayId>igw-1</gatewayId>"),
+			},
+			wantActions: []string{"CreateRoute", "DescribeRouteTables"},
+		},
+		{
+			name: "route via peering connection exists",
+			responses: map[string]string{
+				"CreateRoute":         routeAlreadyExists,
+				"DescribeRouteTables": routeTableWith("<vpcPeeringConnectionId>pcx-1</vpcPeeringConnectionId>"),
+			},
+			wantActions: []string{"CreateRoute", "DescribeRouteTables"},
+			wantErr:     "route to 0.0.0.0/0 in route table rtb-1 already exists via pcx-1, not igw-1",
+		},
+		{
+			name: "route via transit gateway replaced",
+			responses: map[string]string{
+				"CreateRoute":         routeAlreadyExists,
+				"DescribeRouteTables": routeTableWith("<transitGatewayId>tgw-1</transitGatewayId>"),
+				"ReplaceRoute":        routeReplaced,
+			},
+			replace:     true,
+			wantActions: []string{"CreateRoute", "DescribeRouteTables", "ReplaceRoute"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeEC2{responses: tt.responses}
+			c := newFakeClient(t, f)
+			err := c.CreateRoute(context.Background(), CreateRouteRequest{
+				RouteTableID:    "rtb-1",
+				DestinationCIDR: "0.0.0.0/0",
+				GatewayID:       "igw-1",
+				Replace:         tt.replace,
+			})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("CreateRoute() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("CreateRoute() error = %v", err)
+			}
+			if strings.Join(f.actions, ",") != strings.Join(tt.wantActions, ",") {
+				t.Errorf("CreateRoute() called %v, want %v", f.actions, tt.wantActions)
+			}
+		})
+	}
+}
+
+func TestCreateRouteToVpcPeeringConnection(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		wantErr   string
+	}{
+		{
+			name: "identical route exists",
+			responses: map[string]string{
+				"CreateRoute":         routeAlreadyExists,
+				"DescribeRouteTables": routeTableWith("<vpcPeeringConnectionId>pcx-1</vpcPeeringConnectionId>"),
+			},
+		},
+		{
+			name: "route via another peering connection exists",
+			responses: map[string]string{
+				"CreateRoute":         routeAlreadyExists,
+				"DescribeRouteTables": routeTableWith("<vpcPeeringConnectionId>pcx-2</vpcPeeringConnectionId>"),
+			},
+			wantErr: "route to 0.0.0.0/0 in route table rtb-1 already exists via pcx-2, not vpc peering connection pcx-1",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t, &fakeEC2{responses: tt.responses})
+			err := c.CreateRouteToVpcPeeringConnection(context.Background(), "rtb-1", "0.0.0.0/0", "pcx-1")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("CreateRouteToVpcPeeringConnection() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("CreateRouteToVpcPeeringConnection() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+)
+
+// SecurityGroupRule is a rule allowing traffic of a protocol and port range from a cidr
+type SecurityGroupRule struct {
+	// Protocol is tcp, udp, icmp, or -1 for all protocols
+	Protocol string
+	FromPort int32
+	ToPort   int32
+	CIDR     string
+}
+
+// ParseSecurityGroupRule will parse a rule given as {protocol}:{port}[-{port}]:{cidr}, such as tcp:443:0.0.0.0/0,
+// or as all:{cidr} to allow every protocol and port
+func ParseSecurityGroupRule(s string) (SecurityGroupRule, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 && strings.EqualFold(parts[0], "all") {
+		return SecurityGroupRule{Protocol: "-1", FromPort: -1, ToPort: -1, CIDR: parts[1]}, nil
+	}
+	if len(parts) != 3 {
+		return SecurityGroupRule{}, fmt.Errorf("invalid security group rule %q, must be {protocol}:{port}[-{port}]:{cidr} or all:{cidr}", s)
+	}
+	ports := strings.SplitN(parts[1], "-", 2)
+	fromPort, err := strconv.ParseInt(ports[0], 10, 32)
+	if err != nil {
+		return SecurityGroupRule{}, fmt.Errorf("invalid port range %q of security group rule %q", parts[1], s)
+	}
+	toPort := fromPort
+	if len(ports) == 2 {
+		if toPort, err = strconv.ParseInt(ports[1], 10, 32); err != nil {
+			return SecurityGroupRule{}, fmt.Errorf("invalid port range %q of security group rule %q", parts[1], s)
+		}
+	}
+	return SecurityGroupRule{Protocol: strings.ToLower(parts[0]), FromPort: int32(fromPort), ToPort: int32(toPort), CIDR: parts[2]}, nil
+}
+
+// CreateSecurityGroupRequest is a request to create a security group in a vpc, allowing the given ingress
+type CreateSecurityGroupRequest struct {
+	Name              string
+	Description       string
+	VPCId             string
+	Ingress           []SecurityGroupRule
+	TagSpecifications []types.TagSpecification
+}
+
+// CreateSecurityGroup will create a security group, which allows all egress, and the ingress of the request
+func (c *Client) CreateSecurityGroup(ctx context.Context, request CreateSecurityGroupRequest) (types.SecurityGroup, error) {
+	description := request.Description
+	if description == "" {
+		description = request.Name
+	}
+	out, err := c.ec2Client.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:         to.StringPtr(request.Name),
+		Description:       to.StringPtr(description),
+		VpcId:             to.StringPtr(request.VPCId),
+		TagSpecifications: request.TagSpecifications,
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.SecurityGroup{}, errors.Wrapf(err, "failed to create security group %s in vpc %s", request.Name, request.VPCId)
+	}
+	id := to.String(out.GroupId)
+	c.Logger.Infof("security group %s created as %s", request.Name, id)
+	if len(request.Ingress) > 0 {
+		if err = c.AuthorizeSecurityGroupIngress(ctx, id, request.Ingress...); err != nil {
+			return types.SecurityGroup{}, err
+		}
+	}
+	return c.GetSecurityGroup(ctx, id)
+}
+
+// AuthorizeSecurityGroupIngress will allow the ingress of the given rules in a security group.  Rules which are
+// already allowed are not an error.
+func (c *Client) AuthorizeSecurityGroupIngress(ctx context.Context, id string, rules ...SecurityGroupRule) error {
+	permissions := make([]types.IpPermission, 0, len(rules))
+	for _, rule := range rules {
+		permissions = append(permissions, types.IpPermission{
+			IpProtocol: to.StringPtr(rule.Protocol),
+			FromPort:   rule.FromPort,
+			ToPort:     rule.ToPort,
+			IpRanges:   []types.IpRange{{CidrIp: to.StringPtr(rule.CIDR)}},
+		})
+	}
+	_, err := c.ec2Client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       to.StringPtr(id),
+		IpPermissions: permissions,
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		if isAPIErrorCode(err, "InvalidPermission.Duplicate") {
+			c.Logger.Debugf("ingress rules already allowed in security group %s", id)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to authorize ingress in security group %s", id)
+	}
+	c.Logger.Infof("%d ingress rules authorized in security group %s", len(rules), id)
+	return nil
+}
+
+// GetSecurityGroup will get a single security group by id
+func (c *Client) GetSecurityGroup(ctx context.Context, id string) (types.SecurityGroup, error) {
+	out, err := c.ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		GroupIds: []string{id},
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.SecurityGroup{}, errors.Wrapf(err, "failed to get security group %s", id)
+	}
+	if len(out.SecurityGroups) == 0 {
+		return types.SecurityGroup{}, errors.Errorf("security group %s not found", id)
+	}
+	return out.SecurityGroups[0], nil
+}
+
+// ListSecurityGroupsInVPC will list the security groups within a given vpc, including its default group
+func (c *Client) ListSecurityGroupsInVPC(ctx context.Context, vpcID string) ([]types.SecurityGroup, error) {
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			{
+				Name:   to.StringPtr("vpc-id"),
+				Values: []string{vpcID},
+			},
+		},
+	}
+	var groups []types.SecurityGroup
+	for {
+		out, err := c.ec2Client.DescribeSecurityGroups(ctx, input, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list security groups in vpc %s", vpcID)
+		}
+		groups = append(groups, out.SecurityGroups...)
+		if out.NextToken == nil || *out.NextToken == "" {
+			return groups, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// DeleteSecurityGroup will delete the given security group id
+func (c *Client) DeleteSecurityGroup(ctx context.Context, id string) error {
+	_, err := c.ec2Client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{
+		GroupId: to.StringPtr(id),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete security group %s", id)
+	}
+	return nil
+}
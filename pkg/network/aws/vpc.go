@@ -4,16 +4,25 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/smithy-go/logging"
 	aws_auth "github.com/naemono/go-cloud-actions/pkg/auth/aws"
+	"github.com/naemono/go-cloud-actions/pkg/ipam"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	// ErrNotFound is the error when a vpc cannot be found
+	ErrNotFound = errors.New("vpc not found")
+	// ErrDryRun is the error of a dry run request which would have succeeded, had it not been a dry run
+	ErrDryRun = errors.New("dry run succeeded")
+)
+
 // Config is an aws network config
 type Config struct {
 	aws_auth.AuthConfig
@@ -32,15 +41,22 @@ type CreateVpcRequest struct {
 	InstanceTenacy    types.Tenancy
 	TagSpecifications []types.TagSpecification
 	DryRun            bool
+	// Wait is how long to wait for the vpc to be available, or zero to return it as soon as it is created
+	Wait time.Duration
 }
 
 // CreateVpcSubnetRequest is a request to create a subnet within a vpc
 type CreateVpcSubnetRequest struct {
-	CidrBlock         string
+	CidrBlock string
+	// PrefixLength allocates the next free cidr block of the vpc with a prefix of this many bits, when CidrBlock is
+	// empty
+	PrefixLength      int
 	VPCId             string
 	AvailabilityZone  string
 	TagSpecifications []types.TagSpecification
 	DryRun            bool
+	// Wait is how long to wait for the subnet to be available, or zero to return it as soon as it is created
+	Wait time.Duration
 }
 
 // ec2Logger is a Logger implementation that wraps the standard library logger, and delegates logging to it's
@@ -89,10 +105,11 @@ func New(conf Config) (*Client, error) {
 	return c, nil
 }
 
-// CreateVPC will create an aws vpc
-func (c *Client) CreateVPC(ctx context.Context, request CreateVpcRequest) error {
+// CreateVPC will create an aws vpc, and return it once available when request.Wait is given.  A dry run returns
+// ErrDryRun when the vpc would have been created.
+func (c *Client) CreateVPC(ctx context.Context, request CreateVpcRequest) (types.Vpc, error) {
 	if len(request.TagSpecifications) == 0 {
-		return fmt.Errorf("tags are required")
+		return types.Vpc{}, fmt.Errorf("tags are required")
 	}
 	var found bool
 	for _, s := range request.TagSpecifications {
@@ -103,15 +120,32 @@ func (c *Client) CreateVPC(ctx context.Context, request CreateVpcRequest) error
 		}
 	}
 	if !found {
-		return fmt.Errorf("name tag is required")
+		return types.Vpc{}, fmt.Errorf("name tag is required")
 	}
-	c.ec2Client.CreateVpc(ctx, &ec2.CreateVpcInput{
+	response, err := c.ec2Client.CreateVpc(ctx, &ec2.CreateVpcInput{
 		CidrBlock:         &request.CidrBlock,
 		InstanceTenancy:   request.InstanceTenacy,
 		DryRun:            request.DryRun,
 		TagSpecifications: request.TagSpecifications,
 	}, withLogger(newEc2Logger(c.Logger)))
-	return nil
+	if isAPIErrorCode(err, "DryRunOperation") {
+		return types.Vpc{}, ErrDryRun
+	}
+	if err != nil {
+		return types.Vpc{}, errors.Wrap(err, "failed to create vpc")
+	}
+	vpc := *response.Vpc
+	c.Logger.Infof("vpc id %s created", to.String(vpc.VpcId))
+	if request.Wait == 0 {
+		return vpc, nil
+	}
+	err = ec2.NewVpcAvailableWaiter(c.ec2Client).Wait(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []string{to.String(vpc.VpcId)},
+	}, request.Wait)
+	if err != nil {
+		return vpc, errors.Wrapf(err, "failed waiting for vpc id %s to be available", to.String(vpc.VpcId))
+	}
+	return c.GetVPC(ctx, to.String(vpc.VpcId))
 }
 
 // ListVPCs will list vpcs in the region in which the client is configured
@@ -137,31 +171,186 @@ func (c *Client) DeleteVPC(ctx context.Context, id string) error {
 	return nil
 }
 
-// CreateSubnetInVPC will attempt to create a subnet within a given vpc id
-func (c *Client) CreateSubnetInVPC(ctx context.Context, request CreateVpcSubnetRequest) error {
-	_, err := c.ec2Client.CreateSubnet(ctx, &ec2.CreateSubnetInput{
-		CidrBlock:        &request.CidrBlock,
-		VpcId:            &request.VPCId,
-		AvailabilityZone: &request.AvailabilityZone,
+// CreateSubnetInVPC will attempt to create a subnet within a given vpc id, and return it once available when
+// request.Wait is given.  A dry run returns ErrDryRun when the subnet would have been created.
+func (c *Client) CreateSubnetInVPC(ctx context.Context, request CreateVpcSubnetRequest) (types.Subnet, error) {
+	if request.CidrBlock == "" {
+		if request.PrefixLength == 0 {
+			return types.Subnet{}, errors.New("one of cidr block or prefix length is required")
+		}
+		cidr, err := c.NextSubnetCIDR(ctx, request.VPCId, request.PrefixLength)
+		if err != nil {
+			return types.Subnet{}, err
+		}
+		request.CidrBlock = cidr
+	}
+	var availabilityZone *string
+	if request.AvailabilityZone != "" {
+		availabilityZone = &request.AvailabilityZone
+	}
+	response, err := c.ec2Client.CreateSubnet(ctx, &ec2.CreateSubnetInput{
+		CidrBlock:         &request.CidrBlock,
+		VpcId:             &request.VPCId,
+		AvailabilityZone:  availabilityZone,
+		DryRun:            request.DryRun,
+		TagSpecifications: request.TagSpecifications,
 	}, withLogger(newEc2Logger(c.Logger)))
+	if isAPIErrorCode(err, "DryRunOperation") {
+		return types.Subnet{}, ErrDryRun
+	}
 	if err != nil {
-		return errors.Wrap(err, "failed to create subnet in vpc")
+		return types.Subnet{}, errors.Wrap(err, "failed to create subnet in vpc")
 	}
-	return nil
+	subnet := *response.Subnet
+	c.Logger.Infof("subnet id %s created in vpc id %s", to.String(subnet.SubnetId), request.VPCId)
+	if request.Wait == 0 {
+		return subnet, nil
+	}
+	input := &ec2.DescribeSubnetsInput{SubnetIds: []string{to.String(subnet.SubnetId)}}
+	if err = ec2.NewSubnetAvailableWaiter(c.ec2Client).Wait(ctx, input, request.Wait); err != nil {
+		return subnet, errors.Wrapf(err, "failed waiting for subnet id %s to be available", to.String(subnet.SubnetId))
+	}
+	out, err := c.ec2Client.DescribeSubnets(ctx, input, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return subnet, errors.Wrapf(err, "failed to get subnet id %s", to.String(subnet.SubnetId))
+	}
+	if len(out.Subnets) == 0 {
+		return subnet, errors.Errorf("subnet id %s not found", to.String(subnet.SubnetId))
+	}
+	return out.Subnets[0], nil
 }
 
 // ListSubnetsInVPC will list the existing subnet within a given vpc
 func (c *Client) ListSubnetsInVPC(ctx context.Context, vpcID string) ([]types.Subnet, error) {
-	out, err := c.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+	paginator := ec2.NewDescribeSubnetsPaginator(c.ec2Client, &ec2.DescribeSubnetsInput{
 		Filters: []types.Filter{
 			{
 				Name:   to.StringPtr("vpc-id"),
 				Values: []string{vpcID},
 			},
 		},
+	})
+	var subnets []types.Subnet
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx, withLogger(newEc2Logger(c.Logger)))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list subnets in vpc")
+		}
+		subnets = append(subnets, out.Subnets...)
+	}
+	return subnets, nil
+}
+
+// NextSubnetCIDR will return the first cidr block with a prefix of prefixLength bits, within the cidr blocks
+// associated with a vpc, which overlaps none of its subnets
+func (c *Client) NextSubnetCIDR(ctx context.Context, vpcID string, prefixLength int) (string, error) {
+	vpc, err := c.GetVPC(ctx, vpcID)
+	if err != nil {
+		return "", err
+	}
+	subnets, err := c.ListSubnetsInVPC(ctx, vpcID)
+	if err != nil {
+		return "", err
+	}
+	existing := make([]string, 0, len(subnets))
+	for _, subnet := range subnets {
+		// ipv6 only subnets have no ipv4 cidr block
+		if subnet.CidrBlock != nil && *subnet.CidrBlock != "" {
+			existing = append(existing, *subnet.CidrBlock)
+		}
+	}
+	for _, parent := range VPCCIDRBlocks(vpc) {
+		if !ipam.IsIPv4(parent) {
+			continue
+		}
+		cidr, err := ipam.Next(parent, prefixLength, existing)
+		if errors.Is(err, ipam.ErrExhausted) {
+			continue
+		}
+		return cidr, err
+	}
+	return "", errors.Wrapf(ipam.ErrExhausted, "no free /%d block in vpc %s", prefixLength, vpcID)
+}
+
+// VPCCIDRBlocks will return the ipv4 cidr blocks associated with a vpc
+func VPCCIDRBlocks(vpc types.Vpc) []string {
+	blocks := []string{}
+	for _, association := range vpc.CidrBlockAssociationSet {
+		if association.CidrBlockState != nil && association.CidrBlockState.State != types.VpcCidrBlockStateCodeAssociated {
+			continue
+		}
+		blocks = append(blocks, to.String(association.CidrBlock))
+	}
+	if len(blocks) == 0 && vpc.CidrBlock != nil {
+		blocks = append(blocks, to.String(vpc.CidrBlock))
+	}
+	return blocks
+}
+
+// DeleteSubnet will delete the given subnet id
+func (c *Client) DeleteSubnet(ctx context.Context, id string) error {
+	_, err := c.ec2Client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{
+		SubnetId: to.StringPtr(id),
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete subnet %s", id)
+	}
+	return nil
+}
+
+// FindVPCsByTags will list the vpcs having every one of the given tags, of which there must be at least one
+func (c *Client) FindVPCsByTags(ctx context.Context, tags map[string]string) ([]types.Vpc, error) {
+	if len(tags) == 0 {
+		return nil, errors.New("tags cannot be empty")
+	}
+	filters := []types.Filter{}
+	for key, value := range tags {
+		filters = append(filters, types.Filter{
+			Name:   to.StringPtr("tag:" + key),
+			Values: []string{value},
+		})
+	}
+	response, err := c.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		Filters: filters,
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find vpcs by tags")
+	}
+	return response.Vpcs, nil
+}
+
+// GetVPC will get a single vpc by id
+func (c *Client) GetVPC(ctx context.Context, id string) (types.Vpc, error) {
+	response, err := c.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []string{id},
+	}, withLogger(newEc2Logger(c.Logger)))
+	if err != nil {
+		return types.Vpc{}, errors.Wrapf(err, "failed to get vpc id %s", id)
+	}
+	if len(response.Vpcs) == 0 {
+		return types.Vpc{}, errors.Errorf("vpc id %s not found", id)
+	}
+	return response.Vpcs[0], nil
+}
+
+// FindVPCByName will get a single vpc by its Name tag, returning ErrNotFound if none has the name
+func (c *Client) FindVPCByName(ctx context.Context, name string) (types.Vpc, error) {
+	response, err := c.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		Filters: []types.Filter{
+			{
+				Name:   to.StringPtr("tag:Name"),
+				Values: []string{name},
+			},
+		},
 	}, withLogger(newEc2Logger(c.Logger)))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to list subnets in vpc")
+		return types.Vpc{}, errors.Wrapf(err, "failed to find vpc named %s", name)
+	}
+	switch len(response.Vpcs) {
+	case 0:
+		return types.Vpc{}, ErrNotFound
+	case 1:
+		return response.Vpcs[0], nil
 	}
-	return out.Subnets, nil
+	return types.Vpc{}, errors.Errorf("found %d vpcs named %s", len(response.Vpcs), name)
 }
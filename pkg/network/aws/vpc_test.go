@@ -0,0 +1,42 @@
+package aws
+
+import (
+	"context"
+	"testing"
+)
+
+const vpcWithCIDRBlocks = `<DescribeVpcsResponse><requestId>1</requestId><vpcSet><item>` +
+	`<vpcId>vpc-1</vpcId><cidrBlock>10.0.0.0/16</cidrBlock><cidrBlockAssociationSet>` +
+	`<item><cidrBlock>10.0.0.0/16</cidrBlock><cidrBlockState><state>associated</state></cidrBlockState></item>` +
+	`</cidrBlockAssociationSet></item></vpcSet></DescribeVpcsResponse>`
+
+func subnetsPage(nextToken string, cidrs ...string) string {
+	page := `<DescribeSubnetsResponse><requestId>1</requestId><subnetSet>`
+	for _, cidr := range cidrs {
+		page += `<item><subnetId>subnet-` + cidr + `</subnetId><cidrBlock>` + cidr + `</cidrBlock></item>`
+	}
+	page += `</subnetSet>`
+	if nextToken != "" {
+		page += `<nextToken>` + nextToken + `</nextToken>`
+	}
+	return page + `</DescribeSubnetsResponse>`
+}
+
+func TestNextSubnetCIDR(t *testing.T) {
+	f := &fakeEC2{responses: map[string]string{
+		"DescribeVpcs": vpcWithCIDRBlocks,
+		// the last page holds the subnet of the first block free on the first pages, and the second an ipv6 only
+		// subnet without an ipv4 cidr block
+		"DescribeSubnets":        subnetsPage("page-2", "10.0.0.0/24"),
+		"DescribeSubnets?page-2": subnetsPage("page-3", "10.0.1.0/24", ""),
+		"DescribeSubnets?page-3": subnetsPage("", "10.0.2.0/24"),
+	}}
+	c := newFakeClient(t, f)
+	got, err := c.NextSubnetCIDR(context.Background(), "vpc-1", 24)
+	if err != nil {
+		t.Fatalf("NextSubnetCIDR() error = %v", err)
+	}
+	if got != "10.0.3.0/24" {
+		t.Errorf("NextSubnetCIDR() = %s, want 10.0.3.0/24", got)
+	}
+}
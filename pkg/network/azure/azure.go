@@ -3,26 +3,42 @@ package azure
 import (
 	"context"
 	"net"
+	"net/http"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2020-11-01/network"
+	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/to"
 
 	"github.com/pkg/errors"
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/davecgh/go-spew/spew"
-
 	azure_auth "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	"github.com/naemono/go-cloud-actions/pkg/ipam"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
 )
 
-const aciDelegationServiceName = "Microsoft.ContainerInstance/containerGroups"
+const (
+	aciDelegationServiceName = "Microsoft.ContainerInstance/containerGroups"
+	// DefaultVnetAddressCIDR is the address space of a vnet created for a network profile request without one
+	DefaultVnetAddressCIDR = "10.0.0.0/16"
+	// DefaultSubnetPrefixLength is the prefix length of the subnet allocated for a network profile request without
+	// a subnet address cidr or prefix length
+	DefaultSubnetPrefixLength = 24
+)
+
+var (
+	// ErrNotFound is the error when a network profile cannot be found
+	ErrNotFound = errors.New("network profile not found")
+)
 
 // Config is the configuration for the azure networks peering package
 type Config struct {
 	azure_auth.AuthConfig
 	Logger *logrus.Entry
+	// Factory is the azure client factory to share with other clients, created from AuthConfig when nil
+	Factory *azure_auth.ClientFactory
 }
 
 // Client is the client for the azure networks peering package
@@ -33,6 +49,13 @@ type Client struct {
 	snetClient network.SubnetsClient
 }
 
+// NetworkProfile is a created network profile, along with the address cidr of the subnet it places container
+// groups in
+type NetworkProfile struct {
+	Profile    network.Profile `json:"profile"`
+	SubnetCIDR string          `json:"subnetCidr"`
+}
+
 // NetworkProfileRequest is a request for a new network profile
 type NetworkProfileRequest struct {
 	Name              string
@@ -42,6 +65,11 @@ type NetworkProfileRequest struct {
 	VnetAddressCIDR   string
 	SubnetName        string
 	SubnetAddressCIDR string
+	// SubnetPrefixLength allocates the next free block of the vnet's address space with a prefix of this many bits to
+	// a created subnet, when SubnetAddressCIDR is empty
+	SubnetPrefixLength int
+	// Tags are applied to the network profile, and to a created vnet
+	Tags tags.Tags
 }
 
 // New will return a new azure networks client
@@ -50,50 +78,55 @@ func New(conf Config) (*Client, error) {
 	c := &Client{
 		Config: conf,
 	}
-	c.profClient, err = azure_auth.NewNetworkProfilesClient(conf.AuthConfig)
+	if c.Logger == nil {
+		c.Logger = logrus.NewEntry(logrus.New())
+		c.Logger.Logger.SetLevel(logrus.InfoLevel)
+		c.Logger.Logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+	if c.Factory == nil {
+		c.Factory, err = azure_auth.NewClientFactory(azure_auth.FactoryConfig{AuthConfig: conf.AuthConfig, Logger: c.Logger})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get new azure client factory")
+		}
+	}
+	c.profClient, err = c.Factory.NetworkProfilesClient()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate new network profiles client")
 	}
-	c.vnetClient, err = azure_auth.NewVirtualNetworksClient(conf.AuthConfig)
+	c.vnetClient, err = c.Factory.VirtualNetworksClient()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate new  virtual networks client")
 	}
-	c.snetClient, err = azure_auth.NewSubnetsClient(conf.AuthConfig)
+	c.snetClient, err = c.Factory.SubnetsClient()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate new network subnets client")
 	}
-	if c.Logger == nil {
-		c.Logger = logrus.NewEntry(logrus.New())
-		c.Logger.Logger.SetLevel(logrus.InfoLevel)
-		c.Logger.Logger.SetFormatter(&logrus.JSONFormatter{})
-	}
 	return c, nil
 }
 
-// CreateNetworkProfile will create an azure network profile
-func (c *Client) CreateNetworkProfile(ctx context.Context, req NetworkProfileRequest) error {
+// CreateNetworkProfile will create an azure network profile, along with its vnet and subnet when they do not exist,
+// returning the profile and the address cidr of its subnet
+func (c *Client) CreateNetworkProfile(ctx context.Context, req NetworkProfileRequest) (NetworkProfile, error) {
 	err := validateNetworkProfileRequest(req)
 	if err != nil {
-		return err
+		return NetworkProfile{}, err
 	}
 	if req.VnetAddressCIDR == "" {
-		req.VnetAddressCIDR = "10.0.0.0/16"
+		c.Logger.Infof("vnet address cidr defaulting to %s", DefaultVnetAddressCIDR)
+		req.VnetAddressCIDR = DefaultVnetAddressCIDR
 	}
-	if req.SubnetAddressCIDR == "" {
-		req.SubnetAddressCIDR = "10.0.0.0/24"
-	}
-	err = c.ensureVnet(ctx, req)
+	vnet, err := c.ensureVnet(ctx, req)
 	if err != nil {
-		return err
+		return NetworkProfile{}, err
 	}
-	var snet network.Subnet
-	snet, err = c.ensureSubnet(ctx, req)
+	snet, err := c.ensureSubnet(ctx, vnet, req)
 	if err != nil {
-		return err
+		return NetworkProfile{}, err
 	}
 	// Path properties.containerNetworkInterfaceConfigurations[0].properties.ipConfigurations[0].properties.subnet.
-	_, err = c.profClient.CreateOrUpdate(ctx, req.ResourceGroupName, req.Name, network.Profile{
+	profile, err := c.profClient.CreateOrUpdate(ctx, req.ResourceGroupName, req.Name, network.Profile{
 		Location: to.StringPtr(req.Location),
+		Tags:     req.Tags.Azure(),
 		ProfilePropertiesFormat: &network.ProfilePropertiesFormat{
 			ContainerNetworkInterfaceConfigurations: &[]network.ContainerNetworkInterfaceConfiguration{
 				{
@@ -116,17 +149,18 @@ func (c *Client) CreateNetworkProfile(ctx context.Context, req NetworkProfileReq
 		},
 	})
 	if err != nil {
-		return errors.Wrapf(err, "failed to create network profile %s", req.Name)
+		return NetworkProfile{}, errors.Wrapf(err, "failed to create network profile %s", req.Name)
 	}
-	return nil
+	return NetworkProfile{Profile: profile, SubnetCIDR: strings.Join(subnetPrefixes(snet), ",")}, nil
 }
 
-func (c *Client) ensureVnet(ctx context.Context, req NetworkProfileRequest) (err error) {
-	_, err = c.vnetClient.Get(ctx, req.ResourceGroupName, req.VnetName, "")
+func (c *Client) ensureVnet(ctx context.Context, req NetworkProfileRequest) (network.VirtualNetwork, error) {
+	vnet, err := c.vnetClient.Get(ctx, req.ResourceGroupName, req.VnetName, "")
 	if err != nil && strings.Contains(err.Error(), "found") {
 		c.Logger.Infof("vnet %s was not found, attempting create", req.VnetName)
 		res, err := c.vnetClient.CreateOrUpdate(ctx, req.ResourceGroupName, req.VnetName, network.VirtualNetwork{
 			Location: to.StringPtr(req.Location),
+			Tags:     req.Tags.Azure(),
 			VirtualNetworkPropertiesFormat: &network.VirtualNetworkPropertiesFormat{
 				AddressSpace: &network.AddressSpace{
 					AddressPrefixes: to.StringSlicePtr([]string{req.VnetAddressCIDR}),
@@ -134,27 +168,32 @@ func (c *Client) ensureVnet(ctx context.Context, req NetworkProfileRequest) (err
 			},
 		})
 		if err != nil {
-			return errors.Wrap(err, "failed to create vnet")
+			return vnet, errors.Wrap(err, "failed to create vnet")
 		}
 		if err = res.WaitForCompletionRef(ctx, c.vnetClient.Client); err != nil {
-			return errors.Wrap(err, "failed to wait on vnet creation")
+			return vnet, errors.Wrap(err, "failed to wait on vnet creation")
 		}
 		c.Logger.Infof("vnet %s was created", req.VnetName)
+		return res.Result(c.vnetClient)
 	} else if err != nil {
-		return errors.Wrap(err, "request to create vnet failed")
+		return vnet, errors.Wrap(err, "request to create vnet failed")
 	}
 	c.Logger.Infof("vnet %s already exists", req.VnetName)
-	return nil
+	return vnet, nil
 }
 
-func (c *Client) ensureSubnet(ctx context.Context, req NetworkProfileRequest) (snet network.Subnet, err error) {
+func (c *Client) ensureSubnet(ctx context.Context, vnet network.VirtualNetwork, req NetworkProfileRequest) (snet network.Subnet, err error) {
 	snet, err = c.snetClient.Get(ctx, req.ResourceGroupName, req.VnetName, req.SubnetName, "")
 	if err != nil && strings.Contains(err.Error(), "found") {
 		c.Logger.Infof("subnet %s was not found, attempting create", req.SubnetName)
+		cidr, err := subnetCIDR(vnet, req)
+		if err != nil {
+			return snet, err
+		}
 		res, err := c.snetClient.CreateOrUpdate(ctx, req.ResourceGroupName, req.VnetName, req.SubnetName, network.Subnet{
 			Name: &req.SubnetName,
 			SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
-				AddressPrefix: &req.SubnetAddressCIDR,
+				AddressPrefix: &cidr,
 				Delegations: &[]network.Delegation{
 					{
 						Name: to.StringPtr(aciDelegationServiceName),
@@ -171,7 +210,7 @@ func (c *Client) ensureSubnet(ctx context.Context, req NetworkProfileRequest) (s
 		if err = res.WaitForCompletionRef(ctx, c.snetClient.Client); err != nil {
 			return snet, errors.Wrap(err, "failed to wait on subnet creation")
 		}
-		c.Logger.Infof("subnet %s was created", req.SubnetName)
+		c.Logger.Infof("subnet %s was created with address cidr %s", req.SubnetName, cidr)
 		return res.Result(c.snetClient)
 	} else if err != nil {
 		return snet, errors.Wrap(err, "request to create subnet failed")
@@ -180,6 +219,96 @@ func (c *Client) ensureSubnet(ctx context.Context, req NetworkProfileRequest) (s
 	return snet, nil
 }
 
+// SubnetCIDR will return the address cidr of the subnet of a network profile request: that of its subnet when it
+// already exists, or else its subnet address cidr, which must be within the address space of its vnet and overlap
+// none of the vnet's subnets, or the next free block of its subnet prefix length within the address space
+func (c *Client) SubnetCIDR(ctx context.Context, req NetworkProfileRequest) (string, error) {
+	if err := validateNetworkProfileRequest(req); err != nil {
+		return "", err
+	}
+	vnet, err := c.vnetClient.Get(ctx, req.ResourceGroupName, req.VnetName, "")
+	if isNotFound(err) {
+		if req.VnetAddressCIDR == "" {
+			req.VnetAddressCIDR = DefaultVnetAddressCIDR
+		}
+		vnet = network.VirtualNetwork{
+			VirtualNetworkPropertiesFormat: &network.VirtualNetworkPropertiesFormat{
+				AddressSpace: &network.AddressSpace{AddressPrefixes: to.StringSlicePtr([]string{req.VnetAddressCIDR})},
+			},
+		}
+	} else if err != nil {
+		return "", errors.Wrapf(err, "failed to get vnet %s", req.VnetName)
+	}
+	for _, subnet := range vnetSubnets(vnet) {
+		if to.String(subnet.Name) == req.SubnetName {
+			return strings.Join(subnetPrefixes(subnet), ","), nil
+		}
+	}
+	return subnetCIDR(vnet, req)
+}
+
+// subnetCIDR will return the address cidr of a new subnet of the vnet for a network profile request
+func subnetCIDR(vnet network.VirtualNetwork, req NetworkProfileRequest) (string, error) {
+	var space []string
+	if vnet.VirtualNetworkPropertiesFormat != nil && vnet.AddressSpace != nil && vnet.AddressSpace.AddressPrefixes != nil {
+		space = *vnet.AddressSpace.AddressPrefixes
+	}
+	existing := []string{}
+	for _, subnet := range vnetSubnets(vnet) {
+		existing = append(existing, subnetPrefixes(subnet)...)
+	}
+	if req.SubnetAddressCIDR != "" {
+		var err error
+		for _, parent := range space {
+			if !ipam.IsIPv4(parent) {
+				continue
+			}
+			if err = ipam.Validate(parent, req.SubnetAddressCIDR, existing); err == nil {
+				return req.SubnetAddressCIDR, nil
+			}
+		}
+		if err == nil {
+			err = errors.Errorf("vnet %s has no address space", to.String(vnet.Name))
+		}
+		return "", errors.Wrapf(err, "invalid subnet address cidr of vnet %s", to.String(vnet.Name))
+	}
+	prefixLength := req.SubnetPrefixLength
+	if prefixLength == 0 {
+		prefixLength = DefaultSubnetPrefixLength
+	}
+	for _, parent := range space {
+		if !ipam.IsIPv4(parent) {
+			continue
+		}
+		cidr, err := ipam.Next(parent, prefixLength, existing)
+		if errors.Is(err, ipam.ErrExhausted) {
+			continue
+		}
+		return cidr, err
+	}
+	return "", errors.Wrapf(ipam.ErrExhausted, "no free /%d block in vnet %s", prefixLength, to.String(vnet.Name))
+}
+
+func vnetSubnets(vnet network.VirtualNetwork) []network.Subnet {
+	if vnet.VirtualNetworkPropertiesFormat == nil || vnet.Subnets == nil {
+		return nil
+	}
+	return *vnet.Subnets
+}
+
+func subnetPrefixes(subnet network.Subnet) []string {
+	if subnet.SubnetPropertiesFormat == nil {
+		return nil
+	}
+	if subnet.AddressPrefixes != nil && len(*subnet.AddressPrefixes) > 0 {
+		return *subnet.AddressPrefixes
+	}
+	if subnet.AddressPrefix != nil {
+		return []string{*subnet.AddressPrefix}
+	}
+	return nil
+}
+
 func validateNetworkProfileRequest(req NetworkProfileRequest) (err error) {
 	if req.Location == "" {
 		return errors.New("location cannot be empty")
@@ -208,17 +337,63 @@ func validateNetworkProfileRequest(req NetworkProfileRequest) (err error) {
 			return errors.Wrap(err, "subnet address cidr is invalid")
 		}
 	}
+	if req.SubnetAddressCIDR != "" && req.SubnetPrefixLength != 0 {
+		return errors.New("only one of subnet address cidr or subnet prefix length can be given")
+	}
+	if req.SubnetPrefixLength < 0 || req.SubnetPrefixLength > 29 {
+		return errors.New("subnet prefix length must be between 0 and 29")
+	}
 	return nil
 }
 
-// ListNetworkProfiles will list all network profiles for a given resource group
-func (c *Client) ListNetworkProfiles(ctx context.Context, resourceGroupName string) error {
-	res, err := c.profClient.List(ctx, resourceGroupName)
+// ListNetworkProfiles will list all network profiles for a given resource group, following every page of results
+func (c *Client) ListNetworkProfiles(ctx context.Context, resourceGroupName string) ([]network.Profile, error) {
+	page, err := c.profClient.List(ctx, resourceGroupName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list network profile in resource group %s", resourceGroupName)
+	}
+	profiles := []network.Profile{}
+	for page.NotDone() {
+		profiles = append(profiles, page.Values()...)
+		if err = page.NextWithContext(ctx); err != nil {
+			return nil, errors.Wrapf(err, "failed to list next page of network profiles in resource group %s", resourceGroupName)
+		}
+	}
+	return profiles, nil
+}
+
+// GetNetworkProfile will get a single network profile, returning ErrNotFound if it does not exist
+func (c *Client) GetNetworkProfile(ctx context.Context, resourceGroupName, name string) (network.Profile, error) {
+	profile, err := c.profClient.Get(ctx, resourceGroupName, name, "")
 	if err != nil {
-		return errors.Wrapf(err, "failed to list network profile in resource group %s", resourceGroupName)
+		if isNotFound(err) {
+			return profile, ErrNotFound
+		}
+		return profile, errors.Wrapf(err, "failed to get network profile %s", name)
 	}
-	for _, r := range res.Values() {
-		c.Logger.Infof("profile: %s", spew.Sdump(r))
+	return profile, nil
+}
+
+// DeleteNetworkProfile will delete a network profile, which must not be used by any container group, and wait for
+// the deletion to complete.  A network profile which does not exist is not an error.
+func (c *Client) DeleteNetworkProfile(ctx context.Context, resourceGroupName, name string) error {
+	future, err := c.profClient.Delete(ctx, resourceGroupName, name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete network profile %s", name)
+	}
+	if err = future.WaitForCompletionRef(ctx, c.profClient.Client); err != nil {
+		return errors.Wrapf(err, "failed waiting for network profile %s to be deleted", name)
 	}
 	return nil
 }
+
+func isNotFound(err error) bool {
+	var de autorest.DetailedError
+	if errors.As(err, &de) {
+		return de.StatusCode == http.StatusNotFound
+	}
+	return false
+}
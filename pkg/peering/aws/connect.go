@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+
+	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+)
+
+// Accept will accept a pending vpc peering connection using the peer auth config
+func (p *Peerer) Accept(ctx context.Context, id string) (peering.Peering, error) {
+	conn, err := p.peerClient.AcceptVpcPeeringConnection(ctx, id)
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	return toPeering(conn), nil
+}
+
+// WaitUntilActive will wait until the vpc peering connection is active, or has failed
+func (p *Peerer) WaitUntilActive(ctx context.Context, id string) (peering.Peering, error) {
+	conn, err := p.client.WaitForVpcPeeringConnection(ctx, id, types.VpcPeeringConnectionStateReasonCodeActive)
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	return toPeering(conn), nil
+}
+
+// WaitUntilPendingAcceptance will wait until the vpc peering connection is ready to be accepted by the peer
+func (p *Peerer) WaitUntilPendingAcceptance(ctx context.Context, id string) (peering.Peering, error) {
+	conn, err := p.client.WaitForVpcPeeringConnection(
+		ctx,
+		id,
+		types.VpcPeeringConnectionStateReasonCodePendingAcceptance,
+		types.VpcPeeringConnectionStateReasonCodeProvisioning,
+		types.VpcPeeringConnectionStateReasonCodeActive)
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	return toPeering(conn), nil
+}
+
+// CreateRoutes will add routes for the peer's cidr blocks through the vpc peering connection to every
+// route table of both the requester vpc (using the auth config) and the accepter vpc (using the peer
+// auth config).  The vpc peering connection must be active.
+func (p *Peerer) CreateRoutes(ctx context.Context, id string) error {
+	conn, err := p.client.GetVpcPeeringConnection(ctx, id)
+	if err != nil {
+		return err
+	}
+	if conn.RequesterVpcInfo == nil || conn.AccepterVpcInfo == nil {
+		return errors.Errorf("vpc peering connection %s is missing vpc information", id)
+	}
+	if err = addRoutes(ctx, p.client, to.String(conn.RequesterVpcInfo.VpcId), cidrBlocks(conn.AccepterVpcInfo), id); err != nil {
+		return err
+	}
+	return addRoutes(ctx, p.peerClient, to.String(conn.AccepterVpcInfo.VpcId), cidrBlocks(conn.RequesterVpcInfo), id)
+}
+
+// DeleteRoutes will remove every route through the vpc peering connection from the route tables of both
+// the requester and accepter vpcs
+func (p *Peerer) DeleteRoutes(ctx context.Context, id string) error {
+	conn, err := p.client.GetVpcPeeringConnection(ctx, id)
+	if err != nil {
+		return err
+	}
+	if conn.RequesterVpcInfo != nil {
+		if err = deleteRoutes(ctx, p.client, to.String(conn.RequesterVpcInfo.VpcId), id); err != nil {
+			return err
+		}
+	}
+	if conn.AccepterVpcInfo != nil {
+		return deleteRoutes(ctx, p.peerClient, to.String(conn.AccepterVpcInfo.VpcId), id)
+	}
+	return nil
+}
+
+func addRoutes(ctx context.Context, client *aws_network.Client, vpcID string, cidrs []string, peeringID string) error {
+	routeTables, err := client.ListRouteTablesInVPC(ctx, vpcID)
+	if err != nil {
+		return err
+	}
+	for _, rt := range routeTables {
+		for _, cidr := range cidrs {
+			if err = client.CreateRouteToVpcPeeringConnection(ctx, to.String(rt.RouteTableId), cidr, peeringID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func deleteRoutes(ctx context.Context, client *aws_network.Client, vpcID, peeringID string) error {
+	routeTables, err := client.ListRouteTablesInVPC(ctx, vpcID)
+	if err != nil {
+		return err
+	}
+	for _, rt := range routeTables {
+		for _, route := range rt.Routes {
+			if to.String(route.VpcPeeringConnectionId) != peeringID || route.DestinationCidrBlock == nil {
+				continue
+			}
+			if err = client.DeleteRoute(ctx, to.String(rt.RouteTableId), *route.DestinationCidrBlock); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cidrBlocks will return every ipv4 cidr block of a vpc in a peering connection
+func cidrBlocks(info *types.VpcPeeringConnectionVpcInfo) []string {
+	var cidrs []string
+	seen := map[string]bool{}
+	for _, block := range info.CidrBlockSet {
+		if block.CidrBlock != nil && !seen[*block.CidrBlock] {
+			seen[*block.CidrBlock] = true
+			cidrs = append(cidrs, *block.CidrBlock)
+		}
+	}
+	if info.CidrBlock != nil && !seen[*info.CidrBlock] {
+		cidrs = append(cidrs, *info.CidrBlock)
+	}
+	return cidrs
+}
@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	aws_auth "github.com/naemono/go-cloud-actions/pkg/auth/aws"
+	aws_network "github.com/naemono/go-cloud-actions/pkg/network/aws"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+)
+
+// Config is an aws peering config
+type Config struct {
+	aws_auth.AuthConfig
+	// PeerAuthConfig is the authentication configuration of the account and region owning the remote
+	// vpc, used to accept peerings and route back to the local vpc.  When nil, AuthConfig is used.
+	PeerAuthConfig *aws_auth.AuthConfig
+	Logger         *logrus.Entry
+}
+
+// Peerer adapts the aws network client's vpc peering connections to the provider independent
+// peering.Peerer interface
+type Peerer struct {
+	Config
+	client     *aws_network.Client
+	peerClient *aws_network.Client
+}
+
+var _ peering.Peerer = &Peerer{}
+
+// NewPeerer will return a new aws peering.Peerer
+func NewPeerer(conf Config) (*Peerer, error) {
+	client, err := aws_network.New(aws_network.Config{
+		AuthConfig: conf.AuthConfig,
+		Logger:     conf.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p := &Peerer{
+		Config:     conf,
+		client:     client,
+		peerClient: client,
+	}
+	if conf.PeerAuthConfig != nil && !conf.PeerAuthConfig.Equal(conf.AuthConfig) {
+		p.peerClient, err = aws_network.New(aws_network.Config{
+			AuthConfig: *conf.PeerAuthConfig,
+			Logger:     conf.Logger,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create peer network client")
+		}
+	}
+	return p, nil
+}
+
+// Create will request a vpc peering connection, which must still be accepted by the owner of the remote vpc, unless
+// the cidr blocks of the vpcs overlap.  When the peer account is unknown, but a separate peer auth config is given,
+// the owner of the remote vpc is looked up using the peer credentials, as are its cidr blocks.  Overlaps which
+// cannot be verified, as neither credentials can read the remote vpc, are warned about.
+func (p *Peerer) Create(ctx context.Context, request peering.CreateRequest) (peering.Peering, error) {
+	peerOwnerID := request.RemoteNetwork.Account
+	if peerOwnerID == "" && p.peerClient != p.client {
+		vpc, err := p.peerClient.GetVPC(ctx, request.RemoteNetwork.Name)
+		if err != nil {
+			return peering.Peering{}, errors.Wrap(err, "failed to lookup owner of peer vpc")
+		}
+		peerOwnerID = to.String(vpc.OwnerId)
+	}
+	peerRegion := request.RemoteNetwork.Region
+	if peerRegion == "" && p.PeerAuthConfig != nil && p.PeerAuthConfig.Region != p.Region {
+		peerRegion = p.PeerAuthConfig.Region
+	}
+	request.RemoteNetwork.Region = peerRegion
+	if err := peering.CheckLocalOverlaps(ctx, p, request, p.client.Logger); err != nil {
+		return peering.Peering{}, err
+	}
+	conn, err := p.client.CreateVpcPeeringConnection(ctx, aws_network.CreateVpcPeeringRequest{
+		VPCId:       request.Network.Name,
+		PeerVPCId:   request.RemoteNetwork.Name,
+		PeerOwnerID: peerOwnerID,
+		PeerRegion:  peerRegion,
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeVpcPeeringConnection,
+				Tags: append(request.Tags.AWS(), types.Tag{
+					Key:   to.StringPtr("Name"),
+					Value: to.StringPtr(request.Name),
+				}),
+			},
+		},
+	})
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	return toPeering(conn), nil
+}
+
+// List will list all vpc peering connections of a vpc
+func (p *Peerer) List(ctx context.Context, n peering.Network) ([]peering.Peering, error) {
+	conns, err := p.client.ListVpcPeeringConnections(ctx, n.Name)
+	if err != nil {
+		return nil, err
+	}
+	peerings := []peering.Peering{}
+	for _, conn := range conns {
+		peerings = append(peerings, toPeering(conn))
+	}
+	return peerings, nil
+}
+
+// Get will get a single vpc peering connection of a vpc by id or Name tag
+func (p *Peerer) Get(ctx context.Context, n peering.Network, name string) (peering.Peering, error) {
+	peerings, err := p.List(ctx, n)
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	for _, pcx := range peerings {
+		if pcx.ID == name || pcx.Name == name {
+			return pcx, nil
+		}
+	}
+	return peering.Peering{}, peering.ErrNotFound
+}
+
+// Delete will delete a single vpc peering connection of a vpc by id or Name tag
+func (p *Peerer) Delete(ctx context.Context, n peering.Network, name string) error {
+	pcx, err := p.Get(ctx, n, name)
+	if err != nil {
+		return err
+	}
+	return p.client.DeleteVpcPeeringConnection(ctx, pcx.ID)
+}
+
+// AddressSpace will return the cidr blocks of a vpc, which is looked up with the credentials of its region, or of
+// the peer account for vpcs the local account cannot see
+func (p *Peerer) AddressSpace(ctx context.Context, n peering.Network) ([]string, error) {
+	clients := []*aws_network.Client{p.client}
+	if p.peerClient != p.client {
+		clients = append(clients, p.peerClient)
+	}
+	if n.Region != "" && n.Region != p.Region {
+		clients = []*aws_network.Client{p.peerClient}
+		if p.PeerAuthConfig == nil || p.PeerAuthConfig.Region != n.Region {
+			conf := p.AuthConfig
+			conf.Region = n.Region
+			client, err := aws_network.New(aws_network.Config{AuthConfig: conf, Logger: p.Logger})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to create network client of region %s", n.Region)
+			}
+			clients = []*aws_network.Client{client}
+		}
+	}
+	var err error
+	for _, client := range clients {
+		var vpc types.Vpc
+		if vpc, err = client.GetVPC(ctx, n.Name); err == nil {
+			return aws_network.VPCCIDRBlocks(vpc), nil
+		}
+	}
+	return nil, err
+}
+
+func toPeering(conn types.VpcPeeringConnection) peering.Peering {
+	result := peering.Peering{
+		Provider: peering.ProviderAWS,
+		ID:       to.String(conn.VpcPeeringConnectionId),
+		State:    peering.StateUnknown,
+	}
+	for _, tag := range conn.Tags {
+		if strings.EqualFold(to.String(tag.Key), "name") {
+			result.Name = to.String(tag.Value)
+		}
+	}
+	if result.Name == "" {
+		result.Name = result.ID
+	}
+	result.Network = vpcInfoString(conn.RequesterVpcInfo)
+	result.RemoteNetwork = vpcInfoString(conn.AccepterVpcInfo)
+	if conn.AccepterVpcInfo != nil {
+		result.RemoteAddressSpace = cidrBlocks(conn.AccepterVpcInfo)
+	}
+	if conn.Status != nil {
+		switch conn.Status.Code {
+		case types.VpcPeeringConnectionStateReasonCodeInitiatingRequest,
+			types.VpcPeeringConnectionStateReasonCodePendingAcceptance,
+			types.VpcPeeringConnectionStateReasonCodeProvisioning:
+			result.State = peering.StatePending
+		case types.VpcPeeringConnectionStateReasonCodeActive:
+			result.State = peering.StateActive
+		case types.VpcPeeringConnectionStateReasonCodeDeleted,
+			types.VpcPeeringConnectionStateReasonCodeRejected,
+			types.VpcPeeringConnectionStateReasonCodeExpired:
+			result.State = peering.StateInactive
+		case types.VpcPeeringConnectionStateReasonCodeDeleting:
+			result.State = peering.StateDeleting
+		case types.VpcPeeringConnectionStateReasonCodeFailed:
+			result.State = peering.StateFailed
+		}
+	}
+	return result
+}
+
+// vpcInfoString will return a vpc's info as {region}/{account}/{vpc-id}
+func vpcInfoString(info *types.VpcPeeringConnectionVpcInfo) string {
+	if info == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s", to.String(info.Region), to.String(info.OwnerId), to.String(info.VpcId))
+}
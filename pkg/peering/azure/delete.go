@@ -0,0 +1,25 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+)
+
+// Delete will delete an existing peering connection, and wait for the deletion to complete
+func (c *Client) Delete(ctx context.Context, resourceGroup, vnet, name string) error {
+	future, err := c.vnpClient.Delete(ctx, resourceGroup, vnet, name)
+	if err != nil {
+		if isNotFound(err) {
+			return peering.ErrNotFound
+		}
+		return errors.Wrapf(err, "unable to delete peering %s", name)
+	}
+	if err = future.WaitForCompletionRef(ctx, c.vnpClient.Client); err != nil {
+		return errors.Wrapf(err, "failed waiting for peering %s to be deleted", name)
+	}
+	c.Logger.Debugf("succesfully deleted peering %s", name)
+	return nil
+}
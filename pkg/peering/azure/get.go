@@ -0,0 +1,95 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2020-11-01/network"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+)
+
+// Get will get a single existing peering connection, returning peering.ErrNotFound if it does not exist
+func (c *Client) Get(ctx context.Context, resourceGroup, vnet, name string) (network.VirtualNetworkPeering, error) {
+	result, err := c.vnpClient.Get(ctx, resourceGroup, vnet, name)
+	if err != nil {
+		if isNotFound(err) {
+			return result, peering.ErrNotFound
+		}
+		return result, errors.Wrapf(err, "unable to get peering %s", name)
+	}
+	return result, nil
+}
+
+// GetAddressSpace will get the address prefixes of a virtual network, which may be in another subscription than the
+// client's
+func (c *Client) GetAddressSpace(ctx context.Context, subscriptionID, resourceGroup, vnet string) ([]string, error) {
+	client := c.vnetClient
+	if subscriptionID != "" {
+		client.SubscriptionID = subscriptionID
+	}
+	result, err := client.Get(ctx, resourceGroup, vnet, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get virtual network %s", vnet)
+	}
+	if result.VirtualNetworkPropertiesFormat == nil || result.AddressSpace == nil || result.AddressSpace.AddressPrefixes == nil {
+		return nil, nil
+	}
+	return *result.AddressSpace.AddressPrefixes, nil
+}
+
+func isNotFound(err error) bool {
+	var de autorest.DetailedError
+	if errors.As(err, &de) {
+		return de.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// syncLevelAPIVersion is the first network api version returning a peering's sync level, which is newer
+// than the network api version of the sdk in use
+const syncLevelAPIVersion = "2021-02-01"
+
+// GetSyncLevel will get whether an existing peering's view of the local and remote address spaces is in
+// sync (FullyInSync, LocalNotInSync, RemoteNotInSync, or LocalAndRemoteNotInSync)
+func (c *Client) GetSyncLevel(ctx context.Context, resourceGroup, vnet, name string) (string, error) {
+	pathParameters := map[string]interface{}{
+		"resourceGroupName":         autorest.Encode("path", resourceGroup),
+		"subscriptionId":            autorest.Encode("path", c.vnpClient.SubscriptionID),
+		"virtualNetworkName":        autorest.Encode("path", vnet),
+		"virtualNetworkPeeringName": autorest.Encode("path", name),
+	}
+	preparer := autorest.CreatePreparer(
+		autorest.AsGet(),
+		autorest.WithBaseURL(c.vnpClient.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Network/virtualNetworks/{virtualNetworkName}/virtualNetworkPeerings/{virtualNetworkPeeringName}", pathParameters),
+		autorest.WithQueryParameters(map[string]interface{}{
+			"api-version": syncLevelAPIVersion,
+		}))
+	req, err := preparer.Prepare((&http.Request{}).WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to prepare peering sync level request")
+	}
+	resp, err := c.vnpAutorestClient.Send(req, azure.DoRetryWithRegistration(c.vnpAutorestClient))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send peering sync level request")
+	}
+	var result struct {
+		Properties struct {
+			PeeringSyncLevel string `json:"peeringSyncLevel"`
+		} `json:"properties"`
+	}
+	err = autorest.Respond(
+		resp,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result),
+		autorest.ByClosing())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read peering sync level response")
+	}
+	return result.Properties.PeeringSyncLevel, nil
+}
@@ -3,26 +3,23 @@ package azure
 import (
 	"context"
 
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2020-11-01/network"
 	"github.com/pkg/errors"
 )
 
-// List will list existing peering connections
-func (c *Client) List(ctx context.Context, resourceGroup, vnet string) error {
-	result, err := c.vnpClient.List(ctx, resourceGroup, vnet)
+// List will list all existing peering connections of a vnet, following every page of results
+func (c *Client) List(ctx context.Context, resourceGroup, vnet string) ([]network.VirtualNetworkPeering, error) {
+	page, err := c.vnpClient.List(ctx, resourceGroup, vnet)
 	if err != nil {
-		return errors.Wrap(err, "unable to list peerings")
+		return nil, errors.Wrap(err, "unable to list peerings")
 	}
-	resp := result.Response()
-	if resp.Value != nil {
-		if len(*resp.Value) == 0 {
-			c.Logger.Debug("no peerings found")
-			return nil
+	peerings := []network.VirtualNetworkPeering{}
+	for page.NotDone() {
+		peerings = append(peerings, page.Values()...)
+		if err = page.NextWithContext(ctx); err != nil {
+			return nil, errors.Wrap(err, "unable to list next page of peerings")
 		}
-		for i, v := range *resp.Value {
-			c.Logger.WithField("name", v.Name).Infof("peer %d", i)
-		}
-		return nil
 	}
-	c.Logger.Debug("no peerings found")
-	return nil
+	c.Logger.Debugf("found %d peerings", len(peerings))
+	return peerings, nil
 }
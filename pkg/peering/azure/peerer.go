@@ -0,0 +1,162 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2020-11-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+)
+
+// Peerer adapts the azure peering client to the provider independent peering.Peerer interface
+type Peerer struct {
+	client *Client
+}
+
+var (
+	_ peering.Peerer  = &Peerer{}
+	_ peering.Updater = &Peerer{}
+)
+
+// NewPeerer will return a new azure peering.Peerer
+func NewPeerer(conf Config) (*Peerer, error) {
+	client, err := New(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Peerer{client: client}, nil
+}
+
+// VirtualNetworkID will return the azure resource id of a virtual network
+func VirtualNetworkID(subscriptionID, resourceGroup, vnet string) string {
+	return fmt.Sprintf(
+		"/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/virtualNetworks/%s",
+		subscriptionID,
+		resourceGroup,
+		vnet,
+	)
+}
+
+// Create will create the local half of an azure vnet peering, unless the address spaces of the vnets overlap.  When
+// the remote vnet cannot be read with the local credentials, the overlap is only warned as unverified.
+func (p *Peerer) Create(ctx context.Context, request peering.CreateRequest) (peering.Peering, error) {
+	remoteSubscriptionID := request.RemoteNetwork.Account
+	if remoteSubscriptionID == "" {
+		remoteSubscriptionID = p.client.SubscriptionID
+	}
+	if err := peering.CheckLocalOverlaps(ctx, p, request, p.client.Logger); err != nil {
+		return peering.Peering{}, err
+	}
+	err := p.client.Create(ctx, CreatePeeringRequest{
+		SourceResourceGroup:       request.Network.Group,
+		SourceVnetName:            request.Network.Name,
+		SourcePeeringName:         request.Name,
+		RemoteVnetID:              VirtualNetworkID(remoteSubscriptionID, request.RemoteNetwork.Group, request.RemoteNetwork.Name),
+		AllowVirtualNetworkAccess: true,
+		AllowForwardedTraffic:     request.RouteExchange.AllowForwardedTraffic,
+		AllowGatewayTransit:       request.RouteExchange.AllowGatewayTransit,
+		UseRemoteGateways:         request.RouteExchange.UseRemoteGateways,
+	})
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	return p.Get(ctx, request.Network, request.Name)
+}
+
+// List will list all peerings of an azure vnet
+func (p *Peerer) List(ctx context.Context, n peering.Network) ([]peering.Peering, error) {
+	results, err := p.client.List(ctx, n.Group, n.Name)
+	if err != nil {
+		return nil, err
+	}
+	peerings := []peering.Peering{}
+	for _, result := range results {
+		peerings = append(peerings, toPeering(result))
+	}
+	return peerings, nil
+}
+
+// Get will get a single peering of an azure vnet by name, including its sync level
+func (p *Peerer) Get(ctx context.Context, n peering.Network, name string) (peering.Peering, error) {
+	result, err := p.client.Get(ctx, n.Group, n.Name, name)
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	vnp := toPeering(result)
+	if vnp.SyncLevel, err = p.client.GetSyncLevel(ctx, n.Group, n.Name, name); err != nil {
+		p.client.Logger.WithError(err).Debugf("unable to get sync level of peering %s", name)
+	}
+	return vnp, nil
+}
+
+// Delete will delete a single peering of an azure vnet by name
+func (p *Peerer) Delete(ctx context.Context, n peering.Network, name string) error {
+	return p.client.Delete(ctx, n.Group, n.Name, name)
+}
+
+// Update will update the traffic flags of a single peering of an azure vnet by name
+func (p *Peerer) Update(ctx context.Context, n peering.Network, name string, update peering.Update) (peering.Peering, error) {
+	_, err := p.client.Update(ctx, UpdatePeeringRequest{
+		ResourceGroup:         n.Group,
+		VnetName:              n.Name,
+		PeeringName:           name,
+		AllowForwardedTraffic: update.AllowForwardedTraffic,
+		AllowGatewayTransit:   update.AllowGatewayTransit,
+		UseRemoteGateways:     update.UseRemoteGateways,
+	})
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	return p.Get(ctx, n, name)
+}
+
+// AddressSpace will return the address prefixes of an azure vnet
+func (p *Peerer) AddressSpace(ctx context.Context, n peering.Network) ([]string, error) {
+	return p.client.GetAddressSpace(ctx, n.Account, n.Group, n.Name)
+}
+
+func toPeering(vnp network.VirtualNetworkPeering) peering.Peering {
+	id := to.String(vnp.ID)
+	result := peering.Peering{
+		Provider: peering.ProviderAzure,
+		ID:       id,
+		Name:     to.String(vnp.Name),
+		State:    peering.StateUnknown,
+	}
+	if i := strings.Index(strings.ToLower(id), "/virtualnetworkpeerings/"); i > 0 {
+		result.Network = id[:i]
+	}
+	props := vnp.VirtualNetworkPeeringPropertiesFormat
+	if props == nil {
+		return result
+	}
+	switch props.PeeringState {
+	case network.VirtualNetworkPeeringStateInitiated:
+		result.State = peering.StatePending
+	case network.VirtualNetworkPeeringStateConnected:
+		result.State = peering.StateActive
+	case network.VirtualNetworkPeeringStateDisconnected:
+		result.State = peering.StateInactive
+	}
+	switch props.ProvisioningState {
+	case network.Deleting:
+		result.State = peering.StateDeleting
+	case network.Failed:
+		result.State = peering.StateFailed
+	}
+	if props.RemoteVirtualNetwork != nil {
+		result.RemoteNetwork = to.String(props.RemoteVirtualNetwork.ID)
+	}
+	if props.RemoteAddressSpace != nil && props.RemoteAddressSpace.AddressPrefixes != nil {
+		result.RemoteAddressSpace = *props.RemoteAddressSpace.AddressPrefixes
+	}
+	result.RouteExchange = peering.RouteExchange{
+		AllowForwardedTraffic: to.Bool(props.AllowForwardedTraffic),
+		AllowGatewayTransit:   to.Bool(props.AllowGatewayTransit),
+		UseRemoteGateways:     to.Bool(props.UseRemoteGateways),
+	}
+	return result
+}
@@ -1,9 +1,6 @@
 package azure
 
 import (
-	"net/http"
-	"net/http/httputil"
-
 	"github.com/pkg/errors"
 
 	"github.com/sirupsen/logrus"
@@ -18,6 +15,8 @@ import (
 type Config struct {
 	azure_auth.AuthConfig
 	Logger *logrus.Entry
+	// Factory is the azure client factory to share with other clients, created from AuthConfig when nil
+	Factory *azure_auth.ClientFactory
 }
 
 // Client is an azure peering client
@@ -25,50 +24,34 @@ type Client struct {
 	Config
 	vnpClient         network.VirtualNetworkPeeringsClient
 	vnpAutorestClient autorest.Client
+	vnetClient        network.VirtualNetworksClient
 }
 
 // New will return a new azure peering client
 func New(conf Config) (*Client, error) {
-	vnpc, err := azure_auth.NewVirtualNetworkPeeringsClient(conf.AuthConfig)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get new virtual network peerings client")
-	}
+	var err error
 	client := &Client{
-		Config:            conf,
-		vnpClient:         vnpc,
-		vnpAutorestClient: vnpc.Client,
+		Config: conf,
 	}
 	if client.Logger == nil {
 		client.Logger = logrus.NewEntry(logrus.New())
 		client.Logger.Logger.SetLevel(logrus.InfoLevel)
 		client.Logger.Logger.SetFormatter(&logrus.JSONFormatter{})
 	}
-	return client, nil
-}
-
-func withRequestLogging() autorest.SendDecorator {
-	return func(s autorest.Sender) autorest.Sender {
-		return autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
-			// dump request to wire format
-			if dump, err := httputil.DumpRequestOut(r, true); err == nil {
-				logrus.Printf("[DEBUG] AzureRM Request: \n%s\n", dump)
-			} else {
-				// fallback to basic message
-				logrus.Printf("[DEBUG] AzureRM Request: %s to %s\n", r.Method, r.URL)
-			}
-			resp, err := s.Do(r)
-			if resp != nil {
-				// dump response to wire format
-				if dump, err := httputil.DumpResponse(resp, true); err == nil {
-					logrus.Printf("[DEBUG] AzureRM Response for %s: \n%s\n", r.URL, dump)
-				} else {
-					// fallback to basic message
-					logrus.Printf("[DEBUG] AzureRM Response: %s for %s\n", resp.Status, r.URL)
-				}
-			} else {
-				logrus.Printf("[DEBUG] Request to %s completed with no response", r.URL)
-			}
-			return resp, err
-		})
+	if client.Factory == nil {
+		client.Factory, err = azure_auth.NewClientFactory(azure_auth.FactoryConfig{AuthConfig: conf.AuthConfig, Logger: client.Logger})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get new azure client factory")
+		}
 	}
+	client.vnpClient, err = client.Factory.VirtualNetworkPeeringsClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get new virtual network peerings client")
+	}
+	client.vnpAutorestClient = client.vnpClient.Client
+	client.vnetClient, err = client.Factory.VirtualNetworksClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get new virtual networks client")
+	}
+	return client, nil
 }
@@ -0,0 +1,62 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2020-11-01/network"
+)
+
+// UpdatePeeringRequest is a request to update the traffic flags of an existing peering.  Nil flags are
+// left unchanged.
+type UpdatePeeringRequest struct {
+	ResourceGroup             string
+	VnetName                  string
+	PeeringName               string
+	AllowVirtualNetworkAccess *bool
+	AllowForwardedTraffic     *bool
+	AllowGatewayTransit       *bool
+	UseRemoteGateways         *bool
+}
+
+// Update will update the traffic flags of an existing peering connection, and wait for the update to complete
+func (c *Client) Update(ctx context.Context, request UpdatePeeringRequest) (network.VirtualNetworkPeering, error) {
+	vnp, err := c.Get(ctx, request.ResourceGroup, request.VnetName, request.PeeringName)
+	if err != nil {
+		return vnp, err
+	}
+	if vnp.VirtualNetworkPeeringPropertiesFormat == nil {
+		return vnp, errors.Errorf("peering %s is missing properties", request.PeeringName)
+	}
+	props := vnp.VirtualNetworkPeeringPropertiesFormat
+	if request.AllowVirtualNetworkAccess != nil {
+		props.AllowVirtualNetworkAccess = request.AllowVirtualNetworkAccess
+	}
+	if request.AllowForwardedTraffic != nil {
+		props.AllowForwardedTraffic = request.AllowForwardedTraffic
+	}
+	if request.AllowGatewayTransit != nil {
+		props.AllowGatewayTransit = request.AllowGatewayTransit
+	}
+	if request.UseRemoteGateways != nil {
+		props.UseRemoteGateways = request.UseRemoteGateways
+	}
+	c.Logger.Debugf("attempting to update peering with request %+v", request)
+	future, err := c.vnpClient.CreateOrUpdate(
+		ctx,
+		request.ResourceGroup,
+		request.VnetName,
+		request.PeeringName,
+		network.VirtualNetworkPeering{
+			Name:                                  vnp.Name,
+			VirtualNetworkPeeringPropertiesFormat: props,
+		})
+	if err != nil {
+		return vnp, errors.Wrapf(err, "unable to update peering %s", request.PeeringName)
+	}
+	if err = future.WaitForCompletionRef(ctx, c.vnpClient.Client); err != nil {
+		return vnp, errors.Wrapf(err, "failed waiting for peering %s to be updated", request.PeeringName)
+	}
+	return future.Result(c.vnpClient)
+}
@@ -0,0 +1,126 @@
+package peering
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPollInterval is the interval at which peerings are polled while waiting on their state
+	DefaultPollInterval = 5 * time.Second
+	rollbackTimeout     = 5 * time.Minute
+)
+
+// BidirectionalRequest is a request to create both halves of a peering
+type BidirectionalRequest struct {
+	CreateRequest
+	// RemoteName is the name of the remote half of the peering, and defaults to Name
+	RemoteName string
+	// PollInterval is the interval at which both halves are polled while waiting for them to
+	// become active, and defaults to DefaultPollInterval
+	PollInterval time.Duration
+}
+
+// CreateBidirectional will create the local half of a peering using local, and the remote half using
+// remote, then wait until both halves are active.  Neither half is created when the address spaces of the
+// networks overlap, unless the request allows overlaps.  If the remote half cannot be created, or either half
+// does not become active, the halves created are deleted again, so that no half-initiated peering is left behind.
+func CreateBidirectional(ctx context.Context, local, remote Peerer, request BidirectionalRequest) (Peering, Peering, error) {
+	remoteName := request.RemoteName
+	if remoteName == "" {
+		remoteName = request.Name
+	}
+	if err := CheckOverlaps(ctx, local, remote, request.CreateRequest); err != nil {
+		return Peering{}, Peering{}, err
+	}
+	// the address spaces were checked with the peerer of each network, which are both needed
+	request.AllowOverlap = true
+	localResult, err := local.Create(ctx, request.CreateRequest)
+	if err != nil {
+		return localResult, Peering{}, errors.Wrap(err, "failed to create local half of peering")
+	}
+	remoteResult, err := remote.Create(ctx, CreateRequest{
+		Name:          remoteName,
+		Network:       request.RemoteNetwork,
+		RemoteNetwork: request.Network,
+		RouteExchange: ReverseRouteExchange(request.RouteExchange),
+		AllowOverlap:  true,
+	})
+	if err != nil {
+		if rollbackErr := rollback(local, request.Network, request.Name); rollbackErr != nil {
+			return localResult, remoteResult, errors.Wrapf(err,
+				"failed to create remote half of peering, and failed to roll back local half %s (%s)",
+				request.Name, rollbackErr)
+		}
+		return localResult, remoteResult, errors.Wrapf(err,
+			"failed to create remote half of peering, local half %s was rolled back", request.Name)
+	}
+	if localResult, err = WaitUntilActive(ctx, local, request.Network, request.Name, request.PollInterval); err == nil {
+		remoteResult, err = WaitUntilActive(ctx, remote, request.RemoteNetwork, remoteName, request.PollInterval)
+	}
+	if err != nil {
+		// ctx may have expired while waiting, so both halves are rolled back with their own timeout, remote first
+		remoteErr := rollback(remote, request.RemoteNetwork, remoteName)
+		localErr := rollback(local, request.Network, request.Name)
+		if remoteErr != nil || localErr != nil {
+			return localResult, remoteResult, errors.Wrapf(err,
+				"peering did not become active, and failed to roll back remote half %s (%v) or local half %s (%v)",
+				remoteName, remoteErr, request.Name, localErr)
+		}
+		return localResult, remoteResult, errors.Wrapf(err,
+			"peering did not become active, remote half %s and local half %s were rolled back", remoteName, request.Name)
+	}
+	return localResult, remoteResult, nil
+}
+
+// rollback will delete a half of a peering with a fresh context, as that of the creation may have expired.  A half
+// which is not found, such as the accepted side of an aws peering deleted along with its requester, is rolled back.
+func rollback(p Peerer, network Network, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+	if err := p.Delete(ctx, network, name); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// WaitUntilActive will poll a peering until it is active, has failed, or the context is done
+func WaitUntilActive(ctx context.Context, p Peerer, network Network, name string, interval time.Duration) (Peering, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := p.Get(ctx, network, name)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return result, err
+		}
+		switch result.State {
+		case StateActive:
+			return result, nil
+		case StateFailed:
+			return result, errors.Errorf("peering %s failed", name)
+		}
+		select {
+		case <-ctx.Done():
+			return result, errors.Wrapf(ctx.Err(), "timed out waiting for peering %s to become active (state %s)", name, result.State)
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReverseRouteExchange will return the route exchange flags as seen from the remote half of a peering:
+// routes imported locally are exported remotely and vice versa, and gateway transit offered locally
+// is used remotely and vice versa
+func ReverseRouteExchange(re RouteExchange) RouteExchange {
+	return RouteExchange{
+		AllowForwardedTraffic: re.AllowForwardedTraffic,
+		AllowGatewayTransit:   re.UseRemoteGateways,
+		UseRemoteGateways:     re.AllowGatewayTransit,
+		ImportCustomRoutes:    re.ExportCustomRoutes,
+		ExportCustomRoutes:    re.ImportCustomRoutes,
+	}
+}
@@ -3,12 +3,16 @@ package google
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	google_auth "github.com/naemono/go-cloud-actions/pkg/auth/google"
+	"github.com/naemono/go-cloud-actions/pkg/peering"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
 )
 
 // PeeringCommon are the common fields between create/list peering requests
@@ -44,7 +48,8 @@ type Config struct {
 // Client is an azure peering client
 type Client struct {
 	Config
-	networksServiceClient *compute.NetworksService
+	networksServiceClient    *compute.NetworksService
+	subnetworksServiceClient *compute.SubnetworksService
 }
 
 // New will return a new google peering client
@@ -55,9 +60,14 @@ func New(conf Config) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	subnetworkClient, err := google_auth.NewSubnetworksClient(ctx, conf.AuthConfig)
+	if err != nil {
+		return nil, err
+	}
 	client := &Client{
-		Config:                conf,
-		networksServiceClient: networkClient,
+		Config:                   conf,
+		networksServiceClient:    networkClient,
+		subnetworksServiceClient: subnetworkClient,
 	}
 	if client.Logger == nil {
 		client.Logger = logrus.NewEntry(logrus.New())
@@ -67,13 +77,14 @@ func New(conf Config) (*Client, error) {
 	return client, nil
 }
 
+// NetworkURL will return the fully qualified url of a google project's network
+func NetworkURL(project, network string) string {
+	return fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/global/networks/%s", project, network)
+}
+
 // CreatePeering will create a google peering between 2 project's networks
 func (c *Client) CreatePeering(ctx context.Context, req CreatePeeringRequest) error {
-	remoteNetworkURL := fmt.Sprintf(
-		"https://www.googleapis.com/compute/v1/projects/%s/global/networks/%s",
-		req.RemoteProjectName,
-		req.RemoteNetworkName,
-	)
+	remoteNetworkURL := NetworkURL(req.RemoteProjectName, req.RemoteNetworkName)
 	_, err := c.networksServiceClient.AddPeering(req.ProjectID, req.NetworkName, &compute.NetworksAddPeeringRequest{
 		NetworkPeering: &compute.NetworkPeering{
 			ExchangeSubnetRoutes:           true,
@@ -92,20 +103,152 @@ func (c *Client) CreatePeering(ctx context.Context, req CreatePeeringRequest) er
 	return nil
 }
 
-// ListPeerings will list a google project's network peering
-func (c *Client) ListPeerings(ctx context.Context, req ListPeeringRequest) error {
-	var (
-		err error
-		res *compute.ExchangedPeeringRoutesList
-	)
+// ListPeerings will list the routes exchanged over a google project's network peering in the given region,
+// both those exported to (OUTGOING) and imported from (INCOMING) the remote network
+func (c *Client) ListPeerings(ctx context.Context, req ListPeeringRequest) ([]*compute.ExchangedPeeringRoute, error) {
+	var routes []*compute.ExchangedPeeringRoute
 	for _, direction := range []string{"OUTGOING", "INCOMING"} {
-		res, err = c.networksServiceClient.ListPeeringRoutes(req.ProjectID, req.NetworkName).PeeringName(req.PeeringName).Region(req.Region).Direction(direction).Do()
+		result, err := c.listPeeringRoutes(ctx, req, direction)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		for _, r := range res.Items {
-			c.Logger.Infof("peer: %+v", r)
+		routes = append(routes, result...)
+	}
+	return routes, nil
+}
+
+// listPeeringRoutes will list the routes exchanged over a peering in a single direction, following every page of results
+func (c *Client) listPeeringRoutes(ctx context.Context, req ListPeeringRequest, direction string) ([]*compute.ExchangedPeeringRoute, error) {
+	var routes []*compute.ExchangedPeeringRoute
+	err := c.networksServiceClient.ListPeeringRoutes(req.ProjectID, req.NetworkName).
+		PeeringName(req.PeeringName).
+		Region(req.Region).
+		Direction(direction).
+		Pages(ctx, func(page *compute.ExchangedPeeringRoutesList) error {
+			routes = append(routes, page.Items...)
+			return nil
+		})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list %s routes of peer %s", strings.ToLower(direction), req.PeeringName)
+	}
+	return routes, nil
+}
+
+// DeletePeering will remove a google project's network peering
+func (c *Client) DeletePeering(ctx context.Context, req PeeringCommon) error {
+	_, err := c.networksServiceClient.RemovePeering(req.ProjectID, req.NetworkName, &compute.NetworksRemovePeeringRequest{
+		Name: req.PeeringName,
+	}).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return peering.ErrNotFound
 		}
+		return errors.Wrapf(err, "failed to delete peer %s", req.PeeringName)
 	}
+	c.Logger.Debugf("peering %s deleted succesfully", req.PeeringName)
 	return nil
 }
+
+// GetAddressSpace will get the ranges of a google project's network: the primary and secondary ranges of its
+// subnetworks in every region, or the range of a legacy network
+func (c *Client) GetAddressSpace(ctx context.Context, project, networkName string) ([]string, error) {
+	network, err := c.networksServiceClient.Get(project, networkName).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get network %s", networkName)
+	}
+	if network.IPv4Range != "" {
+		return []string{network.IPv4Range}, nil
+	}
+	ranges := []string{}
+	err = c.subnetworksServiceClient.AggregatedList(project).Pages(ctx, func(list *compute.SubnetworkAggregatedList) error {
+		for _, scoped := range list.Items {
+			for _, subnetwork := range scoped.Subnetworks {
+				if subnetwork.Network != network.SelfLink {
+					continue
+				}
+				ranges = append(ranges, subnetwork.IpCidrRange)
+				for _, secondary := range subnetwork.SecondaryIpRanges {
+					ranges = append(ranges, secondary.IpCidrRange)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list subnetworks of network %s", networkName)
+	}
+	return ranges, nil
+}
+
+func isNotFound(err error) bool {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return gerr.Code == http.StatusNotFound
+	}
+	return false
+}
+
+// UpdatePeeringRequest is a request to update the custom route exchange of an existing peering.  Nil flags
+// are left unchanged.
+type UpdatePeeringRequest struct {
+	PeeringCommon
+	ImportCustomRoutes *bool
+	ExportCustomRoutes *bool
+}
+
+// GetPeering will get a single google project's network peering, returning peering.ErrNotFound if it does not exist
+func (c *Client) GetPeering(ctx context.Context, req PeeringCommon) (*compute.NetworkPeering, error) {
+	network, err := c.networksServiceClient.Get(req.ProjectID, req.NetworkName).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get network %s", req.NetworkName)
+	}
+	for _, np := range network.Peerings {
+		if np.Name == req.PeeringName {
+			return np, nil
+		}
+	}
+	return nil, peering.ErrNotFound
+}
+
+// UpdatePeering will update the custom route exchange of a google project's network peering
+func (c *Client) UpdatePeering(ctx context.Context, req UpdatePeeringRequest) error {
+	np, err := c.GetPeering(ctx, req.PeeringCommon)
+	if err != nil {
+		return err
+	}
+	update := &compute.NetworkPeering{
+		Name:               np.Name,
+		ImportCustomRoutes: np.ImportCustomRoutes,
+		ExportCustomRoutes: np.ExportCustomRoutes,
+		// send false values, which would otherwise be omitted, and left unchanged
+		ForceSendFields: []string{"ImportCustomRoutes", "ExportCustomRoutes"},
+	}
+	if req.ImportCustomRoutes != nil {
+		update.ImportCustomRoutes = *req.ImportCustomRoutes
+	}
+	if req.ExportCustomRoutes != nil {
+		update.ExportCustomRoutes = *req.ExportCustomRoutes
+	}
+	_, err = c.networksServiceClient.UpdatePeering(req.ProjectID, req.NetworkName, &compute.NetworksUpdatePeeringRequest{
+		NetworkPeering: update,
+	}).Context(ctx).Do()
+	if err != nil {
+		return errors.Wrapf(err, "failed to update peer %s", req.PeeringName)
+	}
+	c.Logger.Debugf("peering %s updated succesfully", req.PeeringName)
+	return nil
+}
+
+// ListPeeringRemoteAddressSpace will list the destination ranges of the routes imported over a google
+// project's network peering in the given region
+func (c *Client) ListPeeringRemoteAddressSpace(ctx context.Context, req ListPeeringRequest) ([]string, error) {
+	routes, err := c.listPeeringRoutes(ctx, req, "INCOMING")
+	if err != nil {
+		return nil, err
+	}
+	var ranges []string
+	for _, r := range routes {
+		ranges = append(ranges, r.DestRange)
+	}
+	return ranges, nil
+}
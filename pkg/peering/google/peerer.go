@@ -0,0 +1,150 @@
+package google
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/compute/v1"
+
+	"github.com/naemono/go-cloud-actions/pkg/peering"
+)
+
+// Peerer adapts the google peering client to the provider independent peering.Peerer interface
+type Peerer struct {
+	client *Client
+}
+
+var (
+	_ peering.Peerer  = &Peerer{}
+	_ peering.Updater = &Peerer{}
+)
+
+// NewPeerer will return a new google peering.Peerer
+func NewPeerer(conf Config) (*Peerer, error) {
+	client, err := New(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Peerer{client: client}, nil
+}
+
+// Create will create the local half of a google network peering, unless the address spaces of the networks overlap.
+// A remote network of another project which cannot be read is warned about rather than failing the create.
+func (p *Peerer) Create(ctx context.Context, request peering.CreateRequest) (peering.Peering, error) {
+	remoteProject := request.RemoteNetwork.Account
+	if remoteProject == "" {
+		remoteProject = request.Network.Account
+		request.RemoteNetwork.Account = remoteProject
+	}
+	if err := peering.CheckLocalOverlaps(ctx, p, request, p.client.Logger); err != nil {
+		return peering.Peering{}, err
+	}
+	err := p.client.CreatePeering(ctx, CreatePeeringRequest{
+		PeeringCommon: PeeringCommon{
+			ProjectID:   request.Network.Account,
+			NetworkName: request.Network.Name,
+			PeeringName: request.Name,
+		},
+		RemoteProjectName:  remoteProject,
+		RemoteNetworkName:  request.RemoteNetwork.Name,
+		ImportCustomRoutes: request.RouteExchange.ImportCustomRoutes,
+		ExportCustomRoutes: request.RouteExchange.ExportCustomRoutes,
+	})
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	result, err := p.Get(ctx, request.Network, request.Name)
+	if errors.Is(err, peering.ErrNotFound) {
+		// the add peering operation has not yet been applied to the network
+		return peering.Peering{
+			Provider:      peering.ProviderGoogle,
+			Name:          request.Name,
+			State:         peering.StatePending,
+			Network:       NetworkURL(request.Network.Account, request.Network.Name),
+			RemoteNetwork: NetworkURL(remoteProject, request.RemoteNetwork.Name),
+			RouteExchange: request.RouteExchange,
+		}, nil
+	}
+	return result, err
+}
+
+// List will list all peerings of a google network
+func (p *Peerer) List(ctx context.Context, n peering.Network) ([]peering.Peering, error) {
+	network, err := p.client.networksServiceClient.Get(n.Account, n.Name).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get network %s", n.Name)
+	}
+	peerings := []peering.Peering{}
+	for _, np := range network.Peerings {
+		peerings = append(peerings, toPeering(network.SelfLink, np))
+	}
+	return peerings, nil
+}
+
+// Get will get a single peering of a google network by name
+func (p *Peerer) Get(ctx context.Context, n peering.Network, name string) (peering.Peering, error) {
+	peerings, err := p.List(ctx, n)
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	for _, np := range peerings {
+		if np.Name == name {
+			return np, nil
+		}
+	}
+	return peering.Peering{}, peering.ErrNotFound
+}
+
+// Delete will delete a single peering of a google network by name
+func (p *Peerer) Delete(ctx context.Context, n peering.Network, name string) error {
+	return p.client.DeletePeering(ctx, PeeringCommon{
+		ProjectID:   n.Account,
+		NetworkName: n.Name,
+		PeeringName: name,
+	})
+}
+
+// Update will update the custom route exchange of a single peering of a google network by name
+func (p *Peerer) Update(ctx context.Context, n peering.Network, name string, update peering.Update) (peering.Peering, error) {
+	err := p.client.UpdatePeering(ctx, UpdatePeeringRequest{
+		PeeringCommon: PeeringCommon{
+			ProjectID:   n.Account,
+			NetworkName: n.Name,
+			PeeringName: name,
+		},
+		ImportCustomRoutes: update.ImportCustomRoutes,
+		ExportCustomRoutes: update.ExportCustomRoutes,
+	})
+	if err != nil {
+		return peering.Peering{}, err
+	}
+	return p.Get(ctx, n, name)
+}
+
+// AddressSpace will return the ranges of the subnetworks of a google network
+func (p *Peerer) AddressSpace(ctx context.Context, n peering.Network) ([]string, error) {
+	return p.client.GetAddressSpace(ctx, n.Account, n.Name)
+}
+
+func toPeering(selfLink string, np *compute.NetworkPeering) peering.Peering {
+	result := peering.Peering{
+		Provider:      peering.ProviderGoogle,
+		ID:            selfLink + "/peerings/" + np.Name,
+		Name:          np.Name,
+		State:         peering.StateUnknown,
+		Network:       selfLink,
+		RemoteNetwork: np.Network,
+		RouteExchange: peering.RouteExchange{
+			ImportCustomRoutes: np.ImportCustomRoutes,
+			ExportCustomRoutes: np.ExportCustomRoutes,
+		},
+	}
+	switch strings.ToUpper(np.State) {
+	case "ACTIVE":
+		result.State = peering.StateActive
+	case "INACTIVE":
+		result.State = peering.StatePending
+	}
+	return result
+}
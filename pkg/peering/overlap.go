@@ -0,0 +1,91 @@
+package peering
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/naemono/go-cloud-actions/pkg/ipam"
+)
+
+var (
+	// ErrOverlap is the error when the address spaces of the networks of a peering overlap
+	ErrOverlap = errors.New("address spaces overlap")
+	// ErrUnverified is the error when the address space of the remote network of a peering cannot be read, so
+	// overlaps cannot be verified
+	ErrUnverified = errors.New("address spaces cannot be compared")
+)
+
+// Overlap is a prefix of a network's address space overlapping a prefix of its remote network's address space
+type Overlap struct {
+	Prefix       string `json:"prefix"`
+	RemotePrefix string `json:"remotePrefix"`
+}
+
+func (o Overlap) String() string {
+	return fmt.Sprintf("%s overlaps %s", o.Prefix, o.RemotePrefix)
+}
+
+// Overlaps will fetch the address spaces of the network of a create request using local, and of its remote network
+// using remote, and return every pair of overlapping prefixes
+func Overlaps(ctx context.Context, local, remote Peerer, request CreateRequest) ([]Overlap, error) {
+	space, err := local.AddressSpace(ctx, request.Network)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get address space of network %s", request.Network.Name)
+	}
+	remoteSpace, err := remote.AddressSpace(ctx, request.RemoteNetwork)
+	if err != nil {
+		return nil, errors.Wrapf(ErrUnverified, "failed to get address space of remote network %s (%s)",
+			request.RemoteNetwork.Name, err)
+	}
+	overlaps := []Overlap{}
+	for _, prefix := range space {
+		for _, remotePrefix := range remoteSpace {
+			overlapping, err := ipam.Overlaps(prefix, remotePrefix)
+			if err != nil {
+				return nil, err
+			}
+			if overlapping {
+				overlaps = append(overlaps, Overlap{Prefix: prefix, RemotePrefix: remotePrefix})
+			}
+		}
+	}
+	return overlaps, nil
+}
+
+// CheckOverlaps will return an ErrOverlap error listing the overlapping prefixes of the networks of a create
+// request, unless the request allows overlaps
+func CheckOverlaps(ctx context.Context, local, remote Peerer, request CreateRequest) error {
+	if request.AllowOverlap {
+		return nil
+	}
+	overlaps, err := Overlaps(ctx, local, remote, request)
+	if err != nil {
+		return err
+	}
+	if len(overlaps) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, len(overlaps))
+	for _, o := range overlaps {
+		pairs = append(pairs, o.String())
+	}
+	return errors.Wrapf(ErrOverlap, "network %s and remote network %s cannot be peered (%s)",
+		request.Network.Name, request.RemoteNetwork.Name, strings.Join(pairs, ", "))
+}
+
+// CheckLocalOverlaps will check the overlaps of the networks of a create request as CheckOverlaps does, reading both
+// address spaces using p.  As the remote network may not be readable with the credentials of p, a remote address
+// space which cannot be read only warns that the overlaps could not be verified.
+func CheckLocalOverlaps(ctx context.Context, p Peerer, request CreateRequest, logger *logrus.Entry) error {
+	err := CheckOverlaps(ctx, p, p, request)
+	if errors.Is(err, ErrUnverified) {
+		logger.WithError(err).Warnf("unable to verify that network %s and remote network %s do not overlap",
+			request.Network.Name, request.RemoteNetwork.Name)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+package peering
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+// Provider is a public cloud provider that supports network peering
+type Provider string
+
+const (
+	// ProviderAWS is the aws public cloud
+	ProviderAWS Provider = "aws"
+	// ProviderAzure is the azure public cloud
+	ProviderAzure Provider = "azure"
+	// ProviderGoogle is the google public cloud
+	ProviderGoogle Provider = "google"
+)
+
+// State is the provider independent state of a peering
+type State string
+
+const (
+	// StatePending is a peering that has been requested, but is not yet usable (azure Initiated,
+	// google INACTIVE, aws pending-acceptance/provisioning)
+	StatePending State = "Pending"
+	// StateActive is a peering that is connected and passing traffic
+	StateActive State = "Active"
+	// StateInactive is a peering that was once connected, but no longer is (azure Disconnected,
+	// aws rejected/expired/deleted)
+	StateInactive State = "Inactive"
+	// StateDeleting is a peering in the process of being removed
+	StateDeleting State = "Deleting"
+	// StateFailed is a peering that failed to be established
+	StateFailed State = "Failed"
+	// StateUnknown is a peering whose state could not be mapped
+	StateUnknown State = "Unknown"
+)
+
+var (
+	// ErrNotFound is the error when a peering cannot be found
+	ErrNotFound = errors.New("peering not found")
+)
+
+// Network identifies a network in a public cloud: an azure vnet, a google vpc network, or an aws vpc
+type Network struct {
+	// Account is the azure subscription id, google project id, or aws account id owning the network
+	Account string
+	// Group is the azure resource group of the network, and is unused for other providers
+	Group string
+	// Name is the azure vnet name, google network name, or aws vpc id
+	Name string
+	// Region is the aws region of the vpc, and is unused for other providers
+	Region string
+}
+
+// RouteExchange are the flags controlling which traffic and routes are exchanged over a peering.
+// Flags not supported by a provider are ignored.
+type RouteExchange struct {
+	AllowForwardedTraffic bool `json:"allowForwardedTraffic"`
+	AllowGatewayTransit   bool `json:"allowGatewayTransit"`
+	UseRemoteGateways     bool `json:"useRemoteGateways"`
+	ImportCustomRoutes    bool `json:"importCustomRoutes"`
+	ExportCustomRoutes    bool `json:"exportCustomRoutes"`
+}
+
+// Peering is the provider independent view of a network peering
+type Peering struct {
+	Provider      Provider      `json:"provider"`
+	ID            string        `json:"id"`
+	Name          string        `json:"name"`
+	State         State         `json:"state"`
+	Network       string        `json:"network"`
+	RemoteNetwork string        `json:"remoteNetwork"`
+	RouteExchange RouteExchange `json:"routeExchange"`
+	// RemoteAddressSpace are the remote network's prefixes as known to the peering, when available
+	RemoteAddressSpace []string `json:"remoteAddressSpace,omitempty"`
+	// SyncLevel is whether the peering's view of both address spaces is current (azure only)
+	SyncLevel string `json:"syncLevel,omitempty"`
+}
+
+// CreateRequest is a request to create a peering from Network to RemoteNetwork
+type CreateRequest struct {
+	Name          string
+	Network       Network
+	RemoteNetwork Network
+	RouteExchange RouteExchange
+	// AllowOverlap creates the peering even when the address spaces of the networks overlap
+	AllowOverlap bool
+	// Tags are applied to the peering by the providers supporting tags on peerings, which is only aws
+	Tags tags.Tags
+}
+
+// Peerer is implemented by every provider's peering adapter
+type Peerer interface {
+	// Create will create the local half of a peering, and return the resulting peering
+	Create(ctx context.Context, request CreateRequest) (Peering, error)
+	// List will list all peerings of the given network
+	List(ctx context.Context, network Network) ([]Peering, error)
+	// Get will get a single peering of the given network by name (or id), returning ErrNotFound
+	// when it does not exist
+	Get(ctx context.Context, network Network, name string) (Peering, error)
+	// Delete will delete a single peering of the given network by name (or id)
+	Delete(ctx context.Context, network Network, name string) error
+	// AddressSpace will return the prefixes of the address space of the given network
+	AddressSpace(ctx context.Context, network Network) ([]string, error)
+}
+
+// Update are the route exchange flags to change on an existing peering.  Nil flags are left unchanged, and flags not
+// supported by a provider are ignored.
+type Update struct {
+	AllowForwardedTraffic *bool
+	AllowGatewayTransit   *bool
+	UseRemoteGateways     *bool
+	ImportCustomRoutes    *bool
+	ExportCustomRoutes    *bool
+}
+
+// Updater is implemented by the peering adapters of providers whose peerings can be updated in place
+type Updater interface {
+	// Update will change the route exchange flags of a single peering of the given network by name, and return
+	// the updated peering
+	Update(ctx context.Context, network Network, name string, update Update) (Peering, error)
+}
+
+// ParseProvider will parse a provider name into a Provider
+func ParseProvider(s string) (Provider, error) {
+	switch p := Provider(strings.ToLower(s)); p {
+	case ProviderAWS, ProviderAzure, ProviderGoogle:
+		return p, nil
+	}
+	return "", fmt.Errorf("unsupported provider %q, must be one of aws, azure, google", s)
+}
+
+// ParseNetwork will parse a provider specific network reference into a Network.  Accepted formats are:
+//
+//	azure:  /subscriptions/{sub}/resourceGroups/{rg}/providers/Microsoft.Network/virtualNetworks/{vnet},
+//	        {sub}/{rg}/{vnet} or {rg}/{vnet}
+//	google: https://www.googleapis.com/compute/v1/projects/{project}/global/networks/{network},
+//	        projects/{project}/global/networks/{network} or {project}/{network}
+//	aws:    {account}/{vpc-id} or {vpc-id}
+func ParseNetwork(provider Provider, s string) (Network, error) {
+	var n Network
+	parts := strings.Split(strings.Trim(s, "/"), "/")
+	switch provider {
+	case ProviderAzure:
+		if len(parts) == 8 && strings.EqualFold(parts[0], "subscriptions") && strings.EqualFold(parts[2], "resourceGroups") {
+			return Network{Account: parts[1], Group: parts[3], Name: parts[7]}, nil
+		}
+		switch len(parts) {
+		case 3:
+			n = Network{Account: parts[0], Group: parts[1], Name: parts[2]}
+		case 2:
+			n = Network{Group: parts[0], Name: parts[1]}
+		}
+	case ProviderGoogle:
+		for i := range parts {
+			if parts[i] == "projects" && len(parts) == i+5 && parts[i+2] == "global" && parts[i+3] == "networks" {
+				return Network{Account: parts[i+1], Name: parts[i+4]}, nil
+			}
+		}
+		if len(parts) == 2 {
+			n = Network{Account: parts[0], Name: parts[1]}
+		}
+	case ProviderAWS:
+		switch len(parts) {
+		case 2:
+			n = Network{Account: parts[0], Name: parts[1]}
+		case 1:
+			n = Network{Name: parts[0]}
+		}
+	default:
+		return n, fmt.Errorf("unsupported provider %q", provider)
+	}
+	if n.Name == "" {
+		return n, fmt.Errorf("invalid %s network reference %q", provider, s)
+	}
+	return n, nil
+}
@@ -0,0 +1,118 @@
+package plan
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Action is what a command would do to a single resource
+type Action string
+
+const (
+	// ActionCreate is a resource which does not exist, and would be created
+	ActionCreate Action = "create"
+	// ActionUpdate is an existing resource which would be changed
+	ActionUpdate Action = "update"
+	// ActionDelete is an existing resource which would be deleted
+	ActionDelete Action = "delete"
+	// ActionNoOp is a resource which would be left unchanged
+	ActionNoOp Action = "no-op"
+)
+
+// ExitCodeChangesPending is the exit code of a command planning changes, so that ci pipelines can tell a pending
+// change from both success (0) and failure (1)
+const ExitCodeChangesPending = 2
+
+var (
+	// ErrChangesPending is the error returned once a plan with changes has been printed
+	ErrChangesPending = errors.New("changes are pending")
+)
+
+// Change is a single field whose actual value differs from the desired one.  An empty actual value is a field
+// being set, and an empty desired value a field being removed.
+type Change struct {
+	Field   string `json:"field"`
+	Actual  string `json:"actual"`
+	Desired string `json:"desired"`
+}
+
+// ResourceChange is the change a command would make to a single resource
+type ResourceChange struct {
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Action  Action   `json:"action"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Plan is the changes a command would make, in the order it would make them
+type Plan struct {
+	Resources []ResourceChange `json:"resources"`
+}
+
+// Add will add the change of a resource to the plan
+func (p *Plan) Add(changes ...ResourceChange) {
+	p.Resources = append(p.Resources, changes...)
+}
+
+// HasChanges will return whether any resource would be created, updated or deleted
+func (p Plan) HasChanges() bool {
+	for _, r := range p.Resources {
+		if r.Action != ActionNoOp {
+			return true
+		}
+	}
+	return false
+}
+
+// Create will return the creation of a resource with the given fields
+func Create(kind, name string, desired map[string]string) ResourceChange {
+	return ResourceChange{Kind: kind, Name: name, Action: ActionCreate, Changes: Diff(desired, nil)}
+}
+
+// Update will return the update of an existing resource to the desired fields, which is a no-op when every
+// desired field already has its actual value
+func Update(kind, name string, desired, actual map[string]string) ResourceChange {
+	r := ResourceChange{Kind: kind, Name: name, Action: ActionNoOp, Changes: Diff(desired, actual)}
+	if len(r.Changes) > 0 {
+		r.Action = ActionUpdate
+	}
+	return r
+}
+
+// Delete will return the deletion of an existing resource with the given fields
+func Delete(kind, name string, actual map[string]string) ResourceChange {
+	r := ResourceChange{Kind: kind, Name: name, Action: ActionDelete}
+	for _, field := range sortedKeys(actual) {
+		if actual[field] != "" {
+			r.Changes = append(r.Changes, Change{Field: field, Actual: actual[field]})
+		}
+	}
+	return r
+}
+
+// NoOp will return a resource which would be left unchanged
+func NoOp(kind, name string) ResourceChange {
+	return ResourceChange{Kind: kind, Name: name, Action: ActionNoOp}
+}
+
+// Diff will return the desired fields which differ from the actual ones, sorted by field.  Empty desired fields are
+// left to the provider, and are never compared.
+func Diff(desired, actual map[string]string) []Change {
+	var changes []Change
+	for _, field := range sortedKeys(desired) {
+		if value := desired[field]; value != "" && value != actual[field] {
+			changes = append(changes, Change{Field: field, Actual: actual[field], Desired: value})
+		}
+	}
+	return changes
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
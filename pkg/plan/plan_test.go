@@ -0,0 +1,136 @@
+package plan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired map[string]string
+		actual  map[string]string
+		want    []Change
+	}{
+		{
+			name:    "equal",
+			desired: map[string]string{"a": "1", "b": "2"},
+			actual:  map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:    "changed fields sorted",
+			desired: map[string]string{"c": "3", "a": "1", "b": "2"},
+			actual:  map[string]string{"a": "0", "b": "2", "c": "0"},
+			want: []Change{
+				{Field: "a", Actual: "0", Desired: "1"},
+				{Field: "c", Actual: "0", Desired: "3"},
+			},
+		},
+		{
+			name:    "field being set",
+			desired: map[string]string{"a": "1"},
+			actual:  map[string]string{},
+			want:    []Change{{Field: "a", Desired: "1"}},
+		},
+		{
+			name:    "nil actual",
+			desired: map[string]string{"a": "1"},
+			want:    []Change{{Field: "a", Desired: "1"}},
+		},
+		{
+			name:    "empty desired fields are left to the provider",
+			desired: map[string]string{"a": "", "b": "2"},
+			actual:  map[string]string{"a": "provider default", "b": "2"},
+		},
+		{
+			name:    "actual fields which are not desired are ignored",
+			desired: map[string]string{"a": "1"},
+			actual:  map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:    "values are compared exactly",
+			desired: map[string]string{"a": "East"},
+			actual:  map[string]string{"a": "east"},
+			want:    []Change{{Field: "a", Actual: "east", Desired: "East"}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.desired, tt.actual); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired map[string]string
+		actual  map[string]string
+		want    ResourceChange
+	}{
+		{
+			name:    "unchanged is a no-op",
+			desired: map[string]string{"a": "1"},
+			actual:  map[string]string{"a": "1", "b": "2"},
+			want:    ResourceChange{Kind: "VPC", Name: "main", Action: ActionNoOp},
+		},
+		{
+			name:    "only empty desired fields is a no-op",
+			desired: map[string]string{"a": ""},
+			actual:  map[string]string{"a": "1"},
+			want:    ResourceChange{Kind: "VPC", Name: "main", Action: ActionNoOp},
+		},
+		{
+			name:    "changed is an update",
+			desired: map[string]string{"a": "2"},
+			actual:  map[string]string{"a": "1"},
+			want: ResourceChange{Kind: "VPC", Name: "main", Action: ActionUpdate, Changes: []Change{
+				{Field: "a", Actual: "1", Desired: "2"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Update("VPC", "main", tt.desired, tt.actual); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Update() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateAndDelete(t *testing.T) {
+	created := Create("VPC", "main", map[string]string{"cidr": "10.0.0.0/16", "name": ""})
+	want := ResourceChange{Kind: "VPC", Name: "main", Action: ActionCreate, Changes: []Change{
+		{Field: "cidr", Desired: "10.0.0.0/16"},
+	}}
+	if !reflect.DeepEqual(created, want) {
+		t.Errorf("Create() = %+v, want %+v", created, want)
+	}
+
+	deleted := Delete("VPC", "main", map[string]string{"id": "vpc-1", "state": ""})
+	want = ResourceChange{Kind: "VPC", Name: "main", Action: ActionDelete, Changes: []Change{
+		{Field: "id", Actual: "vpc-1"},
+	}}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("Delete() = %+v, want %+v", deleted, want)
+	}
+}
+
+func TestHasChanges(t *testing.T) {
+	var p Plan
+	if p.HasChanges() {
+		t.Errorf("empty plan has changes")
+	}
+	p.Add(NoOp("VPC", "a"), Update("VPC", "b", map[string]string{"a": "1"}, map[string]string{"a": "1"}))
+	if p.HasChanges() {
+		t.Errorf("plan of no-ops has changes")
+	}
+	p.Add(Delete("VPC", "c", nil))
+	if !p.HasChanges() {
+		t.Errorf("plan with a deletion has no changes")
+	}
+}
@@ -0,0 +1,307 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// node is a parsed piece of a jsonpath template: literal text, a path expression, or a range over a path
+// expression with a body of nodes
+type node struct {
+	text     string
+	path     string
+	isRange  bool
+	children []node
+}
+
+// ExecuteJSONPath will execute a jsonpath template, in the style of kubectl, against generic json data.
+// Text outside of braces is printed as is, and each {expression} is replaced by its results separated by
+// spaces.  Supported expressions are:
+//
+//	fields            {.name} {.properties.state} {['name']}
+//	indexes           {[0]} {.items[-1]}
+//	wildcards         {[*].name} {.tags.*}
+//	recursive descent {..id}
+//	string literals   {"\n"} {'\t'}
+//	ranges            {range [*]}{.name}{"\n"}{end}
+func ExecuteJSONPath(template string, data interface{}) (string, error) {
+	nodes, _, err := parseJSONPath(template, false)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err = executeNodes(&sb, nodes, data, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// parseJSONPath will parse a template into nodes, until the end of the template, or until {end} when
+// inRange, returning the unparsed remainder after {end}
+func parseJSONPath(template string, inRange bool) ([]node, string, error) {
+	var nodes []node
+	for template != "" {
+		start := strings.Index(template, "{")
+		if start < 0 {
+			nodes = append(nodes, node{text: template})
+			template = ""
+			break
+		}
+		if start > 0 {
+			nodes = append(nodes, node{text: template[:start]})
+		}
+		end := closingBrace(template, start)
+		if end < 0 {
+			return nil, "", errors.Errorf("invalid jsonpath template: unclosed brace in %q", template[start:])
+		}
+		expr := strings.TrimSpace(template[start+1 : end])
+		template = template[end+1:]
+		switch {
+		case expr == "end":
+			if !inRange {
+				return nil, "", errors.New("invalid jsonpath template: {end} without {range}")
+			}
+			return nodes, template, nil
+		case strings.HasPrefix(expr, "range "):
+			children, rest, err := parseJSONPath(template, true)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node{path: strings.TrimSpace(strings.TrimPrefix(expr, "range ")), isRange: true, children: children})
+			template = rest
+		case strings.HasPrefix(expr, `"`) || strings.HasPrefix(expr, "'"):
+			text, err := unquote(expr)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node{text: text})
+		default:
+			nodes = append(nodes, node{path: expr})
+		}
+	}
+	if inRange {
+		return nil, "", errors.New("invalid jsonpath template: {range} without {end}")
+	}
+	return nodes, "", nil
+}
+
+// closingBrace will return the index of the brace closing the one at start, skipping quoted strings
+func closingBrace(s string, start int) int {
+	var quote byte
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == '\\':
+			i++
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote == 0 && (s[i] == '"' || s[i] == '\''):
+			quote = s[i]
+		case quote == 0 && s[i] == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, "'") {
+		s = `"` + strings.ReplaceAll(strings.Trim(s, "'"), `"`, `\"`) + `"`
+	}
+	text, err := strconv.Unquote(s)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid jsonpath string literal %s", s)
+	}
+	return text, nil
+}
+
+func executeNodes(sb *strings.Builder, nodes []node, root, current interface{}) error {
+	for _, n := range nodes {
+		if n.path == "" {
+			sb.WriteString(n.text)
+			continue
+		}
+		results, err := evalPath(n.path, root, current)
+		if err != nil {
+			return err
+		}
+		if n.isRange {
+			for _, result := range results {
+				if err = executeNodes(sb, n.children, root, result); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		values := make([]string, 0, len(results))
+		for _, result := range results {
+			value, err := formatValue(result)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		sb.WriteString(strings.Join(values, " "))
+	}
+	return nil
+}
+
+// evalPath will evaluate a path expression against the current data, or the root data for paths starting with $
+func evalPath(path string, root, current interface{}) ([]interface{}, error) {
+	results := []interface{}{current}
+	if strings.HasPrefix(path, "$") {
+		results = []interface{}{root}
+		path = path[1:]
+	}
+	for path != "" {
+		var next []interface{}
+		switch {
+		case strings.HasPrefix(path, ".."):
+			name, rest := fieldName(path[2:])
+			for _, r := range results {
+				next = append(next, descend(r, name)...)
+			}
+			path = rest
+		case strings.HasPrefix(path, "."):
+			name, rest := fieldName(path[1:])
+			for _, r := range results {
+				next = append(next, field(r, name)...)
+			}
+			path = rest
+		case strings.HasPrefix(path, "["):
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, errors.Errorf("invalid jsonpath expression: unclosed bracket in %q", path)
+			}
+			subscript := strings.TrimSpace(path[1:end])
+			path = path[end+1:]
+			for _, r := range results {
+				values, err := index(r, subscript)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, values...)
+			}
+		default:
+			return nil, errors.Errorf("invalid jsonpath expression: unexpected %q", path)
+		}
+		results = next
+	}
+	return results, nil
+}
+
+// fieldName will split the leading field name from the rest of a path
+func fieldName(path string) (string, string) {
+	end := strings.IndexAny(path, ".[")
+	if end < 0 {
+		return path, ""
+	}
+	return path[:end], path[end:]
+}
+
+// field will return a named field of an object, every value of an object or array for *, or the data itself
+// for an empty name
+func field(data interface{}, name string) []interface{} {
+	if name == "" {
+		return []interface{}{data}
+	}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if name == "*" {
+			return mapValues(v)
+		}
+		if value, ok := v[name]; ok {
+			return []interface{}{value}
+		}
+	case []interface{}:
+		if name == "*" {
+			return v
+		}
+	}
+	return nil
+}
+
+// descend will return the named field of data and of everything nested within it
+func descend(data interface{}, name string) []interface{} {
+	results := field(data, name)
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for _, value := range mapValues(v) {
+			results = append(results, descend(value, name)...)
+		}
+	case []interface{}:
+		for _, value := range v {
+			results = append(results, descend(value, name)...)
+		}
+	}
+	return results
+}
+
+// index will apply a bracket subscript: *, a quoted field name, or an array index which may be negative
+func index(data interface{}, subscript string) ([]interface{}, error) {
+	if subscript == "*" {
+		return field(data, "*"), nil
+	}
+	if strings.HasPrefix(subscript, "'") || strings.HasPrefix(subscript, `"`) {
+		name, err := unquote(subscript)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := data.(map[string]interface{}); ok {
+			if value, ok := m[name]; ok {
+				return []interface{}{value}, nil
+			}
+		}
+		return nil, nil
+	}
+	i, err := strconv.Atoi(subscript)
+	if err != nil {
+		return nil, errors.Errorf("invalid jsonpath subscript [%s]", subscript)
+	}
+	array, ok := data.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if i < 0 {
+		i += len(array)
+	}
+	if i < 0 || i >= len(array) {
+		return nil, nil
+	}
+	return []interface{}{array[i]}, nil
+}
+
+// mapValues will return the values of an object ordered by key, so that output is stable
+func mapValues(m map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]interface{}, 0, len(m))
+	for _, key := range keys {
+		values = append(values, m[key])
+	}
+	return values
+}
+
+// formatValue will print scalars as is, and objects and arrays as compact json
+func formatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number, bool:
+		return fmt.Sprint(v), nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal jsonpath result")
+	}
+	return string(b), nil
+}
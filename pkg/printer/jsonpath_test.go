@@ -0,0 +1,102 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+const testData = `{
+	"items": [
+		{"name": "a", "id": 1, "tags": {"env": "dev", "team": "x"}},
+		{"name": "b", "id": 2, "tags": {"env": "prod"}},
+		{"name": "c", "id": 3, "nested": {"id": 4}}
+	],
+	"odd key": "quoted",
+	"dotted.key": "dotted",
+	"enabled": true,
+	"empty": null
+}`
+
+func testGeneric(t *testing.T) interface{} {
+	t.Helper()
+	var data interface{}
+	d := json.NewDecoder(bytes.NewReader([]byte(testData)))
+	d.UseNumber()
+	if err := d.Decode(&data); err != nil {
+		t.Fatalf("failed to decode test data: %v", err)
+	}
+	return data
+}
+
+func TestExecuteJSONPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "field", template: "{.items[0].name}", want: "a"},
+		{name: "root field", template: "{$.enabled}", want: "true"},
+		{name: "null field", template: "{.empty}", want: ""},
+		{name: "missing field", template: "{.missing}", want: ""},
+		{name: "text around expressions", template: "name={.items[1].name}!", want: "name=b!"},
+		{name: "object as json", template: "{.items[1].tags}", want: `{"env":"prod"}`},
+		{name: "index", template: "{.items[2].id}", want: "3"},
+		{name: "negative index", template: "{.items[-1].name}", want: "c"},
+		{name: "out of range index", template: "{.items[5].name}", want: ""},
+		{name: "negative out of range index", template: "{.items[-4].name}", want: ""},
+		{name: "bracket wildcard", template: "{.items[*].name}", want: "a b c"},
+		{name: "dot wildcard of object ordered by key", template: "{.items[0].tags.*}", want: "dev x"},
+		{name: "dot wildcard of array", template: "{.items.*.id}", want: "1 2 3"},
+		{name: "recursive descent", template: "{..id}", want: "1 2 3 4"},
+		{name: "recursive descent below field", template: "{.items[2]..id}", want: "3 4"},
+		{name: "single quoted key", template: "{['odd key']}", want: "quoted"},
+		{name: "double quoted key", template: `{["dotted.key"]}`, want: "dotted"},
+		{name: "quoted key of missing field", template: "{['nope']}", want: ""},
+		{name: "string literals", template: `{.items[0].name}{"\t"}{.items[1].name}{'\n'}`, want: "a\tb\n"},
+		{name: "escaped braces", template: `{"{"}{.items[0].name}{"}"}`, want: "{a}"},
+		{name: "brace in quoted key", template: `{['a}b']}`, want: ""},
+		{name: "range", template: `{range .items[*]}{.name}={.id}{"\n"}{end}`, want: "a=1\nb=2\nc=3\n"},
+		{name: "range with root reference", template: `{range .items[*]}{.name}:{$.enabled} {end}`, want: "a:true b:true c:true "},
+		{name: "nested range", template: `{range .items[*]}{.name}:{range .tags.*}[{.}]{end};{end}`, want: "a:[dev][x];b:[prod];c:;"},
+		{name: "range over object values", template: `{range .items[0].tags.*}<{.}>{end}`, want: "<dev><x>"},
+		{name: "text after range", template: `{range .items[*]}{.id}{end} done`, want: "123 done"},
+	}
+	data := testGeneric(t)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExecuteJSONPath(tt.template, data)
+			if err != nil {
+				t.Fatalf("ExecuteJSONPath(%q) error = %v", tt.template, err)
+			}
+			if got != tt.want {
+				t.Errorf("ExecuteJSONPath(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{name: "unclosed brace", template: "{.items"},
+		{name: "end without range", template: "{.name}{end}"},
+		{name: "range without end", template: "{range .items[*]}{.name}"},
+		{name: "unclosed bracket", template: "{.items[0}"},
+		{name: "invalid subscript", template: "{.items[x]}"},
+		{name: "unexpected expression", template: "{name}"},
+		{name: "invalid string literal", template: `{"\q"}`},
+	}
+	data := testGeneric(t)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := ExecuteJSONPath(tt.template, data); err == nil {
+				t.Errorf("ExecuteJSONPath(%q) = %q, want an error", tt.template, got)
+			}
+		})
+	}
+}
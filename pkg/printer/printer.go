@@ -0,0 +1,237 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format
+type Format string
+
+const (
+	// FormatTable prints the narrow columns of a table
+	FormatTable Format = "table"
+	// FormatWide prints every column of a table
+	FormatWide Format = "wide"
+	// FormatJSON prints the data as indented json
+	FormatJSON Format = "json"
+	// FormatYAML prints the data as yaml
+	FormatYAML Format = "yaml"
+	// FormatJSONPath prints the result of a jsonpath template applied to the json form of the data
+	FormatJSONPath Format = "jsonpath"
+	// FormatGoTemplate prints the result of a go template applied to the json form of the data
+	FormatGoTemplate Format = "go-template"
+)
+
+// Column is a single column of a table
+type Column struct {
+	Header string
+	// Wide columns are only printed with the wide format
+	Wide bool
+}
+
+// Table is the tabular view of printed data
+type Table struct {
+	Columns []Column
+	Rows    [][]string
+}
+
+// AddRow will add a row of values, one per column, to the table
+func (t *Table) AddRow(values ...string) {
+	t.Rows = append(t.Rows, values)
+}
+
+// Output is the parsed value of an output flag, in the form {format} or {format}={expression}
+type Output struct {
+	Format     Format
+	Expression string
+}
+
+// Parse will parse an output flag value such as wide, json or jsonpath={.items[*].name}
+func Parse(s string) (Output, error) {
+	if s == "" {
+		return Output{Format: FormatTable}, nil
+	}
+	format, expression := s, ""
+	if i := strings.Index(s, "="); i >= 0 {
+		format, expression = s[:i], s[i+1:]
+	}
+	o := Output{Format: Format(strings.ToLower(format)), Expression: expression}
+	switch o.Format {
+	case FormatTable, FormatWide, FormatJSON, FormatYAML:
+		if expression != "" {
+			return o, fmt.Errorf("output format %s does not take an expression", o.Format)
+		}
+	case "template":
+		o.Format = FormatGoTemplate
+		fallthrough
+	case FormatJSONPath, FormatGoTemplate:
+		if expression == "" {
+			return o, fmt.Errorf("output format %s requires an expression, as %s=<template>", o.Format, o.Format)
+		}
+	default:
+		return o, fmt.Errorf("unsupported output format %q, must be one of table, wide, json, yaml, jsonpath=<template>, go-template=<template>", format)
+	}
+	return o, nil
+}
+
+// String will return the output in flag form
+func (o *Output) String() string {
+	if o.Format == "" {
+		return string(FormatTable)
+	}
+	if o.Expression == "" {
+		return string(o.Format)
+	}
+	return fmt.Sprintf("%s=%s", o.Format, o.Expression)
+}
+
+// Set will parse and set the output from a flag, so that invalid formats are rejected before a command runs
+func (o *Output) Set(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*o = parsed
+	return nil
+}
+
+// Type is the flag type of the output
+func (o *Output) Type() string {
+	return "format"
+}
+
+// Printer prints data in a configured output format
+type Printer struct {
+	Output
+	Out io.Writer
+}
+
+// New will return a new printer for an output flag value, writing to out
+func New(output string, out io.Writer) (*Printer, error) {
+	o, err := Parse(output)
+	if err != nil {
+		return nil, err
+	}
+	return &Printer{Output: o, Out: out}, nil
+}
+
+// Print will print data in the configured format.  Table formats print the given table, all other
+// formats print the data itself.
+func (p *Printer) Print(data interface{}, table Table) error {
+	switch p.Format {
+	case FormatJSON:
+		b, err := json.MarshalIndent(data, "", "    ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal output to json")
+		}
+		_, err = fmt.Fprintln(p.Out, string(b))
+		return err
+	case FormatYAML:
+		generic, err := toYAMLGeneric(data)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(p.Out)
+		enc.SetIndent(2)
+		if err = enc.Encode(generic); err != nil {
+			return errors.Wrap(err, "failed to marshal output to yaml")
+		}
+		return enc.Close()
+	case FormatJSONPath:
+		generic, err := toGeneric(data)
+		if err != nil {
+			return err
+		}
+		result, err := ExecuteJSONPath(p.Expression, generic)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.Out, result)
+		return err
+	case FormatGoTemplate:
+		generic, err := toGeneric(data)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New("output").Parse(p.Expression)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse go template")
+		}
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, generic); err != nil {
+			return errors.Wrap(err, "failed to execute go template")
+		}
+		_, err = fmt.Fprintln(p.Out, buf.String())
+		return err
+	}
+	return p.printTable(table)
+}
+
+func (p *Printer) printTable(table Table) error {
+	w := tabwriter.NewWriter(p.Out, 0, 8, 3, ' ', 0)
+	var headers []string
+	for _, c := range table.Columns {
+		if c.Wide && p.Format != FormatWide {
+			continue
+		}
+		headers = append(headers, strings.ToUpper(c.Header))
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range table.Rows {
+		var values []string
+		for i, c := range table.Columns {
+			if c.Wide && p.Format != FormatWide {
+				continue
+			}
+			value := ""
+			if i < len(row) {
+				value = row[i]
+			}
+			if value == "" {
+				value = "<none>"
+			}
+			values = append(values, value)
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
+
+// toGeneric will convert data to its generic json form of maps, slices and scalars, so that field names
+// follow the json tags of the data
+func toGeneric(data interface{}) (interface{}, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal output to json")
+	}
+	var generic interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err = d.Decode(&generic); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal output from json")
+	}
+	return generic, nil
+}
+
+// toYAMLGeneric will convert data to its generic json form, as toGeneric does, but decoded as yaml, of which json is
+// a subset, so that numbers are ints or floats rather than json numbers
+func toYAMLGeneric(data interface{}) (interface{}, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal output to json")
+	}
+	var generic interface{}
+	if err = yaml.Unmarshal(b, &generic); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal output from json")
+	}
+	return generic, nil
+}
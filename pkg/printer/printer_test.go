@@ -0,0 +1,47 @@
+package printer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintYAML(t *testing.T) {
+	data := struct {
+		Name   string            `json:"name"`
+		Count  int64             `json:"count"`
+		Ratio  float64           `json:"ratio"`
+		Number string            `json:"number"`
+		Tags   map[string]string `json:"tags"`
+		Items  []int             `json:"items"`
+	}{
+		Name:   "a",
+		Count:  9007199254740993,
+		Ratio:  1.5,
+		Number: "3",
+		Tags:   map[string]string{"b": "y: z", "a": "x"},
+		Items:  []int{1, 2},
+	}
+	// fields are sorted, as are those of every generic map
+	want := `count: 9007199254740993
+items:
+  - 1
+  - 2
+name: a
+number: "3"
+ratio: 1.5
+tags:
+  a: x
+  b: 'y: z'
+`
+	var out bytes.Buffer
+	p, err := New("yaml", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Print(data, Table{}); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("Print() = %q, want %q", out.String(), want)
+	}
+}
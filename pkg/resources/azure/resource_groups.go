@@ -2,20 +2,30 @@ package azure
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
 
+	"github.com/Azure/go-autorest/autorest"
 	"github.com/pkg/errors"
 
 	"github.com/sirupsen/logrus"
 
 	azure_auth "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+var (
+	// ErrNotFound is the error when a resource group cannot be found
+	ErrNotFound = errors.New("resource group not found")
 )
 
 // Config is the configuration for the azure resources package
 type Config struct {
 	azure_auth.AuthConfig
 	Logger *logrus.Entry
+	// Factory is the azure client factory to share with other clients, created from AuthConfig when nil
+	Factory *azure_auth.ClientFactory
 }
 
 // Client is the client for the azure resources package
@@ -30,26 +40,100 @@ func New(conf Config) (*Client, error) {
 	c := &Client{
 		Config: conf,
 	}
-	c.groupsClient, err = azure_auth.NewGroupsClient(conf.AuthConfig)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate new groups client")
-	}
 	if c.Logger == nil {
 		c.Logger = logrus.NewEntry(logrus.New())
 		c.Logger.Logger.SetLevel(logrus.InfoLevel)
 		c.Logger.Logger.SetFormatter(&logrus.JSONFormatter{})
 	}
+	if c.Factory == nil {
+		c.Factory, err = azure_auth.NewClientFactory(azure_auth.FactoryConfig{AuthConfig: conf.AuthConfig, Logger: c.Logger})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get new azure client factory")
+		}
+	}
+	c.groupsClient, err = c.Factory.GroupsClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new groups client")
+	}
 	return c, nil
 }
 
-// CreateResourceGroup will create an azure resource group
-func (c *Client) CreateResourceGroup(ctx context.Context, name, location string) error {
-	_, err := c.groupsClient.CreateOrUpdate(ctx, name, resources.Group{
+// CreateResourceGroup will create an azure resource group with the given tags, returning the resulting group
+func (c *Client) CreateResourceGroup(ctx context.Context, name, location string, t tags.Tags) (resources.Group, error) {
+	group, err := c.groupsClient.CreateOrUpdate(ctx, name, resources.Group{
 		Name:     &name,
 		Location: &location,
+		Tags:     t.Azure(),
 	})
 	if err != nil {
-		return errors.Wrapf(err, "failed to create resource group %s", name)
+		return group, errors.Wrapf(err, "failed to create resource group %s", name)
+	}
+	return group, nil
+}
+
+// GetResourceGroup will get an existing azure resource group, returning ErrNotFound if it does not exist
+func (c *Client) GetResourceGroup(ctx context.Context, name string) (resources.Group, error) {
+	group, err := c.groupsClient.Get(ctx, name)
+	if err != nil {
+		if isNotFound(err) {
+			return group, ErrNotFound
+		}
+		return group, errors.Wrapf(err, "failed to get resource group %s", name)
+	}
+	return group, nil
+}
+
+// ListResourceGroups will list the resource groups having every one of the given tags, or all of them when there
+// are none, following every page of results
+func (c *Client) ListResourceGroups(ctx context.Context, tags map[string]string) ([]resources.Group, error) {
+	page, err := c.groupsClient.List(ctx, "", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list resource groups")
+	}
+	groups := []resources.Group{}
+	for page.NotDone() {
+		for _, group := range page.Values() {
+			if hasTags(group.Tags, tags) {
+				groups = append(groups, group)
+			}
+		}
+		if err = page.NextWithContext(ctx); err != nil {
+			return nil, errors.Wrap(err, "failed to list next page of resource groups")
+		}
+	}
+	return groups, nil
+}
+
+// DeleteResourceGroup will delete a resource group, along with every resource in it, and wait for the deletion to
+// complete.  A resource group which does not exist is not an error.
+func (c *Client) DeleteResourceGroup(ctx context.Context, name string) error {
+	future, err := c.groupsClient.Delete(ctx, name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete resource group %s", name)
+	}
+	if err = future.WaitForCompletionRef(ctx, c.groupsClient.Client); err != nil {
+		return errors.Wrapf(err, "failed waiting for resource group %s to be deleted", name)
 	}
 	return nil
 }
+
+// hasTags will return whether tags contains every one of the wanted tags
+func hasTags(tags map[string]*string, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if tag, ok := tags[key]; !ok || tag == nil || *tag != value {
+			return false
+		}
+	}
+	return true
+}
+
+func isNotFound(err error) bool {
+	var de autorest.DetailedError
+	if errors.As(err, &de) {
+		return de.StatusCode == http.StatusNotFound
+	}
+	return false
+}
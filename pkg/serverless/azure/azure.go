@@ -2,26 +2,38 @@ package azure
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
 
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/pkg/errors"
 
 	"github.com/sirupsen/logrus"
 
 	azure_auth "github.com/naemono/go-cloud-actions/pkg/auth/azure"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
+)
+
+var (
+	// ErrNotFound is the error when a container group cannot be found
+	ErrNotFound = errors.New("container group not found")
 )
 
 // Config is the configuration for the azure serverless package
 type Config struct {
 	azure_auth.AuthConfig
 	Logger *logrus.Entry
+	// Factory is the azure client factory to share with other clients, created from AuthConfig when nil
+	Factory *azure_auth.ClientFactory
 }
 
 // Client is the client fo rthe azure serverless package
 type Client struct {
 	Config
-	cgClient containerinstance.ContainerGroupsClient
+	cgClient  containerinstance.ContainerGroupsClient
+	ctrClient containerinstance.ContainersClient
 }
 
 // CreateContainerRequest is a request to create an azure Container Instance
@@ -32,6 +44,7 @@ type CreateContainerRequest struct {
 	Location                 string                                     `json:"location" yaml:"location"`
 	ResourceGroupName        string                                     `json:"resourceGroup" yaml:"resourceGroup"`
 	ContainerGroupProperties containerinstance.ContainerGroupProperties `json:"properties" yaml:"properties"`
+	Tags                     tags.Tags                                  `json:"tags,omitempty" yaml:"tags,omitempty"`
 }
 
 // New will return a new azure serverless (container instances) client
@@ -40,15 +53,25 @@ func New(conf Config) (*Client, error) {
 	c := &Client{
 		Config: conf,
 	}
-	c.cgClient, err = azure_auth.NewContainerInstanceClient(conf.AuthConfig)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate new container service client")
-	}
 	if c.Logger == nil {
 		c.Logger = logrus.NewEntry(logrus.New())
 		c.Logger.Logger.SetLevel(logrus.InfoLevel)
 		c.Logger.Logger.SetFormatter(&logrus.JSONFormatter{})
 	}
+	if c.Factory == nil {
+		c.Factory, err = azure_auth.NewClientFactory(azure_auth.FactoryConfig{AuthConfig: conf.AuthConfig, Logger: c.Logger})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get new azure client factory")
+		}
+	}
+	c.cgClient, err = c.Factory.ContainerGroupsClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new container service client")
+	}
+	c.ctrClient, err = c.Factory.ContainersClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new containers client")
+	}
 	return c, nil
 }
 
@@ -63,6 +86,7 @@ func (c *Client) CreateContainerGroup(ctx context.Context, req CreateContainerRe
 			Name:                     &req.ContainerGroupName,
 			Location:                 &req.Location,
 			ContainerGroupProperties: &req.ContainerGroupProperties,
+			Tags:                     req.Tags.Azure(),
 		})
 
 	if err != nil {
@@ -75,3 +99,120 @@ func (c *Client) CreateContainerGroup(ctx context.Context, req CreateContainerRe
 	}
 	return future.Result(c.cgClient)
 }
+
+// GetContainerGroup will get a single container group, returning ErrNotFound if it does not exist
+func (c *Client) GetContainerGroup(ctx context.Context, resourceGroupName, name string) (containerinstance.ContainerGroup, error) {
+	cg, err := c.cgClient.Get(ctx, resourceGroupName, name)
+	if err != nil {
+		if isNotFound(err) {
+			return cg, ErrNotFound
+		}
+		return cg, errors.Wrapf(err, "failed to get container group %s", name)
+	}
+	return cg, nil
+}
+
+// ListContainerGroups will list all container groups of a resource group, following every page of results
+func (c *Client) ListContainerGroups(ctx context.Context, resourceGroupName string) ([]containerinstance.ContainerGroup, error) {
+	page, err := c.cgClient.ListByResourceGroup(ctx, resourceGroupName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list container groups in resource group %s", resourceGroupName)
+	}
+	groups := []containerinstance.ContainerGroup{}
+	for page.NotDone() {
+		groups = append(groups, page.Values()...)
+		if err = page.NextWithContext(ctx); err != nil {
+			return nil, errors.Wrapf(err, "failed to list next page of container groups in resource group %s", resourceGroupName)
+		}
+	}
+	return groups, nil
+}
+
+// DeleteContainerGroup will delete a container group, and wait for the deletion to complete.  A container group
+// which does not exist is not an error.
+func (c *Client) DeleteContainerGroup(ctx context.Context, resourceGroupName, name string) error {
+	future, err := c.cgClient.Delete(ctx, resourceGroupName, name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete container group %s", name)
+	}
+	if err = future.WaitForCompletionRef(ctx, c.cgClient.Client); err != nil {
+		return errors.Wrapf(err, "failed waiting for container group %s to be deleted", name)
+	}
+	return nil
+}
+
+// RestartContainerGroup will restart every container of a container group in place, and wait for the restart to
+// complete
+func (c *Client) RestartContainerGroup(ctx context.Context, resourceGroupName, name string) error {
+	future, err := c.cgClient.Restart(ctx, resourceGroupName, name)
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return errors.Wrapf(err, "failed to restart container group %s", name)
+	}
+	if err = future.WaitForCompletionRef(ctx, c.cgClient.Client); err != nil {
+		return errors.Wrapf(err, "failed waiting for container group %s to restart", name)
+	}
+	return nil
+}
+
+// StartContainerGroup will start every container of a stopped container group, and wait for them to start
+func (c *Client) StartContainerGroup(ctx context.Context, resourceGroupName, name string) error {
+	future, err := c.cgClient.Start(ctx, resourceGroupName, name)
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return errors.Wrapf(err, "failed to start container group %s", name)
+	}
+	if err = future.WaitForCompletionRef(ctx, c.cgClient.Client); err != nil {
+		return errors.Wrapf(err, "failed waiting for container group %s to start", name)
+	}
+	return nil
+}
+
+// StopContainerGroup will stop every container of a container group, which keeps its configuration, but releases
+// its compute, and is no longer billed
+func (c *Client) StopContainerGroup(ctx context.Context, resourceGroupName, name string) error {
+	if _, err := c.cgClient.Stop(ctx, resourceGroupName, name); err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return errors.Wrapf(err, "failed to stop container group %s", name)
+	}
+	return nil
+}
+
+// ContainerName will return the given container name, or the name of the only container of a container group when
+// it is empty
+func (c *Client) ContainerName(ctx context.Context, resourceGroupName, containerGroupName, name string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+	cg, err := c.GetContainerGroup(ctx, resourceGroupName, containerGroupName)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	if cg.ContainerGroupProperties != nil && cg.Containers != nil {
+		for _, container := range *cg.Containers {
+			names = append(names, to.String(container.Name))
+		}
+	}
+	if len(names) != 1 {
+		return "", errors.Errorf("container group %s has %d containers %v, one of which must be given", containerGroupName, len(names), names)
+	}
+	return names[0], nil
+}
+
+func isNotFound(err error) bool {
+	var de autorest.DetailedError
+	if errors.As(err, &de) {
+		return de.StatusCode == http.StatusNotFound
+	}
+	return false
+}
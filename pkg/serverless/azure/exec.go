@@ -0,0 +1,102 @@
+package azure
+
+import (
+	"context"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/containerinstance/mgmt/containerinstance"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+// execOrigin is the origin of the exec websocket, which azure does not check
+const execOrigin = "http://localhost/"
+
+// ExecRequest is a request to execute a command in a container of a container group
+type ExecRequest struct {
+	ResourceGroupName  string
+	ContainerGroupName string
+	// ContainerName defaults to the only container of the container group
+	ContainerName string
+	// Command is the command to execute, such as /bin/sh, which azure does not split into arguments
+	Command string
+	// Rows and Cols are the size of the terminal of the command
+	Rows int
+	Cols int
+}
+
+// Exec will execute a command in a container of a container group, over the websocket returned by azure, writing
+// stdin to the command, and the output of the command to stdout, until the command exits or the context is done
+func (c *Client) Exec(ctx context.Context, req ExecRequest, stdin io.Reader, stdout io.Writer) error {
+	name, err := c.ContainerName(ctx, req.ResourceGroupName, req.ContainerGroupName, req.ContainerName)
+	if err != nil {
+		return err
+	}
+	resp, err := c.ctrClient.ExecuteCommand(ctx, req.ResourceGroupName, req.ContainerGroupName, name, containerinstance.ContainerExecRequest{
+		Command: to.StringPtr(req.Command),
+		TerminalSize: &containerinstance.ContainerExecRequestTerminalSize{
+			Rows: to.Int32Ptr(int32(req.Rows)),
+			Cols: to.Int32Ptr(int32(req.Cols)),
+		},
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return errors.Wrapf(err, "failed to execute command in container %s of container group %s", name, req.ContainerGroupName)
+	}
+	ws, err := websocket.Dial(to.String(resp.WebSocketURI), "", execOrigin)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to container %s of container group %s", name, req.ContainerGroupName)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		ws.Close()
+	}()
+	// the first message authenticates the websocket
+	if err = websocket.Message.Send(ws, to.String(resp.Password)); err != nil {
+		return errors.Wrap(err, "failed to authenticate exec websocket")
+	}
+	if stdin != nil {
+		go sendStdin(ws, stdin, done)
+	}
+	for {
+		var msg []byte
+		if err = websocket.Message.Receive(ws, &msg); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.Wrap(err, "failed to receive command output")
+		}
+		if _, err = stdout.Write(msg); err != nil {
+			return errors.Wrap(err, "failed to write command output")
+		}
+	}
+}
+
+// sendStdin will send what is read from stdin to the command, until either stdin or the websocket is closed
+func sendStdin(ws *websocket.Conn, stdin io.Reader, done <-chan struct{}) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if werr := websocket.Message.Send(ws, string(buf[:n])); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
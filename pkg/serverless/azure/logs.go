@@ -0,0 +1,95 @@
+package azure
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pkg/errors"
+)
+
+// logsOverlap is how much of the end of the logs already written is searched for in newly polled logs, to find
+// where the new lines begin once azure has truncated the start of the logs
+const logsOverlap = 1024
+
+// LogsRequest is a request for the logs of a container of a container group
+type LogsRequest struct {
+	ResourceGroupName  string
+	ContainerGroupName string
+	// ContainerName defaults to the only container of the container group
+	ContainerName string
+	// Tail is the number of lines from the end of the logs to return, or all of them when zero
+	Tail int
+	// Timestamps prefixes every line with the time it was written
+	Timestamps bool
+}
+
+// ContainerLogs will return the logs of a container of a container group
+func (c *Client) ContainerLogs(ctx context.Context, req LogsRequest) (string, error) {
+	name, err := c.ContainerName(ctx, req.ResourceGroupName, req.ContainerGroupName, req.ContainerName)
+	if err != nil {
+		return "", err
+	}
+	var tail *int32
+	if req.Tail > 0 {
+		tail = to.Int32Ptr(int32(req.Tail))
+	}
+	logs, err := c.ctrClient.ListLogs(ctx, req.ResourceGroupName, req.ContainerGroupName, name, tail, to.BoolPtr(req.Timestamps))
+	if err != nil {
+		if isNotFound(err) {
+			return "", ErrNotFound
+		}
+		return "", errors.Wrapf(err, "failed to get logs of container %s of container group %s", name, req.ContainerGroupName)
+	}
+	return to.String(logs.Content), nil
+}
+
+// FollowContainerLogs will write the logs of a container of a container group to w, and then poll for, and write,
+// the lines written since at every interval, until the context is done.  Azure only returns the logs written so
+// far, so lines written and truncated between two polls are lost.
+func (c *Client) FollowContainerLogs(ctx context.Context, req LogsRequest, interval time.Duration, w io.Writer) error {
+	name, err := c.ContainerName(ctx, req.ResourceGroupName, req.ContainerGroupName, req.ContainerName)
+	if err != nil {
+		return err
+	}
+	req.ContainerName = name
+	written := ""
+	for {
+		logs, err := c.ContainerLogs(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if _, err = io.WriteString(w, newLogs(written, logs)); err != nil {
+			return errors.Wrap(err, "failed to write logs")
+		}
+		written = logs
+		// only the first poll is limited to the tail, later polls must overlap what was written
+		req.Tail = 0
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// newLogs will return the part of logs following the logs written before, or all of logs when the container was
+// restarted, and none of the logs written before remain
+func newLogs(written, logs string) string {
+	if strings.HasPrefix(logs, written) {
+		return logs[len(written):]
+	}
+	end := written
+	if len(end) > logsOverlap {
+		end = end[len(end)-logsOverlap:]
+	}
+	if i := strings.LastIndex(logs, end); end != "" && i >= 0 {
+		return logs[i+len(end):]
+	}
+	return logs
+}
@@ -3,12 +3,24 @@ package google
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	google_auth "github.com/naemono/go-cloud-actions/pkg/auth/google"
+	"github.com/naemono/go-cloud-actions/pkg/tags"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/container/v1"
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	// ErrNotFound is the error when a cluster cannot be found
+	ErrNotFound = errors.New("cluster not found")
 )
 
 // ClusterCommon are the common fields between cluster operations
@@ -17,13 +29,60 @@ type ClusterCommon struct {
 	NetworkName string
 }
 
+// ClusterMode is the mode of operation of a gke cluster
+type ClusterMode string
+
+const (
+	// ModeAutopilot clusters have their nodes managed by google
+	ModeAutopilot ClusterMode = "autopilot"
+	// ModeStandard clusters have node pools managed with the cluster
+	ModeStandard ClusterMode = "standard"
+
+	// defaultVersion is the kubernetes version of clusters and node pools created without one
+	defaultVersion = "latest"
+	// defaultNodePoolName is the name of the node pool of standard clusters
+	defaultNodePoolName = "default-pool"
+)
+
 // CreateClusterRequest is a request to create a gke cluster
 type CreateClusterRequest struct {
 	ClusterCommon
+	// Subnetwork is the name of the subnetwork of NetworkName holding the nodes of the cluster, which must be in
+	// the cluster's region, the network's automatically created one when empty
+	Subnetwork string
+	// ClusterIpv4Cidr is the cidr of the pods of the cluster, when no PodsRange is given
 	ClusterIpv4Cidr string
-	Description     string
-	Location        string
-	Name            string
+	// PodsRange and ServicesRange are the ranges of the pods and services of the cluster, either the name of an
+	// existing secondary range of the subnetwork, or the cidr, or only the netmask such as /14, of a secondary range
+	// created for the cluster.  The ranges are chosen by gke when empty.
+	PodsRange     string
+	ServicesRange string
+	Description   string
+	Location      string
+	Name          string
+	// Tags are applied to the cluster as its resource labels
+	Tags tags.Tags
+	// Mode is the mode of the cluster, autopilot when empty
+	Mode ClusterMode
+	// Version is the initial kubernetes version of the cluster, the latest when empty
+	Version string
+	// NodePool is the default node pool of standard clusters
+	NodePool NodePoolConfig
+	// Private makes the nodes, and optionally the endpoint, of the cluster private
+	Private PrivateConfig
+	// MasterAuthorizedNetworks are the only cidr blocks allowed to reach the endpoint of the cluster, when any are
+	// given
+	MasterAuthorizedNetworks []string
+}
+
+// PrivateConfig is the private cluster configuration of a gke cluster
+type PrivateConfig struct {
+	// Nodes gives the nodes of the cluster internal ips only
+	Nodes bool
+	// Endpoint only exposes the internal ip of the cluster's endpoint, and requires Nodes
+	Endpoint bool
+	// MasterIpv4CidrBlock is the /28 block of the masters of a cluster with private nodes
+	MasterIpv4CidrBlock string
 }
 
 // Config is an google peering config
@@ -36,6 +95,7 @@ type Config struct {
 type Client struct {
 	Config
 	containersClient *container.ProjectsService
+	igmClient        *compute.InstanceGroupManagersService
 }
 
 // New will return a new google serverless client
@@ -46,9 +106,14 @@ func New(conf Config) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	igmClient, err := google_auth.NewInstanceGroupManagersClient(ctx, conf.AuthConfig)
+	if err != nil {
+		return nil, err
+	}
 	client := &Client{
 		Config:           conf,
 		containersClient: containersClient,
+		igmClient:        igmClient,
 	}
 	if client.Logger == nil {
 		client.Logger = logrus.NewEntry(logrus.New())
@@ -58,26 +123,227 @@ func New(conf Config) (*Client, error) {
 	return client, nil
 }
 
-// CreateCluster will create a google gke cluster within a given region
-func (c *Client) CreateCluster(ctx context.Context, req CreateClusterRequest) error {
-	parent := fmt.Sprintf("projects/%s/locations/%s", req.ProjectID, req.Location)
-	_, err := c.containersClient.Locations.Clusters.Create(parent, &container.CreateClusterRequest{
-		Cluster: &container.Cluster{
-			Autopilot: &container.Autopilot{
-				Enabled: true,
-			},
-			ClusterIpv4Cidr:       req.ClusterIpv4Cidr,
-			Description:           req.Description,
-			InitialClusterVersion: "latest",
-			IpAllocationPolicy:    &container.IPAllocationPolicy{},
-			Location:              req.Location,
-			Name:                  req.Name,
-		},
-		Parent: parent,
-	}).Do()
+// CreateCluster will request the creation of a google gke cluster within a given region, returning the operation
+// creating it, which can be waited for with WaitForOperation
+func (c *Client) CreateCluster(ctx context.Context, req CreateClusterRequest) (*container.Operation, error) {
+	cluster, err := newCluster(req)
+	if err != nil {
+		return nil, err
+	}
+	parent := locationName(req.ProjectID, req.Location)
+	op, err := c.containersClient.Locations.Clusters.Create(parent, &container.CreateClusterRequest{
+		Cluster: cluster,
+		Parent:  parent,
+	}).Context(ctx).Do()
 	if err != nil {
-		return errors.Wrap(err, "failed to create cluster")
+		return nil, errors.Wrap(err, "failed to create cluster")
+	}
+	c.Logger.Infof("cluster %s creation requested", req.Name)
+	return op, nil
+}
+
+// newCluster will return the cluster of a create cluster request
+func newCluster(req CreateClusterRequest) (*container.Cluster, error) {
+	version := req.Version
+	if version == "" {
+		version = defaultVersion
+	}
+	policy, err := ipAllocationPolicy(req)
+	if err != nil {
+		return nil, err
+	}
+	cluster := &container.Cluster{
+		Description:           req.Description,
+		InitialClusterVersion: version,
+		IpAllocationPolicy:    policy,
+		Location:              req.Location,
+		Name:                  req.Name,
+		Network:               req.NetworkName,
+		Subnetwork:            req.Subnetwork,
+		ResourceLabels:        req.Tags.Labels(),
+	}
+	switch req.Mode {
+	case ModeAutopilot, "":
+		if req.NodePool != (NodePoolConfig{}) {
+			return nil, errors.New("the nodes of autopilot clusters are managed by google, and cannot be configured")
+		}
+		cluster.Autopilot = &container.Autopilot{Enabled: true}
+	case ModeStandard:
+		pool, err := newNodePool(defaultNodePoolName, "", req.NodePool)
+		if err != nil {
+			return nil, err
+		}
+		cluster.NodePools = []*container.NodePool{pool}
+	default:
+		return nil, errors.Errorf("invalid cluster mode %q, must be one of %s, %s", req.Mode, ModeAutopilot, ModeStandard)
+	}
+	if req.Private.Endpoint && !req.Private.Nodes {
+		return nil, errors.New("a private endpoint requires private nodes")
+	}
+	if req.Private.Nodes {
+		if req.Private.MasterIpv4CidrBlock == "" && req.Mode == ModeStandard {
+			return nil, errors.New("private nodes of standard clusters require a master ipv4 cidr block")
+		}
+		cluster.PrivateClusterConfig = &container.PrivateClusterConfig{
+			EnablePrivateNodes:    true,
+			EnablePrivateEndpoint: req.Private.Endpoint,
+			MasterIpv4CidrBlock:   req.Private.MasterIpv4CidrBlock,
+		}
+	}
+	if len(req.MasterAuthorizedNetworks) > 0 {
+		cluster.MasterAuthorizedNetworksConfig = authorizedNetworks(req.MasterAuthorizedNetworks)
+	}
+	return cluster, nil
+}
+
+// ipAllocationPolicy will return the vpc native ip allocation policy of the pods and services ranges of a create
+// cluster request, whose pods range is its cluster cidr when not given
+func ipAllocationPolicy(req CreateClusterRequest) (*container.IPAllocationPolicy, error) {
+	pods := req.PodsRange
+	if pods == "" {
+		pods = req.ClusterIpv4Cidr
+	} else if req.ClusterIpv4Cidr != "" && req.ClusterIpv4Cidr != pods {
+		return nil, errors.Errorf("the cluster cidr %s and pods range %s cannot both be given", req.ClusterIpv4Cidr, pods)
+	}
+	policy := &container.IPAllocationPolicy{
+		// vpc native clusters are only the default of autopilot clusters
+		UseIpAliases: true,
+	}
+	if IsCIDR(pods) {
+		policy.ClusterIpv4CidrBlock = pods
+	} else {
+		policy.ClusterSecondaryRangeName = pods
+	}
+	if IsCIDR(req.ServicesRange) {
+		policy.ServicesIpv4CidrBlock = req.ServicesRange
+	} else {
+		policy.ServicesSecondaryRangeName = req.ServicesRange
+	}
+	if (policy.ClusterSecondaryRangeName == "") != (policy.ServicesSecondaryRangeName == "") {
+		return nil, errors.New("the pods and services ranges must either both be secondary range names, or both be created")
+	}
+	if policy.ClusterSecondaryRangeName != "" && req.Subnetwork == "" {
+		return nil, errors.New("secondary range names require the subnetwork holding them")
+	}
+	return policy, nil
+}
+
+// IsCIDR will return whether a range is a cidr, or only the netmask of one, rather than the name of a secondary range
+func IsCIDR(r string) bool {
+	if strings.HasPrefix(r, "/") {
+		_, err := strconv.Atoi(r[1:])
+		return err == nil
+	}
+	_, _, err := net.ParseCIDR(r)
+	return err == nil
+}
+
+// authorizedNetworks will return the master authorized networks config allowing only the given cidr blocks
+func authorizedNetworks(cidrs []string) *container.MasterAuthorizedNetworksConfig {
+	config := &container.MasterAuthorizedNetworksConfig{Enabled: true}
+	for _, cidr := range cidrs {
+		config.CidrBlocks = append(config.CidrBlocks, &container.CidrBlock{CidrBlock: cidr})
+	}
+	return config
+}
+
+// ListClusters will list the gke clusters of a location, or of every location when it is "-"
+func (c *Client) ListClusters(ctx context.Context, projectID, location string) ([]*container.Cluster, error) {
+	resp, err := c.containersClient.Locations.Clusters.List(locationName(projectID, location)).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list clusters of project %s", projectID)
+	}
+	if len(resp.MissingZones) > 0 {
+		c.Logger.Warnf("clusters of zones %v could not be listed", resp.MissingZones)
+	}
+	return resp.Clusters, nil
+}
+
+// UpgradeCluster will request the upgrade of the masters of a gke cluster to the given kubernetes version, or the
+// latest when empty, returning the operation upgrading it.  The node pools of standard clusters are upgraded
+// separately with UpgradeNodePool.
+func (c *Client) UpgradeCluster(ctx context.Context, projectID, location, name, version string) (*container.Operation, error) {
+	if version == "" {
+		version = defaultVersion
+	}
+	op, err := c.containersClient.Locations.Clusters.Update(clusterName(projectID, location, name), &container.UpdateClusterRequest{
+		Update: &container.ClusterUpdate{DesiredMasterVersion: version},
+	}).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to upgrade cluster %s", name)
+	}
+	c.Logger.Infof("cluster %s upgrade to %s requested", name, version)
+	return op, nil
+}
+
+// ResolveMasterVersion will resolve a kubernetes version of the masters of clusters, such as latest, - for the
+// default version, or a version prefix such as 1.19, into the concrete version gke would upgrade to, from the
+// versions valid in the location, newest first
+func (c *Client) ResolveMasterVersion(ctx context.Context, projectID, location, version string) (string, error) {
+	config, err := c.containersClient.Locations.GetServerConfig(locationName(projectID, location)).Context(ctx).Do()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the server config of location %s", location)
+	}
+	switch version {
+	case "", defaultVersion:
+		if len(config.ValidMasterVersions) > 0 {
+			return config.ValidMasterVersions[0], nil
+		}
+	case "-":
+		if config.DefaultClusterVersion != "" {
+			return config.DefaultClusterVersion, nil
+		}
+	default:
+		for _, valid := range config.ValidMasterVersions {
+			if valid == version || strings.HasPrefix(valid, version+".") || strings.HasPrefix(valid, version+"-") {
+				return valid, nil
+			}
+		}
+	}
+	return "", errors.Errorf("version %s is not a valid master version in location %s", version, location)
+}
+
+// GetCluster will get a single gke cluster, returning ErrNotFound if it does not exist
+func (c *Client) GetCluster(ctx context.Context, projectID, location, name string) (*container.Cluster, error) {
+	cluster, err := c.containersClient.Locations.Clusters.Get(clusterName(projectID, location, name)).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get cluster %s", name)
+	}
+	return cluster, nil
+}
+
+// DeleteCluster will request the deletion of a gke cluster, returning the operation deleting it, which continues in
+// the background.  A cluster which does not exist is not an error, and has no operation.
+func (c *Client) DeleteCluster(ctx context.Context, projectID, location, name string) (*container.Operation, error) {
+	op, err := c.containersClient.Locations.Clusters.Delete(clusterName(projectID, location, name)).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to delete cluster %s", name)
+	}
+	c.Logger.Infof("cluster %s deletion requested", name)
+	return op, nil
+}
+
+func locationName(projectID, location string) string {
+	return fmt.Sprintf("projects/%s/locations/%s", projectID, location)
+}
+
+func clusterName(projectID, location, name string) string {
+	return fmt.Sprintf("%s/clusters/%s", locationName(projectID, location), name)
+}
+
+func isNotFound(err error) bool {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return gerr.Code == http.StatusNotFound
 	}
-	c.Logger.Infof("cluster created succesfully")
-	return nil
+	return false
 }
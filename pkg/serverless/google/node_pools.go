@@ -0,0 +1,187 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/container/v1"
+)
+
+// NodePoolConfig is the configuration of the nodes of a gke node pool
+type NodePoolConfig struct {
+	// NodeCount is the initial number of nodes of the node pool in each of the cluster's zones
+	NodeCount   int
+	MachineType string
+	DiskSizeGB  int
+	// MinNodes and MaxNodes autoscale the node pool between them, when MaxNodes is given
+	MinNodes int
+	MaxNodes int
+}
+
+// NodePoolRequest is a request to create a node pool of a standard gke cluster
+type NodePoolRequest struct {
+	ProjectID   string
+	Location    string
+	ClusterName string
+	Name        string
+	NodePoolConfig
+	// Version is the kubernetes version of the nodes, that of the cluster's masters when empty
+	Version string
+}
+
+// newNodePool will return a named node pool of the given config and version
+func newNodePool(name, version string, config NodePoolConfig) (*container.NodePool, error) {
+	if config.NodeCount < 0 || config.MinNodes < 0 || config.MaxNodes < config.MinNodes {
+		return nil, errors.Errorf("invalid node counts of node pool %s, must be non negative, with a minimum no greater than the maximum", name)
+	}
+	pool := &container.NodePool{
+		Name:             name,
+		InitialNodeCount: int64(config.NodeCount),
+		Version:          version,
+		Config: &container.NodeConfig{
+			MachineType: config.MachineType,
+			DiskSizeGb:  int64(config.DiskSizeGB),
+		},
+	}
+	if config.MaxNodes > 0 {
+		pool.Autoscaling = &container.NodePoolAutoscaling{
+			Enabled:      true,
+			MinNodeCount: int64(config.MinNodes),
+			MaxNodeCount: int64(config.MaxNodes),
+		}
+	}
+	return pool, nil
+}
+
+// CreateNodePool will request the creation of a node pool of a standard gke cluster, returning the operation
+// creating it
+func (c *Client) CreateNodePool(ctx context.Context, req NodePoolRequest) (*container.Operation, error) {
+	pool, err := newNodePool(req.Name, req.Version, req.NodePoolConfig)
+	if err != nil {
+		return nil, err
+	}
+	parent := clusterName(req.ProjectID, req.Location, req.ClusterName)
+	op, err := c.containersClient.Locations.Clusters.NodePools.Create(parent, &container.CreateNodePoolRequest{
+		NodePool: pool,
+		Parent:   parent,
+	}).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to create node pool %s of cluster %s", req.Name, req.ClusterName)
+	}
+	c.Logger.Infof("node pool %s of cluster %s creation requested", req.Name, req.ClusterName)
+	return op, nil
+}
+
+// GetNodePool will get a single node pool of a gke cluster, returning ErrNotFound if it does not exist
+func (c *Client) GetNodePool(ctx context.Context, projectID, location, cluster, name string) (*container.NodePool, error) {
+	pool, err := c.containersClient.Locations.Clusters.NodePools.Get(nodePoolName(projectID, location, cluster, name)).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get node pool %s of cluster %s", name, cluster)
+	}
+	return pool, nil
+}
+
+// ListNodePools will list the node pools of a gke cluster
+func (c *Client) ListNodePools(ctx context.Context, projectID, location, cluster string) ([]*container.NodePool, error) {
+	resp, err := c.containersClient.Locations.Clusters.NodePools.List(clusterName(projectID, location, cluster)).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to list node pools of cluster %s", cluster)
+	}
+	return resp.NodePools, nil
+}
+
+// NodePoolSizes will return the current number of nodes of a node pool in each of its zones, which are the target
+// sizes of its instance group managers, as the initial node count of the pool is not changed by resizes
+func (c *Client) NodePoolSizes(ctx context.Context, pool *container.NodePool) ([]int64, error) {
+	sizes := make([]int64, 0, len(pool.InstanceGroupUrls))
+	for _, url := range pool.InstanceGroupUrls {
+		project, zone, name, err := instanceGroupManager(url)
+		if err != nil {
+			return nil, err
+		}
+		igm, err := c.igmClient.Get(project, zone, name).Context(ctx).Do()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get instance group manager %s of node pool %s", name, pool.Name)
+		}
+		sizes = append(sizes, igm.TargetSize)
+	}
+	return sizes, nil
+}
+
+// instanceGroupManager will return the project, zone and name of the instance group manager of an instance group
+// url, such as https://www.googleapis.com/compute/v1/projects/{project}/zones/{zone}/instanceGroupManagers/{name}
+func instanceGroupManager(url string) (string, string, string, error) {
+	parts := strings.Split(url, "/")
+	for i := 0; i+5 < len(parts); i++ {
+		if parts[i] == "projects" && parts[i+2] == "zones" && parts[i+4] == "instanceGroupManagers" {
+			return parts[i+1], parts[i+3], parts[i+5], nil
+		}
+	}
+	return "", "", "", errors.Errorf("invalid instance group url %s", url)
+}
+
+// ResizeNodePool will request the resize of a node pool of a gke cluster to the given number of nodes in each of the
+// cluster's zones, returning the operation resizing it
+func (c *Client) ResizeNodePool(ctx context.Context, projectID, location, cluster, name string, nodeCount int) (*container.Operation, error) {
+	op, err := c.containersClient.Locations.Clusters.NodePools.SetSize(nodePoolName(projectID, location, cluster, name), &container.SetNodePoolSizeRequest{
+		NodeCount: int64(nodeCount),
+		// zero nodes must be sent explicitly
+		ForceSendFields: []string{"NodeCount"},
+	}).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to resize node pool %s of cluster %s", name, cluster)
+	}
+	c.Logger.Infof("node pool %s of cluster %s resize to %d nodes requested", name, cluster, nodeCount)
+	return op, nil
+}
+
+// UpgradeNodePool will request the upgrade of the nodes of a node pool of a gke cluster to the given kubernetes
+// version, or that of the cluster's masters when empty, returning the operation upgrading it
+func (c *Client) UpgradeNodePool(ctx context.Context, projectID, location, cluster, name, version string) (*container.Operation, error) {
+	if version == "" {
+		version = "-"
+	}
+	op, err := c.containersClient.Locations.Clusters.NodePools.Update(nodePoolName(projectID, location, cluster, name), &container.UpdateNodePoolRequest{
+		NodeVersion: version,
+	}).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to upgrade node pool %s of cluster %s", name, cluster)
+	}
+	c.Logger.Infof("node pool %s of cluster %s upgrade requested", name, cluster)
+	return op, nil
+}
+
+// DeleteNodePool will request the deletion of a node pool of a gke cluster, returning the operation deleting it.  A
+// node pool which does not exist is not an error, and has no operation.
+func (c *Client) DeleteNodePool(ctx context.Context, projectID, location, cluster, name string) (*container.Operation, error) {
+	op, err := c.containersClient.Locations.Clusters.NodePools.Delete(nodePoolName(projectID, location, cluster, name)).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to delete node pool %s of cluster %s", name, cluster)
+	}
+	c.Logger.Infof("node pool %s of cluster %s deletion requested", name, cluster)
+	return op, nil
+}
+
+func nodePoolName(projectID, location, cluster, name string) string {
+	return fmt.Sprintf("%s/nodePools/%s", clusterName(projectID, location, cluster), name)
+}
@@ -0,0 +1,92 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/container/v1"
+)
+
+const (
+	// operationPollInterval is how often operations are polled while waiting for them to be done
+	operationPollInterval = 10 * time.Second
+	// operationDone is the status of finished operations
+	operationDone = "DONE"
+)
+
+// WaitForOperation will poll an operation of a location until it is done, logging its progress, and return it.  An
+// operation which finished with an error is an error.  No operation, such as the deletion of a cluster which did not
+// exist, is immediately done.
+func (c *Client) WaitForOperation(ctx context.Context, projectID, location string, op *container.Operation) (*container.Operation, error) {
+	if op == nil {
+		return nil, nil
+	}
+	name := fmt.Sprintf("%s/operations/%s", locationName(projectID, location), op.Name)
+	ticker := time.NewTicker(operationPollInterval)
+	defer ticker.Stop()
+	logged := ""
+	for {
+		if progress := operationProgress(op); progress != logged {
+			c.Logger.Infof("operation %s %s of %s is %s", op.Name, strings.ToLower(op.OperationType), targetName(op), progress)
+			logged = progress
+		}
+		if op.Status == operationDone {
+			if op.StatusMessage != "" {
+				return op, errors.Errorf("operation %s %s of %s failed: %s", op.Name, strings.ToLower(op.OperationType), targetName(op), op.StatusMessage)
+			}
+			return op, nil
+		}
+		select {
+		case <-ctx.Done():
+			return op, errors.Wrapf(ctx.Err(), "timed out waiting for operation %s", op.Name)
+		case <-ticker.C:
+		}
+		next, err := c.containersClient.Locations.Operations.Get(name).Context(ctx).Do()
+		if err != nil {
+			return op, errors.Wrapf(err, "failed to get operation %s", op.Name)
+		}
+		op = next
+	}
+}
+
+// operationProgress will return the status of an operation, along with its running stage and metrics when known
+func operationProgress(op *container.Operation) string {
+	progress := strings.ToLower(op.Status)
+	if op.Progress == nil {
+		return progress
+	}
+	for _, stage := range op.Progress.Stages {
+		if stage.Status == "RUNNING" && stage.Name != "" {
+			progress += ": " + strings.ToLower(stage.Name)
+		}
+	}
+	var metrics []string
+	for _, m := range op.Progress.Metrics {
+		switch {
+		case m.StringValue != "":
+			metrics = append(metrics, fmt.Sprintf("%s=%s", strings.ToLower(m.Name), m.StringValue))
+		case m.DoubleValue != 0:
+			metrics = append(metrics, fmt.Sprintf("%s=%g", strings.ToLower(m.Name), m.DoubleValue))
+		default:
+			metrics = append(metrics, fmt.Sprintf("%s=%d", strings.ToLower(m.Name), m.IntValue))
+		}
+	}
+	if len(metrics) > 0 {
+		progress += " (" + strings.Join(metrics, ", ") + ")"
+	}
+	return progress
+}
+
+// targetName will return the last segments of the target of an operation, such as clusters/name
+func targetName(op *container.Operation) string {
+	segments := strings.Split(op.TargetLink, "/")
+	for i, segment := range segments {
+		if segment == "clusters" {
+			return strings.Join(segments[i:], "/")
+		}
+	}
+	return op.TargetLink
+}
@@ -0,0 +1,148 @@
+// Package tags holds the tags applied to every created resource, as aws tags, azure tags, and google labels
+package tags
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// KeyOwner is the key of the default tag naming the owner of a resource
+	KeyOwner = "owner"
+	// KeyCreatedBy is the key of the default tag naming the tool which created a resource
+	KeyCreatedBy = "created-by"
+	// KeyCreatedAt is the key of the default tag holding the RFC 3339 time at which a resource was created
+	KeyCreatedAt = "created-at"
+	// CreatedBy is the value of the created-by default tag
+	CreatedBy = "go-cloud-actions"
+
+	// maxLabelLength is the maximum length of the keys and values of google labels
+	maxLabelLength = 63
+)
+
+// Tags are the key/value tags of a resource
+type Tags map[string]string
+
+// Parse will parse tags from key=value pairs, such as given to a flag
+func Parse(pairs []string) (Tags, error) {
+	tags := Tags{}
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, errors.Errorf("invalid tag %q, must be key=value", pair)
+		}
+		tags[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return tags, nil
+}
+
+// ReadFile will read tags from a yaml or json file holding a single map of keys to values
+func ReadFile(path string) (Tags, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read tags file %s", path)
+	}
+	tags := Tags{}
+	if err = yaml.Unmarshal(b, &tags); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse tags file %s, which must be a map of keys to values", path)
+	}
+	return tags, nil
+}
+
+// Defaults will return the default tags of a resource created by owner at the given time
+func Defaults(owner string, at time.Time) Tags {
+	tags := Tags{
+		KeyCreatedBy: CreatedBy,
+		KeyCreatedAt: at.UTC().Format(time.RFC3339),
+	}
+	if owner != "" {
+		tags[KeyOwner] = owner
+	}
+	return tags
+}
+
+// Merge will merge tags, with the values of later tags replacing those of earlier tags
+func Merge(tags ...Tags) Tags {
+	merged := Tags{}
+	for _, t := range tags {
+		for k, v := range t {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Keys will return the keys of the tags in order
+func (t Tags) Keys() []string {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AWS will return the tags as aws ec2 tags, in order of their keys
+func (t Tags) AWS() []types.Tag {
+	tags := make([]types.Tag, 0, len(t))
+	for _, k := range t.Keys() {
+		tags = append(tags, types.Tag{Key: to.StringPtr(k), Value: to.StringPtr(t[k])})
+	}
+	return tags
+}
+
+// Azure will return the tags as azure resource tags
+func (t Tags) Azure() map[string]*string {
+	if len(t) == 0 {
+		return nil
+	}
+	tags := make(map[string]*string, len(t))
+	for k, v := range t {
+		tags[k] = to.StringPtr(v)
+	}
+	return tags
+}
+
+// Labels will return the tags as google labels, whose keys and values may only hold lowercase letters, digits,
+// underscores and dashes, up to 63 characters, and whose keys must start with a letter.  Invalid characters are
+// replaced with underscores, and keys not starting with a letter are dropped.
+func (t Tags) Labels() map[string]string {
+	if len(t) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(t))
+	for k, v := range t {
+		key := label(k)
+		if key == "" || !unicode.IsLetter(rune(key[0])) {
+			continue
+		}
+		labels[key] = label(v)
+	}
+	return labels
+}
+
+func label(s string) string {
+	s = strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+	if len(s) > maxLabelLength {
+		s = s[:maxLabelLength]
+	}
+	return s
+}